@@ -88,6 +88,15 @@ func NewCloudWatchLogsServiceWithCredentials(id, secret string) *CloudWatchLogsS
 	return &cloudWatchLogsService
 }
 
+// NewCloudWatchLogsServiceWithClient Creates a new instance of the CloudWatchLogsService calling the given client
+func NewCloudWatchLogsServiceWithClient(client cloudwatchlogsinterface.CloudWatchLogsClient) *CloudWatchLogsService {
+	cloudWatchLogsService := CloudWatchLogsService{
+		cloudWatchLogsClient: client,
+		stopPolicy:           createCloudWatchStopPolicy(),
+	}
+	return &cloudWatchLogsService
+}
+
 // CreateNewServiceIfUnHealthy checks service healthy and create new service if original is unhealthy
 func (service *CloudWatchLogsService) CreateNewServiceIfUnHealthy() {
 	if service.stopPolicy == nil {
@@ -116,10 +116,20 @@ func DefaultConfig() SsmagentConfig {
 		CustomInventoryDefaultLocation:        DefaultCustomInventoryFolder,
 		AssociationLogsRetentionDurationHours: DefaultAssociationLogsRetentionDurationHours,
 		RunCommandLogsRetentionDurationHours:  DefaultRunCommandLogsRetentionDurationHours,
+		AssociationWorkersLimit:               DefaultAssociationWorkersLimit,
+		AssociationMaxSplaySeconds:            DefaultAssociationMaxSplaySeconds,
+		AssociationMissedRunPolicy:            DefaultAssociationMissedRunPolicy,
+		AssociationOutsideWindowPolicy:        DefaultAssociationOutsideWindowPolicy,
+		PluginStdoutMaxLength:                 MaxStdoutLength,
+		PluginStderrMaxLength:                 MaxStderrLength,
+		PluginOutputTruncation:                DefaultPluginOutputTruncation,
+		OutputSinks:                           DefaultOutputSinks(),
 	}
 	var agent = AgentInfo{
-		Name:                 "amazon-ssm-agent",
-		OrchestrationRootDir: defaultOrchestrationRootDirName,
+		Name:                               "amazon-ssm-agent",
+		OrchestrationRootDir:               defaultOrchestrationRootDirName,
+		LocalCommandNotifierTimeoutSeconds: DefaultLocalCommandNotifierTimeoutSeconds,
+		TrustedKeysDir:                     DefaultTrustedKeysDir,
 	}
 	var os = OsInfo{
 		Lang:    "en-US",
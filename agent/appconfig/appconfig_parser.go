@@ -28,6 +28,14 @@ func parser(config *SsmagentConfig) {
 	config.Agent.Name = getStringValue(config.Agent.Name, DefaultAgentName)
 	config.Agent.OrchestrationRootDir = getStringValue(config.Agent.OrchestrationRootDir, defaultOrchestrationRootDirName)
 	config.Agent.Region = getStringValue(config.Agent.Region, "")
+	config.Agent.LocalCommandNotifierTimeoutSeconds = getNumericValue(
+		config.Agent.LocalCommandNotifierTimeoutSeconds,
+		DefaultLocalCommandNotifierTimeoutSecondsMin,
+		DefaultLocalCommandNotifierTimeoutSecondsMax,
+		DefaultLocalCommandNotifierTimeoutSeconds)
+	config.Agent.AuditTrail = getAuditTrailValue(config.Agent.AuditTrail, "")
+	config.Agent.DocumentSignatureEnforcement = getDocumentSignatureEnforcementValue(config.Agent.DocumentSignatureEnforcement, "")
+	config.Agent.TrustedKeysDir = getStringValue(config.Agent.TrustedKeysDir, DefaultTrustedKeysDir)
 
 	// MDS config
 	config.Mds.CommandWorkersLimit = getNumericValue(
@@ -67,7 +75,65 @@ func parser(config *SsmagentConfig) {
 		config.Ssm.RunCommandLogsRetentionDurationHours,
 		DefaultStateOrchestrationLogsRetentionDurationHoursMin,
 		DefaultRunCommandLogsRetentionDurationHours)
+	config.Ssm.AssociationWorkersLimit = getNumericValueAboveMin(
+		config.Ssm.AssociationWorkersLimit,
+		DefaultAssociationWorkersLimitMin,
+		DefaultAssociationWorkersLimit)
+	config.Ssm.AssociationMaxSplaySeconds = getNumericValue(
+		config.Ssm.AssociationMaxSplaySeconds,
+		DefaultAssociationMaxSplaySecondsMin,
+		DefaultAssociationMaxSplaySecondsMax,
+		DefaultAssociationMaxSplaySeconds)
+	for association, maxSplaySeconds := range config.Ssm.AssociationMaxSplayOverrides {
+		config.Ssm.AssociationMaxSplayOverrides[association] = getNumericValue(
+			maxSplaySeconds,
+			DefaultAssociationMaxSplaySecondsMin,
+			DefaultAssociationMaxSplaySecondsMax,
+			config.Ssm.AssociationMaxSplaySeconds)
+	}
+	config.Ssm.AssociationMissedRunPolicy = getMissedRunPolicyValue(
+		config.Ssm.AssociationMissedRunPolicy,
+		DefaultAssociationMissedRunPolicy)
+	for association, policy := range config.Ssm.AssociationMissedRunPolicyOverrides {
+		config.Ssm.AssociationMissedRunPolicyOverrides[association] = getMissedRunPolicyValue(
+			policy,
+			config.Ssm.AssociationMissedRunPolicy)
+	}
+	config.Ssm.AssociationOutsideWindowPolicy = getOutsideWindowPolicyValue(
+		config.Ssm.AssociationOutsideWindowPolicy,
+		DefaultAssociationOutsideWindowPolicy)
+	for association, policy := range config.Ssm.AssociationOutsideWindowPolicyOverrides {
+		config.Ssm.AssociationOutsideWindowPolicyOverrides[association] = getOutsideWindowPolicyValue(
+			policy,
+			config.Ssm.AssociationOutsideWindowPolicy)
+	}
+	config.Ssm.PluginStdoutMaxLength = getNumericValue(
+		config.Ssm.PluginStdoutMaxLength,
+		PluginOutputMaxLengthMin,
+		PluginOutputMaxLengthMax,
+		MaxStdoutLength)
+	config.Ssm.PluginStderrMaxLength = getNumericValue(
+		config.Ssm.PluginStderrMaxLength,
+		PluginOutputMaxLengthMin,
+		PluginOutputMaxLengthMax,
+		MaxStderrLength)
+	config.Ssm.PluginOutputTruncation = getPluginOutputTruncationValue(
+		config.Ssm.PluginOutputTruncation,
+		DefaultPluginOutputTruncation)
+	config.Ssm.PluginOutputTailLength = getNumericValue(
+		config.Ssm.PluginOutputTailLength,
+		0,
+		PluginOutputMaxLengthMax,
+		0)
+	if len(config.Ssm.OutputSinks) == 0 {
+		config.Ssm.OutputSinks = DefaultOutputSinks()
+	}
 
+	// S3 config
+	config.S3.ServerSideEncryption = getServerSideEncryptionValue(config.S3.ServerSideEncryption, "")
+	if config.S3.ServerSideEncryption != S3ServerSideEncryptionKMS {
+		config.S3.KmsKeyId = ""
+	}
 }
 
 // TODO https://sim.amazon.com/issues/SSM-3439
@@ -91,6 +157,60 @@ func getStringValue(configValue string, defaultValue string) string {
 	return configValue
 }
 
+// getMissedRunPolicyValue returns the default value if config is not a known missed run policy, else the config value
+func getMissedRunPolicyValue(configValue string, defaultValue string) string {
+	switch configValue {
+	case AssociationMissedRunPolicyRunOnce, AssociationMissedRunPolicySkip:
+		return configValue
+	}
+	return defaultValue
+}
+
+// getOutsideWindowPolicyValue returns the default value if config is not a known outside window policy, else the config value
+func getOutsideWindowPolicyValue(configValue string, defaultValue string) string {
+	switch configValue {
+	case AssociationOutsideWindowPolicyDefer, AssociationOutsideWindowPolicySkip:
+		return configValue
+	}
+	return defaultValue
+}
+
+// getPluginOutputTruncationValue returns the default value if config is not a known output truncation, else the config value
+func getPluginOutputTruncationValue(configValue string, defaultValue string) string {
+	switch configValue {
+	case PluginOutputTruncationHead, PluginOutputTruncationHeadAndTail:
+		return configValue
+	}
+	return defaultValue
+}
+
+// getServerSideEncryptionValue returns the default value if config is not a known server-side encryption, else the config value
+func getServerSideEncryptionValue(configValue string, defaultValue string) string {
+	switch configValue {
+	case S3ServerSideEncryptionAES256, S3ServerSideEncryptionKMS:
+		return configValue
+	}
+	return defaultValue
+}
+
+// getAuditTrailValue returns the default value if config is not a known audit trail, else the config value
+func getAuditTrailValue(configValue string, defaultValue string) string {
+	switch configValue {
+	case AuditTrailSyslog, AuditTrailJournald:
+		return configValue
+	}
+	return defaultValue
+}
+
+// getDocumentSignatureEnforcementValue returns the default value if config is not a known enforcement, else the config value
+func getDocumentSignatureEnforcementValue(configValue string, defaultValue string) string {
+	switch configValue {
+	case DocumentSignatureEnforcementLocal, DocumentSignatureEnforcementAll:
+		return configValue
+	}
+	return defaultValue
+}
+
 // getNumericValueAboveMin returns the default if config is below minimum
 func getNumericValueAboveMin(configValue int, minValue int, defaultValue int) int {
 	if configValue < minValue {
@@ -41,6 +41,112 @@ func TestGetStringValue(t *testing.T) {
 	}
 }
 
+// getMissedRunPolicyValue Tests
+
+var (
+	getMissedRunPolicyValueTests = []GetStringValueTest{
+		{"", AssociationMissedRunPolicyRunOnce, AssociationMissedRunPolicyRunOnce},
+		{"Foo", AssociationMissedRunPolicySkip, AssociationMissedRunPolicySkip},
+		{AssociationMissedRunPolicySkip, AssociationMissedRunPolicyRunOnce, AssociationMissedRunPolicySkip},
+	}
+)
+
+func TestGetMissedRunPolicyValue(t *testing.T) {
+	for _, test := range getMissedRunPolicyValueTests {
+		output := getMissedRunPolicyValue(test.Input, test.DefaultValue)
+		assert.Equal(t, test.Output, output)
+	}
+}
+
+// getOutsideWindowPolicyValue Tests
+
+var (
+	getOutsideWindowPolicyValueTests = []GetStringValueTest{
+		{"", AssociationOutsideWindowPolicyDefer, AssociationOutsideWindowPolicyDefer},
+		{"RunOnce", AssociationOutsideWindowPolicySkip, AssociationOutsideWindowPolicySkip},
+		{AssociationOutsideWindowPolicySkip, AssociationOutsideWindowPolicyDefer, AssociationOutsideWindowPolicySkip},
+	}
+)
+
+func TestGetOutsideWindowPolicyValue(t *testing.T) {
+	for _, test := range getOutsideWindowPolicyValueTests {
+		output := getOutsideWindowPolicyValue(test.Input, test.DefaultValue)
+		assert.Equal(t, test.Output, output)
+	}
+}
+
+// getPluginOutputTruncationValue Tests
+
+var (
+	getPluginOutputTruncationValueTests = []GetStringValueTest{
+		{"", PluginOutputTruncationHead, PluginOutputTruncationHead},
+		{"Tail", PluginOutputTruncationHead, PluginOutputTruncationHead},
+		{PluginOutputTruncationHeadAndTail, PluginOutputTruncationHead, PluginOutputTruncationHeadAndTail},
+	}
+)
+
+func TestGetPluginOutputTruncationValue(t *testing.T) {
+	for _, test := range getPluginOutputTruncationValueTests {
+		output := getPluginOutputTruncationValue(test.Input, test.DefaultValue)
+		assert.Equal(t, test.Output, output)
+	}
+}
+
+// getServerSideEncryptionValue Tests
+
+var (
+	getServerSideEncryptionValueTests = []GetStringValueTest{
+		{"", "", ""},
+		{"aws:kms:dsse", "", ""},
+		{S3ServerSideEncryptionAES256, "", S3ServerSideEncryptionAES256},
+		{S3ServerSideEncryptionKMS, S3ServerSideEncryptionAES256, S3ServerSideEncryptionKMS},
+	}
+)
+
+func TestGetServerSideEncryptionValue(t *testing.T) {
+	for _, test := range getServerSideEncryptionValueTests {
+		output := getServerSideEncryptionValue(test.Input, test.DefaultValue)
+		assert.Equal(t, test.Output, output)
+	}
+}
+
+// getAuditTrailValue Tests
+
+var (
+	getAuditTrailValueTests = []GetStringValueTest{
+		{"", "", ""},
+		{"eventlog", "", ""},
+		{AuditTrailSyslog, "", AuditTrailSyslog},
+		{AuditTrailJournald, "", AuditTrailJournald},
+	}
+)
+
+func TestGetAuditTrailValue(t *testing.T) {
+	for _, test := range getAuditTrailValueTests {
+		output := getAuditTrailValue(test.Input, test.DefaultValue)
+		assert.Equal(t, test.Output, output)
+	}
+}
+
+// getDocumentSignatureEnforcementValue Tests
+
+var (
+	getDocumentSignatureEnforcementValueTests = []GetStringValueTest{
+		{"", "", ""},
+		{"None", "", ""},
+		{"local", "", ""},
+		{DocumentSignatureEnforcementLocal, "", DocumentSignatureEnforcementLocal},
+		{DocumentSignatureEnforcementAll, "", DocumentSignatureEnforcementAll},
+	}
+)
+
+func TestGetDocumentSignatureEnforcementValue(t *testing.T) {
+	for _, test := range getDocumentSignatureEnforcementValueTests {
+		output := getDocumentSignatureEnforcementValue(test.Input, test.DefaultValue)
+		assert.Equal(t, test.Output, output)
+	}
+}
+
 //GetDefaultEndpointTests
 
 type GetDefaultEndPointTest struct {
@@ -40,6 +40,67 @@ const (
 	DefaultSsmAssociationFrequencyMinutesMin = 5
 	DefaultSsmAssociationFrequencyMinutesMax = 60
 
+	DefaultAssociationWorkersLimit    = 1
+	DefaultAssociationWorkersLimitMin = 1
+
+	DefaultAssociationMaxSplaySeconds    = 0
+	DefaultAssociationMaxSplaySecondsMin = 0
+	DefaultAssociationMaxSplaySecondsMax = 86400 // splay the scheduled runs over a day at most
+
+	// AssociationMissedRunPolicyRunOnce runs an association once on startup when its scheduled runs were missed
+	AssociationMissedRunPolicyRunOnce = "RunOnce"
+	// AssociationMissedRunPolicySkip skips the missed runs of an association and waits for its next scheduled run
+	AssociationMissedRunPolicySkip = "Skip"
+
+	DefaultAssociationMissedRunPolicy = AssociationMissedRunPolicyRunOnce
+
+	// AssociationOutsideWindowPolicyDefer defers an association run due outside the maintenance windows to the next window opening
+	AssociationOutsideWindowPolicyDefer = "Defer"
+	// AssociationOutsideWindowPolicySkip skips an association run due outside the maintenance windows
+	AssociationOutsideWindowPolicySkip = "Skip"
+
+	DefaultAssociationOutsideWindowPolicy = AssociationOutsideWindowPolicyDefer
+
+	// PluginOutputTruncationHead keeps the beginning of a plugin output longer than its limit
+	PluginOutputTruncationHead = "Head"
+	// PluginOutputTruncationHeadAndTail keeps the beginning and the end of a plugin output longer than its limit
+	PluginOutputTruncationHeadAndTail = "HeadAndTail"
+
+	DefaultPluginOutputTruncation = PluginOutputTruncationHead
+
+	PluginOutputMaxLengthMin = 100
+	PluginOutputMaxLengthMax = 1024 * 1024
+
+	// S3ServerSideEncryptionAES256 encrypts the output uploaded to S3 with keys managed by S3 (SSE-S3)
+	S3ServerSideEncryptionAES256 = "AES256"
+	// S3ServerSideEncryptionKMS encrypts the output uploaded to S3 with a KMS key (SSE-KMS)
+	S3ServerSideEncryptionKMS = "aws:kms"
+
+	// OutputSinkFile writes the output of the plugins to files in the orchestration directory
+	OutputSinkFile = "file"
+	// OutputSinkS3 uploads the output of the plugins to the S3 bucket of the command
+	OutputSinkS3 = "s3"
+	// OutputSinkCloudWatchLogs streams the output of the plugins to the CloudWatch Logs log group of the command
+	OutputSinkCloudWatchLogs = "cloudwatchlogs"
+	// OutputSinkSyslog forwards the output of the plugins to the local syslog daemon
+	OutputSinkSyslog = "syslog"
+	// OutputSinkHttp posts the output of the plugins to Ssm.OutputHttpEndpoint
+	OutputSinkHttp = "http"
+
+	// AuditTrailSyslog writes the audit trail to the local syslog daemon
+	AuditTrailSyslog = "syslog"
+	// AuditTrailJournald writes the audit trail to journald
+	AuditTrailJournald = "journald"
+
+	// DocumentSignatureEnforcementLocal requires a signature for the offline commands and the local associations
+	DocumentSignatureEnforcementLocal = "Local"
+	// DocumentSignatureEnforcementAll requires a signature for every document the agent runs
+	DocumentSignatureEnforcementAll = "All"
+
+	DefaultLocalCommandNotifierTimeoutSeconds    = 60
+	DefaultLocalCommandNotifierTimeoutSecondsMin = 1
+	DefaultLocalCommandNotifierTimeoutSecondsMax = 3600
+
 	//aws-ssm-agent bookkeeping constants
 	DefaultLocationOfPending     = "pending"
 	DefaultLocationOfCurrent     = "current"
@@ -170,3 +231,9 @@ var SupportedDocumentVersions = map[string]struct{}{
 	"2.0.3": {},
 	"2.2":   {},
 }
+
+// DefaultOutputSinks returns the destinations the output of the plugins is written to when none is configured,
+// the output is only uploaded to S3 or streamed to CloudWatch Logs when the command asks for it
+func DefaultOutputSinks() []string {
+	return []string{OutputSinkFile, OutputSinkS3, OutputSinkCloudWatchLogs}
+}
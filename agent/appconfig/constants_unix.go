@@ -23,6 +23,12 @@ const (
 	// AppConfigPath is the path of the AppConfig
 	AppConfigPath = DefaultProgramFolder + AppConfigFileName
 
+	// DefaultTrustedKeysDir is the folder of the public keys the signatures of the documents are verified with
+	DefaultTrustedKeysDir = DefaultProgramFolder + "trustedkeys"
+
+	// PluginPolicyPath is the file of the policy restricting the plugins the documents may run
+	PluginPolicyPath = DefaultProgramFolder + "plugin-policy.json"
+
 	// PackageRoot specifies the directory under which packages will be downloaded and installed
 	PackageRoot = "/var/lib/amazon/ssm/packages"
 
@@ -47,6 +53,27 @@ const (
 	// are moved if the service cannot validate the document (generally impossible via cli)
 	LocalCommandRootInvalid = "/var/lib/amazon/ssm/localcommands/invalid"
 
+	// LocalCommandRootCancel is the directory where requests to cancel locally submitted commands are written
+	LocalCommandRootCancel = "/var/lib/amazon/ssm/localcommands/cancel"
+
+	// LocalApiSocketPath is the path of the unix domain socket the local control api listens on
+	LocalApiSocketPath = "/var/lib/amazon/ssm/ipc/localapi.sock"
+
+	// LocalDocumentRoot specifies the directory of the document library used by the cli to submit commands offline
+	LocalDocumentRoot = "/var/lib/amazon/ssm/localdocuments"
+
+	// LocalAssociationRoot specifies the directory where users can define associations offline
+	LocalAssociationRoot = "/var/lib/amazon/ssm/localassociations"
+
+	// LocalAssociationRootStatus is the directory where the execution status of local associations is recorded
+	LocalAssociationRootStatus = "/var/lib/amazon/ssm/localassociations/status"
+
+	// LocalInstanceIDPath is the file keeping the instance ID the agent assigns itself in offline mode
+	LocalInstanceIDPath = "/var/lib/amazon/ssm/localinstanceid"
+
+	// ExecutionHistoryPath is the file keeping the hash-chained history of the documents executed by the agent
+	ExecutionHistoryPath = "/var/lib/amazon/ssm/history/executions.log"
+
 	// DownloadRoot specifies the directory under which files will be downloaded
 	DownloadRoot = "/var/log/amazon/ssm/download/"
 
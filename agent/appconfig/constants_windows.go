@@ -78,6 +78,12 @@ var DefaultDocumentWorker string
 // AppConfig Path
 var AppConfigPath string
 
+// DefaultTrustedKeysDir is the folder of the public keys the signatures of the documents are verified with
+var DefaultTrustedKeysDir string
+
+// PluginPolicyPath is the file of the policy restricting the plugins the documents may run
+var PluginPolicyPath string
+
 // DefaultDataStorePath represents the directory for storing system data
 var DefaultDataStorePath string
 
@@ -102,6 +108,27 @@ var LocalCommandRootCompleted string
 // are moved if the service cannot validate the document (generally impossible via cli)
 var LocalCommandRootInvalid string
 
+// LocalCommandRootCancel is the directory where requests to cancel locally submitted commands are written
+var LocalCommandRootCancel string
+
+// LocalApiSocketPath is the path of the unix domain socket the local control api listens on
+var LocalApiSocketPath string
+
+// LocalDocumentRoot specifies the directory of the document library used by the cli to submit commands offline
+var LocalDocumentRoot string
+
+// LocalAssociationRoot specifies the directory where users can define associations offline
+var LocalAssociationRoot string
+
+// LocalAssociationRootStatus is the directory where the execution status of local associations is recorded
+var LocalAssociationRootStatus string
+
+// LocalInstanceIDPath is the file keeping the instance ID the agent assigns itself in offline mode
+var LocalInstanceIDPath string
+
+// ExecutionHistoryPath is the file keeping the hash-chained history of the documents executed by the agent
+var ExecutionHistoryPath string
+
 // DefaultPluginPath represents the directory for storing plugins in SSM
 var DefaultPluginPath string
 
@@ -167,6 +194,8 @@ func init() {
 	DefaultDocumentWorker = filepath.Join(DefaultProgramFolder, "ssm-document-worker.exe")
 	ManifestCacheDirectory = filepath.Join(EnvProgramFiles, ManifestCacheFolder)
 	AppConfigPath = filepath.Join(DefaultProgramFolder, AppConfigFileName)
+	DefaultTrustedKeysDir = filepath.Join(DefaultProgramFolder, "TrustedKeys")
+	PluginPolicyPath = filepath.Join(DefaultProgramFolder, "plugin-policy.json")
 	DefaultDataStorePath = filepath.Join(SSMDataPath, "InstanceData")
 	PackageRoot = filepath.Join(SSMDataPath, "Packages")
 	PackageLockRoot = filepath.Join(SSMDataPath, "Locks\\Packages")
@@ -175,6 +204,13 @@ func init() {
 	LocalCommandRootSubmitted = filepath.Join(LocalCommandRoot, "Submitted")
 	LocalCommandRootCompleted = filepath.Join(LocalCommandRoot, "Completed")
 	LocalCommandRootInvalid = filepath.Join(LocalCommandRoot, "Invalid")
+	LocalCommandRootCancel = filepath.Join(LocalCommandRoot, "Cancel")
+	LocalDocumentRoot = filepath.Join(SSMDataPath, "LocalDocuments")
+	LocalApiSocketPath = filepath.Join(SSMDataPath, "IPC", "localapi.sock")
+	LocalAssociationRoot = filepath.Join(SSMDataPath, "LocalAssociations")
+	LocalAssociationRootStatus = filepath.Join(LocalAssociationRoot, "Status")
+	LocalInstanceIDPath = filepath.Join(SSMDataPath, "LocalInstanceID")
+	ExecutionHistoryPath = filepath.Join(SSMDataPath, "History", "Executions.log")
 	DownloadRoot = filepath.Join(temp, SSMFolder, "Download")
 	UpdaterArtifactsRoot = filepath.Join(temp, SSMFolder, "Update")
 	EC2UpdateArtifactsRoot = filepath.Join(EnvWinDir, EC2ConfigServiceFolder, "Update")
@@ -42,6 +42,70 @@ type SsmCfg struct {
 	CustomInventoryDefaultLocation        string
 	AssociationLogsRetentionDurationHours int
 	RunCommandLogsRetentionDurationHours  int
+	// AssociationWorkersLimit is the maximum number of associations running concurrently
+	AssociationWorkersLimit int
+	// AssociationMaxSplaySeconds is the maximum delay of the scheduled association runs, each instance derives
+	// its own delay from its instance ID so that instances sharing a schedule do not run at the same second
+	AssociationMaxSplaySeconds int
+	// AssociationMaxSplayOverrides overrides AssociationMaxSplaySeconds for the associations with the given ID or name
+	AssociationMaxSplayOverrides map[string]int
+	// AssociationMissedRunPolicy tells what to do with the association runs missed while the agent was stopped,
+	// either RunOnce or Skip
+	AssociationMissedRunPolicy string
+	// AssociationMissedRunPolicyOverrides overrides AssociationMissedRunPolicy for the associations with the given ID or name
+	AssociationMissedRunPolicyOverrides map[string]string
+	// AssociationScheduleOptions sets, for an association ID or name, the time zone and the offset in days
+	// the cron schedule expression of the association is evaluated with, instead of UTC without offset
+	AssociationScheduleOptions map[string]ScheduleOptionsCfg
+	// AssociationDependencies lists, for an association ID or name, the IDs or names of the associations
+	// which must succeed before the association runs in each of its schedule cycles
+	AssociationDependencies map[string][]string
+	// AssociationExclusionGroups lists, for a group name, the IDs or names of the associations which never run concurrently
+	AssociationExclusionGroups map[string][]string
+	// AssociationCheckOnly lists the IDs or names of the associations which only check whether the instance complies
+	// with their document, without changing it
+	AssociationCheckOnly []string
+	// AssociationMaintenanceWindows are the recurring windows associations are allowed to run in,
+	// associations run at any time when no window is configured
+	AssociationMaintenanceWindows []MaintenanceWindowCfg
+	// AssociationBlackoutCalendar is the path of a file listing the periods associations must not run in
+	AssociationBlackoutCalendar string
+	// AssociationOutsideWindowPolicy tells what to do with the association runs due outside the maintenance windows,
+	// either Defer or Skip
+	AssociationOutsideWindowPolicy string
+	// AssociationOutsideWindowPolicyOverrides overrides AssociationOutsideWindowPolicy for the associations with the given ID or name
+	AssociationOutsideWindowPolicyOverrides map[string]string
+	// PluginStdoutMaxLength and PluginStderrMaxLength are the number of bytes of the standard output and standard error
+	// of a plugin kept in the command result, the output files always keep the whole output
+	PluginStdoutMaxLength int
+	PluginStderrMaxLength int
+	// PluginOutputTruncation tells which part of a plugin output longer than its limit is kept, either Head or HeadAndTail
+	PluginOutputTruncation string
+	// PluginOutputTailLength is the number of bytes of the limit kept from the end of the output in HeadAndTail mode,
+	// half of the limit when zero
+	PluginOutputTailLength int
+	// OutputSinks names the destinations the output of the plugins is written to, unless the command names its own,
+	// the full output of the offline commands is only kept when the file sink is enabled
+	OutputSinks []string
+	// OutputHttpEndpoint is the local url the http output sink posts the output of the plugins to
+	OutputHttpEndpoint string
+}
+
+// MaintenanceWindowCfg represents a recurring window associations are allowed to run in
+type MaintenanceWindowCfg struct {
+	// Open and Close are the cron expressions of the opening and closing times of the window
+	Open  string
+	Close string
+	// TimeZone is the IANA time zone name the cron expressions are evaluated in, UTC if empty
+	TimeZone string
+}
+
+// ScheduleOptionsCfg represents the optional settings of the cron schedule expression of an association
+type ScheduleOptionsCfg struct {
+	// TimeZone is the IANA time zone name the cron expression is evaluated in, UTC if empty
+	TimeZone string
+	// ScheduleOffset is the number of days to wait after the date and time matched by the cron expression
+	ScheduleOffset int
 }
 
 // AgentInfo represents metadata for amazon-ssm-agent
@@ -51,6 +115,27 @@ type AgentInfo struct {
 	Region               string
 	OrchestrationRootDir string
 	DownloadRootDir      string
+	// OfflineMode runs the agent without any AWS service, it assigns itself a stable local instance ID and only
+	// runs offline commands, local associations and long running plugins
+	OfflineMode bool
+	// LocalApiEnabled exposes the local control api on LocalApiSocketPath, only root may use it. The local api
+	// is not supported on windows, where it does not start
+	LocalApiEnabled bool
+	// LocalCommandOutbox is the folder the normalized results of the offline commands are written to when they complete
+	LocalCommandOutbox string
+	// LocalCommandNotifier is the command, and its arguments, run with the path of the result of each completed
+	// offline command appended
+	LocalCommandNotifier []string
+	// LocalCommandNotifierTimeoutSeconds is the time LocalCommandNotifier is given to run before it is killed
+	LocalCommandNotifierTimeoutSeconds int
+	// AuditTrail is where the audit records of the executed documents and plugins are written,
+	// either syslog or journald, no audit trail is kept when empty
+	AuditTrail string
+	// DocumentSignatureEnforcement tells which documents must carry a valid detached signature before they run,
+	// either Local for the offline commands and the local associations or All, no signature is required when empty
+	DocumentSignatureEnforcement string
+	// TrustedKeysDir is the folder of the PEM encoded public keys the signatures of the documents are verified with
+	TrustedKeysDir string
 }
 
 // MfsCfg represents configuration for HummingBird service (MFS)
@@ -73,6 +158,11 @@ type S3Cfg struct {
 	Region    string
 	LogBucket string
 	LogKey    string
+	// ServerSideEncryption encrypts the output uploaded to S3 unless the command sets its own encryption,
+	// either AES256 for SSE-S3 or aws:kms for SSE-KMS, the bucket default applies when empty
+	ServerSideEncryption string
+	// KmsKeyId is the KMS key used with SSE-KMS, the default key of the account is used when empty
+	KmsKeyId string
 }
 
 // BirdwatcherCfg represents configuration related to ConfigurePackage Birdwatcher integration
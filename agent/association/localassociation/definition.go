@@ -0,0 +1,205 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package localassociation loads associations defined on disk and schedules them along with the associations
+// of the service, so that air-gapped instances can run associations without any service call.
+package localassociation
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/association/scheduleexpression"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docsignature"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/twinj/uuid"
+)
+
+const (
+	// DocumentVersion is the document version reported for local associations
+	DocumentVersion = "$LOCAL"
+
+	definitionExtension = ".json"
+)
+
+// Definition represents an association defined in a file of the local association directory
+type Definition struct {
+	// AssociationID is optional, it is derived from the file name when empty
+	AssociationID string
+	// Name is the name of the document the association runs
+	Name string
+	// Document is the content of the document the association runs
+	Document json.RawMessage
+	// Parameters are the parameters the document runs with
+	Parameters map[string][]*string
+	// ScheduleExpression is the cron or rate expression of the association, the association runs once when empty
+	ScheduleExpression string
+	// TimeZone and ScheduleOffset change how the cron expression is evaluated
+	TimeZone       string
+	ScheduleOffset int
+	// DependsOn lists the IDs or names of the associations which must succeed before this association runs in a cycle
+	DependsOn []string
+	// ExclusionGroups lists the groups of associations which never run concurrently with this association
+	ExclusionGroups []string
+	// CheckOnly tells whether the association only checks the instance complies with the document without applying it
+	CheckOnly bool
+
+	fileName  string
+	checksum  string
+	signature []byte
+}
+
+// LoadDefinitions reads the association definitions of the given directory, invalid definitions are logged and skipped
+func LoadDefinitions(log log.T, dir string) []*Definition {
+	definitions, errs := ReadDefinitions(dir)
+	for _, err := range errs {
+		log.Errorf("Skipping local association, %v", err)
+	}
+	return definitions
+}
+
+// ReadDefinitions reads the valid association definitions of the given directory in file name order
+// and returns the errors of the invalid ones
+func ReadDefinitions(dir string) (definitions []*Definition, errs []error) {
+	definitions = []*Definition{}
+	if !fileutil.Exists(dir) {
+		return
+	}
+
+	fileNames, err := fileutil.GetFileNames(dir)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("unable to read local associations from %v, %v", dir, err))
+		return
+	}
+
+	associationIDs := make(map[string]string)
+	for _, fileName := range fileNames {
+		if !strings.HasSuffix(strings.ToLower(fileName), definitionExtension) {
+			continue
+		}
+
+		definition, err := loadDefinition(filepath.Join(dir, fileName))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v", fileName, err))
+			continue
+		}
+		if other, found := associationIDs[definition.AssociationID]; found {
+			errs = append(errs, fmt.Errorf("%v: association ID %v is already used by %v", fileName, definition.AssociationID, other))
+			continue
+		}
+		associationIDs[definition.AssociationID] = fileName
+		definitions = append(definitions, definition)
+	}
+	return
+}
+
+// loadDefinition reads and validates the association definition of the given file,
+// the schedule expression is validated by the association processor like for any other association
+func loadDefinition(fileName string) (*Definition, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var definition Definition
+	if err = json.Unmarshal(content, &definition); err != nil {
+		return nil, fmt.Errorf("invalid association definition, %v", err)
+	}
+	if definition.Name == "" {
+		return nil, errors.New("association definition has no document name")
+	}
+	if len(definition.Document) == 0 {
+		return nil, errors.New("association definition has no document content")
+	}
+
+	definition.fileName = fileName
+	if definition.signature, err = readSignature(fileName); err != nil {
+		return nil, err
+	}
+	if definition.AssociationID == "" {
+		definition.AssociationID = associationIDFromFileName(filepath.Base(fileName))
+	}
+	sum := md5.Sum(append(content, definition.signature...))
+	definition.checksum = base64.StdEncoding.EncodeToString(sum[:])
+	return &definition, nil
+}
+
+// readSignature reads the detached signature of the document of the definition, <definition file>.sig, if any
+func readSignature(fileName string) ([]byte, error) {
+	signatureFileName := fileName + docsignature.SignatureExtension
+	if !fileutil.Exists(signatureFileName) {
+		return nil, nil
+	}
+	signature, err := ioutil.ReadFile(signatureFileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the document signature, %v", err)
+	}
+	return signature, nil
+}
+
+// associationIDFromFileName derives a stable association ID from the name of the definition file
+func associationIDFromFileName(fileName string) string {
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	return uuid.Formatter(uuid.NewV5(uuid.NamespaceURL, uuid.Name("localassociation:"+name)), uuid.CleanHyphen)
+}
+
+// ToInstanceAssociation converts the definition to an association of the given instance.
+// The last recorded status tells whether and when the association ran before.
+func (d *Definition) ToInstanceAssociation(instanceID string, status *Status) *model.InstanceAssociation {
+	assoc := &model.InstanceAssociation{}
+	assoc.Association = &ssm.InstanceAssociationSummary{
+		AssociationId:   aws.String(d.AssociationID),
+		Name:            aws.String(d.Name),
+		InstanceId:      aws.String(instanceID),
+		DocumentVersion: aws.String(DocumentVersion),
+		Checksum:        aws.String(d.checksum),
+		Parameters:      d.Parameters,
+		DetailedStatus:  aws.String(contracts.AssociationStatusAssociated),
+	}
+	if d.ScheduleExpression != "" {
+		assoc.Association.ScheduleExpression = aws.String(d.ScheduleExpression)
+	}
+	if status != nil {
+		assoc.Association.DetailedStatus = aws.String(status.Status)
+		if status.ExecutionDate != "" {
+			assoc.Association.LastExecutionDate = aws.Time(times.ParseIso8601UTC(status.ExecutionDate))
+		}
+	}
+	assoc.ScheduleOptions = scheduleexpression.ScheduleOptions{TimeZone: d.TimeZone, ScheduleOffset: d.ScheduleOffset}
+	assoc.DependsOn = append([]string{}, d.DependsOn...)
+	assoc.ExclusionGroups = append([]string{}, d.ExclusionGroups...)
+	assoc.CheckOnly = d.CheckOnly
+	assoc.Document = aws.String(string(d.Document))
+	assoc.Local = true
+	assoc.DocumentSignature = d.signature
+	assoc.CreateDate = time.Now().UTC()
+	return assoc
+}
+
+// FileName returns the file the association is defined in
+func (d *Definition) FileName() string {
+	return d.fileName
+}
@@ -0,0 +1,169 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localassociation
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/association/service"
+	complianceModel "github.com/aws/amazon-ssm-agent/agent/compliance/model"
+	complianceUploader "github.com/aws/amazon-ssm-agent/agent/compliance/uploader"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testDefinition = `{
+	"Name": "ConfigureNtp",
+	"Document": {"schemaVersion": "2.2", "mainSteps": []},
+	"Parameters": {"server": ["169.254.169.123"]},
+	"ScheduleExpression": "cron(0 0/30 * 1/1 * ? *)"
+}`
+
+func createTestService(t *testing.T, delegate service.T) (*Service, func()) {
+	dir, err := ioutil.TempDir("", "localassociation")
+	assert.Nil(t, err)
+
+	s := NewService(delegate)
+	s.definitionDir = filepath.Join(dir, "definitions")
+	s.statusDir = filepath.Join(dir, "status")
+	assert.Nil(t, os.MkdirAll(s.definitionDir, 0700))
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func TestReadDefinitions(t *testing.T) {
+	s, cleanup := createTestService(t, nil)
+	defer cleanup()
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(s.definitionDir, "ntp.json"), []byte(testDefinition), 0600))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(s.definitionDir, "invalid.json"), []byte(`{"Name": "NoDocument"}`), 0600))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(s.definitionDir, "readme.txt"), []byte("ignored"), 0600))
+
+	definitions, errs := ReadDefinitions(s.definitionDir)
+
+	assert.Equal(t, 1, len(definitions))
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "ConfigureNtp", definitions[0].Name)
+	assert.Equal(t, filepath.Join(s.definitionDir, "ntp.json"), definitions[0].FileName())
+	// the association ID is derived from the file name and stays the same across reads
+	assert.Equal(t, 36, len(definitions[0].AssociationID))
+	assert.Equal(t, associationIDFromFileName("ntp.json"), definitions[0].AssociationID)
+}
+
+func TestToInstanceAssociation(t *testing.T) {
+	s, cleanup := createTestService(t, nil)
+	defer cleanup()
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(s.definitionDir, "ntp.json"), []byte(testDefinition), 0600))
+	definitions, _ := ReadDefinitions(s.definitionDir)
+
+	assoc := definitions[0].ToInstanceAssociation("i-1234567890abcdef0", nil)
+	assert.Equal(t, "i-1234567890abcdef0", *assoc.Association.InstanceId)
+	assert.Equal(t, DocumentVersion, *assoc.Association.DocumentVersion)
+	assert.Equal(t, contracts.AssociationStatusAssociated, *assoc.Association.DetailedStatus)
+	assert.Equal(t, "169.254.169.123", *assoc.Association.Parameters["server"][0])
+	assert.Nil(t, assoc.Association.LastExecutionDate)
+	assert.Contains(t, *assoc.Document, "mainSteps")
+
+	executionDate := time.Date(2017, 10, 12, 10, 0, 0, 0, time.UTC)
+	status := &Status{Status: contracts.AssociationStatusSuccess, ExecutionDate: times.ToIso8601UTC(executionDate)}
+	assoc = definitions[0].ToInstanceAssociation("i-1234567890abcdef0", status)
+	assert.Equal(t, contracts.AssociationStatusSuccess, *assoc.Association.DetailedStatus)
+	assert.Equal(t, executionDate, *assoc.Association.LastExecutionDate)
+}
+
+func TestListInstanceAssociationsWhenServiceIsUnreachable(t *testing.T) {
+	logger := log.NewMockLog()
+	delegate := service.NewMockDefault()
+	delegate.On("ListInstanceAssociations", logger, "i-1234567890abcdef0").Return([]*model.InstanceAssociation{}, errors.New("unreachable"))
+	s, cleanup := createTestService(t, delegate)
+	defer cleanup()
+
+	// without local associations the error of the service is returned
+	_, err := s.ListInstanceAssociations(logger, "i-1234567890abcdef0")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(s.definitionDir, "ntp.json"), []byte(testDefinition), 0600))
+	associations, err := s.ListInstanceAssociations(logger, "i-1234567890abcdef0")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(associations))
+	assert.True(t, s.IsLocal(*associations[0].Association.AssociationId))
+	assert.Nil(t, s.LoadAssociationDetail(logger, associations[0]))
+}
+
+func TestStatusAndComplianceOfLocalAssociationsAreRecordedLocally(t *testing.T) {
+	logger := log.NewMockLog()
+	delegate := service.NewMockDefault()
+	delegate.On("UpdateInstanceAssociationStatus", logger, "serviceAssociationID", "name", "i-1234567890abcdef0", mock.Anything).Return()
+	uploaderMock := complianceUploader.NewMockDefault()
+	uploaderMock.On("UpdateAssociationCompliance", "serviceAssociationID", "i-1234567890abcdef0", "name", "1", contracts.AssociationStatusSuccess, mock.Anything).Return(nil)
+
+	s, cleanup := createTestService(t, delegate)
+	defer cleanup()
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(s.definitionDir, "ntp.json"), []byte(testDefinition), 0600))
+	localID := associationIDFromFileName("ntp.json")
+	uploader := s.ComplianceUploader(uploaderMock)
+	executionDate := time.Date(2017, 10, 12, 10, 0, 0, 0, time.UTC)
+
+	s.UpdateInstanceAssociationStatus(logger, localID, "ConfigureNtp", "i-1234567890abcdef0",
+		contracts.AssociationStatusFailed, contracts.AssociationErrorCodeExecutionError, times.ToIso8601UTC(executionDate), "failed", "")
+	assert.Nil(t, uploader.UpdateAssociationCompliance(localID, "i-1234567890abcdef0", "ConfigureNtp", DocumentVersion,
+		contracts.AssociationStatusFailed, executionDate))
+
+	s.UpdateInstanceAssociationStatus(logger, "serviceAssociationID", "name", "i-1234567890abcdef0",
+		contracts.AssociationStatusSuccess, contracts.AssociationErrorCodeNoError, times.ToIso8601UTC(executionDate), "", "")
+	assert.Nil(t, uploader.UpdateAssociationCompliance("serviceAssociationID", "i-1234567890abcdef0", "name", "1",
+		contracts.AssociationStatusSuccess, executionDate))
+
+	status, err := LoadStatus(s.statusDir, localID)
+	assert.Nil(t, err)
+	assert.Equal(t, "ConfigureNtp", status.Name)
+	assert.Equal(t, contracts.AssociationStatusFailed, status.Status)
+	assert.Equal(t, contracts.AssociationErrorCodeExecutionError, status.ErrorCode)
+	assert.Equal(t, "failed", status.ExecutionSummary)
+	assert.Equal(t, complianceModel.NON_COMPLIANT, status.ComplianceStatus)
+
+	status, err = LoadStatus(s.statusDir, "serviceAssociationID")
+	assert.Nil(t, err)
+	assert.Nil(t, status)
+	delegate.AssertExpectations(t)
+	uploaderMock.AssertExpectations(t)
+}
+
+func TestOfflineServesOnlyLocalAssociations(t *testing.T) {
+	logger := log.NewMockLog()
+	s, cleanup := createTestService(t, Offline{})
+	defer cleanup()
+
+	associations, err := s.ListInstanceAssociations(logger, "li-0123456789abcdef0")
+	assert.Nil(t, err)
+	assert.Empty(t, associations)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(s.definitionDir, "ntp.json"), []byte(testDefinition), 0600))
+	associations, err = s.ListInstanceAssociations(logger, "li-0123456789abcdef0")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(associations))
+	assert.True(t, s.IsLocal(*associations[0].Association.AssociationId))
+
+	uploader := s.ComplianceUploader(Offline{})
+	assert.Nil(t, uploader.UpdateAssociationCompliance("serviceAssociationID", "li-0123456789abcdef0", "name", "1",
+		contracts.AssociationStatusSuccess, time.Now()))
+}
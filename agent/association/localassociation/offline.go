@@ -0,0 +1,78 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localassociation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// Offline stands in for the association service and the compliance uploader when the agent runs offline,
+// there are no associations besides the local ones and nothing is sent to the service
+type Offline struct{}
+
+// CreateNewServiceIfUnHealthy does nothing, there is no service to reconnect to
+func (Offline) CreateNewServiceIfUnHealthy(log log.T) {}
+
+// ListInstanceAssociations returns no associations
+func (Offline) ListInstanceAssociations(log log.T, instanceID string) ([]*model.InstanceAssociation, error) {
+	return []*model.InstanceAssociation{}, nil
+}
+
+// LoadAssociationDetail fails, only local associations are known offline and they carry their document
+func (Offline) LoadAssociationDetail(log log.T, assoc *model.InstanceAssociation) error {
+	return fmt.Errorf("association %v is not a local association", *assoc.Association.AssociationId)
+}
+
+// UpdateAssociationStatus does nothing
+func (Offline) UpdateAssociationStatus(log log.T, associationName string, instanceID string, status string, executionSummary string) {
+}
+
+// UpdateInstanceAssociationStatus does nothing, the status of local associations is recorded by the Service
+func (Offline) UpdateInstanceAssociationStatus(
+	log log.T,
+	associationID string,
+	associationName string,
+	instanceID string,
+	status string,
+	errorCode string,
+	executionDate string,
+	executionSummary string,
+	outputUrl string) {
+}
+
+// IsInstanceAssociationApiMode returns true, local associations are processed like instance associations
+func (Offline) IsInstanceAssociationApiMode() bool {
+	return true
+}
+
+// DescribeAssociation fails, there is no service to describe associations
+func (Offline) DescribeAssociation(log log.T, instanceID string, docName string) (*ssm.DescribeAssociationOutput, error) {
+	return nil, fmt.Errorf("associations cannot be described in offline mode")
+}
+
+// UpdateAssociationCompliance does nothing, the compliance of local associations is recorded by the Service
+func (Offline) UpdateAssociationCompliance(
+	associationID string,
+	instanceID string,
+	documentName string,
+	documentVersion string,
+	associationStatus string,
+	executionTime time.Time) error {
+	return nil
+}
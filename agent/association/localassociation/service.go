@@ -0,0 +1,194 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localassociation
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/association/schedulemanager"
+	"github.com/aws/amazon-ssm-agent/agent/association/service"
+	complianceModel "github.com/aws/amazon-ssm-agent/agent/compliance/model"
+	complianceUploader "github.com/aws/amazon-ssm-agent/agent/compliance/uploader"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+)
+
+// Service serves the local associations along with the associations of the wrapped association service.
+// The status of local associations is recorded on disk instead of being sent to the service.
+type Service struct {
+	service.T
+	definitionDir string
+	statusDir     string
+
+	mutex       sync.RWMutex
+	definitions map[string]*Definition
+}
+
+// NewService returns a Service reading the local associations from the default directory
+func NewService(delegate service.T) *Service {
+	return &Service{
+		T:             delegate,
+		definitionDir: appconfig.LocalAssociationRoot,
+		statusDir:     appconfig.LocalAssociationRootStatus,
+		definitions:   make(map[string]*Definition),
+	}
+}
+
+// ListInstanceAssociations returns the associations of the service followed by the local associations.
+// When the service cannot be reached the local associations are still returned.
+func (s *Service) ListInstanceAssociations(log log.T, instanceID string) ([]*model.InstanceAssociation, error) {
+	results, err := s.T.ListInstanceAssociations(log, instanceID)
+	definitions := s.loadDefinitions(log)
+	if err != nil {
+		if len(definitions) == 0 {
+			return results, err
+		}
+		log.Errorf("Unable to load associations from the service, only local associations are scheduled, %v", err)
+		results = []*model.InstanceAssociation{}
+	}
+
+	for _, definition := range definitions {
+		status, err := LoadStatus(s.statusDir, definition.AssociationID)
+		if err != nil {
+			log.Errorf("Unable to load status of local association %v, %v", definition.AssociationID, err)
+		}
+		results = append(results, definition.ToInstanceAssociation(instanceID, status))
+	}
+
+	log.Debugf("Number of local associations is %v", len(definitions))
+	return results, nil
+}
+
+// LoadAssociationDetail loads the document of the association, local associations carry their document already
+func (s *Service) LoadAssociationDetail(log log.T, assoc *model.InstanceAssociation) error {
+	if s.IsLocal(*assoc.Association.AssociationId) {
+		return nil
+	}
+	return s.T.LoadAssociationDetail(log, assoc)
+}
+
+// UpdateInstanceAssociationStatus records the status of local associations on disk and sends the status of
+// other associations to the service
+func (s *Service) UpdateInstanceAssociationStatus(
+	log log.T,
+	associationID string,
+	associationName string,
+	instanceID string,
+	status string,
+	errorCode string,
+	executionDate string,
+	executionSummary string,
+	outputUrl string) {
+
+	if !s.IsLocal(associationID) {
+		s.T.UpdateInstanceAssociationStatus(log, associationID, associationName, instanceID, status, errorCode, executionDate, executionSummary, outputUrl)
+		return
+	}
+
+	// Update status in schedulemanager to ensure state matches with the one recorded
+	schedulemanager.UpdateAssociationStatus(log, associationID, status)
+
+	err := updateStatus(s.statusDir, associationID, func(recorded *Status) {
+		if associationName != "" {
+			recorded.Name = associationName
+		}
+		recorded.Status = status
+		recorded.ErrorCode = errorCode
+		recorded.ExecutionSummary = executionSummary
+		recorded.ExecutionDate = executionDate
+	})
+	if err != nil {
+		log.Errorf("Unable to record status of local association %v, %v", associationID, err)
+	}
+}
+
+// ComplianceUploader wraps the given compliance uploader so that the compliance of local associations
+// is recorded on disk instead of being uploaded
+func (s *Service) ComplianceUploader(delegate complianceUploader.T) complianceUploader.T {
+	return &localComplianceUploader{T: delegate, service: s}
+}
+
+// IsLocal returns true if the given association is defined in the local association directory
+func (s *Service) IsLocal(associationID string) bool {
+	s.mutex.RLock()
+	_, found := s.definitions[associationID]
+	loaded := len(s.definitions) > 0
+	s.mutex.RUnlock()
+
+	if found || loaded {
+		return found
+	}
+
+	// results of documents resumed on startup may be reported before the associations are listed
+	definitions, _ := ReadDefinitions(s.definitionDir)
+	for _, definition := range definitions {
+		if definition.AssociationID == associationID {
+			return true
+		}
+	}
+	return false
+}
+
+// loadDefinitions reads the local association definitions in file name order and caches them
+func (s *Service) loadDefinitions(log log.T) []*Definition {
+	definitions := LoadDefinitions(log, s.definitionDir)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.definitions = make(map[string]*Definition)
+	for _, definition := range definitions {
+		s.definitions[definition.AssociationID] = definition
+	}
+	return definitions
+}
+
+// localComplianceUploader records the compliance of local associations on disk
+type localComplianceUploader struct {
+	complianceUploader.T
+	service *Service
+}
+
+// UpdateAssociationCompliance records the compliance of local associations and uploads the compliance of the others
+func (u *localComplianceUploader) UpdateAssociationCompliance(
+	associationID string,
+	instanceID string,
+	documentName string,
+	documentVersion string,
+	associationStatus string,
+	executionTime time.Time) error {
+
+	if !u.service.IsLocal(associationID) {
+		return u.T.UpdateAssociationCompliance(associationID, instanceID, documentName, documentVersion, associationStatus, executionTime)
+	}
+
+	// like the service, compliance is only recorded for the final status
+	if contracts.AssociationStatusTimedOut != associationStatus &&
+		contracts.AssociationStatusSuccess != associationStatus &&
+		contracts.AssociationStatusFailed != associationStatus {
+		return nil
+	}
+
+	complianceStatus := complianceModel.COMPLIANT
+	if contracts.AssociationStatusSuccess != associationStatus {
+		complianceStatus = complianceModel.NON_COMPLIANT
+	}
+	return updateStatus(u.service.statusDir, associationID, func(recorded *Status) {
+		recorded.ComplianceStatus = complianceStatus
+		recorded.ComplianceDate = times.ToIso8601UTC(executionTime)
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localassociation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+// Status represents the last execution status recorded for a local association
+type Status struct {
+	AssociationID    string
+	Name             string
+	Status           string
+	ErrorCode        string
+	ExecutionSummary string
+	ExecutionDate    string
+	// ComplianceStatus is the compliance of the instance with the association, COMPLIANT or NON_COMPLIANT
+	ComplianceStatus string `json:",omitempty"`
+	// ComplianceDate is the execution date the compliance status was recorded for
+	ComplianceDate string `json:",omitempty"`
+}
+
+var statusLock sync.RWMutex
+
+// LoadStatus returns the status recorded for the given association in the given directory,
+// nil is returned if the association never ran
+func LoadStatus(dir string, associationID string) (*Status, error) {
+	statusLock.RLock()
+	defer statusLock.RUnlock()
+
+	return loadStatus(getStatusFileName(dir, associationID))
+}
+
+// updateStatus applies the given change to the status recorded for the association and persists it
+func updateStatus(dir string, associationID string, update func(status *Status)) error {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+
+	fileName := getStatusFileName(dir, associationID)
+	status, err := loadStatus(fileName)
+	if err != nil || status == nil {
+		status = &Status{AssociationID: associationID}
+	}
+	update(status)
+	return saveStatus(dir, fileName, status)
+}
+
+// loadStatus reads the status from the given file
+func loadStatus(fileName string) (*Status, error) {
+	if !fileutil.Exists(fileName) {
+		return nil, nil
+	}
+
+	var status Status
+	if err := jsonutil.UnmarshalFile(fileName, &status); err != nil {
+		return nil, fmt.Errorf("cannot read association status from %v because: %v", fileName, err)
+	}
+	return &status, nil
+}
+
+// saveStatus replaces the given file with the status, a crash during the write never leaves a partial status behind
+func saveStatus(dir string, fileName string, status *Status) error {
+	var err error
+	var content string
+
+	//verify if parent folder exist
+	if !fileutil.Exists(dir) {
+		if err = fileutil.MakeDirs(dir); err != nil {
+			return fmt.Errorf("cannot make directory of %v because: %v", dir, err)
+		}
+	}
+
+	if content, err = jsonutil.Marshal(status); err != nil {
+		return err
+	}
+
+	if err = fileutil.WriteFileAtomic(fileName, []byte(content), os.FileMode(int(appconfig.ReadWriteAccess))); err != nil {
+		return fmt.Errorf("cannot write association status to %v because: %v", fileName, err)
+	}
+	return nil
+}
+
+// getStatusFileName returns the full file name of the status of the given association
+func getStatusFileName(dir string, associationID string) string {
+	return filepath.Join(dir, associationID+".json")
+}
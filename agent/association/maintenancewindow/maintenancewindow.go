@@ -0,0 +1,195 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package maintenancewindow tells whether associations are allowed to run at a given time,
+// based upon the locally configured maintenance windows and blackout calendar.
+package maintenancewindow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/association/scheduleexpression"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// maxOpeningLookups bounds the search of the next opening through windows and blackouts
+	maxOpeningLookups = 1000
+
+	cronExpressionPrefix = "cron("
+)
+
+// Window is a recurring period opened and closed by cron expressions
+type Window struct {
+	open  scheduleexpression.ScheduleExpression
+	close scheduleexpression.ScheduleExpression
+}
+
+// Blackout is a period associations must not run in, from Start included to End excluded
+type Blackout struct {
+	Start       time.Time
+	End         time.Time
+	Description string
+}
+
+// BlackoutCalendar represents the content of the blackout calendar file
+type BlackoutCalendar struct {
+	Blackouts []*Blackout
+}
+
+// Calendar holds the maintenance windows and the blackouts associations are scheduled with.
+// Associations run at any time outside the blackouts when no window is configured.
+type Calendar struct {
+	windows   []*Window
+	blackouts []*Blackout
+}
+
+// New creates the calendar from the maintenance windows and the blackout calendar file of the configuration
+func New(log log.T, config appconfig.SsmCfg) (*Calendar, error) {
+	calendar := &Calendar{}
+	for _, windowCfg := range config.AssociationMaintenanceWindows {
+		window, err := NewWindow(log, windowCfg)
+		if err != nil {
+			return nil, err
+		}
+		calendar.windows = append(calendar.windows, window)
+	}
+
+	if config.AssociationBlackoutCalendar != "" {
+		blackouts, err := LoadBlackouts(config.AssociationBlackoutCalendar)
+		if err != nil {
+			return nil, err
+		}
+		calendar.blackouts = blackouts
+	}
+	return calendar, nil
+}
+
+// NewWindow parses the cron expressions of the maintenance window
+func NewWindow(log log.T, windowCfg appconfig.MaintenanceWindowCfg) (*Window, error) {
+	for _, expression := range []string{windowCfg.Open, windowCfg.Close} {
+		if !strings.HasPrefix(strings.ToLower(expression), cronExpressionPrefix) {
+			return nil, fmt.Errorf("maintenance windows are opened and closed by cron expressions, got %v", expression)
+		}
+	}
+	options := scheduleexpression.ScheduleOptions{TimeZone: windowCfg.TimeZone}
+	open, err := scheduleexpression.CreateScheduleExpressionWithOptions(log, windowCfg.Open, options)
+	if err != nil {
+		return nil, fmt.Errorf("invalid opening %v of maintenance window, %v", windowCfg.Open, err)
+	}
+	close, err := scheduleexpression.CreateScheduleExpressionWithOptions(log, windowCfg.Close, options)
+	if err != nil {
+		return nil, fmt.Errorf("invalid closing %v of maintenance window, %v", windowCfg.Close, err)
+	}
+	return &Window{open: open, close: close}, nil
+}
+
+// LoadBlackouts reads the blackouts of the given blackout calendar file
+func LoadBlackouts(fileName string) ([]*Blackout, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read blackout calendar %v, %v", fileName, err)
+	}
+
+	var calendar BlackoutCalendar
+	if err = json.Unmarshal(content, &calendar); err != nil {
+		return nil, fmt.Errorf("invalid blackout calendar %v, %v", fileName, err)
+	}
+	for _, blackout := range calendar.Blackouts {
+		if blackout == nil || !blackout.End.After(blackout.Start) {
+			return nil, fmt.Errorf("invalid blackout calendar %v, blackouts must end after they start", fileName)
+		}
+	}
+	return calendar.Blackouts, nil
+}
+
+// IsEmpty returns true if the calendar never prevents associations from running
+func (c *Calendar) IsEmpty() bool {
+	return c == nil || (len(c.windows) == 0 && len(c.blackouts) == 0)
+}
+
+// IsOpen returns true if associations are allowed to run at the given time
+func (c *Calendar) IsOpen(t time.Time) bool {
+	if c.IsEmpty() {
+		return true
+	}
+	if c.BlackoutAt(t) != nil {
+		return false
+	}
+	if len(c.windows) == 0 {
+		return true
+	}
+	for _, window := range c.windows {
+		if window.IsOpen(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpening returns the first time from the given time associations are allowed to run at,
+// the zero time is returned if the calendar never opens again
+func (c *Calendar) NextOpening(t time.Time) time.Time {
+	for i := 0; i < maxOpeningLookups; i++ {
+		if c.IsOpen(t) {
+			return t
+		}
+
+		if blackout := c.BlackoutAt(t); blackout != nil {
+			t = blackout.End
+			continue
+		}
+
+		var next time.Time
+		for _, window := range c.windows {
+			opening := window.open.Next(t)
+			if !opening.IsZero() && (next.IsZero() || opening.Before(next)) {
+				next = opening
+			}
+		}
+		if next.IsZero() {
+			return next
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+// BlackoutAt returns the blackout the given time falls in, nil if there is none
+func (c *Calendar) BlackoutAt(t time.Time) *Blackout {
+	if c == nil {
+		return nil
+	}
+	for _, blackout := range c.blackouts {
+		if !t.Before(blackout.Start) && t.Before(blackout.End) {
+			return blackout
+		}
+	}
+	return nil
+}
+
+// IsOpen returns true if the window is open at the given time,
+// i.e. the window closes before it opens again
+func (w *Window) IsOpen(t time.Time) bool {
+	nextClose := w.close.Next(t)
+	if nextClose.IsZero() {
+		return false
+	}
+	nextOpen := w.open.Next(t)
+	return nextOpen.IsZero() || nextClose.Before(nextOpen)
+}
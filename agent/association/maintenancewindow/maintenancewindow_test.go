@@ -0,0 +1,150 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package maintenancewindow
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var businessHours = appconfig.MaintenanceWindowCfg{
+	Open:  "cron(0 18 ? * MON-FRI *)",
+	Close: "cron(0 8 ? * MON-FRI *)",
+}
+
+func parseTime(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	assert.Nil(t, err)
+	return parsed
+}
+
+func writeBlackoutCalendar(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "maintenancewindow")
+	assert.Nil(t, err)
+	fileName := filepath.Join(dir, "blackouts.json")
+	assert.Nil(t, ioutil.WriteFile(fileName, []byte(content), 0600))
+	return fileName, func() { os.RemoveAll(dir) }
+}
+
+func TestEmptyCalendarIsAlwaysOpen(t *testing.T) {
+	calendar, err := New(log.NewMockLog(), appconfig.SsmCfg{})
+	assert.Nil(t, err)
+
+	now := time.Now()
+	assert.True(t, calendar.IsEmpty())
+	assert.True(t, calendar.IsOpen(now))
+	assert.Equal(t, now, calendar.NextOpening(now))
+
+	var nilCalendar *Calendar
+	assert.True(t, nilCalendar.IsOpen(now))
+}
+
+func TestWindowIsOpen(t *testing.T) {
+	calendar, err := New(log.NewMockLog(), appconfig.SsmCfg{
+		AssociationMaintenanceWindows: []appconfig.MaintenanceWindowCfg{businessHours},
+	})
+	assert.Nil(t, err)
+
+	// 2017-10-16 is a Monday, the window opens every weekday at 18:00 and closes the next weekday at 08:00
+	assert.True(t, calendar.IsOpen(parseTime(t, "2017-10-16T18:00:00Z")))
+	assert.True(t, calendar.IsOpen(parseTime(t, "2017-10-17T07:59:00Z")))
+	assert.False(t, calendar.IsOpen(parseTime(t, "2017-10-17T08:00:00Z")))
+	assert.False(t, calendar.IsOpen(parseTime(t, "2017-10-17T12:00:00Z")))
+
+	assert.Equal(t, parseTime(t, "2017-10-17T18:00:00Z"), calendar.NextOpening(parseTime(t, "2017-10-17T12:00:00Z")))
+	assert.Equal(t, parseTime(t, "2017-10-17T20:00:00Z"), calendar.NextOpening(parseTime(t, "2017-10-17T20:00:00Z")))
+}
+
+func TestWindowInTimeZone(t *testing.T) {
+	window, err := NewWindow(log.NewMockLog(), appconfig.MaintenanceWindowCfg{
+		Open:     "cron(0 22 * * ? *)",
+		Close:    "cron(0 2 * * ? *)",
+		TimeZone: "America/New_York",
+	})
+	assert.Nil(t, err)
+
+	// 22:00 in New York is 02:00 UTC in October
+	assert.False(t, window.IsOpen(parseTime(t, "2017-10-17T01:59:00Z")))
+	assert.True(t, window.IsOpen(parseTime(t, "2017-10-17T02:00:00Z")))
+	assert.True(t, window.IsOpen(parseTime(t, "2017-10-17T05:59:00Z")))
+	assert.False(t, window.IsOpen(parseTime(t, "2017-10-17T06:00:00Z")))
+}
+
+func TestInvalidWindow(t *testing.T) {
+	_, err := New(log.NewMockLog(), appconfig.SsmCfg{
+		AssociationMaintenanceWindows: []appconfig.MaintenanceWindowCfg{{Open: "cron(0 18 * * ? *)", Close: "rate(1 day)"}},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "cron expressions")
+
+	_, err = New(log.NewMockLog(), appconfig.SsmCfg{
+		AssociationMaintenanceWindows: []appconfig.MaintenanceWindowCfg{{Open: "cron(0 18 * * ? *)", Close: "cron(0 99 * * ? *)"}},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid closing")
+}
+
+func TestBlackouts(t *testing.T) {
+	fileName, cleanup := writeBlackoutCalendar(t, `{"Blackouts": [
+		{"Start": "2017-10-17T18:00:00Z", "End": "2017-10-19T00:00:00Z", "Description": "release freeze"}
+	]}`)
+	defer cleanup()
+
+	calendar, err := New(log.NewMockLog(), appconfig.SsmCfg{
+		AssociationMaintenanceWindows: []appconfig.MaintenanceWindowCfg{businessHours},
+		AssociationBlackoutCalendar:   fileName,
+	})
+	assert.Nil(t, err)
+
+	assert.False(t, calendar.IsOpen(parseTime(t, "2017-10-17T18:00:00Z")))
+	assert.Equal(t, "release freeze", calendar.BlackoutAt(parseTime(t, "2017-10-18T02:00:00Z")).Description)
+	assert.Nil(t, calendar.BlackoutAt(parseTime(t, "2017-10-19T00:00:00Z")))
+
+	// the blackout ends while the window is open
+	assert.Equal(t, parseTime(t, "2017-10-19T00:00:00Z"), calendar.NextOpening(parseTime(t, "2017-10-17T12:00:00Z")))
+}
+
+func TestBlackoutsWithoutWindows(t *testing.T) {
+	fileName, cleanup := writeBlackoutCalendar(t, `{"Blackouts": [
+		{"Start": "2017-12-24T00:00:00Z", "End": "2017-12-26T00:00:00Z", "Description": "holidays"}
+	]}`)
+	defer cleanup()
+
+	calendar, err := New(log.NewMockLog(), appconfig.SsmCfg{AssociationBlackoutCalendar: fileName})
+	assert.Nil(t, err)
+
+	assert.True(t, calendar.IsOpen(parseTime(t, "2017-12-23T23:59:00Z")))
+	assert.False(t, calendar.IsOpen(parseTime(t, "2017-12-25T12:00:00Z")))
+	assert.Equal(t, parseTime(t, "2017-12-26T00:00:00Z"), calendar.NextOpening(parseTime(t, "2017-12-25T12:00:00Z")))
+}
+
+func TestInvalidBlackoutCalendar(t *testing.T) {
+	fileName, cleanup := writeBlackoutCalendar(t, `{"Blackouts": [
+		{"Start": "2017-12-26T00:00:00Z", "End": "2017-12-24T00:00:00Z"}
+	]}`)
+	defer cleanup()
+
+	_, err := New(log.NewMockLog(), appconfig.SsmCfg{AssociationBlackoutCalendar: fileName})
+	assert.NotNil(t, err)
+
+	_, err = New(log.NewMockLog(), appconfig.SsmCfg{AssociationBlackoutCalendar: fileName + ".missing"})
+	assert.NotNil(t, err)
+}
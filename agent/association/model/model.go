@@ -16,8 +16,10 @@ package model
 
 import (
 	"fmt"
+	"hash/fnv"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/association/scheduleexpression"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -33,14 +35,34 @@ type InstanceAssociation struct {
 	NextScheduledDate *time.Time
 	Association       *ssm.InstanceAssociationSummary
 	ParsedExpression  scheduleexpression.ScheduleExpression
-	Document          *string
-	Errors            []error
+	// ScheduleOptions holds the time zone and the offset in days the schedule expression is evaluated with
+	ScheduleOptions scheduleexpression.ScheduleOptions
+	// MaxSplaySeconds is the maximum delay of the scheduled runs, 0 disables the splay
+	MaxSplaySeconds int
+	// MissedRunPolicy tells whether the runs missed while the agent was stopped are run once or skipped
+	MissedRunPolicy string
+	// DependsOn lists the IDs or names of the associations which must succeed before this association runs in a cycle
+	DependsOn []string
+	// ExclusionGroups lists the groups of associations which never run concurrently with this association
+	ExclusionGroups []string
+	// CheckOnly tells whether the association only checks the instance complies with the document without applying it
+	CheckOnly bool
+	// OutsideWindowPolicy tells whether the runs due outside the maintenance windows are deferred or skipped
+	OutsideWindowPolicy string
+	Document            *string
+	Errors              []error
+	// Local tells whether the association is defined in the local association folder
+	Local bool
+	// DocumentSignature is the detached signature of the document of a local association, if any
+	DocumentSignature []byte
 }
 
 // ParseExpression parses the expression with the given association
 func (newAssoc *InstanceAssociation) ParseExpression(log log.T) error {
 
-	parsedScheduleExpression, err := scheduleexpression.CreateScheduleExpression(log, *newAssoc.Association.ScheduleExpression)
+	parsedScheduleExpression, err := scheduleexpression.CreateScheduleExpressionWithOptions(log,
+		*newAssoc.Association.ScheduleExpression,
+		newAssoc.ScheduleOptions)
 
 	if err != nil {
 		return fmt.Errorf("Failed to parse schedule expression %v, %v", *newAssoc.Association.ScheduleExpression, err)
@@ -55,6 +77,37 @@ func (assoc *InstanceAssociation) IsRunOnceAssociation() bool {
 	return assoc.Association.ScheduleExpression == nil || *assoc.Association.ScheduleExpression == ""
 }
 
+// SplayOffset returns the delay applied to the scheduled runs of the association on this instance.
+// The delay is derived from the instance ID and the association ID, so it is stable across agent restarts
+// while instances sharing the same schedule are spread over [0, MaxSplaySeconds] seconds.
+func (assoc *InstanceAssociation) SplayOffset() time.Duration {
+	if assoc.MaxSplaySeconds <= 0 || assoc.Association == nil ||
+		assoc.Association.InstanceId == nil || assoc.Association.AssociationId == nil {
+		return 0
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(*assoc.Association.InstanceId))
+	hash.Write([]byte(*assoc.Association.AssociationId))
+	return time.Duration(hash.Sum64()%uint64(assoc.MaxSplaySeconds+1)) * time.Second
+}
+
+// Matches returns true if the given value is the ID or the name of the association
+func (assoc *InstanceAssociation) Matches(associationIDOrName string) bool {
+	return (assoc.Association.AssociationId != nil && *assoc.Association.AssociationId == associationIDOrName) ||
+		(assoc.Association.Name != nil && *assoc.Association.Name == associationIDOrName)
+}
+
+// InExclusionGroup returns true if the association belongs to the given exclusion group
+func (assoc *InstanceAssociation) InExclusionGroup(group string) bool {
+	for _, g := range assoc.ExclusionGroups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
 // RunNow sets the NextScheduledDate to current time
 func (newAssoc *InstanceAssociation) RunNow() {
 	newAssoc.NextScheduledDate = aws.Time(time.Now().UTC())
@@ -98,10 +151,42 @@ func (newAssoc *InstanceAssociation) SetNextScheduledDate(log log.T) {
 		}
 	}
 
-	// Set next schedule date of association according to it's schedule
+	// Set next schedule date of association according to it's schedule.
+	// The last execution date already includes the splay, remove it before evaluating the schedule
+	// so that the splay does not accumulate from one run to the next.
+	splay := newAssoc.SplayOffset()
 	newAssoc.NextScheduledDate = aws.Time(
-		newAssoc.ParsedExpression.Next(newAssoc.Association.LastExecutionDate.UTC()).UTC())
-	log.Infof("Based upon expression %v and last execution date %v, next scheduled date for association %v is %v",
-		*newAssoc.Association.ScheduleExpression, times.ToIsoDashUTC(*newAssoc.Association.LastExecutionDate),
+		newAssoc.ParsedExpression.Next(newAssoc.Association.LastExecutionDate.UTC().Add(-splay)).Add(splay).UTC())
+	log.Infof("Based upon expression %v, last execution date %v and splay offset %v, next scheduled date for association %v is %v",
+		*newAssoc.Association.ScheduleExpression, times.ToIsoDashUTC(*newAssoc.Association.LastExecutionDate), splay,
 		*newAssoc.Association.AssociationId, times.ToIsoDashUTC(*newAssoc.NextScheduledDate))
 }
+
+// ApplyMissedRunPolicy handles a next scheduled date that passed while the agent was stopped.
+// With the Skip policy the missed runs are dropped and the association waits for its next scheduled run,
+// otherwise the association runs once immediately.
+func (assoc *InstanceAssociation) ApplyMissedRunPolicy(log log.T, now time.Time) {
+	if assoc.NextScheduledDate == nil || !assoc.NextScheduledDate.Before(now) ||
+		assoc.IsRunOnceAssociation() || assoc.Association.LastExecutionDate == nil || assoc.ParsedExpression == nil {
+		return
+	}
+
+	// Associations requested to run immediately are not missed runs
+	if assoc.Association.DetailedStatus != nil &&
+		*assoc.Association.DetailedStatus == contracts.AssociationStatusPending {
+		return
+	}
+
+	missedScheduledDate := *assoc.NextScheduledDate
+	if assoc.MissedRunPolicy == appconfig.AssociationMissedRunPolicySkip {
+		splay := assoc.SplayOffset()
+		assoc.NextScheduledDate = aws.Time(assoc.ParsedExpression.Next(now.UTC().Add(-splay)).Add(splay).UTC())
+		log.Infof("Association %v missed its run scheduled at %v, skipping to the next scheduled date %v",
+			*assoc.Association.AssociationId, times.ToIsoDashUTC(missedScheduledDate), times.ToIsoDashUTC(*assoc.NextScheduledDate))
+		return
+	}
+
+	log.Infof("Association %v missed its run scheduled at %v, running it once now",
+		*assoc.Association.AssociationId, times.ToIsoDashUTC(missedScheduledDate))
+	assoc.RunNow()
+}
@@ -17,6 +17,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/association/scheduleexpression"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -210,6 +211,105 @@ func TestNextScheduledDateIsCorrectWhenExpressionIsValidCronAndHasNotBeenParsedB
 	assert.Equal(t, expectedNextScheduledDateTime, *assocRawData.NextScheduledDate)
 }
 
+func TestNextScheduledDateIsCorrectWhenCronExpressionHasTimeZoneAndOffset(t *testing.T) {
+
+	// Assemble
+	logger := log.Logger()
+
+	assocRawData := InstanceAssociation{}
+
+	assocRawData.Association = &ssm.InstanceAssociationSummary{}
+	testAssociationName := "Test"
+	assocRawData.Association.Name = &testAssociationName
+	assocId := "b2f71a28-cbe1-4429-b848-26c7e1f5ad0d"
+	assocRawData.Association.AssociationId = &assocId
+	testCronExpression := "cron(0 2 ? * TUE#2 *)" // second tuesday of the month at 2AM
+	assocRawData.Association.ScheduleExpression = &testCronExpression
+	assocRawData.ScheduleOptions = scheduleexpression.ScheduleOptions{
+		TimeZone:       "America/Los_Angeles",
+		ScheduleOffset: 2,
+	}
+
+	lastExecutionDateTime := time.Date(
+		2017, 10, 12, 9, 0, 0, 0, time.UTC)
+	assocRawData.Association.LastExecutionDate = &lastExecutionDateTime
+
+	// second thursday of November at 2AM PST
+	expectedNextScheduledDateTime := time.Date(
+		2017, 11, 16, 10, 00, 00, 000000000, time.UTC)
+
+	// Act
+	assocRawData.SetNextScheduledDate(logger)
+
+	// Assert
+	assert.Equal(t, expectedNextScheduledDateTime, *assocRawData.NextScheduledDate)
+}
+
+func TestSplayOffsetIsDeterministicAndBounded(t *testing.T) {
+
+	// Assemble
+	assocId := "b2f71a28-cbe1-4429-b848-26c7e1f5ad0d"
+	newAssociation := func(instanceId string, maxSplaySeconds int) *InstanceAssociation {
+		return &InstanceAssociation{
+			Association: &ssm.InstanceAssociationSummary{
+				AssociationId: &assocId,
+				InstanceId:    &instanceId,
+			},
+			MaxSplaySeconds: maxSplaySeconds,
+		}
+	}
+
+	// Assert
+	assert.Equal(t, time.Duration(0), newAssociation("i-1234567890abcdef0", 0).SplayOffset())
+	assert.Equal(t, time.Duration(0), (&InstanceAssociation{Association: &ssm.InstanceAssociationSummary{}, MaxSplaySeconds: 600}).SplayOffset())
+
+	offsets := make(map[time.Duration]bool)
+	for _, instanceId := range []string{"i-1234567890abcdef0", "i-0fedcba0987654321", "i-00000000000000001", "mi-0123456789abcdef0"} {
+		splay := newAssociation(instanceId, 600).SplayOffset()
+		assert.Equal(t, splay, newAssociation(instanceId, 600).SplayOffset())
+		assert.True(t, splay >= 0 && splay <= 600*time.Second)
+		assert.Equal(t, time.Duration(0), splay%time.Second)
+		offsets[splay] = true
+	}
+	assert.True(t, len(offsets) > 1)
+}
+
+func TestNextScheduledDateIncludesSplayOffset(t *testing.T) {
+
+	// Assemble
+	logger := log.Logger()
+
+	assocRawData := InstanceAssociation{}
+
+	assocRawData.Association = &ssm.InstanceAssociationSummary{}
+	testAssociationName := "Test"
+	assocRawData.Association.Name = &testAssociationName
+	assocId := "b2f71a28-cbe1-4429-b848-26c7e1f5ad0d"
+	assocRawData.Association.AssociationId = &assocId
+	instanceId := "i-1234567890abcdef0"
+	assocRawData.Association.InstanceId = &instanceId
+	testCronExpression := "cron(0 0/1 * * ? *)" // hourly cron expression
+	assocRawData.Association.ScheduleExpression = &testCronExpression
+	assocRawData.MaxSplaySeconds = 1800
+
+	splay := assocRawData.SplayOffset()
+	assert.NotEqual(t, time.Duration(0), splay)
+
+	// the last run happened at 10AM plus the splay
+	lastExecutionDateTime := time.Date(
+		2017, 10, 12, 10, 0, 0, 0, time.UTC).Add(splay)
+	assocRawData.Association.LastExecutionDate = &lastExecutionDateTime
+
+	expectedNextScheduledDateTime := time.Date(
+		2017, 10, 12, 11, 0, 0, 0, time.UTC).Add(splay)
+
+	// Act
+	assocRawData.SetNextScheduledDate(logger)
+
+	// Assert
+	assert.Equal(t, expectedNextScheduledDateTime, *assocRawData.NextScheduledDate)
+}
+
 func TestNextScheduledDateIsNilWhenCronExpressionIsInvalid(t *testing.T) {
 
 	// Assemble
@@ -384,3 +484,39 @@ func TestNextScheduledDateIsNilWhenAssociationExpressionTypeIsUnknown(t *testing
 	// Assert
 	assert.Nil(t, assocRawData.NextScheduledDate)
 }
+
+func TestApplyMissedRunPolicy(t *testing.T) {
+
+	// Assemble
+	logger := log.Logger()
+	now := time.Date(2017, 10, 12, 14, 30, 0, 0, time.UTC)
+	newAssociation := func(policy string) *InstanceAssociation {
+		assocRawData := &InstanceAssociation{MissedRunPolicy: policy}
+		assocRawData.Association = &ssm.InstanceAssociationSummary{}
+		testAssociationName := "Test"
+		assocRawData.Association.Name = &testAssociationName
+		assocId := "b2f71a28-cbe1-4429-b848-26c7e1f5ad0d"
+		assocRawData.Association.AssociationId = &assocId
+		testCronExpression := "cron(0 0/1 * * ? *)" // hourly cron expression
+		assocRawData.Association.ScheduleExpression = &testCronExpression
+		lastExecutionDateTime := time.Date(2017, 10, 12, 10, 0, 0, 0, time.UTC)
+		assocRawData.Association.LastExecutionDate = &lastExecutionDateTime
+		assocRawData.SetNextScheduledDate(logger)
+		return assocRawData
+	}
+
+	// Act
+	skipped := newAssociation(appconfig.AssociationMissedRunPolicySkip)
+	skipped.ApplyMissedRunPolicy(logger, now)
+
+	runOnce := newAssociation(appconfig.AssociationMissedRunPolicyRunOnce)
+	runOnce.ApplyMissedRunPolicy(logger, now)
+
+	upToDate := newAssociation(appconfig.AssociationMissedRunPolicySkip)
+	upToDate.ApplyMissedRunPolicy(logger, time.Date(2017, 10, 12, 10, 30, 0, 0, time.UTC))
+
+	// Assert
+	assert.Equal(t, time.Date(2017, 10, 12, 15, 0, 0, 0, time.UTC), *skipped.NextScheduledDate)
+	assert.True(t, runOnce.NextScheduledDate.After(now))
+	assert.Equal(t, time.Date(2017, 10, 12, 11, 0, 0, 0, time.UTC), *upToDate.NextScheduledDate)
+}
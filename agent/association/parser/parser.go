@@ -26,6 +26,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/docparser"
+	"github.com/aws/amazon-ssm-agent/agent/docsignature"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
@@ -89,14 +90,28 @@ func InitializeDocumentState(context context.T,
 	orchestrationDir := filepath.Join(orchestrationRootDir, documentInfo.AssociationID, documentInfo.RunID)
 
 	parserInfo := docparser.DocumentParserInfo{
-		OrchestrationDir: orchestrationDir,
-		S3Bucket:         payload.OutputS3BucketName,
-		S3Prefix:         s3KeyPrefix,
-		MessageId:        documentInfo.MessageID,
-		DocumentId:       documentInfo.DocumentID,
+		OrchestrationDir:  orchestrationDir,
+		S3Bucket:          payload.OutputS3BucketName,
+		S3Prefix:          s3KeyPrefix,
+		MessageId:         documentInfo.MessageID,
+		DocumentId:        documentInfo.DocumentID,
+		SignatureRequired: docsignature.Required(context.AppConfig().Agent, rawData.Local),
+		DocumentName:      trustedDocumentName(rawData, payload.DocumentName),
+		Signature:         rawData.DocumentSignature,
+		RawContent:        []byte(*rawData.Document),
+		TrustedKeysDir:    context.AppConfig().Agent.TrustedKeysDir,
 	}
 
-	return docparser.InitializeDocState(context.Log(), contracts.Association, &payload.DocumentContent, documentInfo, parserInfo, payload.Parameters)
+	docState, err := docparser.InitializeDocState(context.Log(), contracts.Association, &payload.DocumentContent, documentInfo, parserInfo, payload.Parameters)
+	if err != nil || !rawData.CheckOnly {
+		return docState, err
+	}
+
+	// plugins only check the instance complies with their configuration
+	for i := range docState.InstancePluginsInformation {
+		docState.InstancePluginsInformation[i].Configuration.CheckOnly = true
+	}
+	return docState, nil
 }
 
 // newDocumentInfo initializes new DocumentInfo object
@@ -117,3 +132,12 @@ func newDocumentInfo(rawData *model.InstanceAssociation, payload *messageContrac
 
 	return *documentInfo
 }
+
+// trustedDocumentName returns the name of the document of the associations delivered by the service, the names of
+// the local associations are chosen locally and the plugin policy only matches them by hash
+func trustedDocumentName(rawData *model.InstanceAssociation, documentName string) string {
+	if rawData.Local {
+		return ""
+	}
+	return documentName
+}
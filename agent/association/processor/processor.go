@@ -20,12 +20,17 @@ import (
 	"time"
 
 	"regexp"
+	"sort"
 
 	"path"
 	"strings"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/association/cache"
+	"github.com/aws/amazon-ssm-agent/agent/association/localassociation"
+	"github.com/aws/amazon-ssm-agent/agent/association/maintenancewindow"
 	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/association/scheduleexpression"
 	"github.com/aws/amazon-ssm-agent/agent/association/schedulemanager"
 	"github.com/aws/amazon-ssm-agent/agent/association/schedulemanager/signal"
 	assocScheduler "github.com/aws/amazon-ssm-agent/agent/association/scheduler"
@@ -43,10 +48,14 @@ import (
 
 const (
 	name                                    = "Association"
-	documentWorkersLimit                    = 1
+	cancelWorkersLimit                      = 1
 	cancelWaitDurationMillisecond           = 10000
 	documentLevelTimeOutDurationHour        = 2
 	outputMessageTemplate            string = "%v out of %v plugin%v processed, %v success, %v failed, %v timedout, %v skipped"
+	splayMessageTemplate             string = "%v, scheduled run delayed by splay offset of %v"
+	checkOnlyMessageTemplate         string = "Check only, instance is %v, %v"
+	compliantMessage                        = "Compliant"
+	nonCompliantMessage                     = "NonCompliant"
 	defaultRetryWaitOnBootInSeconds         = 30
 )
 
@@ -59,6 +68,7 @@ type Processor struct {
 	agentInfo          *contracts.AgentInfo
 	proc               processor.Processor
 	resChan            chan contracts.DocumentResult
+	workers            *workers
 	onBoot             bool
 }
 
@@ -79,18 +89,28 @@ func NewAssociationProcessor(context context.T) *Processor {
 		OsVersion: config.Os.Version,
 	}
 
-	assocSvc := service.NewAssociationService(name)
-	uploader := complianceUploader.NewComplianceUploader(context)
+	// local associations are served along with the associations of the service
+	var assocSvc *localassociation.Service
+	var uploader complianceUploader.T
+	if config.Agent.OfflineMode {
+		assocSvc = localassociation.NewService(localassociation.Offline{})
+		uploader = assocSvc.ComplianceUploader(localassociation.Offline{})
+	} else {
+		assocSvc = localassociation.NewService(service.NewAssociationService(name))
+		uploader = assocSvc.ComplianceUploader(complianceUploader.NewComplianceUploader(context))
+	}
 
 	//TODO Rename everything to service and move package to framework
 	//association has no cancel worker
-	proc := processor.NewEngineProcessor(assocContext, documentWorkersLimit, documentWorkersLimit, []contracts.DocumentType{contracts.Association})
+	workersLimit := config.Ssm.AssociationWorkersLimit
+	proc := processor.NewEngineProcessor(assocContext, workersLimit, cancelWorkersLimit, []contracts.DocumentType{contracts.Association})
 	return &Processor{
 		context:            assocContext,
 		assocSvc:           assocSvc,
 		complianceUploader: uploader,
 		agentInfo:          &agentInfo,
 		proc:               proc,
+		workers:            newWorkers(workersLimit),
 		onBoot:             true,
 	}
 }
@@ -127,6 +147,11 @@ func (p *Processor) InitializeAssociationProcessor() {
 	} else {
 		p.resChan = resChan
 	}
+	if instanceID, err := sys.InstanceID(); err != nil {
+		log.Errorf("Unable to retrieve instance id, association schedule state will not be persisted, %v", err)
+	} else {
+		schedulemanager.LoadScheduleState(log, instanceID)
+	}
 	log.Info("Initializing association scheduling service")
 	signal.InitializeAssociationSignalService(log, p.runScheduledAssociation)
 	log.Info("Association scheduling service initialized")
@@ -178,6 +203,8 @@ func (p *Processor) ProcessAssociation() {
 
 	// read from cache or load association details from service
 	for _, assoc := range associations {
+		applyScheduleConfig(p.context.AppConfig().Ssm, assoc)
+
 		var assocContent string
 		if assocContent, err = jsonutil.Marshal(assoc); err != nil {
 			return
@@ -240,11 +267,23 @@ func (p *Processor) ProcessAssociation() {
 		}
 	}
 
+	p.loadMaintenanceWindows(log)
 	schedulemanager.Refresh(log, associations)
 	signal.ExecuteAssociation(log)
 }
 
-// runScheduledAssociation runs the next scheduled association
+// loadMaintenanceWindows reloads the maintenance windows and the blackout calendar, the blackout calendar file
+// can change at any time. The previous windows are kept when the new ones are invalid.
+func (p *Processor) loadMaintenanceWindows(log log.T) {
+	calendar, err := maintenancewindow.New(log, p.context.AppConfig().Ssm)
+	if err != nil {
+		log.Errorf("Unable to load association maintenance windows, %v", err)
+		return
+	}
+	schedulemanager.SetMaintenanceWindows(calendar)
+}
+
+// runScheduledAssociation runs the due associations as long as association workers are available
 func (p *Processor) runScheduledAssociation(log log.T) {
 	lock.Lock()
 	defer lock.Unlock()
@@ -256,14 +295,28 @@ func (p *Processor) runScheduledAssociation(log log.T) {
 		}
 	}()
 
+	p.reportTimedOutAssociations(log)
+	for p.workers.available() {
+		if !p.runNextScheduledAssociation(log) {
+			return
+		}
+	}
+	log.Debugf("All %v association workers are busy, system will retry once an association completes", p.workers.limit)
+}
+
+// runNextScheduledAssociation submits the next scheduled association, it returns false if no association was submitted
+func (p *Processor) runNextScheduledAssociation(log log.T) bool {
 	var (
 		scheduledAssociation *model.InstanceAssociation
+		blockedAssociations  []*schedulemanager.BlockedAssociation
 		err                  error
 	)
 
-	if scheduledAssociation, err = schedulemanager.LoadNextScheduledAssociation(log); err != nil {
+	scheduledAssociation, blockedAssociations, err = schedulemanager.LoadNextScheduledAssociation(log)
+	p.reportBlockedAssociations(log, blockedAssociations)
+	if err != nil {
 		log.Errorf("Unable to get next scheduled association, %v, system will retry later", err)
-		return
+		return false
 	}
 
 	if scheduledAssociation == nil {
@@ -274,37 +327,16 @@ func (p *Processor) runScheduledAssociation(log log.T) {
 		} else {
 			log.Debug("No association scheduled at this time, system will retry later")
 		}
-		return
+		return false
 	}
 
 	// stop previous wait timer if there is scheduled association
 	signal.StopWaitTimerForNextScheduledAssociation()
 
-	if schedulemanager.IsAssociationInProgress(*scheduledAssociation.Association.AssociationId) {
-		if isAssociationTimedOut(scheduledAssociation) {
-			err = fmt.Errorf("Association stuck at InProgress for longer than %v hours", documentLevelTimeOutDurationHour)
-			log.Error(err)
-			p.assocSvc.UpdateInstanceAssociationStatus(
-				log,
-				*scheduledAssociation.Association.AssociationId,
-				*scheduledAssociation.Association.Name,
-				*scheduledAssociation.Association.InstanceId,
-				contracts.AssociationStatusFailed,
-				contracts.AssociationErrorCodeStuckAtInProgressError,
-				times.ToIso8601UTC(time.Now()),
-				err.Error(),
-				service.NoOutputUrl)
-			p.complianceUploader.UpdateAssociationCompliance(
-				*scheduledAssociation.Association.AssociationId,
-				*scheduledAssociation.Association.InstanceId,
-				*scheduledAssociation.Association.Name,
-				*scheduledAssociation.Association.DocumentVersion,
-				contracts.AssociationStatusFailed,
-				time.Now().UTC())
-
-		}
-
-		return
+	if schedulemanager.IsAssociationInProgress(*scheduledAssociation.Association.AssociationId) ||
+		!p.workers.acquire(*scheduledAssociation.Association.AssociationId) {
+		log.Debugf("Association %v is already running", *scheduledAssociation.Association.AssociationId)
+		return false
 	}
 
 	log.Debugf("Update association %v to pending ", *scheduledAssociation.Association.AssociationId)
@@ -343,7 +375,8 @@ func (p *Processor) runScheduledAssociation(log log.T) {
 			*scheduledAssociation.Association.DocumentVersion,
 			contracts.AssociationStatusFailed,
 			time.Now().UTC())
-		return
+		p.workers.release(*scheduledAssociation.Association.AssociationId)
+		return false
 	}
 	updatePluginAssociationInstances(*scheduledAssociation.Association.AssociationId, docState)
 	log = p.context.With("[associationId=" + docState.DocumentInformation.AssociationID + "]").Log()
@@ -360,6 +393,67 @@ func (p *Processor) runScheduledAssociation(log log.T) {
 		service.NoOutputUrl)
 
 	p.proc.Submit(*docState)
+	return true
+}
+
+// reportTimedOutAssociations fails the due associations stuck at InProgress for longer than the document timeout
+func (p *Processor) reportTimedOutAssociations(log log.T) {
+	currentTime := time.Now().UTC()
+	for _, assoc := range schedulemanager.InProgressAssociations() {
+		if assoc.NextScheduledDate == nil || assoc.NextScheduledDate.After(currentTime) || !isAssociationTimedOut(assoc) {
+			continue
+		}
+
+		err := fmt.Errorf("Association stuck at InProgress for longer than %v hours", documentLevelTimeOutDurationHour)
+		log.Error(err)
+		p.assocSvc.UpdateInstanceAssociationStatus(
+			log,
+			*assoc.Association.AssociationId,
+			*assoc.Association.Name,
+			*assoc.Association.InstanceId,
+			contracts.AssociationStatusFailed,
+			contracts.AssociationErrorCodeStuckAtInProgressError,
+			times.ToIso8601UTC(time.Now()),
+			err.Error(),
+			service.NoOutputUrl)
+		p.complianceUploader.UpdateAssociationCompliance(
+			*assoc.Association.AssociationId,
+			*assoc.Association.InstanceId,
+			*assoc.Association.Name,
+			*assoc.Association.DocumentVersion,
+			contracts.AssociationStatusFailed,
+			time.Now().UTC())
+		p.workers.release(*assoc.Association.AssociationId)
+	}
+}
+
+// reportBlockedAssociations reports the due associations blocked by their dependencies, exclusion groups
+// or maintenance windows, skipped associations are scheduled for their next cycle
+func (p *Processor) reportBlockedAssociations(log log.T, blockedAssociations []*schedulemanager.BlockedAssociation) {
+	for _, blocked := range blockedAssociations {
+		assoc := blocked.Association.Association
+		// waiting and deferred associations are reported once
+		if blocked.Status != contracts.AssociationStatusSkipped &&
+			assoc.DetailedStatus != nil && *assoc.DetailedStatus == blocked.Status {
+			continue
+		}
+
+		log.Infof("Association %v is %v, %v", *assoc.AssociationId, blocked.Status, blocked.Reason)
+		p.assocSvc.UpdateInstanceAssociationStatus(
+			log,
+			*assoc.AssociationId,
+			*assoc.Name,
+			*assoc.InstanceId,
+			blocked.Status,
+			blocked.ErrorCode,
+			times.ToIso8601UTC(time.Now()),
+			blocked.Reason,
+			service.NoOutputUrl)
+
+		if blocked.Status == contracts.AssociationStatusSkipped {
+			schedulemanager.UpdateNextScheduledDate(log, *assoc.AssociationId)
+		}
+	}
 }
 
 func isAssociationTimedOut(assoc *model.InstanceAssociation) bool {
@@ -445,6 +539,7 @@ func (r *Processor) pluginExecutionReport(
 	}
 
 	executionSummary, outputUrl := buildOutput(runtimeStatuses, totalNumberOfPlugins)
+	executionSummary = appendSplayOffset(executionSummary, schedulemanager.SplayOffset(associationID))
 
 	r.assocSvc.UpdateInstanceAssociationStatus(
 		log,
@@ -478,6 +573,14 @@ func (r *Processor) associationExecutionReport(
 	log.Info("Update instance association status with results ", jsonutil.Indent(runtimeStatusesContent))
 
 	executionSummary, outputUrl := buildOutput(runtimeStatuses, totalNumberOfPlugins)
+	executionSummary = appendSplayOffset(executionSummary, schedulemanager.SplayOffset(associationID))
+	if schedulemanager.IsCheckOnly(associationID) {
+		// the association did not change the instance, a failed check means the instance is out of compliance
+		executionSummary = checkOnlySummary(executionSummary, associationStatus)
+		if associationStatus == contracts.AssociationStatusFailed {
+			errorCode = contracts.AssociationErrorCodeNonCompliant
+		}
+	}
 	instanceID, _ := sys.InstanceID()
 	r.assocSvc.UpdateInstanceAssociationStatus(
 		log,
@@ -548,6 +651,7 @@ func (r *Processor) lisenToResponses() {
 				r.context.AppConfig().Ssm.AssociationLogsRetentionDurationHours,
 				isAssociationLogFile)
 			//TODO move this part to service
+			r.workers.release(res.AssociationID)
 			schedulemanager.UpdateNextScheduledDate(log, res.AssociationID)
 			signal.ExecuteAssociation(log)
 
@@ -601,6 +705,142 @@ func buildOutput(runtimeStatuses map[string]*contracts.PluginRuntimeStatus, tota
 	return fmt.Sprintf(outputMessageTemplate, completed, totalNumberOfPlugins, plural, success, failed, timedOut, skipped), outputUrl
 }
 
+// applyScheduleConfig applies the schedule settings of the agent configuration to the association
+func applyScheduleConfig(config appconfig.SsmCfg, assoc *model.InstanceAssociation) {
+	assoc.MaxSplaySeconds = maxSplaySeconds(config, assoc)
+	assoc.MissedRunPolicy = missedRunPolicy(config, assoc)
+	// local associations carry the schedule options of their definition
+	if !assoc.Local {
+		assoc.ScheduleOptions = scheduleOptions(config, assoc)
+	}
+	assoc.OutsideWindowPolicy = outsideWindowPolicy(config, assoc)
+	assoc.DependsOn = appendUnique(assoc.DependsOn, dependencies(config, assoc)...)
+	assoc.ExclusionGroups = appendUnique(assoc.ExclusionGroups, exclusionGroups(config, assoc)...)
+	assoc.CheckOnly = assoc.CheckOnly || checkOnly(config, assoc)
+}
+
+// dependencies returns the dependencies configured for the association by association ID and by association name
+func dependencies(config appconfig.SsmCfg, assoc *model.InstanceAssociation) (result []string) {
+	for association, dependencies := range config.AssociationDependencies {
+		if assoc.Matches(association) {
+			result = appendUnique(result, dependencies...)
+		}
+	}
+	return result
+}
+
+// checkOnly returns true if the association is configured in check-only mode by association ID or name
+func checkOnly(config appconfig.SsmCfg, assoc *model.InstanceAssociation) bool {
+	for _, member := range config.AssociationCheckOnly {
+		if assoc.Matches(member) {
+			return true
+		}
+	}
+	return false
+}
+
+// exclusionGroups returns the exclusion groups the association is configured in by association ID or name
+func exclusionGroups(config appconfig.SsmCfg, assoc *model.InstanceAssociation) (result []string) {
+	for group, members := range config.AssociationExclusionGroups {
+		for _, member := range members {
+			if assoc.Matches(member) {
+				result = appendUnique(result, group)
+				break
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// appendUnique appends the values missing from the slice
+func appendUnique(slice []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range slice {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			slice = append(slice, value)
+		}
+	}
+	return slice
+}
+
+// checkOnlySummary tells whether the instance complies with the document of an association run in check-only mode
+func checkOnlySummary(outputSummary string, associationStatus string) string {
+	switch associationStatus {
+	case contracts.AssociationStatusSuccess:
+		return fmt.Sprintf(checkOnlyMessageTemplate, compliantMessage, outputSummary)
+	case contracts.AssociationStatusFailed, contracts.AssociationStatusTimedOut:
+		return fmt.Sprintf(checkOnlyMessageTemplate, nonCompliantMessage, outputSummary)
+	}
+	return outputSummary
+}
+
+// appendSplayOffset adds the splay offset the association run was delayed by to the output message
+func appendSplayOffset(outputSummary string, splay time.Duration) string {
+	if splay <= 0 {
+		return outputSummary
+	}
+	return fmt.Sprintf(splayMessageTemplate, outputSummary, splay)
+}
+
+// overrideKeys returns the keys the overrides of the association are looked up with in the configuration,
+// the association ID first and then the association name
+func overrideKeys(assoc *model.InstanceAssociation) (keys []string) {
+	if assoc.Association.AssociationId != nil {
+		keys = append(keys, *assoc.Association.AssociationId)
+	}
+	if assoc.Association.Name != nil {
+		keys = append(keys, *assoc.Association.Name)
+	}
+	return keys
+}
+
+// maxSplaySeconds returns the maximum splay configured for the association
+func maxSplaySeconds(config appconfig.SsmCfg, assoc *model.InstanceAssociation) int {
+	for _, key := range overrideKeys(assoc) {
+		if value, ok := config.AssociationMaxSplayOverrides[key]; ok {
+			return value
+		}
+	}
+	return config.AssociationMaxSplaySeconds
+}
+
+// missedRunPolicy returns the missed run policy configured for the association
+func missedRunPolicy(config appconfig.SsmCfg, assoc *model.InstanceAssociation) string {
+	for _, key := range overrideKeys(assoc) {
+		if value, ok := config.AssociationMissedRunPolicyOverrides[key]; ok {
+			return value
+		}
+	}
+	return config.AssociationMissedRunPolicy
+}
+
+// scheduleOptions returns the schedule options configured for the association
+func scheduleOptions(config appconfig.SsmCfg, assoc *model.InstanceAssociation) scheduleexpression.ScheduleOptions {
+	for _, key := range overrideKeys(assoc) {
+		if value, ok := config.AssociationScheduleOptions[key]; ok {
+			return scheduleexpression.ScheduleOptions{TimeZone: value.TimeZone, ScheduleOffset: value.ScheduleOffset}
+		}
+	}
+	return scheduleexpression.ScheduleOptions{}
+}
+
+// outsideWindowPolicy returns the outside window policy configured for the association
+func outsideWindowPolicy(config appconfig.SsmCfg, assoc *model.InstanceAssociation) string {
+	for _, key := range overrideKeys(assoc) {
+		if value, ok := config.AssociationOutsideWindowPolicyOverrides[key]; ok {
+			return value
+		}
+	}
+	return config.AssociationOutsideWindowPolicy
+}
+
 // filterByStatus represents the helper method that filter pluginResults base on ResultStatus
 func filterByStatus(runtimeStatuses map[string]*contracts.PluginRuntimeStatus, predicate func(contracts.ResultStatus) bool) map[string]*contracts.PluginRuntimeStatus {
 	result := make(map[string]*contracts.PluginRuntimeStatus)
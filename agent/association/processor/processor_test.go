@@ -19,7 +19,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/association/scheduleexpression"
 	"github.com/aws/amazon-ssm-agent/agent/association/schedulemanager"
 	"github.com/aws/amazon-ssm-agent/agent/association/service"
 	complianceUploader "github.com/aws/amazon-ssm-agent/agent/compliance/uploader"
@@ -282,6 +284,205 @@ func TestRemovePluginAssociationInstances(t *testing.T) {
 	assert.Equal(t, resultMap, pluginAssociationInstances)
 }
 
+func TestOverrideKeys(t *testing.T) {
+	testAssociationID := "testAssociationID"
+	testName := "testName"
+	assert.Equal(t, []string{testAssociationID, testName}, overrideKeys(&model.InstanceAssociation{
+		Association: &ssm.InstanceAssociationSummary{AssociationId: &testAssociationID, Name: &testName},
+	}))
+	assert.Equal(t, []string{testName}, overrideKeys(&model.InstanceAssociation{
+		Association: &ssm.InstanceAssociationSummary{Name: &testName},
+	}))
+	assert.Empty(t, overrideKeys(&model.InstanceAssociation{Association: &ssm.InstanceAssociationSummary{}}))
+}
+
+func TestMaxSplaySeconds(t *testing.T) {
+	testAssociationID := "testAssociationID"
+	testName := "testName"
+	assoc := &model.InstanceAssociation{
+		Association: &ssm.InstanceAssociationSummary{
+			AssociationId: &testAssociationID,
+			Name:          &testName,
+		},
+	}
+
+	config := appconfig.SsmCfg{AssociationMaxSplaySeconds: 300}
+	assert.Equal(t, 300, maxSplaySeconds(config, assoc))
+
+	config.AssociationMaxSplayOverrides = map[string]int{testName: 60}
+	assert.Equal(t, 60, maxSplaySeconds(config, assoc))
+
+	config.AssociationMaxSplayOverrides[testAssociationID] = 0
+	assert.Equal(t, 0, maxSplaySeconds(config, assoc))
+}
+
+func TestScheduleOptions(t *testing.T) {
+	testAssociationID := "testAssociationID"
+	testName := "testName"
+	assoc := &model.InstanceAssociation{
+		Association: &ssm.InstanceAssociationSummary{
+			AssociationId:      &testAssociationID,
+			Name:               &testName,
+			ScheduleExpression: aws.String("cron(0 2 ? * TUE#2 *)"),
+		},
+	}
+
+	config := appconfig.SsmCfg{}
+	applyScheduleConfig(config, assoc)
+	assert.True(t, assoc.ScheduleOptions.IsDefault())
+
+	config.AssociationScheduleOptions = map[string]appconfig.ScheduleOptionsCfg{testName: {TimeZone: "America/New_York"}}
+	applyScheduleConfig(config, assoc)
+	assert.Equal(t, scheduleexpression.ScheduleOptions{TimeZone: "America/New_York"}, assoc.ScheduleOptions)
+
+	config.AssociationScheduleOptions[testAssociationID] = appconfig.ScheduleOptionsCfg{TimeZone: "America/New_York", ScheduleOffset: 2}
+	applyScheduleConfig(config, assoc)
+	assert.Equal(t, scheduleexpression.ScheduleOptions{TimeZone: "America/New_York", ScheduleOffset: 2}, assoc.ScheduleOptions)
+
+	// the service association is scheduled with the configured options, the second Tuesday of January 2018
+	// is the 9th, two days later at 2 AM in New York
+	assert.Nil(t, assoc.ParseExpression(log.NewMockLog()))
+	next := assoc.ParsedExpression.Next(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2018, 1, 11, 7, 0, 0, 0, time.UTC), next.UTC())
+
+	// the options removed from the configuration are removed from the association
+	config.AssociationScheduleOptions = nil
+	applyScheduleConfig(config, assoc)
+	assert.True(t, assoc.ScheduleOptions.IsDefault())
+
+	// local associations keep the options of their definition
+	local := &model.InstanceAssociation{
+		Association:     &ssm.InstanceAssociationSummary{AssociationId: &testAssociationID, Name: &testName},
+		ScheduleOptions: scheduleexpression.ScheduleOptions{TimeZone: "Europe/London"},
+		Local:           true,
+	}
+	config.AssociationScheduleOptions = map[string]appconfig.ScheduleOptionsCfg{testName: {TimeZone: "America/New_York"}}
+	applyScheduleConfig(config, local)
+	assert.Equal(t, scheduleexpression.ScheduleOptions{TimeZone: "Europe/London"}, local.ScheduleOptions)
+}
+
+func TestMissedRunPolicy(t *testing.T) {
+	testAssociationID := "testAssociationID"
+	testName := "testName"
+	assoc := &model.InstanceAssociation{
+		Association: &ssm.InstanceAssociationSummary{
+			AssociationId: &testAssociationID,
+			Name:          &testName,
+		},
+	}
+
+	config := appconfig.SsmCfg{AssociationMissedRunPolicy: appconfig.AssociationMissedRunPolicyRunOnce}
+	assert.Equal(t, appconfig.AssociationMissedRunPolicyRunOnce, missedRunPolicy(config, assoc))
+
+	config.AssociationMissedRunPolicyOverrides = map[string]string{testName: appconfig.AssociationMissedRunPolicySkip}
+	assert.Equal(t, appconfig.AssociationMissedRunPolicySkip, missedRunPolicy(config, assoc))
+}
+
+func TestApplyScheduleConfig(t *testing.T) {
+	testAssociationID := "testAssociationID"
+	testName := "testName"
+	assoc := &model.InstanceAssociation{
+		Association: &ssm.InstanceAssociationSummary{
+			AssociationId: &testAssociationID,
+			Name:          &testName,
+		},
+		DependsOn: []string{"local"},
+	}
+
+	config := appconfig.SsmCfg{
+		AssociationDependencies: map[string][]string{
+			testAssociationID: {"first", "local"},
+			testName:          {"second"},
+			"other":           {"third"},
+		},
+		AssociationExclusionGroups: map[string][]string{
+			"patching":  {testName, "other"},
+			"install":   {testAssociationID},
+			"unrelated": {"other"},
+		},
+		AssociationCheckOnly:                    []string{"other", testName},
+		AssociationOutsideWindowPolicy:          appconfig.AssociationOutsideWindowPolicyDefer,
+		AssociationOutsideWindowPolicyOverrides: map[string]string{testName: appconfig.AssociationOutsideWindowPolicySkip},
+	}
+	applyScheduleConfig(config, assoc)
+
+	assert.Equal(t, 3, len(assoc.DependsOn))
+	assert.Equal(t, "local", assoc.DependsOn[0])
+	assert.Contains(t, assoc.DependsOn, "first")
+	assert.Contains(t, assoc.DependsOn, "second")
+	assert.Equal(t, []string{"install", "patching"}, assoc.ExclusionGroups)
+	assert.Equal(t, appconfig.AssociationOutsideWindowPolicySkip, assoc.OutsideWindowPolicy)
+	assert.True(t, assoc.CheckOnly)
+}
+
+func TestCheckOnlySummary(t *testing.T) {
+	summary := "1 out of 1 plugin processed, 1 success, 0 failed, 0 timedout, 0 skipped"
+	assert.Equal(t, "Check only, instance is Compliant, "+summary, checkOnlySummary(summary, contracts.AssociationStatusSuccess))
+	assert.Equal(t, "Check only, instance is NonCompliant, "+summary, checkOnlySummary(summary, contracts.AssociationStatusFailed))
+	assert.Equal(t, summary, checkOnlySummary(summary, contracts.AssociationStatusSkipped))
+}
+
+func TestAppendSplayOffset(t *testing.T) {
+	summary := "1 out of 1 plugin processed, 1 success, 0 failed, 0 timedout, 0 skipped"
+	assert.Equal(t, summary, appendSplayOffset(summary, 0))
+	assert.Equal(t, summary+", scheduled run delayed by splay offset of 2m5s", appendSplayOffset(summary, 125*time.Second))
+}
+
+// runOverlappingAssociations runs two associations due at the same time with the given number of workers
+// and returns the number of associations submitted to the engine
+func runOverlappingAssociations(t *testing.T, workersLimit int) int {
+	processor := createProcessor()
+	processor.workers = newWorkers(workersLimit)
+	svcMock := service.NewMockDefault()
+	parserMock := parserMock{}
+	processorMock := &processormock.MockedProcessor{}
+	processor.assocSvc = svcMock
+	processor.proc = processorMock
+	assocParser = &parserMock
+	sys = &systemStub{}
+
+	// neither association has run before, both are due now
+	assocs := []*model.InstanceAssociation{}
+	for _, associationID := range []string{"inventory", "baseline"} {
+		assocs = append(assocs, &model.InstanceAssociation{
+			Association: &ssm.InstanceAssociationSummary{
+				AssociationId:      aws.String(associationID),
+				Name:               aws.String(associationID + "-document"),
+				InstanceId:         aws.String("i-test"),
+				DocumentVersion:    aws.String("1"),
+				ScheduleExpression: aws.String("cron(0 0/30 * 1/1 * ? *)"),
+			},
+		})
+	}
+	schedulemanager.Refresh(log.NewMockLog(), assocs)
+	defer schedulemanager.Refresh(log.NewMockLog(), []*model.InstanceAssociation{})
+
+	// the service records the status of the association in the schedule manager
+	svcMock.On("UpdateInstanceAssociationStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(
+		func(args mock.Arguments) {
+			schedulemanager.UpdateAssociationStatus(log.NewMockLog(), args.String(1), contracts.AssociationStatusInProgress)
+		})
+	parserMock.On("ParseDocumentForPayload", mock.Anything, mock.Anything).Return(&messageContracts.SendCommandPayload{}, nil)
+	for _, assoc := range assocs {
+		docState := contracts.DocumentState{}
+		docState.DocumentInformation.AssociationID = *assoc.Association.AssociationId
+		parserMock.On("InitializeDocumentState", mock.Anything, mock.Anything, assoc).Return(docState, nil)
+	}
+	processorMock.On("Submit", mock.Anything)
+
+	processor.runScheduledAssociation(log.NewMockLog())
+
+	return len(processorMock.Calls)
+}
+
+func TestRunOverlappingAssociationsConcurrently(t *testing.T) {
+	assert.Equal(t, 2, runOverlappingAssociations(t, 2))
+}
+
+func TestRunOverlappingAssociationsWithSingleWorker(t *testing.T) {
+	assert.Equal(t, 1, runOverlappingAssociations(t, 1))
+}
+
 func mockParser(parserMock *parserMock, payload *messageContracts.SendCommandPayload, docState contracts.DocumentState) {
 	parserMock.On(
 		"InitializeDocumentState",
@@ -293,6 +494,7 @@ func mockParser(parserMock *parserMock, payload *messageContracts.SendCommandPay
 func createProcessor() *Processor {
 	processor := Processor{}
 	processor.context = context.NewMockDefault()
+	processor.workers = newWorkers(1)
 	return &processor
 }
 
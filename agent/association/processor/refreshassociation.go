@@ -27,7 +27,6 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
-	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/times"
 
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
@@ -63,13 +62,13 @@ func (p *Processor) ProcessRefreshAssociation(log log.T, pluginRes *contracts.Pl
 		}
 	}
 	out.Close(log)
-	pluginConfig := iohandler.DefaultOutputConfig()
+	pluginConfig := iohandler.DocumentOutputConfig(out.GetIOConfig())
 
 	pluginRes.Code = out.GetExitCode()
 	pluginRes.Status = contracts.ResultStatusSuccess
 	pluginRes.Output = out.GetOutput()
-	pluginRes.StandardOutput = pluginutil.StringPrefix(out.GetStdout(), pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
-	pluginRes.StandardError = pluginutil.StringPrefix(out.GetStderr(), pluginConfig.MaxStderrLength, pluginConfig.OutputTruncatedSuffix)
+	pluginRes.StandardOutput = pluginConfig.TruncateStdout(out.GetStdout())
+	pluginRes.StandardError = pluginConfig.TruncateStderr(out.GetStderr())
 }
 
 // refreshAssociation executes one the command and returns their output.
@@ -102,6 +101,8 @@ func (p *Processor) refreshAssociation(log log.T, associationIds []string, orche
 
 	// read from cache or load association details from service
 	for _, assoc := range associations {
+		applyScheduleConfig(p.context.AppConfig().Ssm, assoc)
+
 		if err = p.assocSvc.LoadAssociationDetail(log, assoc); err != nil {
 			err = fmt.Errorf("Encountered error while loading association %v contents, %v",
 				*assoc.Association.AssociationId,
@@ -163,6 +164,7 @@ func (p *Processor) refreshAssociation(log log.T, associationIds []string, orche
 		}
 	}
 
+	p.loadMaintenanceWindows(log)
 	schedulemanager.Refresh(log, associations)
 
 	if applyAll {
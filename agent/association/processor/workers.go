@@ -0,0 +1,60 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package processor
+
+import (
+	"sync"
+)
+
+// workers keeps track of the associations submitted to the engine which have not completed yet,
+// so that no more than limit associations run concurrently
+type workers struct {
+	mutex   sync.Mutex
+	limit   int
+	running map[string]bool
+}
+
+// newWorkers creates the tracker of limit association workers
+func newWorkers(limit int) *workers {
+	return &workers{
+		limit:   limit,
+		running: make(map[string]bool),
+	}
+}
+
+// available returns true if another association can be submitted
+func (w *workers) available() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return len(w.running) < w.limit
+}
+
+// acquire reserves a worker for the association, it returns false when all workers are busy
+// or the association is already running
+func (w *workers) acquire(associationID string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(w.running) >= w.limit || w.running[associationID] {
+		return false
+	}
+	w.running[associationID] = true
+	return true
+}
+
+// release frees the worker of the association once it completes
+func (w *workers) release(associationID string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	delete(w.running, associationID)
+}
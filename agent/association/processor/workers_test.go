@@ -0,0 +1,40 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkers(t *testing.T) {
+	w := newWorkers(2)
+	assert.True(t, w.available())
+
+	assert.True(t, w.acquire("first"))
+	// an association never runs concurrently with itself
+	assert.False(t, w.acquire("first"))
+	assert.True(t, w.acquire("second"))
+	assert.False(t, w.available())
+	assert.False(t, w.acquire("third"))
+
+	w.release("first")
+	assert.True(t, w.available())
+	assert.True(t, w.acquire("third"))
+
+	// releasing an association which is not running is a no-op
+	w.release("unknown")
+	assert.False(t, w.available())
+}
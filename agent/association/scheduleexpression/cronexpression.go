@@ -0,0 +1,91 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package scheduleexpression provides interface for schedule expression and factory for constructing generic parsed
+// schedule expression
+package scheduleexpression
+
+import (
+	"time"
+
+	"github.com/gorhill/cronexpr"
+)
+
+const (
+	// maxScheduleOffset is the maximum number of days a cron schedule can be offset by
+	maxScheduleOffset = 6
+)
+
+// CronExpression is a cron expression evaluated on the wall clock of a time zone, optionally offset by a number of days.
+//
+// The cron fields are matched against the local wall clock time, so a schedule keeps firing at the same local time
+// across daylight saving time transitions:
+//  - a wall clock time skipped when the clocks move forward fires right after the transition, e.g. 02:30 fires at 03:30
+//  - a wall clock time repeated when the clocks move back fires only once, at its first occurrence
+type CronExpression struct {
+	expression     *cronexpr.Expression
+	location       *time.Location
+	scheduleOffset int
+}
+
+// Next returns the closest time instant immediately following fromTime which matches the cron expression,
+// shifted by the schedule offset. The zero time is returned if no matching time instant exists.
+func (c *CronExpression) Next(fromTime time.Time) time.Time {
+	// Evaluate the expression on the wall clock, expressed in UTC where days always last 24 hours
+	wallClock := toWallClock(fromTime.In(c.location))
+	next := wallClock.AddDate(0, 0, -c.scheduleOffset)
+	for {
+		next = c.expression.Next(next)
+		if next.IsZero() {
+			return next
+		}
+		if instant, found := c.firstInstantAfter(next.AddDate(0, 0, c.scheduleOffset), fromTime); found {
+			return instant
+		}
+	}
+}
+
+// firstInstantAfter returns the first time instant after fromTime showing the given wall clock time in the location.
+func (c *CronExpression) firstInstantAfter(wallClock time.Time, fromTime time.Time) (time.Time, bool) {
+	// The offset of the location can only change around the wall clock time during a transition,
+	// try the offsets in effect half a day before and after it.
+	_, offsetBefore := wallClock.Add(-12 * time.Hour).In(c.location).Zone()
+	_, offsetAfter := wallClock.Add(12 * time.Hour).In(c.location).Zone()
+
+	var first time.Time
+	for _, offset := range []int{offsetBefore, offsetAfter} {
+		instant := wallClock.Add(-time.Duration(offset) * time.Second).In(c.location)
+		if !toWallClock(instant).Equal(wallClock) || !instant.After(fromTime) {
+			continue
+		}
+		if first.IsZero() || instant.Before(first) {
+			first = instant
+		}
+	}
+	if !first.IsZero() {
+		return first, true
+	}
+
+	// The wall clock time does not exist in the location as the clocks moved forward,
+	// run at the same elapsed time from the transition instead.
+	instant := wallClock.Add(-time.Duration(offsetBefore) * time.Second).In(c.location)
+	if offsetBefore != offsetAfter && instant.After(fromTime) {
+		return instant, true
+	}
+	return time.Time{}, false
+}
+
+// toWallClock returns the time instant in UTC showing the same wall clock time as t.
+func toWallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
@@ -16,6 +16,7 @@
 package scheduleexpression
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -36,26 +37,53 @@ type ScheduleExpression interface {
 	Next(fromTime time.Time) time.Time
 }
 
+// ScheduleOptions represents the optional settings of a cron schedule expression
+type ScheduleOptions struct {
+	// TimeZone is the IANA time zone name (e.g. America/Los_Angeles) the cron expression is evaluated in, UTC if empty
+	TimeZone string
+	// ScheduleOffset is the number of days to wait after the date and time matched by the cron expression
+	ScheduleOffset int
+}
+
+// IsDefault returns true if the options do not change how the schedule expression is evaluated
+func (options ScheduleOptions) IsDefault() bool {
+	return (options.TimeZone == "" || options.TimeZone == "UTC") && options.ScheduleOffset == 0
+}
+
 func CreateScheduleExpression(log log.T, scheduleExpression string) (ScheduleExpression, error) {
+	return CreateScheduleExpressionWithOptions(log, scheduleExpression, ScheduleOptions{})
+}
+
+// CreateScheduleExpressionWithOptions parses the schedule expression, cron expressions are evaluated according to the options
+func CreateScheduleExpressionWithOptions(log log.T, scheduleExpression string, options ScheduleOptions) (ScheduleExpression, error) {
 
 	lowerCasedScheduledExpression := strings.ToLower(scheduleExpression)
 
 	if strings.HasPrefix(lowerCasedScheduledExpression, expressionTypeCron) {
 		err := validateCronExpression(log, scheduleExpression)
 		if err != nil {
-			return nil, fmt.Errorf(err.Error())
+			return nil, err
 		}
 
 		cronExpression := scheduleExpression[len(expressionTypeCron)+1 : len(scheduleExpression)-1]
 		parsedCronExpression, err := cronexpr.Parse(cronExpression)
 
-		if err == nil {
-			return parsedCronExpression, nil
-		} else {
+		if err != nil {
 			message := fmt.Sprintf("Error %v received while parsing cron expression %v", err, scheduleExpression)
 			log.Error(message)
-			return nil, fmt.Errorf(message)
+			return nil, errors.New(message)
 		}
+
+		if options.IsDefault() {
+			return parsedCronExpression, nil
+		}
+		return createCronExpression(log, parsedCronExpression, options)
+	}
+
+	if !options.IsDefault() {
+		message := fmt.Sprintf("Time zone and schedule offset are only supported for cron expressions, got %v", scheduleExpression)
+		log.Error(message)
+		return nil, errors.New(message)
 	}
 
 	if strings.HasPrefix(lowerCasedScheduledExpression, expressionTypeRate) {
@@ -66,13 +94,34 @@ func CreateScheduleExpression(log log.T, scheduleExpression string) (ScheduleExp
 		} else {
 			message := fmt.Sprintf("An error %v received while parsing rate expression %v", err, scheduleExpression)
 			log.Error(message)
-			return nil, fmt.Errorf(message)
+			return nil, errors.New(message)
 		}
 	}
 
 	return nil, fmt.Errorf("Unknown expression type detected in expression %v", scheduleExpression)
 }
 
+func createCronExpression(log log.T, parsedCronExpression *cronexpr.Expression, options ScheduleOptions) (ScheduleExpression, error) {
+	location, err := time.LoadLocation(options.TimeZone)
+	if err != nil {
+		message := fmt.Sprintf("Time zone %v is invalid: %v", options.TimeZone, err)
+		log.Error(message)
+		return nil, errors.New(message)
+	}
+
+	if options.ScheduleOffset < 0 || options.ScheduleOffset > maxScheduleOffset {
+		message := fmt.Sprintf("Schedule offset %v is invalid, it must be between 0 and %v days.", options.ScheduleOffset, maxScheduleOffset)
+		log.Error(message)
+		return nil, errors.New(message)
+	}
+
+	return &CronExpression{
+		expression:     parsedCronExpression,
+		location:       location,
+		scheduleOffset: options.ScheduleOffset,
+	}, nil
+}
+
 func validateCronExpression(log log.T, scheduleExpression string) error {
 	cronRegularExpression := regexp.MustCompile("(?i)(cron\\(.*\\))")
 	result := cronRegularExpression.FindAllStringSubmatch(scheduleExpression, -1)
@@ -80,20 +129,20 @@ func validateCronExpression(log log.T, scheduleExpression string) error {
 
 	if len(result) != 1 {
 		log.Error(errorMessage)
-		return fmt.Errorf(errorMessage)
+		return errors.New(errorMessage)
 	}
 
 	match := result[0]
 	if match == nil {
 		log.Error(errorMessage)
-		return fmt.Errorf(errorMessage)
+		return errors.New(errorMessage)
 	}
 
 	if len(match) == 2 && match[1] != "" {
 		// Ensure we do not match cron(0 0 0/1 * * ? *)abc
 		if len(match[1]) != len(scheduleExpression) {
 			log.Error(errorMessage)
-			return fmt.Errorf(errorMessage)
+			return errors.New(errorMessage)
 		}
 	}
 
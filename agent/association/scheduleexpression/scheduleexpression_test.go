@@ -15,6 +15,7 @@ package scheduleexpression
 
 import (
 	"testing"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/stretchr/testify/assert"
@@ -131,3 +132,59 @@ func TestParseReturnsErrorWhenScheduleExpressionIsOfUnknownType(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, "Unknown expression type detected in expression at(12:00)", err.Error())
 }
+
+func TestCronExpressionWithOptions(t *testing.T) {
+	testCases := []struct {
+		description string
+		expression  string
+		options     ScheduleOptions
+		fromTime    string
+		expected    string
+	}{
+		{"UTC without options", "cron(0 2 * * ? *)", ScheduleOptions{}, "2017-03-11T03:00:00Z", "2017-03-12T02:00:00Z"},
+		{"local time before daylight saving time starts", "cron(0 9 * * ? *)", ScheduleOptions{TimeZone: "America/New_York"}, "2017-03-10T14:00:00Z", "2017-03-11T14:00:00Z"},
+		{"local time kept when daylight saving time starts", "cron(0 9 * * ? *)", ScheduleOptions{TimeZone: "America/New_York"}, "2017-03-11T14:00:00Z", "2017-03-12T13:00:00Z"},
+		{"local time kept when daylight saving time ends", "cron(0 9 * * ? *)", ScheduleOptions{TimeZone: "America/New_York"}, "2017-11-04T13:00:00Z", "2017-11-05T14:00:00Z"},
+		{"skipped wall clock time runs after the transition", "cron(30 2 * * ? *)", ScheduleOptions{TimeZone: "America/New_York"}, "2017-03-11T07:30:00Z", "2017-03-12T07:30:00Z"},
+		{"day after skipped wall clock time", "cron(30 2 * * ? *)", ScheduleOptions{TimeZone: "America/New_York"}, "2017-03-12T07:30:00Z", "2017-03-13T06:30:00Z"},
+		{"repeated wall clock time runs at first occurrence", "cron(30 1 * * ? *)", ScheduleOptions{TimeZone: "America/New_York"}, "2017-11-04T05:30:00Z", "2017-11-05T05:30:00Z"},
+		{"repeated wall clock time runs only once", "cron(30 1 * * ? *)", ScheduleOptions{TimeZone: "America/New_York"}, "2017-11-05T05:30:00Z", "2017-11-06T06:30:00Z"},
+		{"hourly schedule when clocks move forward", "cron(0 * * * ? *)", ScheduleOptions{TimeZone: "Europe/London"}, "2017-03-26T00:30:00Z", "2017-03-26T01:00:00Z"},
+		{"hourly schedule after clocks moved forward", "cron(0 * * * ? *)", ScheduleOptions{TimeZone: "Europe/London"}, "2017-03-26T01:00:00Z", "2017-03-26T02:00:00Z"},
+		{"hourly schedule when clocks move back", "cron(0 * * * ? *)", ScheduleOptions{TimeZone: "Europe/London"}, "2017-10-29T00:00:00Z", "2017-10-29T02:00:00Z"},
+		{"second tuesday plus two days", "cron(0 2 ? * TUE#2 *)", ScheduleOptions{ScheduleOffset: 2}, "2018-01-01T00:00:00Z", "2018-01-11T02:00:00Z"},
+		{"second tuesday plus two days after the tuesday", "cron(0 2 ? * TUE#2 *)", ScheduleOptions{ScheduleOffset: 2}, "2018-01-10T00:00:00Z", "2018-01-11T02:00:00Z"},
+		{"second tuesday plus two days next month", "cron(0 2 ? * TUE#2 *)", ScheduleOptions{ScheduleOffset: 2}, "2018-01-11T02:00:00Z", "2018-02-15T02:00:00Z"},
+		{"offset across daylight saving time start", "cron(0 23 ? * SAT#2 *)", ScheduleOptions{TimeZone: "America/New_York", ScheduleOffset: 1}, "2017-03-01T00:00:00Z", "2017-03-13T03:00:00Z"},
+	}
+
+	logger := log.NewMockLog()
+	for _, testCase := range testCases {
+		parsedExpression, err := CreateScheduleExpressionWithOptions(logger, testCase.expression, testCase.options)
+		assert.Nil(t, err, testCase.description)
+
+		fromTime, _ := time.Parse(time.RFC3339, testCase.fromTime)
+		expected, _ := time.Parse(time.RFC3339, testCase.expected)
+		assert.Equal(t, expected.UTC(), parsedExpression.Next(fromTime).UTC(), testCase.description)
+	}
+}
+
+func TestCreateScheduleExpressionWithInvalidOptions(t *testing.T) {
+	testCases := []struct {
+		expression string
+		options    ScheduleOptions
+	}{
+		{"cron(0 2 * * ? *)", ScheduleOptions{TimeZone: "Mars/Olympus_Mons"}},
+		{"cron(0 2 * * ? *)", ScheduleOptions{ScheduleOffset: 7}},
+		{"cron(0 2 * * ? *)", ScheduleOptions{ScheduleOffset: -1}},
+		{"rate(30 minutes)", ScheduleOptions{TimeZone: "America/New_York"}},
+		{"rate(30 minutes)", ScheduleOptions{ScheduleOffset: 1}},
+	}
+
+	logger := log.NewMockLog()
+	for _, testCase := range testCases {
+		parsedExpression, err := CreateScheduleExpressionWithOptions(logger, testCase.expression, testCase.options)
+		assert.Nil(t, parsedExpression)
+		assert.NotNil(t, err)
+	}
+}
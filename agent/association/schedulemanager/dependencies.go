@@ -0,0 +1,150 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package schedulemanager
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+const (
+	dependencyNotAssociatedMessage = "dependency %v is not associated with this instance"
+	dependencyCircularMessage      = "dependency %v depends on this association"
+	dependencyNotSucceededMessage  = "dependency %v did not succeed in this cycle, its status is %v"
+	dependencyNotScheduledMessage  = "dependency %v is not scheduled to run"
+	dependencyWaitingMessage       = "waiting for dependency %v to succeed"
+	exclusionGroupWaitingMessage   = "waiting for association %v of exclusion group %v to complete"
+)
+
+// BlockedAssociation represents a due association which cannot run because of its dependencies, exclusion groups
+// or maintenance windows
+type BlockedAssociation struct {
+	Association *model.InstanceAssociation
+	// Status is Waiting or Deferred when the association can still run in this cycle, Skipped otherwise
+	Status string
+	// ErrorCode is the error code the status is reported with
+	ErrorCode string
+	// Reason tells which dependency, exclusion group or maintenance window blocks the association
+	Reason string
+}
+
+// checkBlocked returns the reason why the given association cannot run now, nil if it can run.
+// A dependency is met once it succeeded after the last execution of the association, i.e. in the current cycle.
+// This operation must be called with the lock held.
+func checkBlocked(assoc *model.InstanceAssociation) *BlockedAssociation {
+	for _, dependency := range assoc.DependsOn {
+		if blocked := checkDependency(assoc, dependency); blocked != nil {
+			return blocked
+		}
+	}
+
+	for _, group := range assoc.ExclusionGroups {
+		for _, other := range associations {
+			if other != assoc && other.InExclusionGroup(group) &&
+				detailedStatus(other) == contracts.AssociationStatusInProgress {
+				return newBlockedAssociation(assoc, contracts.AssociationStatusWaiting,
+					exclusionGroupWaitingMessage, *other.Association.AssociationId, group)
+			}
+		}
+	}
+	return nil
+}
+
+// checkDependency returns the reason why the given dependency blocks the association, nil if it's met
+func checkDependency(assoc *model.InstanceAssociation, dependency string) *BlockedAssociation {
+	dep := findAssociation(dependency)
+	if dep == nil {
+		return newBlockedAssociation(assoc, contracts.AssociationStatusSkipped, dependencyNotAssociatedMessage, dependency)
+	}
+	if dep == assoc {
+		return nil
+	}
+	if dependsOn(dep, assoc, make(map[*model.InstanceAssociation]bool)) {
+		return newBlockedAssociation(assoc, contracts.AssociationStatusSkipped, dependencyCircularMessage, dependency)
+	}
+
+	status := detailedStatus(dep)
+	if status == contracts.AssociationStatusInProgress || status == contracts.AssociationStatusPending {
+		return newBlockedAssociation(assoc, contracts.AssociationStatusWaiting, dependencyWaitingMessage, dependency)
+	}
+
+	ranInCycle := dep.Association.LastExecutionDate != nil && (assoc.Association.LastExecutionDate == nil ||
+		dep.Association.LastExecutionDate.After(*assoc.Association.LastExecutionDate))
+	if ranInCycle {
+		switch status {
+		case contracts.AssociationStatusSuccess:
+			return nil
+		case contracts.AssociationStatusFailed, contracts.AssociationStatusTimedOut, contracts.AssociationStatusSkipped:
+			return newBlockedAssociation(assoc, contracts.AssociationStatusSkipped, dependencyNotSucceededMessage, dependency, status)
+		}
+	}
+
+	if dep.NextScheduledDate == nil {
+		return newBlockedAssociation(assoc, contracts.AssociationStatusSkipped, dependencyNotScheduledMessage, dependency)
+	}
+	return newBlockedAssociation(assoc, contracts.AssociationStatusWaiting, dependencyWaitingMessage, dependency)
+}
+
+// dependsOn returns true if the association depends on the target association, directly or not
+func dependsOn(assoc *model.InstanceAssociation, target *model.InstanceAssociation, visited map[*model.InstanceAssociation]bool) bool {
+	visited[assoc] = true
+	for _, dependency := range assoc.DependsOn {
+		dep := findAssociation(dependency)
+		if dep == target {
+			return true
+		}
+		if dep != nil && !visited[dep] && dependsOn(dep, target, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// findAssociation returns the association with the given ID, or else the given name
+func findAssociation(associationIDOrName string) *model.InstanceAssociation {
+	for _, assoc := range associations {
+		if *assoc.Association.AssociationId == associationIDOrName {
+			return assoc
+		}
+	}
+	for _, assoc := range associations {
+		if assoc.Matches(associationIDOrName) {
+			return assoc
+		}
+	}
+	return nil
+}
+
+// detailedStatus returns the detailed status of the association, empty if unknown
+func detailedStatus(assoc *model.InstanceAssociation) string {
+	if assoc.Association.DetailedStatus == nil {
+		return ""
+	}
+	return *assoc.Association.DetailedStatus
+}
+
+func newBlockedAssociation(assoc *model.InstanceAssociation, status string, format string, params ...interface{}) *BlockedAssociation {
+	errorCode := contracts.AssociationErrorCodeNoError
+	if status == contracts.AssociationStatusSkipped {
+		errorCode = contracts.AssociationErrorCodeDependencyNotMet
+	}
+	return &BlockedAssociation{
+		Association: assoc,
+		Status:      status,
+		ErrorCode:   errorCode,
+		Reason:      fmt.Sprintf(format, params...),
+	}
+}
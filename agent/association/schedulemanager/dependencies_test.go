@@ -0,0 +1,149 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package schedulemanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	lastCycle = time.Date(2017, 10, 12, 10, 0, 0, 0, time.UTC)
+	thisCycle = time.Date(2017, 10, 12, 11, 0, 0, 0, time.UTC)
+	notDue    = time.Now().UTC().Add(time.Hour)
+)
+
+func newTestAssociation(associationID string, status string, lastExecutionDate time.Time, dependsOn ...string) *model.InstanceAssociation {
+	assoc := &model.InstanceAssociation{
+		Association: &ssm.InstanceAssociationSummary{
+			AssociationId:     aws.String(associationID),
+			Name:              aws.String(associationID + "-document"),
+			DetailedStatus:    aws.String(status),
+			LastExecutionDate: aws.Time(lastExecutionDate),
+		},
+		DependsOn: dependsOn,
+	}
+	// due in the past
+	assoc.NextScheduledDate = aws.Time(thisCycle)
+	return assoc
+}
+
+func loadNextScheduledAssociation(assocs ...*model.InstanceAssociation) (*model.InstanceAssociation, []*BlockedAssociation) {
+	lock.Lock()
+	associations = assocs
+	lock.Unlock()
+
+	next, blocked, _ := LoadNextScheduledAssociation(log.NewMockLog())
+	return next, blocked
+}
+
+func TestDependencySucceededInCycle(t *testing.T) {
+	dependency := newTestAssociation("dependency", contracts.AssociationStatusSuccess, thisCycle)
+	dependency.NextScheduledDate = aws.Time(notDue)
+	assoc := newTestAssociation("assoc", contracts.AssociationStatusSuccess, lastCycle, "dependency-document")
+
+	next, blocked := loadNextScheduledAssociation(assoc, dependency)
+
+	assert.Equal(t, assoc, next)
+	assert.Empty(t, blocked)
+}
+
+func TestDependencyFailedInCycle(t *testing.T) {
+	dependency := newTestAssociation("dependency", contracts.AssociationStatusFailed, thisCycle)
+	dependency.NextScheduledDate = aws.Time(notDue)
+	assoc := newTestAssociation("assoc", contracts.AssociationStatusSuccess, lastCycle, "dependency")
+
+	next, blocked := loadNextScheduledAssociation(assoc, dependency)
+
+	assert.Nil(t, next)
+	assert.Equal(t, 1, len(blocked))
+	assert.Equal(t, contracts.AssociationStatusSkipped, blocked[0].Status)
+	assert.Contains(t, blocked[0].Reason, "did not succeed")
+}
+
+func TestDependencyNotRunInCycle(t *testing.T) {
+	dependency := newTestAssociation("dependency", contracts.AssociationStatusSuccess, lastCycle.Add(-time.Hour))
+	assoc := newTestAssociation("assoc", contracts.AssociationStatusSuccess, lastCycle, "dependency")
+
+	// the dependency is due as well, it runs first while the association waits for it
+	next, blocked := loadNextScheduledAssociation(assoc, dependency)
+
+	assert.Equal(t, dependency, next)
+	assert.Equal(t, 1, len(blocked))
+	assert.Equal(t, contracts.AssociationStatusWaiting, blocked[0].Status)
+	assert.Equal(t, assoc, blocked[0].Association)
+}
+
+func TestMissingAndCircularDependencies(t *testing.T) {
+	missing := newTestAssociation("missing", contracts.AssociationStatusSuccess, lastCycle, "unknown")
+	first := newTestAssociation("first", contracts.AssociationStatusSuccess, lastCycle, "second")
+	second := newTestAssociation("second", contracts.AssociationStatusSuccess, lastCycle, "first")
+
+	next, blocked := loadNextScheduledAssociation(missing, first, second)
+
+	assert.Nil(t, next)
+	assert.Equal(t, 3, len(blocked))
+	for _, b := range blocked {
+		assert.Equal(t, contracts.AssociationStatusSkipped, b.Status)
+	}
+}
+
+func TestExclusionGroup(t *testing.T) {
+	patching := newTestAssociation("patching", contracts.AssociationStatusInProgress, lastCycle)
+	patching.ExclusionGroups = []string{"maintenance"}
+	patching.NextScheduledDate = aws.Time(notDue)
+	install := newTestAssociation("install", contracts.AssociationStatusSuccess, lastCycle)
+	install.ExclusionGroups = []string{"maintenance"}
+	other := newTestAssociation("other", contracts.AssociationStatusSuccess, lastCycle)
+
+	next, blocked := loadNextScheduledAssociation(install, other, patching)
+
+	assert.Equal(t, other, next)
+	assert.Equal(t, 1, len(blocked))
+	assert.Equal(t, install, blocked[0].Association)
+	assert.Equal(t, contracts.AssociationStatusWaiting, blocked[0].Status)
+
+	// waiting and running associations don't hold the timer for the next scheduled association
+	install.Association.DetailedStatus = aws.String(contracts.AssociationStatusWaiting)
+	other.NextScheduledDate = aws.Time(notDue.Add(time.Hour))
+	loadNextScheduledAssociation(install, other, patching)
+	assert.Equal(t, notDue.Add(time.Hour), *LoadNextScheduledDate(log.NewMockLog()))
+}
+
+func TestOverlappingSchedulesSkipRunningAssociation(t *testing.T) {
+	running := newTestAssociation("running", contracts.AssociationStatusInProgress, lastCycle)
+	due := newTestAssociation("due", contracts.AssociationStatusSuccess, lastCycle)
+
+	next, blocked := loadNextScheduledAssociation(running, due)
+
+	assert.Equal(t, due, next)
+	assert.Empty(t, blocked)
+	assert.Equal(t, []*model.InstanceAssociation{running}, InProgressAssociations())
+}
+
+func TestNextScheduledDateIgnoresRunningAssociation(t *testing.T) {
+	running := newTestAssociation("running", contracts.AssociationStatusInProgress, lastCycle)
+	scheduled := newTestAssociation("scheduled", contracts.AssociationStatusSuccess, lastCycle)
+	scheduled.NextScheduledDate = aws.Time(notDue)
+	loadNextScheduledAssociation(running, scheduled)
+
+	assert.Equal(t, notDue, *LoadNextScheduledDate(log.NewMockLog()))
+}
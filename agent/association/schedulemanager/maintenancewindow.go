@@ -0,0 +1,78 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package schedulemanager
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/association/maintenancewindow"
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const (
+	outsideWindowDeferredMessage = "outside maintenance windows, run deferred to the next window opening at %v"
+	outsideWindowSkippedMessage  = "outside maintenance windows, run skipped until the next scheduled date"
+	blackoutDeferredMessage      = "in blackout %v, run deferred to the next window opening at %v"
+	blackoutSkippedMessage       = "in blackout %v, run skipped until the next scheduled date"
+)
+
+// windows holds the maintenance windows and blackouts the associations are allowed to run in
+var windows *maintenancewindow.Calendar
+
+// SetMaintenanceWindows sets the maintenance windows and blackouts the associations are allowed to run in
+func SetMaintenanceWindows(calendar *maintenancewindow.Calendar) {
+	lock.Lock()
+	defer lock.Unlock()
+	windows = calendar
+}
+
+// checkMaintenanceWindow returns the reason why the given association cannot run at the given time, nil if it can run.
+// With the Defer policy the next scheduled date of the association moves to the next window opening.
+// This operation must be called with the lock held.
+func checkMaintenanceWindow(log log.T, assoc *model.InstanceAssociation, now time.Time) *BlockedAssociation {
+	if windows.IsOpen(now) {
+		return nil
+	}
+
+	blackout := windows.BlackoutAt(now)
+	if assoc.OutsideWindowPolicy != appconfig.AssociationOutsideWindowPolicySkip {
+		if opening := windows.NextOpening(now); !opening.IsZero() {
+			assoc.NextScheduledDate = aws.Time(opening.UTC())
+			log.Infof("Association %v is due outside maintenance windows, deferring it to %v",
+				*assoc.Association.AssociationId, times.ToIsoDashUTC(opening))
+			saveScheduleState(log)
+
+			blocked := newBlockedAssociation(assoc, contracts.AssociationStatusDeferred,
+				outsideWindowDeferredMessage, times.ToIso8601UTC(opening))
+			if blackout != nil {
+				blocked = newBlockedAssociation(assoc, contracts.AssociationStatusDeferred,
+					blackoutDeferredMessage, blackout.Description, times.ToIso8601UTC(opening))
+			}
+			blocked.ErrorCode = contracts.AssociationErrorCodeOutsideMaintenanceWindow
+			return blocked
+		}
+	}
+
+	blocked := newBlockedAssociation(assoc, contracts.AssociationStatusSkipped, outsideWindowSkippedMessage)
+	if blackout != nil {
+		blocked = newBlockedAssociation(assoc, contracts.AssociationStatusSkipped, blackoutSkippedMessage, blackout.Description)
+	}
+	blocked.ErrorCode = contracts.AssociationErrorCodeOutsideMaintenanceWindow
+	return blocked
+}
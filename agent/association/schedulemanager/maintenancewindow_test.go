@@ -0,0 +1,86 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package schedulemanager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/association/maintenancewindow"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// setBlackout makes the associations wait for the end of a blackout in progress
+func setBlackout(t *testing.T, end time.Time) func() {
+	dir, err := ioutil.TempDir("", "schedulemanager")
+	assert.Nil(t, err)
+	fileName := filepath.Join(dir, "blackouts.json")
+	content := fmt.Sprintf(`{"Blackouts": [{"Start": "2017-01-01T00:00:00Z", "End": "%v", "Description": "freeze"}]}`,
+		end.Format(time.RFC3339))
+	assert.Nil(t, ioutil.WriteFile(fileName, []byte(content), 0600))
+
+	calendar, err := maintenancewindow.New(log.NewMockLog(), appconfig.SsmCfg{AssociationBlackoutCalendar: fileName})
+	assert.Nil(t, err)
+	SetMaintenanceWindows(calendar)
+	return func() {
+		SetMaintenanceWindows(nil)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestOutsideMaintenanceWindowDeferred(t *testing.T) {
+	end := notDue.Truncate(time.Second)
+	defer setBlackout(t, end)()
+	assoc := newTestAssociation("assoc", contracts.AssociationStatusSuccess, lastCycle)
+
+	next, blocked := loadNextScheduledAssociation(assoc)
+
+	assert.Nil(t, next)
+	assert.Equal(t, 1, len(blocked))
+	assert.Equal(t, contracts.AssociationStatusDeferred, blocked[0].Status)
+	assert.Equal(t, contracts.AssociationErrorCodeOutsideMaintenanceWindow, blocked[0].ErrorCode)
+	assert.Contains(t, blocked[0].Reason, "in blackout freeze")
+	assert.Equal(t, end, *assoc.NextScheduledDate)
+}
+
+func TestOutsideMaintenanceWindowSkipped(t *testing.T) {
+	defer setBlackout(t, notDue)()
+	assoc := newTestAssociation("assoc", contracts.AssociationStatusSuccess, lastCycle)
+	assoc.OutsideWindowPolicy = appconfig.AssociationOutsideWindowPolicySkip
+
+	next, blocked := loadNextScheduledAssociation(assoc)
+
+	assert.Nil(t, next)
+	assert.Equal(t, 1, len(blocked))
+	assert.Equal(t, contracts.AssociationStatusSkipped, blocked[0].Status)
+	assert.Equal(t, contracts.AssociationErrorCodeOutsideMaintenanceWindow, blocked[0].ErrorCode)
+	assert.Equal(t, thisCycle, *assoc.NextScheduledDate)
+}
+
+func TestInsideMaintenanceWindow(t *testing.T) {
+	defer setBlackout(t, thisCycle)()
+	assoc := newTestAssociation("assoc", contracts.AssociationStatusSuccess, lastCycle)
+
+	next, blocked := loadNextScheduledAssociation(assoc)
+
+	assert.Equal(t, assoc, next)
+	assert.Empty(t, blocked)
+}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/association/schedulestore"
 	complianceModel "github.com/aws/amazon-ssm-agent/agent/compliance/model"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
@@ -32,6 +33,37 @@ import (
 var associations = []*model.InstanceAssociation{}
 var lock sync.RWMutex
 
+// states holds the last known schedule state of the associations, indexed by association ID
+var states = map[string]*schedulestore.ScheduleState{}
+
+// storeInstanceID is the instance the schedule state is persisted for, it's empty until LoadScheduleState is called
+var storeInstanceID string
+
+// scheduledSinceStart records the associations which have been scheduled since the agent started
+var scheduledSinceStart = map[string]bool{}
+
+// LoadScheduleState reloads the schedule state persisted before the agent restarted,
+// the schedule state is persisted for the given instance from now on
+func LoadScheduleState(log log.T, instanceID string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	storeInstanceID = instanceID
+	loaded, err := schedulestore.Load(instanceID)
+	if err != nil {
+		log.Errorf("Failed to load association schedule state, %v", err)
+		return
+	}
+
+	for associationID, state := range loaded {
+		// associations refreshed already hold a more recent state
+		if _, found := states[associationID]; !found {
+			states[associationID] = state
+		}
+	}
+	log.Infof("Loaded schedule state of %v associations", len(loaded))
+}
+
 // Refresh refreshes cached associationRawData
 func Refresh(log log.T, assocs []*model.InstanceAssociation) {
 	lock.Lock()
@@ -50,7 +82,13 @@ func Refresh(log log.T, assocs []*model.InstanceAssociation) {
 
 	numberOfNewAssoc := 0
 	for _, assoc := range associations {
+		restoreScheduleState(log, assoc)
 		assoc.SetNextScheduledDate(log)
+		if !scheduledSinceStart[*assoc.Association.AssociationId] {
+			// runs due before the first schedule since start were missed while the agent was stopped
+			scheduledSinceStart[*assoc.Association.AssociationId] = true
+			assoc.ApplyMissedRunPolicy(log, time.Now().UTC())
+		}
 		if assoc.NextScheduledDate != nil {
 			log.Infof("Scheduling association %v, setting next ScheduledDate to %v", *assoc.Association.AssociationId, times.ToIsoDashUTC(*assoc.NextScheduledDate))
 		}
@@ -66,38 +104,51 @@ func Refresh(log log.T, assocs []*model.InstanceAssociation) {
 		}
 	}
 
+	saveScheduleState(log)
 	complianceModel.RefreshAssociationComplianceItems(associations)
 
 	log.Infof("Schedule manager refreshed with %v associations, %v new assocations associated", len(associations), numberOfNewAssoc)
 }
 
-// LoadNextScheduledAssociation returns next scheduled association
-func LoadNextScheduledAssociation(log log.T) (*model.InstanceAssociation, error) {
+// LoadNextScheduledAssociation returns next scheduled association which is not running along with the due associations
+// which cannot run because of their dependencies, exclusion groups or maintenance windows
+func LoadNextScheduledAssociation(log log.T) (*model.InstanceAssociation, []*BlockedAssociation, error) {
 	lock.Lock()
 	defer lock.Unlock()
 
+	blocked := []*BlockedAssociation{}
 	if len(associations) == 0 {
-		return nil, nil
+		return nil, blocked, nil
 	}
 
 	for _, assoc := range associations {
 		currentTime := time.Now().UTC()
-		if assoc.NextScheduledDate == nil {
+		// an association never runs concurrently with itself, it's scheduled again once its current run completes
+		if assoc.NextScheduledDate == nil || detailedStatus(assoc) == contracts.AssociationStatusInProgress {
 			continue
 		}
 
 		if (*assoc.NextScheduledDate).Before(currentTime) || (*assoc.NextScheduledDate).Equal(currentTime) {
+			if blockedAssoc := checkMaintenanceWindow(log, assoc, currentTime); blockedAssoc != nil {
+				blocked = append(blocked, blockedAssoc)
+				continue
+			}
+			if blockedAssoc := checkBlocked(assoc); blockedAssoc != nil {
+				blocked = append(blocked, blockedAssoc)
+				continue
+			}
+
 			if assocContent, err := jsonutil.Marshal(assoc); err != nil {
-				return nil, fmt.Errorf("failed to parse scheduled association, %v", err)
+				return nil, blocked, fmt.Errorf("failed to parse scheduled association, %v", err)
 			} else {
 				log.Infof("Next scheduled association is %v", jsonutil.Indent(assocContent))
 			}
 
-			return assoc, nil
+			return assoc, blocked, nil
 		}
 	}
 
-	return nil, nil
+	return nil, blocked, nil
 }
 
 // LoadNextScheduledDate returns next scheduled date
@@ -107,7 +158,10 @@ func LoadNextScheduledDate(log log.T) *time.Time {
 
 	var nextScheduleDate *time.Time
 	for _, assoc := range associations {
-		if assoc.NextScheduledDate == nil {
+		// waiting and running associations are signaled to run once the associations they wait for complete
+		status := detailedStatus(assoc)
+		if assoc.NextScheduledDate == nil ||
+			status == contracts.AssociationStatusWaiting || status == contracts.AssociationStatusInProgress {
 			continue
 		}
 
@@ -135,24 +189,66 @@ func UpdateNextScheduledDate(log log.T, associationID string) {
 			if assoc.NextScheduledDate != nil {
 				log.Infof("Scheduling association %v, setting next ScheduledDate to %v", *assoc.Association.AssociationId, times.ToIsoDashUTC(*assoc.NextScheduledDate))
 			}
+			saveScheduleState(log)
 			break
 		}
 	}
 }
 
+// SplayOffset returns the delay applied to the scheduled runs of the given association
+func SplayOffset(associationID string) time.Duration {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	for _, assoc := range associations {
+		if *assoc.Association.AssociationId == associationID {
+			return assoc.SplayOffset()
+		}
+	}
+	return 0
+}
+
+// IsCheckOnly returns true if the given association only checks the instance complies with its document
+func IsCheckOnly(associationID string) bool {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	for _, assoc := range associations {
+		if *assoc.Association.AssociationId == associationID {
+			return assoc.CheckOnly
+		}
+	}
+	return false
+}
+
 // UpdateAssociationStatus sets detailed status for the given association
-func UpdateAssociationStatus(associationID string, status string) {
+func UpdateAssociationStatus(log log.T, associationID string, status string) {
 	lock.Lock()
 	defer lock.Unlock()
 
 	for _, assoc := range associations {
 		if *assoc.Association.AssociationId == associationID {
 			assoc.Association.DetailedStatus = aws.String(status)
+			saveScheduleState(log)
 			break
 		}
 	}
 }
 
+// InProgressAssociations returns the associations which have detailed status as InProgress
+func InProgressAssociations() []*model.InstanceAssociation {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	inProgress := []*model.InstanceAssociation{}
+	for _, assoc := range associations {
+		if detailedStatus(assoc) == contracts.AssociationStatusInProgress {
+			inProgress = append(inProgress, assoc)
+		}
+	}
+	return inProgress
+}
+
 // IsAssociationInProgress returns if given association has detailed status as InProgress
 func IsAssociationInProgress(associationID string) bool {
 	lock.Lock()
@@ -182,6 +278,50 @@ func Schedules() []*model.InstanceAssociation {
 	return associations
 }
 
+// restoreScheduleState applies the last known schedule state to an association freshly loaded from the service,
+// the service may not know about the latest runs yet when the agent restarts or refreshes the associations
+func restoreScheduleState(log log.T, assoc *model.InstanceAssociation) {
+	state, found := states[*assoc.Association.AssociationId]
+	if !found {
+		return
+	}
+
+	if state.LastExecutionDate != nil && (assoc.Association.LastExecutionDate == nil ||
+		state.LastExecutionDate.After(*assoc.Association.LastExecutionDate)) {
+		log.Debugf("Restoring last execution date %v of association %v",
+			times.ToIsoDashUTC(*state.LastExecutionDate), *assoc.Association.AssociationId)
+		assoc.Association.LastExecutionDate = aws.Time(state.LastExecutionDate.UTC())
+	}
+
+	// the document interrupted by the restart is resumed by the processor, don't run the association again meanwhile
+	if state.InProgress && assoc.Association.DetailedStatus == nil {
+		assoc.Association.DetailedStatus = aws.String(contracts.AssociationStatusInProgress)
+	}
+}
+
+// saveScheduleState records the schedule state of the associations and persists it once the store is loaded.
+// This operation must be called with the lock held.
+func saveScheduleState(log log.T) {
+	newStates := make(map[string]*schedulestore.ScheduleState)
+	for _, assoc := range associations {
+		newStates[*assoc.Association.AssociationId] = &schedulestore.ScheduleState{
+			AssociationID:     *assoc.Association.AssociationId,
+			NextScheduledDate: assoc.NextScheduledDate,
+			LastExecutionDate: assoc.Association.LastExecutionDate,
+			InProgress: assoc.Association.DetailedStatus != nil &&
+				*assoc.Association.DetailedStatus == contracts.AssociationStatusInProgress,
+		}
+	}
+	states = newStates
+
+	if storeInstanceID == "" {
+		return
+	}
+	if err := schedulestore.Save(storeInstanceID, states); err != nil {
+		log.Errorf("Failed to persist association schedule state, %v", err)
+	}
+}
+
 func AssociationExists(associationID string) bool {
 	for _, assoc := range associations {
 		if *assoc.Association.AssociationId == associationID {
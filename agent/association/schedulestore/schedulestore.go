@@ -0,0 +1,117 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package schedulestore persists the schedule state of associations so that it survives agent restarts
+package schedulestore
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+// ScheduleStateFileName represents the file recording the schedule state of the associations
+const ScheduleStateFileName = "AssociationSchedules.json"
+
+// ScheduleState contains the schedule state of an association
+type ScheduleState struct {
+	AssociationID     string
+	NextScheduledDate *time.Time `json:",omitempty"`
+	LastExecutionDate *time.Time `json:",omitempty"`
+	InProgress        bool
+}
+
+var lock sync.RWMutex
+
+// Load returns the persisted schedule states of the given instance indexed by association ID.
+// An empty map is returned if no schedule state has been persisted yet.
+func Load(instanceID string) (map[string]*ScheduleState, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	return loadFile(getFileName(instanceID))
+}
+
+// Save persists the schedule states of the given instance, replacing the previously persisted ones
+func Save(instanceID string, states map[string]*ScheduleState) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	return saveFile(getLocation(instanceID), getFileName(instanceID), states)
+}
+
+// loadFile reads the schedule states from the given file
+func loadFile(fileName string) (map[string]*ScheduleState, error) {
+	states := make(map[string]*ScheduleState)
+	if !fileutil.Exists(fileName) {
+		return states, nil
+	}
+
+	var persisted []*ScheduleState
+	if err := jsonutil.UnmarshalFile(fileName, &persisted); err != nil {
+		return states, fmt.Errorf("cannot read schedule state from %v because: %v", fileName, err)
+	}
+
+	for _, state := range persisted {
+		if state != nil && state.AssociationID != "" {
+			states[state.AssociationID] = state
+		}
+	}
+	return states, nil
+}
+
+// saveFile replaces the given file with the schedule states, a crash during the write never leaves a truncated state
+// file behind
+func saveFile(location string, fileName string, states map[string]*ScheduleState) error {
+	var err error
+	var content string
+
+	//verify if parent folder exist
+	if !fileutil.Exists(location) {
+		if err = fileutil.MakeDirs(location); err != nil {
+			return fmt.Errorf("cannot make directory of %v because: %v", location, err)
+		}
+	}
+
+	persisted := make([]*ScheduleState, 0, len(states))
+	for _, state := range states {
+		persisted = append(persisted, state)
+	}
+	if content, err = jsonutil.Marshal(persisted); err != nil {
+		return err
+	}
+
+	if err = fileutil.WriteFileAtomic(fileName, []byte(content), os.FileMode(int(appconfig.ReadWriteAccess))); err != nil {
+		return fmt.Errorf("cannot write schedule state to %v because: %v", fileName, err)
+	}
+	return nil
+}
+
+// getLocation returns the full path for recording the schedule state.
+func getLocation(instanceID string) string {
+	return path.Join(appconfig.DefaultDataStorePath,
+		instanceID,
+		appconfig.DefaultDocumentRootDirName,
+		appconfig.DefaultLocationOfAssociation)
+}
+
+// getFileName returns the full file name of the schedule state.
+func getFileName(instanceID string) string {
+	return path.Join(getLocation(instanceID), ScheduleStateFileName)
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package schedulestore
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoadScheduleState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schedulestore")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	location := path.Join(dir, "association")
+	fileName := path.Join(location, ScheduleStateFileName)
+
+	states, err := loadFile(fileName)
+	assert.Nil(t, err)
+	assert.Empty(t, states)
+
+	lastExecutionDate := time.Date(2017, 10, 12, 10, 0, 0, 0, time.UTC)
+	nextScheduledDate := time.Date(2017, 10, 12, 11, 0, 0, 0, time.UTC)
+	states["assoc1"] = &ScheduleState{
+		AssociationID:     "assoc1",
+		LastExecutionDate: aws.Time(lastExecutionDate),
+		NextScheduledDate: aws.Time(nextScheduledDate),
+	}
+	states["assoc2"] = &ScheduleState{AssociationID: "assoc2", InProgress: true}
+
+	assert.Nil(t, saveFile(location, fileName, states))
+	// no temporary file is left behind
+	files, err := ioutil.ReadDir(location)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+
+	loaded, err := loadFile(fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(loaded))
+	assert.True(t, lastExecutionDate.Equal(*loaded["assoc1"].LastExecutionDate))
+	assert.True(t, nextScheduledDate.Equal(*loaded["assoc1"].NextScheduledDate))
+	assert.False(t, loaded["assoc1"].InProgress)
+	assert.Nil(t, loaded["assoc2"].NextScheduledDate)
+	assert.True(t, loaded["assoc2"].InProgress)
+}
+
+func TestLoadCorruptedScheduleState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schedulestore")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	fileName := path.Join(dir, ScheduleStateFileName)
+	assert.Nil(t, ioutil.WriteFile(fileName, []byte("{corrupted"), 0600))
+
+	states, err := loadFile(fileName)
+	assert.NotNil(t, err)
+	assert.Empty(t, states)
+}
@@ -0,0 +1,222 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package audit keeps a local audit trail of the documents and plugins the agent executes.
+//
+// Each record is a single line JSON object logged to syslog, or to journald, depending on Agent.AuditTrail:
+//
+//	event            DocumentStarted, DocumentFinished, PluginStarted or PluginFinished
+//	time             time of the event, RFC 3339 with nanoseconds
+//	documentId       identifier of the execution, the command ID or the association ID and run ID
+//	user             local user the agent executes the document as
+//	instanceId       for the document events, instance the agent runs on
+//	commandId        for the document events, command ID of the execution of a command
+//	associationId    for the document events, association ID of the execution of an association
+//	documentName     for the document events, name of the document
+//	documentVersion  for the document events, version of the document
+//	documentHash     for the document events, SHA-256 of the document content before the parameters are applied
+//	steps            for the document events, ID, plugin name, and once finished status and exit code of each step
+//	pluginId         for the plugin events, ID of the step
+//	pluginName       for the plugin events, name of the plugin
+//	status           for the finished events, status of the document or plugin
+//	exitCode         for PluginFinished, exit code of the plugin
+//	durationMillis   for the finished events, execution time in milliseconds
+//
+// The syslog records use the daemon facility, the notice severity and the amazon-ssm-agent tag. The journald records
+// carry the JSON object as MESSAGE along with the SSM_EVENT, SSM_DOCUMENT_ID, SSM_COMMAND_ID and SSM_PLUGIN_ID fields.
+package audit
+
+import (
+	"encoding/json"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	EventDocumentStarted  = "DocumentStarted"
+	EventDocumentFinished = "DocumentFinished"
+	EventPluginStarted    = "PluginStarted"
+	EventPluginFinished   = "PluginFinished"
+)
+
+// Record is an entry of the audit trail
+type Record struct {
+	Event           string       `json:"event"`
+	Time            time.Time    `json:"time"`
+	InstanceID      string       `json:"instanceId,omitempty"`
+	DocumentID      string       `json:"documentId"`
+	CommandID       string       `json:"commandId,omitempty"`
+	AssociationID   string       `json:"associationId,omitempty"`
+	DocumentName    string       `json:"documentName,omitempty"`
+	DocumentVersion string       `json:"documentVersion,omitempty"`
+	DocumentHash    string       `json:"documentHash,omitempty"`
+	User            string       `json:"user"`
+	Steps           []StepRecord `json:"steps,omitempty"`
+	PluginID        string       `json:"pluginId,omitempty"`
+	PluginName      string       `json:"pluginName,omitempty"`
+	Status          string       `json:"status,omitempty"`
+	ExitCode        *int         `json:"exitCode,omitempty"`
+	DurationMillis  *int64       `json:"durationMillis,omitempty"`
+}
+
+// StepRecord describes a step of a document in the audit trail
+type StepRecord struct {
+	ID         string `json:"id"`
+	PluginName string `json:"pluginName"`
+	Status     string `json:"status,omitempty"`
+	ExitCode   *int   `json:"exitCode,omitempty"`
+}
+
+// Auditor writes the records of the audit trail
+type Auditor interface {
+	Audit(log log.T, record Record)
+}
+
+// NewAuditor returns the auditor of the audit trail configured for the agent, records are dropped when it is disabled
+func NewAuditor(config appconfig.SsmagentConfig) Auditor {
+	switch config.Agent.AuditTrail {
+	case appconfig.AuditTrailSyslog:
+		return syslogAuditor{dial: dialSyslog}
+	case appconfig.AuditTrailJournald:
+		return journaldAuditor{send: sendToJournald}
+	}
+	return disabledAuditor{}
+}
+
+// disabledAuditor drops the records
+type disabledAuditor struct {
+}
+
+func (disabledAuditor) Audit(log log.T, record Record) {
+}
+
+// syslogWriter writes messages to the local syslog daemon
+type syslogWriter interface {
+	Notice(message string) error
+	Close() error
+}
+
+// syslogAuditor logs each record to the local syslog daemon
+type syslogAuditor struct {
+	dial func() (syslogWriter, error)
+}
+
+func (a syslogAuditor) Audit(log log.T, record Record) {
+	message, err := json.Marshal(record)
+	if err != nil {
+		log.Errorf("Failed to encode the audit record: %v", err)
+		return
+	}
+	writer, err := a.dial()
+	if err != nil {
+		log.Errorf("Failed to connect to syslog to write the audit record: %v", err)
+		return
+	}
+	defer writer.Close()
+	if err = writer.Notice(string(message)); err != nil {
+		log.Errorf("Failed to write the audit record to syslog: %v", err)
+	}
+}
+
+// DocumentStarted returns the record of the start of the execution of a document
+func DocumentStarted(docState *contracts.DocumentState) Record {
+	record := documentRecord(EventDocumentStarted, docState)
+	for _, plugin := range docState.InstancePluginsInformation {
+		record.Steps = append(record.Steps, StepRecord{ID: plugin.Id, PluginName: plugin.Name})
+	}
+	return record
+}
+
+// DocumentFinished returns the record of the end of the execution of a document started at startTime
+func DocumentFinished(docState *contracts.DocumentState, result contracts.DocumentResult, startTime time.Time) Record {
+	record := documentRecord(EventDocumentFinished, docState)
+	record.Status = string(result.Status)
+	record.DurationMillis = durationMillis(startTime, record.Time)
+	for _, plugin := range docState.InstancePluginsInformation {
+		step := StepRecord{ID: plugin.Id, PluginName: plugin.Name}
+		if pluginResult, ok := result.PluginResults[plugin.Id]; ok && pluginResult != nil {
+			step.Status = string(pluginResult.Status)
+			step.ExitCode = exitCode(pluginResult.Code)
+		}
+		record.Steps = append(record.Steps, step)
+	}
+	return record
+}
+
+// PluginStarted returns the record of the start of the execution of a plugin
+func PluginStarted(config contracts.Configuration) Record {
+	return pluginRecord(EventPluginStarted, config)
+}
+
+// PluginFinished returns the record of the end of the execution of a plugin started at startTime
+func PluginFinished(config contracts.Configuration, result contracts.PluginResult, startTime time.Time) Record {
+	record := pluginRecord(EventPluginFinished, config)
+	record.Status = string(result.Status)
+	record.ExitCode = exitCode(result.Code)
+	record.DurationMillis = durationMillis(startTime, record.Time)
+	return record
+}
+
+func documentRecord(event string, docState *contracts.DocumentState) Record {
+	info := docState.DocumentInformation
+	return Record{
+		Event:           event,
+		Time:            time.Now(),
+		InstanceID:      info.InstanceID,
+		DocumentID:      info.DocumentID,
+		CommandID:       info.CommandID,
+		AssociationID:   info.AssociationID,
+		DocumentName:    info.DocumentName,
+		DocumentVersion: info.DocumentVersion,
+		DocumentHash:    info.DocumentHash,
+		User:            currentUser(),
+	}
+}
+
+func pluginRecord(event string, config contracts.Configuration) Record {
+	return Record{
+		Event:      event,
+		Time:       time.Now(),
+		DocumentID: config.BookKeepingFileName,
+		User:       currentUser(),
+		PluginID:   config.PluginID,
+		PluginName: config.PluginName,
+	}
+}
+
+func exitCode(code int) *int {
+	return &code
+}
+
+func durationMillis(startTime time.Time, endTime time.Time) *int64 {
+	duration := int64(endTime.Sub(startTime) / time.Millisecond)
+	return &duration
+}
+
+var userOnce sync.Once
+var userName string
+
+// currentUser returns the name of the user the agent runs as
+func currentUser() string {
+	userOnce.Do(func() {
+		if current, err := user.Current(); err == nil {
+			userName = current.Username
+		}
+	})
+	return userName
+}
@@ -0,0 +1,179 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+// fakeSyslogWriter keeps the logged messages
+type fakeSyslogWriter struct {
+	messages []string
+	closed   bool
+	err      error
+}
+
+func (w *fakeSyslogWriter) Notice(message string) error {
+	w.messages = append(w.messages, message)
+	return w.err
+}
+
+func (w *fakeSyslogWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func testDocumentState() *contracts.DocumentState {
+	return &contracts.DocumentState{
+		DocumentInformation: contracts.DocumentInfo{
+			DocumentID:   "cmd-1",
+			CommandID:    "cmd-1",
+			InstanceID:   "i-1",
+			DocumentName: "AWS-RunShellScript",
+			DocumentHash: "abc",
+		},
+		InstancePluginsInformation: []contracts.PluginState{
+			{Id: "step1", Name: "aws:runShellScript"},
+			{Id: "step2", Name: "aws:runShellScript"},
+		},
+	}
+}
+
+func TestNewAuditor(t *testing.T) {
+	config := appconfig.DefaultConfig()
+	assert.IsType(t, disabledAuditor{}, NewAuditor(config))
+	config.Agent.AuditTrail = appconfig.AuditTrailSyslog
+	assert.IsType(t, syslogAuditor{}, NewAuditor(config))
+	config.Agent.AuditTrail = appconfig.AuditTrailJournald
+	assert.IsType(t, journaldAuditor{}, NewAuditor(config))
+}
+
+func TestSyslogAuditor(t *testing.T) {
+	writer := &fakeSyslogWriter{}
+	auditor := syslogAuditor{dial: func() (syslogWriter, error) { return writer, nil }}
+
+	startTime := time.Now().Add(-2 * time.Second)
+	auditor.Audit(logger, DocumentStarted(testDocumentState()))
+	auditor.Audit(logger, DocumentFinished(testDocumentState(), contracts.DocumentResult{
+		Status: contracts.ResultStatusFailed,
+		PluginResults: map[string]*contracts.PluginResult{
+			"step1": {Status: contracts.ResultStatusFailed, Code: 2},
+		},
+	}, startTime))
+
+	assert.Equal(t, 2, len(writer.messages))
+	assert.True(t, writer.closed)
+	for _, message := range writer.messages {
+		assert.False(t, strings.Contains(message, "\n"))
+	}
+
+	var started, finished map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(writer.messages[0]), &started))
+	assert.NoError(t, json.Unmarshal([]byte(writer.messages[1]), &finished))
+	assert.Equal(t, EventDocumentStarted, started["event"])
+	assert.Equal(t, "cmd-1", started["commandId"])
+	assert.Equal(t, "AWS-RunShellScript", started["documentName"])
+	assert.Equal(t, "abc", started["documentHash"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": "step1", "pluginName": "aws:runShellScript"},
+		map[string]interface{}{"id": "step2", "pluginName": "aws:runShellScript"},
+	}, started["steps"])
+	assert.NotContains(t, started, "status")
+	assert.NotContains(t, started, "durationMillis")
+
+	assert.Equal(t, EventDocumentFinished, finished["event"])
+	assert.Equal(t, "Failed", finished["status"])
+	assert.True(t, finished["durationMillis"].(float64) >= 2000)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": "step1", "pluginName": "aws:runShellScript", "status": "Failed", "exitCode": float64(2)},
+		map[string]interface{}{"id": "step2", "pluginName": "aws:runShellScript"},
+	}, finished["steps"])
+}
+
+func TestSyslogAuditorFailures(t *testing.T) {
+	// the records which cannot be written are only logged
+	syslogAuditor{dial: func() (syslogWriter, error) { return nil, errors.New("no syslog daemon") }}.Audit(logger, DocumentStarted(testDocumentState()))
+
+	writer := &fakeSyslogWriter{err: errors.New("connection reset")}
+	syslogAuditor{dial: func() (syslogWriter, error) { return writer, nil }}.Audit(logger, DocumentStarted(testDocumentState()))
+	assert.Equal(t, 1, len(writer.messages))
+	assert.True(t, writer.closed)
+}
+
+func TestPluginRecords(t *testing.T) {
+	config := contracts.Configuration{PluginID: "step1", PluginName: "aws:runShellScript", BookKeepingFileName: "cmd-1"}
+	started := PluginStarted(config)
+	assert.Equal(t, EventPluginStarted, started.Event)
+	assert.Equal(t, "cmd-1", started.DocumentID)
+	assert.Equal(t, "step1", started.PluginID)
+	assert.Nil(t, started.ExitCode)
+
+	finished := PluginFinished(config, contracts.PluginResult{Status: contracts.ResultStatusSuccess, Code: 0}, time.Now().Add(-time.Second))
+	assert.Equal(t, EventPluginFinished, finished.Event)
+	assert.Equal(t, "Success", finished.Status)
+	assert.Equal(t, 0, *finished.ExitCode)
+	assert.True(t, *finished.DurationMillis >= 1000)
+
+	// the exit code of a successful plugin is kept in the record
+	message, err := json.Marshal(finished)
+	assert.NoError(t, err)
+	assert.Contains(t, string(message), `"exitCode":0`)
+}
+
+func TestJournaldAuditor(t *testing.T) {
+	var datagrams [][]byte
+	auditor := journaldAuditor{send: func(datagram []byte) error {
+		datagrams = append(datagrams, datagram)
+		return nil
+	}}
+
+	auditor.Audit(logger, PluginStarted(contracts.Configuration{PluginID: "step1", PluginName: "aws:runShellScript", BookKeepingFileName: "cmd-1"}))
+
+	assert.Equal(t, 1, len(datagrams))
+	fields := strings.Split(strings.TrimSuffix(string(datagrams[0]), "\n"), "\n")
+	assert.True(t, strings.HasPrefix(fields[0], "MESSAGE={"))
+	assert.Equal(t, []string{
+		"PRIORITY=5",
+		"SYSLOG_IDENTIFIER=amazon-ssm-agent",
+		"SSM_EVENT=PluginStarted",
+		"SSM_DOCUMENT_ID=cmd-1",
+		"SSM_PLUGIN_ID=step1",
+	}, fields[1:])
+}
+
+func TestWriteJournaldField(t *testing.T) {
+	var datagram bytes.Buffer
+	writeJournaldField(&datagram, "EMPTY", "")
+	writeJournaldField(&datagram, "SINGLE", "one line")
+	writeJournaldField(&datagram, "MULTI", "two\nlines")
+
+	var expected bytes.Buffer
+	expected.WriteString("SINGLE=one line\nMULTI\n")
+	binary.Write(&expected, binary.LittleEndian, uint64(9))
+	expected.WriteString("two\nlines\n")
+	assert.Equal(t, expected.Bytes(), datagram.Bytes())
+}
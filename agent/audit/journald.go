@@ -0,0 +1,81 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// journaldSocket is the socket of the native protocol of journald
+	journaldSocket = "/run/systemd/journal/socket"
+	// journaldPriorityNotice is the syslog notice severity
+	journaldPriorityNotice = "5"
+)
+
+// journaldAuditor sends each record to journald with its native protocol
+type journaldAuditor struct {
+	send func(datagram []byte) error
+}
+
+func (a journaldAuditor) Audit(log log.T, record Record) {
+	message, err := json.Marshal(record)
+	if err != nil {
+		log.Errorf("Failed to encode the audit record: %v", err)
+		return
+	}
+	var datagram bytes.Buffer
+	writeJournaldField(&datagram, "MESSAGE", string(message))
+	writeJournaldField(&datagram, "PRIORITY", journaldPriorityNotice)
+	writeJournaldField(&datagram, "SYSLOG_IDENTIFIER", appconfig.DefaultAgentName)
+	writeJournaldField(&datagram, "SSM_EVENT", record.Event)
+	writeJournaldField(&datagram, "SSM_DOCUMENT_ID", record.DocumentID)
+	writeJournaldField(&datagram, "SSM_COMMAND_ID", record.CommandID)
+	writeJournaldField(&datagram, "SSM_PLUGIN_ID", record.PluginID)
+	if err = a.send(datagram.Bytes()); err != nil {
+		log.Errorf("Failed to write the audit record to journald: %v", err)
+	}
+}
+
+// writeJournaldField appends a field to the datagram, values spanning lines are prefixed with their length
+func writeJournaldField(datagram *bytes.Buffer, name string, value string) {
+	if value == "" {
+		return
+	}
+	if !strings.Contains(value, "\n") {
+		datagram.WriteString(name + "=" + value + "\n")
+		return
+	}
+	datagram.WriteString(name + "\n")
+	binary.Write(datagram, binary.LittleEndian, uint64(len(value)))
+	datagram.WriteString(value + "\n")
+}
+
+// sendToJournald sends a datagram to the journald socket
+func sendToJournald(datagram []byte) error {
+	conn, err := net.Dial("unixgram", journaldSocket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(datagram)
+	return err
+}
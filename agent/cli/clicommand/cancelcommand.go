@@ -0,0 +1,165 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+const (
+	cancelCommand          = "cancel-offline-command"
+	cancelCommandCommandID = "command-id"
+)
+
+const cancelCommandHelp = `NAME:
+    {{.CancelCommandName}}
+
+DESCRIPTION
+SYNOPSIS
+    {{.CancelCommandName}}
+    {{.CommandIdFlag}}
+
+PARAMETERS
+    {{.CommandIdFlag}} (string) Command ID from {{.SendCommandName}}.
+
+EXAMPLES
+    This example cancels a command run by the local amazon-ssm-agent service.
+
+    Command:
+
+      {{.SsmCliName}} {{.CancelCommandName}} {{.CommandIdFlag}} 01234567-890a-bcde-f012-34567890abcd
+
+    Output:
+
+      successfully submitted cancel request for command id: 01234567-890a-bcde-f012-34567890abcd
+
+OUTPUT
+    Success message or failure message - failure usually happens because you are not admin or the command already completed.
+    Once the command stops, {{.GetCommandName}} reports its status as Cancelled.
+`
+
+type cancelCommandHelpParams struct {
+	SsmCliName        string
+	CancelCommandName string
+	SendCommandName   string
+	GetCommandName    string
+	CommandIdFlag     string
+}
+
+func init() {
+	cliutil.Register(&CancelOfflineCommand{})
+}
+
+type CancelOfflineCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the cancel-offline-command cli command
+func (c *CancelOfflineCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation, commandID := c.validateCancelCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	if err := c.submitCancelRequest(newOfflineCommandStore(), appconfig.LocalCommandRootCancel, commandID); err != nil {
+		return err, ""
+	}
+	return nil, c.waitForCancelStatus(appconfig.LocalCommandRootCancel, commandID)
+}
+
+// Help prints help for the cancel-offline-command cli command
+func (c *CancelOfflineCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("CancelOfflineCommandHelp").Parse(cancelCommandHelp)
+		params := cancelCommandHelpParams{cliutil.SsmCliName, cancelCommand, sendCommand, getCommand, cliutil.FormatFlag(cancelCommandCommandID)}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (CancelOfflineCommand) Name() string {
+	return cancelCommand
+}
+
+// validateCancelCommandInput checks the subcommands and parameters for required values, format, and unsupported values
+func (CancelOfflineCommand) validateCancelCommandInput(subcommands []string, parameters map[string][]string) (validation []string, commandID string) {
+	validation = make([]string, 0)
+
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", cancelCommand, subcommands), "")
+		return validation, "" // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	// look for required parameters
+	commandIDValidation, commandID := validateCommandID(cancelCommandCommandID, parameters)
+	validation = append(validation, commandIDValidation...)
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != cancelCommandCommandID {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation, commandID
+}
+
+// submitCancelRequest writes a request for the offline service to cancel a command that has not completed yet
+func (CancelOfflineCommand) submitCancelRequest(store offlineCommandStore, cancelDir string, commandID string) error {
+	status, found := store.status(commandID)
+	if !found {
+		if _, documentPath := store.submittedDocument(commandID); documentPath == "" {
+			return fmt.Errorf("No status found for command ID %v", commandID)
+		}
+		status = offlineCommandStatusPending
+	}
+	if status != offlineCommandStatusPending && status != offlineCommandStatusInProgress {
+		return fmt.Errorf("command %v cannot be cancelled, its status is %v", commandID, status)
+	}
+
+	if err := fileutil.MakeDirs(cancelDir); err != nil {
+		return errors.New("failed to submit cancel request")
+	}
+	return fileutil.WriteAllText(filepath.Join(cancelDir, commandID), "")
+}
+
+// waitForCancelStatus waits for the offline service to pick up the cancel request
+func (CancelOfflineCommand) waitForCancelStatus(cancelDir string, commandID string) string {
+	requestPath := filepath.Join(cancelDir, commandID)
+	for i := 0; i < 10; i++ {
+		if !fileutil.Exists(requestPath) {
+			return fmt.Sprintf("successfully submitted cancel request for command id: %v", commandID)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	// withdraw the request unless the service picked it up meanwhile
+	if err := fileutil.DeleteFile(requestPath); err == nil {
+		return "failed to submit cancel request: timed out"
+	}
+	return fmt.Sprintf("successfully submitted cancel request for command id: %v", commandID)
+}
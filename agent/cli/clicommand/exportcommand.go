@@ -0,0 +1,258 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+const (
+	exportCommand          = "export-offline-command-invocation"
+	exportCommandCommandID = "command-id"
+	exportCommandFile      = "file"
+)
+
+const exportCommandHelp = `NAME:
+    {{.ExportCommandName}}
+
+DESCRIPTION
+SYNOPSIS
+    {{.ExportCommandName}}
+    {{.CommandIdFlag}}
+    {{.FileFlag}}
+
+PARAMETERS
+    {{.CommandIdFlag}} (string) Command ID from {{.SendCommandName}}.
+
+    {{.FileFlag}} (string) Path of the gzipped tarball to create.
+
+EXAMPLES
+    This example exports the submitted document, the reply and the output of every step of a command.
+
+    Command:
+
+      {{.SsmCliName}} {{.ExportCommandName}} {{.CommandIdFlag}} 01234567-890a-bcde-f012-34567890abcd {{.FileFlag}} /tmp/command.tar.gz
+
+    Output:
+
+      exported command 01234567-890a-bcde-f012-34567890abcd to /tmp/command.tar.gz
+
+OUTPUT
+    Success message or failure message - failure usually happens because you are not admin or the command is unknown
+`
+
+type exportCommandHelpParams struct {
+	SsmCliName        string
+	ExportCommandName string
+	SendCommandName   string
+	CommandIdFlag     string
+	FileFlag          string
+}
+
+func init() {
+	cliutil.Register(&ExportOfflineCommand{})
+}
+
+type ExportOfflineCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the export-offline-command-invocation cli command
+func (c *ExportOfflineCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation, commandID, file := c.validateExportCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	if err := c.exportCommand(newOfflineCommandStore(), commandID, file); err != nil {
+		return err, ""
+	}
+	return nil, fmt.Sprintf("exported command %v to %v", commandID, file)
+}
+
+// Help prints help for the export-offline-command-invocation cli command
+func (c *ExportOfflineCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("ExportOfflineCommandHelp").Parse(exportCommandHelp)
+		params := exportCommandHelpParams{cliutil.SsmCliName, exportCommand, sendCommand, cliutil.FormatFlag(exportCommandCommandID), cliutil.FormatFlag(exportCommandFile)}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (ExportOfflineCommand) Name() string {
+	return exportCommand
+}
+
+// validateExportCommandInput checks the subcommands and parameters for required values, format, and unsupported values
+func (ExportOfflineCommand) validateExportCommandInput(subcommands []string, parameters map[string][]string) (validation []string, commandID string, file string) {
+	validation = make([]string, 0)
+
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", exportCommand, subcommands), "")
+		return validation, "", "" // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	// look for required parameters
+	commandIDValidation, commandID := validateCommandID(exportCommandCommandID, parameters)
+	validation = append(validation, commandIDValidation...)
+
+	if _, exists := parameters[exportCommandFile]; !exists {
+		validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(exportCommandFile)))
+	} else if len(parameters[exportCommandFile]) != 1 {
+		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(exportCommandFile)))
+	} else {
+		file = parameters[exportCommandFile][0]
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != exportCommandCommandID && key != exportCommandFile {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation, commandID, file
+}
+
+// exportCommand writes the summary, submitted document, reply and plugin output of a command into a gzipped tarball
+func (ExportOfflineCommand) exportCommand(store offlineCommandStore, commandID string, file string) (err error) {
+	invocation, err := store.loadInvocation(commandID)
+	if err != nil {
+		return err
+	}
+	summary, err := jsonutil.Marshal(invocation)
+	if err != nil {
+		return err
+	}
+
+	output, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := output.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(file)
+		}
+	}()
+	gzipWriter := gzip.NewWriter(output)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	if err = addTarContent(tarWriter, filepath.Join(commandID, "invocation.json"), []byte(jsonutil.Indent(summary))); err != nil {
+		return err
+	}
+	if _, documentPath := store.submittedDocument(commandID); documentPath != "" {
+		if err = addTarFile(tarWriter, filepath.Join(commandID, "document.json"), documentPath); err != nil {
+			return err
+		}
+	}
+	if resultPath := store.resultPath(commandID); fileutil.IsFile(resultPath) {
+		if err = addTarFile(tarWriter, filepath.Join(commandID, "result.json"), resultPath); err != nil {
+			return err
+		}
+	}
+	for _, dir := range store.orchestrationDirs(commandID) {
+		// the output of each instance of the data store is kept under the name of its directory
+		var relativeDir string
+		if relativeDir, err = filepath.Rel(store.dataStorePath, dir); err != nil {
+			return err
+		}
+		instanceDir := strings.Split(filepath.ToSlash(relativeDir), "/")[0]
+		if err = addTarDirectory(tarWriter, filepath.Join(commandID, "orchestration", instanceDir), dir); err != nil {
+			return err
+		}
+	}
+
+	if err = tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// addTarDirectory adds every file under dir to the tarball, below prefix
+func addTarDirectory(tarWriter *tar.Writer, prefix string, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return addTarFile(tarWriter, filepath.Join(prefix, relativePath), path)
+	})
+}
+
+// addTarFile copies a file into the tarball under name
+func addTarFile(tarWriter *tar.Writer, name string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{
+		Name:    filepath.ToSlash(name),
+		Mode:    int64(info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	// the output of a command in progress may grow after the header is written, only the size it announces is copied
+	_, err = io.CopyN(tarWriter, file, info.Size())
+	return err
+}
+
+// addTarContent writes content into the tarball under name
+func addTarContent(tarWriter *tar.Writer, name string, content []byte) error {
+	header := &tar.Header{
+		Name: filepath.ToSlash(name),
+		Mode: 0600,
+		Size: int64(len(content)),
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tarWriter.Write(content)
+	return err
+}
@@ -0,0 +1,173 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/docsignature"
+)
+
+const (
+	getCanonicalDocument     = "get-canonical-document"
+	getCanonicalDocumentFile = "file"
+)
+
+const getCanonicalDocumentHelp = `NAME:
+    {{.GetCanonicalDocumentName}}
+
+DESCRIPTION
+SYNOPSIS
+    {{.GetCanonicalDocumentName}}
+    {{.ContentFlag}} | {{.DocumentNameFlag}}
+    {{.FileFlag}}
+
+PARAMETERS
+    {{.ContentFlag}} (string) JSON or URL to command document.
+
+    {{.DocumentNameFlag}} (string) Name of a command document stored in {{.DocumentRoot}}.
+
+    {{.FileFlag}} (string) Path of the file to write the canonical form of the document to.
+
+EXAMPLES
+    This example signs the document {{.DocumentRoot}}/RunShellScript.json with an RSA key
+    whose public key is in the trusted keys folder of the agent, and runs it.
+
+    Command:
+
+      {{.SsmCliName}} {{.GetCanonicalDocumentName}} {{.DocumentNameFlag}} RunShellScript {{.FileFlag}} /tmp/RunShellScript.canonical
+      openssl dgst -sha256 -sign signing-key.pem -out /tmp/RunShellScript.sig /tmp/RunShellScript.canonical
+      {{.SsmCliName}} {{.SendCommandName}} {{.DocumentNameFlag}} RunShellScript {{.SignatureFlag}} /tmp/RunShellScript.sig
+
+    Output:
+
+      wrote the canonical form of the document to /tmp/RunShellScript.canonical
+
+OUTPUT
+    Success message or failure message - failure usually happens because the document is not valid JSON
+`
+
+type getCanonicalDocumentHelpParams struct {
+	SsmCliName               string
+	GetCanonicalDocumentName string
+	SendCommandName          string
+	ContentFlag              string
+	DocumentNameFlag         string
+	FileFlag                 string
+	SignatureFlag            string
+	DocumentRoot             string
+}
+
+func init() {
+	cliutil.Register(&GetCanonicalDocumentCommand{})
+}
+
+type GetCanonicalDocumentCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the get-canonical-document cli command
+func (c *GetCanonicalDocumentCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation, file := c.validateGetCanonicalDocumentInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	if err := c.writeCanonicalDocument(appconfig.LocalDocumentRoot, parameters, file); err != nil {
+		return err, ""
+	}
+	return nil, fmt.Sprintf("wrote the canonical form of the document to %v", file)
+}
+
+// Help prints help for the get-canonical-document cli command
+func (c *GetCanonicalDocumentCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetCanonicalDocumentHelp").Parse(getCanonicalDocumentHelp)
+		params := getCanonicalDocumentHelpParams{
+			SsmCliName:               cliutil.SsmCliName,
+			GetCanonicalDocumentName: getCanonicalDocument,
+			SendCommandName:          sendCommand,
+			ContentFlag:              cliutil.FormatFlag(sendCommandContent),
+			DocumentNameFlag:         cliutil.FormatFlag(sendCommandDocumentName),
+			FileFlag:                 cliutil.FormatFlag(getCanonicalDocumentFile),
+			SignatureFlag:            cliutil.FormatFlag(sendCommandSignature),
+			DocumentRoot:             appconfig.LocalDocumentRoot,
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetCanonicalDocumentCommand) Name() string {
+	return getCanonicalDocument
+}
+
+// validateGetCanonicalDocumentInput checks the subcommands and parameters for required values and unsupported values,
+// the document is given like for send-offline-command
+func (GetCanonicalDocumentCommand) validateGetCanonicalDocumentInput(subcommands []string, parameters map[string][]string) (validation []string, file string) {
+	validation = make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", getCanonicalDocument, subcommands), "")
+		return validation, "" // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	documentParameters := make(map[string][]string)
+	for _, key := range []string{sendCommandContent, sendCommandDocumentName} {
+		if values, exists := parameters[key]; exists {
+			documentParameters[key] = values
+		}
+	}
+	validation = append(validation, SendOfflineCommand{}.validateSendCommandInput(nil, documentParameters)...)
+
+	if values, exists := parameters[getCanonicalDocumentFile]; !exists {
+		validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(getCanonicalDocumentFile)))
+	} else if len(values) != 1 || values[0] == "" {
+		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(getCanonicalDocumentFile)))
+	} else {
+		file = values[0]
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != sendCommandContent && key != sendCommandDocumentName && key != getCanonicalDocumentFile {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation, file
+}
+
+// writeCanonicalDocument writes the canonical form of the document, the content its signature is made over, to the file
+func (GetCanonicalDocumentCommand) writeCanonicalDocument(documentRoot string, parameters map[string][]string, file string) error {
+	err, content := (&SendOfflineCommand{}).loadRawDocument(documentRoot, parameters)
+	if err != nil {
+		return err
+	}
+	canonical, err := docsignature.Canonicalize(content)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, canonical, appconfig.ReadWriteAccess)
+}
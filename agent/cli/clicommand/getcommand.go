@@ -18,13 +18,10 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"path"
 	"strings"
 	"text/template"
 
-	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
-	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 )
 
 const (
@@ -40,12 +37,17 @@ DESCRIPTION
 SYNOPSIS
     {{.GetCommandName}}
     {{.CommandIdFlag}}
-    {{.DetailsFlag}}
+    [{{.DetailsFlag}}]
+    [{{.OutputFlag}} <value>]
 
 PARAMETERS
     {{.CommandIdFlag}} (string) Command ID from {{.SendCommandName}}.
 
-    {{.DetailsFlag}} (boolean) true if provided.
+    {{.DetailsFlag}} (boolean) true if provided. Shows the status, exit code, timing,
+    standard output and standard error of each step of a command.
+
+    {{.OutputFlag}} (string) Format of the details - table (default) or json.
+    Implies {{.DetailsFlag}}.
 
 EXAMPLES
     This example gets status for a command run by the local amazon-ssm-agent service.
@@ -56,10 +58,32 @@ EXAMPLES
 
     Output:
 
-      Completed
+      Complete
+
+    This example gets the results of each step of the command.
+
+    Command:
+
+      {{.SsmCliName}} {{.GetCommandName}} {{.CommandIdFlag}} 01234567-890a-bcde-f012-34567890abcd {{.DetailsFlag}}
+
+    Output:
+
+      Command ID:       01234567-890a-bcde-f012-34567890abcd
+      Document name:    5b3ee1a6-17b1-4b4e-9e0c-5f3c7b1dc9a2
+      Submitted:        2017-10-12T10:00:00.000Z
+      Status:           Complete
+      Document status:  Success
+
+      STEP            STATUS   EXIT CODE  START                     END
+      runShellScript  Success  0          2017-10-12T10:00:01.000Z  2017-10-12T10:00:02.000Z
+
+      ----- runShellScript standard output -----
+      hello
+      ----- runShellScript standard error -----
 
 OUTPUT
-    Status of command - Pending, In Progress, Complete, or Corrupt
+    Status of command - Pending, In Progress, Complete, Cancelled, or Corrupt
+    With {{.DetailsFlag}}, the command and the results of its steps in the requested format
 `
 
 type getCommandHelpParams struct {
@@ -68,6 +92,7 @@ type getCommandHelpParams struct {
 	SendCommandName string
 	CommandIdFlag   string
 	DetailsFlag     string
+	OutputFlag      string
 }
 
 func init() {
@@ -80,20 +105,20 @@ type GetOfflineCommand struct {
 
 // Execute validates and executes the get-offline-command-invocation cli command
 func (c *GetOfflineCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
-	validation, commandID, showDetails := c.validateGetCommandInput(subcommands, parameters)
+	validation, commandID, showDetails, format := c.validateGetCommandInput(subcommands, parameters)
 	// return validation errors if any were found
 	if len(validation) > 0 {
 		return errors.New(strings.Join(validation, "\n")), ""
 	}
 
-	return c.getCommandStatus(commandID, showDetails)
+	return c.getCommandStatus(newOfflineCommandStore(), commandID, showDetails, format)
 }
 
 // Help prints help for the get-offline-command-invocation cli command
 func (c *GetOfflineCommand) Help() string {
 	if len(c.helpText) == 0 {
 		t, _ := template.New("GetOfflineCommandHelp").Parse(getCommandHelp)
-		params := getCommandHelpParams{cliutil.SsmCliName, getCommand, sendCommand, cliutil.FormatFlag(getCommandCommandID), cliutil.FormatFlag(getCommandDetails)}
+		params := getCommandHelpParams{cliutil.SsmCliName, getCommand, sendCommand, cliutil.FormatFlag(getCommandCommandID), cliutil.FormatFlag(getCommandDetails), cliutil.FormatFlag(outputFormatFlag)}
 		buf := new(bytes.Buffer)
 		t.Execute(buf, params)
 		c.helpText = buf.String()
@@ -107,83 +132,52 @@ func (GetOfflineCommand) Name() string {
 }
 
 // validateGetCommandInput checks the subcommands and parameters for required values, format, and unsupported values
-func (GetOfflineCommand) validateGetCommandInput(subcommands []string, parameters map[string][]string) (validation []string, commandID string, showDetails bool) {
+func (GetOfflineCommand) validateGetCommandInput(subcommands []string, parameters map[string][]string) (validation []string, commandID string, showDetails bool, format string) {
 	validation = make([]string, 0)
 
 	if subcommands != nil && len(subcommands) > 0 {
 		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", getCommand, subcommands), "")
-		return validation, "", false // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+		return validation, "", false, "" // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
 	}
 
 	// look for required parameters
-	if _, exists := parameters[getCommandCommandID]; !exists {
-		validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(getCommandCommandID)))
-	} else if len(parameters[getCommandCommandID]) != 1 {
-		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v",
-			cliutil.FormatFlag(getCommandCommandID)))
-	} else {
-		// must be a 36 character UUID
-		commandID = parameters[getCommandCommandID][0]
-		if commandIdLen := len(commandID); commandIdLen != 36 {
-			validation = append(validation,
-				fmt.Sprintf("Invalid length for parameter %v.  Length was %v should be 36",
-					cliutil.FormatFlag(getCommandCommandID), commandIdLen))
-		}
-	}
+	commandIDValidation, commandID := validateCommandID(getCommandCommandID, parameters)
+	validation = append(validation, commandIDValidation...)
+
 	_, showDetails = parameters[getCommandDetails]
 	if showDetails && len(parameters[getCommandDetails]) > 0 {
 		validation = append(validation, fmt.Sprintf("flag %v should not have any values", cliutil.FormatFlag(getCommandDetails)))
 	}
+	if _, exists := parameters[outputFormatFlag]; exists {
+		showDetails = true
+	}
+	formatValidation, format := validateOutputFormat(parameters)
+	validation = append(validation, formatValidation...)
 
 	// look for unsupported parameters
 	for key := range parameters {
-		if key != getCommandCommandID && key != getCommandDetails {
+		if key != getCommandCommandID && key != getCommandDetails && key != outputFormatFlag {
 			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
 		}
 	}
-	return validation, commandID, showDetails
+	return validation, commandID, showDetails, format
 }
 
 // getCommandStatus looks for the command in the local orchestration folders and returns status and optionally details
-func (c *GetOfflineCommand) getCommandStatus(commandID string, showDetails bool) (error, string) {
-	// Look for file with commandID as name in each orchestration folder
-	// If found, return status (or lots of details if showDetails is set)
-	if c.isCommandCompleted(commandID) {
-		return nil, "Complete"
-	}
-	if c.isCommandInState(appconfig.DefaultLocationOfPending, commandID) {
-		return nil, "Pending"
-	}
-	if c.isCommandInState(appconfig.DefaultLocationOfCurrent, commandID) {
-		return nil, "In Progress"
-	}
-	if c.isCommandInState(appconfig.DefaultLocationOfCorrupt, commandID) {
-		return nil, "Corrupt"
-	}
-
-	// If not found, return error
-	return fmt.Errorf("No status found for command ID %v", commandID), ""
-}
-
-func (c *GetOfflineCommand) isCommandCompleted(commandID string) bool {
-	return fileutil.Exists(path.Join(appconfig.LocalCommandRootCompleted, commandID))
-}
-
-func (GetOfflineCommand) isCommandInState(stateFolder string, commandID string) bool {
-	// TODO:MF: Find a way to get the current instanceID instead of trying all possible folders
-	dirs, _ := fileutil.GetDirectoryNames(appconfig.DefaultDataStorePath)
-
-	for _, dir := range dirs {
-		potentialFolder := path.Join(appconfig.DefaultDataStorePath,
-			dir,
-			appconfig.DefaultDocumentRootDirName,
-			appconfig.DefaultLocationOfState,
-			stateFolder,
-			commandID)
-		if fileutil.Exists(potentialFolder) {
-			return true
+func (c *GetOfflineCommand) getCommandStatus(store offlineCommandStore, commandID string, showDetails bool, format string) (error, string) {
+	if !showDetails {
+		if status, found := store.status(commandID); found {
+			return nil, status
 		}
+		return fmt.Errorf("No status found for command ID %v", commandID), ""
 	}
 
-	return false
+	invocation, err := store.loadInvocation(commandID)
+	if err != nil {
+		return err, ""
+	}
+	if format == outputFormatJson {
+		return formatJson(invocation)
+	}
+	return nil, formatInvocationTable(invocation)
 }
@@ -0,0 +1,206 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/history"
+)
+
+const (
+	getExecutionHistory             = "get-execution-history"
+	getExecutionHistoryDocumentName = "document-name"
+	getExecutionHistoryStartTime    = "start-time"
+	getExecutionHistoryEndTime      = "end-time"
+)
+
+const getExecutionHistoryHelp = `NAME:
+    {{.GetExecutionHistoryName}}
+
+DESCRIPTION
+SYNOPSIS
+    {{.GetExecutionHistoryName}}
+    [{{.DocumentNameFlag}} <value>]
+    [{{.StartTimeFlag}} <value>]
+    [{{.EndTimeFlag}} <value>]
+    [{{.OutputFlag}} <value>]
+
+PARAMETERS
+    {{.DocumentNameFlag}} (string) Only list the executions of this document.
+
+    {{.StartTimeFlag}} (string) Only list the documents which finished at or after this time, in RFC 3339 format.
+
+    {{.EndTimeFlag}} (string) Only list the documents which finished at or before this time, in RFC 3339 format.
+
+    {{.OutputFlag}} (string) Format of the list - table (default) or json.
+
+EXAMPLES
+    This example lists the executions of AWS-RunShellScript on October 12th 2017.
+
+    Command:
+
+      {{.SsmCliName}} {{.GetExecutionHistoryName}} {{.DocumentNameFlag}} AWS-RunShellScript {{.StartTimeFlag}} 2017-10-12T00:00:00Z {{.EndTimeFlag}} 2017-10-13T00:00:00Z
+
+    Output:
+
+      SEQUENCE  TIME                      DOCUMENT ID                           DOCUMENT NAME       STATUS
+      42        2017-10-12T10:00:02.000Z  01234567-890a-bcde-f012-34567890abcd  AWS-RunShellScript  Success
+
+OUTPUT
+    Documents executed by the agent, oldest first. The json format includes the SHA-256 of the content and
+    parameters of each document. Use {{.VerifyExecutionHistoryName}} to check that the history was not modified.
+`
+
+type getExecutionHistoryHelpParams struct {
+	SsmCliName                 string
+	GetExecutionHistoryName    string
+	VerifyExecutionHistoryName string
+	DocumentNameFlag           string
+	StartTimeFlag              string
+	EndTimeFlag                string
+	OutputFlag                 string
+}
+
+func init() {
+	cliutil.Register(&GetExecutionHistoryCommand{})
+}
+
+type GetExecutionHistoryCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the get-execution-history cli command
+func (c *GetExecutionHistoryCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation, filter, format := c.validateGetExecutionHistoryInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	return c.getExecutionHistory(history.NewHistory(appconfig.ExecutionHistoryPath), filter, format)
+}
+
+// Help prints help for the get-execution-history cli command
+func (c *GetExecutionHistoryCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetExecutionHistoryHelp").Parse(getExecutionHistoryHelp)
+		params := getExecutionHistoryHelpParams{
+			SsmCliName:                 cliutil.SsmCliName,
+			GetExecutionHistoryName:    getExecutionHistory,
+			VerifyExecutionHistoryName: verifyExecutionHistory,
+			DocumentNameFlag:           cliutil.FormatFlag(getExecutionHistoryDocumentName),
+			StartTimeFlag:              cliutil.FormatFlag(getExecutionHistoryStartTime),
+			EndTimeFlag:                cliutil.FormatFlag(getExecutionHistoryEndTime),
+			OutputFlag:                 cliutil.FormatFlag(outputFormatFlag),
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetExecutionHistoryCommand) Name() string {
+	return getExecutionHistory
+}
+
+// validateGetExecutionHistoryInput checks the subcommands and parameters for format and unsupported values
+func (GetExecutionHistoryCommand) validateGetExecutionHistoryInput(subcommands []string, parameters map[string][]string) (validation []string, filter history.Filter, format string) {
+	validation = make([]string, 0)
+
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", getExecutionHistory, subcommands), "")
+		return validation, filter, "" // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	if values, exists := parameters[getExecutionHistoryDocumentName]; exists {
+		if len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(getExecutionHistoryDocumentName)))
+		} else {
+			filter.DocumentName = values[0]
+		}
+	}
+	validation = append(validation, validateHistoryTime(parameters, getExecutionHistoryStartTime, &filter.StartTime)...)
+	validation = append(validation, validateHistoryTime(parameters, getExecutionHistoryEndTime, &filter.EndTime)...)
+	if !filter.StartTime.IsZero() && !filter.EndTime.IsZero() && filter.EndTime.Before(filter.StartTime) {
+		validation = append(validation, fmt.Sprintf("%v must not be before %v",
+			cliutil.FormatFlag(getExecutionHistoryEndTime), cliutil.FormatFlag(getExecutionHistoryStartTime)))
+	}
+	formatValidation, format := validateOutputFormat(parameters)
+	validation = append(validation, formatValidation...)
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != getExecutionHistoryDocumentName && key != getExecutionHistoryStartTime && key != getExecutionHistoryEndTime && key != outputFormatFlag {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation, filter, format
+}
+
+// validateHistoryTime parses the optional time parameter into value
+func validateHistoryTime(parameters map[string][]string, flag string, value *time.Time) []string {
+	values, exists := parameters[flag]
+	if !exists {
+		return nil
+	}
+	if len(values) != 1 {
+		return []string{fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(flag))}
+	}
+	parsed, err := time.Parse(time.RFC3339, values[0])
+	if err != nil {
+		return []string{fmt.Sprintf("%v must be a time in RFC 3339 format such as 2017-10-12T10:00:00Z", cliutil.FormatFlag(flag))}
+	}
+	*value = parsed
+	return nil
+}
+
+// getExecutionHistory lists the entries of the history matching the filter
+func (GetExecutionHistoryCommand) getExecutionHistory(h *history.History, filter history.Filter, format string) (error, string) {
+	entries, err := h.Query(filter)
+	if err != nil {
+		return err, ""
+	}
+
+	if format == outputFormatJson {
+		return formatJson(struct {
+			Executions []history.Entry `json:"executions"`
+		}{entries})
+	}
+	return nil, formatHistoryTable(entries)
+}
+
+// formatHistoryTable renders one line per executed document
+func formatHistoryTable(entries []history.Entry) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SEQUENCE\tTIME\tDOCUMENT ID\tDOCUMENT NAME\tSTATUS")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", entry.Sequence, entry.Time.Format(time.RFC3339Nano),
+			entry.DocumentID, valueOrDash(entry.DocumentName), entry.Status)
+	}
+	w.Flush()
+	return strings.TrimRight(buf.String(), "\n")
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/history"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateGetExecutionHistoryInput(t *testing.T) {
+	validation, filter, format := GetExecutionHistoryCommand{}.validateGetExecutionHistoryInput(nil, map[string][]string{
+		getExecutionHistoryDocumentName: {"AWS-RunShellScript"},
+		getExecutionHistoryStartTime:    {"2017-10-12T00:00:00Z"},
+		getExecutionHistoryEndTime:      {"2017-10-13T00:00:00Z"},
+		outputFormatFlag:                {"json"},
+	})
+	assert.Empty(t, validation)
+	assert.Equal(t, history.Filter{
+		StartTime:    time.Date(2017, 10, 12, 0, 0, 0, 0, time.UTC),
+		EndTime:      time.Date(2017, 10, 13, 0, 0, 0, 0, time.UTC),
+		DocumentName: "AWS-RunShellScript",
+	}, filter)
+	assert.Equal(t, outputFormatJson, format)
+
+	validation, _, _ = GetExecutionHistoryCommand{}.validateGetExecutionHistoryInput(nil, map[string][]string{
+		getExecutionHistoryStartTime: {"yesterday"},
+		getExecutionHistoryEndTime:   {},
+		"bogus":                      {},
+	})
+	assert.Len(t, validation, 3)
+
+	validation, _, _ = GetExecutionHistoryCommand{}.validateGetExecutionHistoryInput(nil, map[string][]string{
+		getExecutionHistoryStartTime: {"2017-10-13T00:00:00Z"},
+		getExecutionHistoryEndTime:   {"2017-10-12T00:00:00Z"},
+	})
+	assert.Len(t, validation, 1)
+}
+
+func TestGetExecutionHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "executions.log")
+	h := history.NewHistory(path)
+	assert.Nil(t, h.Append(history.Entry{DocumentID: "documentID", DocumentName: "AWS-RunShellScript", Status: "Success"}))
+
+	err, result := GetExecutionHistoryCommand{}.getExecutionHistory(h, history.Filter{DocumentName: "AWS-RunShellScript"}, outputFormatTable)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(strings.Split(result, "\n")))
+	assert.Contains(t, result, "documentID")
+}
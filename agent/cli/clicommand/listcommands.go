@@ -0,0 +1,217 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+)
+
+const (
+	listCommands           = "list-offline-command-invocations"
+	listCommandsStatus     = "status"
+	listCommandsSince      = "since"
+	listCommandsMaxResults = "max-results"
+)
+
+const defaultListCommandsMaxResults = 50
+
+const listCommandsHelp = `NAME:
+    {{.ListCommandsName}}
+
+DESCRIPTION
+SYNOPSIS
+    {{.ListCommandsName}}
+    [{{.StatusFlag}} <value>]
+    [{{.SinceFlag}} <value>]
+    [{{.MaxResultsFlag}} <value>]
+    [{{.OutputFlag}} <value>]
+
+PARAMETERS
+    {{.StatusFlag}} (string) Only list commands in this state - Pending, In Progress, Complete, Cancelled or Corrupt -
+    or whose document finished with this status, for example Success or Failed.
+
+    {{.SinceFlag}} (string) Only list commands submitted within this duration, for example 30m or 24h.
+
+    {{.MaxResultsFlag}} (integer) Maximum number of commands to list, defaults to {{.DefaultMaxResults}}.
+
+    {{.OutputFlag}} (string) Format of the list - table (default) or json.
+
+EXAMPLES
+    This example lists the failed commands submitted during the last day.
+
+    Command:
+
+      {{.SsmCliName}} {{.ListCommandsName}} {{.StatusFlag}} Failed {{.SinceFlag}} 24h
+
+    Output:
+
+      COMMAND ID                            STATUS    DOCUMENT STATUS  SUBMITTED                 STEPS
+      01234567-890a-bcde-f012-34567890abcd  Complete  Failed           2017-10-12T10:00:00.000Z  1
+
+OUTPUT
+    Commands submitted with {{.SendCommandName}}, most recent first
+`
+
+type listCommandsHelpParams struct {
+	SsmCliName        string
+	ListCommandsName  string
+	SendCommandName   string
+	StatusFlag        string
+	SinceFlag         string
+	MaxResultsFlag    string
+	OutputFlag        string
+	DefaultMaxResults int
+}
+
+// listCommandsFilter selects the commands shown by list-offline-command-invocations
+type listCommandsFilter struct {
+	status     string
+	since      time.Duration
+	maxResults int
+}
+
+func init() {
+	cliutil.Register(&ListOfflineCommands{})
+}
+
+type ListOfflineCommands struct {
+	helpText string
+}
+
+// Execute validates and executes the list-offline-command-invocations cli command
+func (c *ListOfflineCommands) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation, filter, format := c.validateListCommandsInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	return c.listCommands(newOfflineCommandStore(), filter, format, time.Now())
+}
+
+// Help prints help for the list-offline-command-invocations cli command
+func (c *ListOfflineCommands) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("ListOfflineCommandsHelp").Parse(listCommandsHelp)
+		params := listCommandsHelpParams{
+			SsmCliName:        cliutil.SsmCliName,
+			ListCommandsName:  listCommands,
+			SendCommandName:   sendCommand,
+			StatusFlag:        cliutil.FormatFlag(listCommandsStatus),
+			SinceFlag:         cliutil.FormatFlag(listCommandsSince),
+			MaxResultsFlag:    cliutil.FormatFlag(listCommandsMaxResults),
+			OutputFlag:        cliutil.FormatFlag(outputFormatFlag),
+			DefaultMaxResults: defaultListCommandsMaxResults,
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (ListOfflineCommands) Name() string {
+	return listCommands
+}
+
+// validateListCommandsInput checks the subcommands and parameters for format and unsupported values
+func (ListOfflineCommands) validateListCommandsInput(subcommands []string, parameters map[string][]string) (validation []string, filter listCommandsFilter, format string) {
+	validation = make([]string, 0)
+	filter.maxResults = defaultListCommandsMaxResults
+
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", listCommands, subcommands), "")
+		return validation, filter, "" // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	if values, exists := parameters[listCommandsStatus]; exists {
+		if len(values) == 0 {
+			validation = append(validation, fmt.Sprintf("expected at least 1 value for parameter %v", cliutil.FormatFlag(listCommandsStatus)))
+		} else {
+			// allow unquoted "In Progress"
+			filter.status = strings.Join(values, " ")
+		}
+	}
+	if values, exists := parameters[listCommandsSince]; exists {
+		if len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(listCommandsSince)))
+		} else if since, err := time.ParseDuration(values[0]); err != nil || since <= 0 {
+			validation = append(validation, fmt.Sprintf("%v must be a positive duration such as 30m or 24h", cliutil.FormatFlag(listCommandsSince)))
+		} else {
+			filter.since = since
+		}
+	}
+	if values, exists := parameters[listCommandsMaxResults]; exists {
+		if len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(listCommandsMaxResults)))
+		} else if maxResults, err := strconv.Atoi(values[0]); err != nil || maxResults < 1 {
+			validation = append(validation, fmt.Sprintf("%v must be a positive integer", cliutil.FormatFlag(listCommandsMaxResults)))
+		} else {
+			filter.maxResults = maxResults
+		}
+	}
+	formatValidation, format := validateOutputFormat(parameters)
+	validation = append(validation, formatValidation...)
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != listCommandsStatus && key != listCommandsSince && key != listCommandsMaxResults && key != outputFormatFlag {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation, filter, format
+}
+
+// listCommands lists the offline commands matching the filter
+func (ListOfflineCommands) listCommands(store offlineCommandStore, filter listCommandsFilter, format string, now time.Time) (error, string) {
+	invocations := make([]offlineCommandInvocation, 0)
+	for _, invocation := range store.listInvocations() {
+		if len(invocations) >= filter.maxResults {
+			break
+		}
+		if filter.matches(invocation, now) {
+			invocations = append(invocations, invocation)
+		}
+	}
+
+	if format == outputFormatJson {
+		return formatJson(struct {
+			Commands []offlineCommandInvocation `json:"commands"`
+		}{invocations})
+	}
+	return nil, formatInvocationListTable(invocations)
+}
+
+// matches returns true if the command passes the status and submission date filters
+func (filter listCommandsFilter) matches(invocation offlineCommandInvocation, now time.Time) bool {
+	if filter.status != "" &&
+		!strings.EqualFold(filter.status, invocation.Status) &&
+		!strings.EqualFold(filter.status, invocation.DocumentStatus) {
+		return false
+	}
+	if filter.since > 0 && invocation.submitted.Before(now.Add(-filter.since)) {
+		return false
+	}
+	return true
+}
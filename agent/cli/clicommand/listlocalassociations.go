@@ -0,0 +1,175 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/association/localassociation"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+const (
+	listLocalAssociationsCommand = "list-local-associations"
+)
+
+const listLocalAssociationsCommandHelp = `NAME:
+    {{.ListLocalAssociationsCommandName}}
+
+DESCRIPTION
+SYNOPSIS
+    {{.ListLocalAssociationsCommandName}}
+
+EXAMPLES
+    This example lists the associations defined in {{.LocalAssociationRoot}} along with
+    the status of their last execution and the compliance of the instance with them.
+
+    Command:
+
+      {{.SsmCliName}} {{.ListLocalAssociationsCommandName}}
+
+    Output:
+      {
+        "associations": [
+          {
+            "association-id": "0f0b48a5-3e17-5c4b-8e4b-8e0d5b1b2a5c",
+            "name": "ConfigureNtp",
+            "file": "{{.LocalAssociationRoot}}/ntp.json",
+            "schedule-expression": "cron(0 0/30 * 1/1 * ? *)",
+            "status": "Success",
+            "execution-date": "2017-10-12T10:00:00.000Z",
+            "execution-summary": "1 out of 1 plugin processed, 1 success, 0 failed, 0 timedout, 0 skipped",
+            "compliance-status": "COMPLIANT"
+          }
+        ]
+      }
+
+OUTPUT
+    Local associations in JSON format, definitions which cannot be loaded are listed under errors
+`
+
+type listLocalAssociationsHelpParams struct {
+	SsmCliName                       string
+	ListLocalAssociationsCommandName string
+	LocalAssociationRoot             string
+}
+
+// localAssociationSummary is the cli representation of a local association
+type localAssociationSummary struct {
+	AssociationID      string `json:"association-id"`
+	Name               string `json:"name"`
+	File               string `json:"file"`
+	ScheduleExpression string `json:"schedule-expression,omitempty"`
+	Status             string `json:"status,omitempty"`
+	ExecutionDate      string `json:"execution-date,omitempty"`
+	ExecutionSummary   string `json:"execution-summary,omitempty"`
+	ComplianceStatus   string `json:"compliance-status,omitempty"`
+}
+
+// localAssociationList is the cli output of the list-local-associations command
+type localAssociationList struct {
+	Associations []localAssociationSummary `json:"associations"`
+	Errors       []string                  `json:"errors,omitempty"`
+}
+
+func init() {
+	cliutil.Register(&ListLocalAssociationsCommand{})
+}
+
+type ListLocalAssociationsCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the list-local-associations cli command
+func (c *ListLocalAssociationsCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateListLocalAssociationsCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	return c.listLocalAssociations(appconfig.LocalAssociationRoot, appconfig.LocalAssociationRootStatus)
+}
+
+// Help prints help for the list-local-associations cli command
+func (c *ListLocalAssociationsCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("ListLocalAssociationsCommandHelp").Parse(listLocalAssociationsCommandHelp)
+		params := listLocalAssociationsHelpParams{cliutil.SsmCliName, listLocalAssociationsCommand, appconfig.LocalAssociationRoot}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (ListLocalAssociationsCommand) Name() string {
+	return listLocalAssociationsCommand
+}
+
+// validateListLocalAssociationsCommandInput checks the subcommands and parameters for unsupported values
+func (ListLocalAssociationsCommand) validateListLocalAssociationsCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", listLocalAssociationsCommand, subcommands), "")
+		return validation // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+	}
+	return validation
+}
+
+// listLocalAssociations reads the local association definitions and their recorded status
+func (ListLocalAssociationsCommand) listLocalAssociations(definitionDir string, statusDir string) (error, string) {
+	definitions, errs := localassociation.ReadDefinitions(definitionDir)
+
+	list := localAssociationList{Associations: []localAssociationSummary{}}
+	for _, err := range errs {
+		list.Errors = append(list.Errors, err.Error())
+	}
+	for _, definition := range definitions {
+		summary := localAssociationSummary{
+			AssociationID:      definition.AssociationID,
+			Name:               definition.Name,
+			File:               definition.FileName(),
+			ScheduleExpression: definition.ScheduleExpression,
+		}
+		if status, err := localassociation.LoadStatus(statusDir, definition.AssociationID); err != nil {
+			list.Errors = append(list.Errors, err.Error())
+		} else if status != nil {
+			summary.Status = status.Status
+			summary.ExecutionDate = status.ExecutionDate
+			summary.ExecutionSummary = status.ExecutionSummary
+			summary.ComplianceStatus = status.ComplianceStatus
+		}
+		list.Associations = append(list.Associations, summary)
+	}
+
+	result, err := jsonutil.Marshal(list)
+	if err != nil {
+		return err, ""
+	}
+	return nil, jsonutil.Indent(result)
+}
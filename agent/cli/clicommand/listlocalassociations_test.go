@@ -0,0 +1,91 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateListLocalAssociationsCommandInput(t *testing.T) {
+	assert.Empty(t, ListLocalAssociationsCommand{}.validateListLocalAssociationsCommandInput(nil, map[string][]string{}))
+	assert.Len(t, ListLocalAssociationsCommand{}.validateListLocalAssociationsCommandInput([]string{"sub"}, nil), 2)
+	assert.Len(t, ListLocalAssociationsCommand{}.validateListLocalAssociationsCommandInput(nil, map[string][]string{"bogus": {}}), 1)
+}
+
+func TestListLocalAssociations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "localassociations")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	definitionDir := filepath.Join(dir, "definitions")
+	statusDir := filepath.Join(dir, "status")
+	assert.Nil(t, os.MkdirAll(definitionDir, 0700))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(definitionDir, "ntp.json"), []byte(`{
+		"Name": "ConfigureNtp",
+		"Document": {"schemaVersion": "2.2", "mainSteps": []},
+		"ScheduleExpression": "cron(0 0/30 * 1/1 * ? *)"
+	}`), 0600))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(definitionDir, "invalid.json"), []byte(`{"Name": "NoDocument"}`), 0600))
+
+	// the association never ran, the invalid definition is listed under the errors
+	err, output := ListLocalAssociationsCommand{}.listLocalAssociations(definitionDir, statusDir)
+	assert.Nil(t, err)
+	var list localAssociationList
+	assert.Nil(t, json.Unmarshal([]byte(output), &list))
+	assert.Len(t, list.Associations, 1)
+	assert.Len(t, list.Errors, 1)
+	association := list.Associations[0]
+	assert.NotEmpty(t, association.AssociationID)
+	assert.Equal(t, "ConfigureNtp", association.Name)
+	assert.Equal(t, filepath.Join(definitionDir, "ntp.json"), association.File)
+	assert.Equal(t, "cron(0 0/30 * 1/1 * ? *)", association.ScheduleExpression)
+	assert.Empty(t, association.Status)
+
+	// the recorded status is listed with the association
+	assert.Nil(t, os.MkdirAll(statusDir, 0700))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(statusDir, association.AssociationID+".json"), []byte(`{
+		"AssociationID": "`+association.AssociationID+`",
+		"Status": "Success",
+		"ExecutionDate": "2017-10-12T10:00:00.000Z",
+		"ExecutionSummary": "1 out of 1 plugin processed, 1 success, 0 failed, 0 timedout, 0 skipped",
+		"ComplianceStatus": "COMPLIANT"
+	}`), 0600))
+	err, output = ListLocalAssociationsCommand{}.listLocalAssociations(definitionDir, statusDir)
+	assert.Nil(t, err)
+	list = localAssociationList{}
+	assert.Nil(t, json.Unmarshal([]byte(output), &list))
+	assert.Equal(t, localAssociationSummary{
+		AssociationID:      association.AssociationID,
+		Name:               "ConfigureNtp",
+		File:               filepath.Join(definitionDir, "ntp.json"),
+		ScheduleExpression: "cron(0 0/30 * 1/1 * ? *)",
+		Status:             "Success",
+		ExecutionDate:      "2017-10-12T10:00:00.000Z",
+		ExecutionSummary:   "1 out of 1 plugin processed, 1 success, 0 failed, 0 timedout, 0 skipped",
+		ComplianceStatus:   "COMPLIANT",
+	}, list.Associations[0])
+
+	// a status which cannot be read is reported
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(statusDir, association.AssociationID+".json"), []byte(`{corrupted`), 0600))
+	err, output = ListLocalAssociationsCommand{}.listLocalAssociations(definitionDir, statusDir)
+	assert.Nil(t, err)
+	list = localAssociationList{}
+	assert.Nil(t, json.Unmarshal([]byte(output), &list))
+	assert.Len(t, list.Errors, 2)
+}
@@ -0,0 +1,377 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+)
+
+const (
+	offlineCommandStatusPending    = "Pending"
+	offlineCommandStatusInProgress = "In Progress"
+	offlineCommandStatusComplete   = "Complete"
+	offlineCommandStatusCancelled  = "Cancelled"
+	offlineCommandStatusCorrupt    = "Corrupt"
+)
+
+const (
+	outputFormatFlag  = "output"
+	outputFormatJson  = "json"
+	outputFormatTable = "table"
+)
+
+// offlineCommandStep is the cli representation of the result of a single plugin
+type offlineCommandStep struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	ExitCode       int    `json:"exit-code"`
+	StartDateTime  string `json:"start-date-time,omitempty"`
+	EndDateTime    string `json:"end-date-time,omitempty"`
+	StandardOutput string `json:"standard-output"`
+	StandardError  string `json:"standard-error"`
+}
+
+// offlineCommandInvocation is the cli representation of a command submitted with send-offline-command
+type offlineCommandInvocation struct {
+	CommandID      string               `json:"command-id"`
+	DocumentName   string               `json:"document-name,omitempty"`
+	Status         string               `json:"status"`
+	DocumentStatus string               `json:"document-status,omitempty"`
+	SubmittedDate  string               `json:"submitted-date,omitempty"`
+	Steps          []offlineCommandStep `json:"steps,omitempty"`
+
+	submitted time.Time
+}
+
+// offlineCommandStore reads the files the offline command service leaves behind for each command
+type offlineCommandStore struct {
+	submittedDir  string
+	resultDir     string
+	dataStorePath string
+	orchestration string
+}
+
+// newOfflineCommandStore creates a store over the default local command folders
+func newOfflineCommandStore() offlineCommandStore {
+	orchestration := ""
+	if config, err := appconfig.Config(false); err == nil {
+		orchestration = config.Agent.OrchestrationRootDir
+	}
+	return offlineCommandStore{
+		submittedDir:  appconfig.LocalCommandRootSubmitted,
+		resultDir:     appconfig.LocalCommandRootCompleted,
+		dataStorePath: appconfig.DefaultDataStorePath,
+		orchestration: orchestration,
+	}
+}
+
+// status returns the state of a command - Pending, In Progress, Complete, Cancelled, or Corrupt
+func (s offlineCommandStore) status(commandID string) (string, bool) {
+	if result, err := s.loadResult(commandID); err == nil {
+		// replies are written as each plugin completes, the document status tells whether the command is done
+		if result.DocumentStatus == contracts.ResultStatusInProgress || result.DocumentStatus == contracts.ResultStatusNotStarted {
+			return offlineCommandStatusInProgress, true
+		}
+		if result.DocumentStatus == contracts.ResultStatusCancelled {
+			return offlineCommandStatusCancelled, true
+		}
+		return offlineCommandStatusComplete, true
+	}
+	if s.isCommandInState(appconfig.DefaultLocationOfPending, commandID) {
+		return offlineCommandStatusPending, true
+	}
+	if s.isCommandInState(appconfig.DefaultLocationOfCurrent, commandID) {
+		return offlineCommandStatusInProgress, true
+	}
+	if s.isCommandInState(appconfig.DefaultLocationOfCorrupt, commandID) {
+		return offlineCommandStatusCorrupt, true
+	}
+	return "", false
+}
+
+// isCommandInState checks the state folder of every instance in the data store for the command
+func (s offlineCommandStore) isCommandInState(stateFolder string, commandID string) bool {
+	// TODO:MF: Find a way to get the current instanceID instead of trying all possible folders
+	dirs, _ := fileutil.GetDirectoryNames(s.dataStorePath)
+
+	for _, dir := range dirs {
+		potentialFolder := filepath.Join(s.dataStorePath,
+			dir,
+			appconfig.DefaultDocumentRootDirName,
+			appconfig.DefaultLocationOfState,
+			stateFolder,
+			commandID)
+		if fileutil.Exists(potentialFolder) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resultPath returns the path of the reply written by the offline command service
+func (s offlineCommandStore) resultPath(commandID string) string {
+	return filepath.Join(s.resultDir, commandID)
+}
+
+// loadResult reads the reply written by the offline command service
+func (s offlineCommandStore) loadResult(commandID string) (result messageContracts.SendReplyPayload, err error) {
+	err = jsonutil.UnmarshalFile(s.resultPath(commandID), &result)
+	return
+}
+
+// submittedDocument returns the name and path of the document submitted for the command
+func (s offlineCommandStore) submittedDocument(commandID string) (string, string) {
+	files, _ := fileutil.GetFileNames(s.submittedDir)
+	for _, file := range files {
+		if strings.HasSuffix(file, "."+commandID) {
+			return strings.TrimSuffix(file, "."+commandID), filepath.Join(s.submittedDir, file)
+		}
+	}
+	return "", ""
+}
+
+// orchestrationDirs returns the plugin output folders of the command for every instance in the data store
+func (s offlineCommandStore) orchestrationDirs(commandID string) []string {
+	if s.orchestration == "" {
+		return nil
+	}
+	var result []string
+	dirs, _ := fileutil.GetDirectoryNames(s.dataStorePath)
+	for _, dir := range dirs {
+		potentialFolder := filepath.Join(s.dataStorePath,
+			dir,
+			appconfig.DefaultDocumentRootDirName,
+			s.orchestration,
+			commandID)
+		if fileutil.IsDirectory(potentialFolder) {
+			result = append(result, potentialFolder)
+		}
+	}
+	return result
+}
+
+// loadInvocation builds the cli representation of a command from its state and result files
+func (s offlineCommandStore) loadInvocation(commandID string) (invocation offlineCommandInvocation, err error) {
+	status, found := s.status(commandID)
+	if !found {
+		return invocation, fmt.Errorf("No status found for command ID %v", commandID)
+	}
+	invocation = offlineCommandInvocation{CommandID: commandID, Status: status}
+
+	documentName, documentPath := s.submittedDocument(commandID)
+	invocation.DocumentName = documentName
+	if documentPath == "" {
+		documentPath = s.resultPath(commandID)
+	}
+	if submitted, err := fileutil.GetFileModificationTime(documentPath); err == nil {
+		invocation.submitted = submitted
+		invocation.SubmittedDate = times.ToIso8601UTC(submitted)
+	}
+
+	if result, err := s.loadResult(commandID); err == nil {
+		invocation.DocumentStatus = string(result.DocumentStatus)
+		invocation.Steps = newOfflineCommandSteps(result.RuntimeStatus)
+	}
+	return invocation, nil
+}
+
+// listInvocations returns every submitted or completed command, most recently submitted first
+func (s offlineCommandStore) listInvocations() []offlineCommandInvocation {
+	commandIDs := make(map[string]bool)
+	files, _ := fileutil.GetFileNames(s.submittedDir)
+	for _, file := range files {
+		if index := strings.LastIndex(file, "."); index >= 0 {
+			commandIDs[file[index+1:]] = true
+		}
+	}
+	files, _ = fileutil.GetFileNames(s.resultDir)
+	for _, file := range files {
+		commandIDs[file] = true
+	}
+
+	invocations := make([]offlineCommandInvocation, 0, len(commandIDs))
+	for commandID := range commandIDs {
+		invocation, err := s.loadInvocation(commandID)
+		if err != nil {
+			// submitted but not yet picked up by the agent
+			invocation = offlineCommandInvocation{CommandID: commandID, Status: offlineCommandStatusPending}
+			if documentName, documentPath := s.submittedDocument(commandID); documentPath != "" {
+				invocation.DocumentName = documentName
+				if submitted, err := fileutil.GetFileModificationTime(documentPath); err == nil {
+					invocation.submitted = submitted
+					invocation.SubmittedDate = times.ToIso8601UTC(submitted)
+				}
+			}
+		}
+		invocations = append(invocations, invocation)
+	}
+	sort.Slice(invocations, func(i, j int) bool {
+		if invocations[i].submitted.Equal(invocations[j].submitted) {
+			return invocations[i].CommandID < invocations[j].CommandID
+		}
+		return invocations[i].submitted.After(invocations[j].submitted)
+	})
+	return invocations
+}
+
+// newOfflineCommandSteps converts the plugin results of a reply into steps ordered by start time
+func newOfflineCommandSteps(runtimeStatus map[string]*contracts.PluginRuntimeStatus) []offlineCommandStep {
+	steps := make([]offlineCommandStep, 0, len(runtimeStatus))
+	for id, status := range runtimeStatus {
+		if status == nil {
+			continue
+		}
+		steps = append(steps, offlineCommandStep{
+			ID:             id,
+			Name:           status.Name,
+			Status:         string(status.Status),
+			ExitCode:       status.Code,
+			StartDateTime:  status.StartDateTime,
+			EndDateTime:    status.EndDateTime,
+			StandardOutput: status.StandardOutput,
+			StandardError:  status.StandardError,
+		})
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		if steps[i].StartDateTime == steps[j].StartDateTime {
+			return steps[i].ID < steps[j].ID
+		}
+		return steps[i].StartDateTime < steps[j].StartDateTime
+	})
+	return steps
+}
+
+// validateCommandID checks that a command id parameter holds a single 36 character UUID
+func validateCommandID(flag string, parameters map[string][]string) (validation []string, commandID string) {
+	if _, exists := parameters[flag]; !exists {
+		validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(flag)))
+	} else if len(parameters[flag]) != 1 {
+		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(flag)))
+	} else {
+		commandID = parameters[flag][0]
+		if commandIdLen := len(commandID); commandIdLen != 36 {
+			validation = append(validation,
+				fmt.Sprintf("Invalid length for parameter %v.  Length was %v should be 36",
+					cliutil.FormatFlag(flag), commandIdLen))
+		}
+	}
+	return validation, commandID
+}
+
+// validateOutputFormat checks the optional output format parameter and returns the format to use
+func validateOutputFormat(parameters map[string][]string) (validation []string, format string) {
+	values, exists := parameters[outputFormatFlag]
+	if !exists {
+		return nil, outputFormatTable
+	}
+	if len(values) != 1 {
+		return []string{fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(outputFormatFlag))}, ""
+	}
+	format = strings.ToLower(values[0])
+	if format != outputFormatJson && format != outputFormatTable {
+		return []string{fmt.Sprintf("%v must be %v or %v", cliutil.FormatFlag(outputFormatFlag), outputFormatJson, outputFormatTable)}, ""
+	}
+	return nil, format
+}
+
+// formatJson renders the cli output as indented json
+func formatJson(obj interface{}) (error, string) {
+	result, err := jsonutil.Marshal(obj)
+	if err != nil {
+		return err, ""
+	}
+	return nil, jsonutil.Indent(result)
+}
+
+// formatInvocationTable renders a command with the status and output of each of its steps
+func formatInvocationTable(invocation offlineCommandInvocation) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Command ID:\t%v\n", invocation.CommandID)
+	if invocation.DocumentName != "" {
+		fmt.Fprintf(w, "Document name:\t%v\n", invocation.DocumentName)
+	}
+	if invocation.SubmittedDate != "" {
+		fmt.Fprintf(w, "Submitted:\t%v\n", invocation.SubmittedDate)
+	}
+	fmt.Fprintf(w, "Status:\t%v\n", invocation.Status)
+	if invocation.DocumentStatus != "" {
+		fmt.Fprintf(w, "Document status:\t%v\n", invocation.DocumentStatus)
+	}
+	w.Flush()
+
+	if len(invocation.Steps) == 0 {
+		return strings.TrimRight(buf.String(), "\n")
+	}
+
+	fmt.Fprintln(&buf)
+	w = tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tSTATUS\tEXIT CODE\tSTART\tEND")
+	for _, step := range invocation.Steps {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", step.ID, step.Status, step.ExitCode, step.StartDateTime, step.EndDateTime)
+	}
+	w.Flush()
+
+	for _, step := range invocation.Steps {
+		fmt.Fprintf(&buf, "\n----- %v standard output -----\n%v", step.ID, terminateLine(step.StandardOutput))
+		fmt.Fprintf(&buf, "----- %v standard error -----\n%v", step.ID, terminateLine(step.StandardError))
+	}
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// formatInvocationListTable renders one line per command
+func formatInvocationListTable(invocations []offlineCommandInvocation) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "COMMAND ID\tSTATUS\tDOCUMENT STATUS\tSUBMITTED\tSTEPS")
+	for _, invocation := range invocations {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", invocation.CommandID, invocation.Status,
+			valueOrDash(invocation.DocumentStatus), valueOrDash(invocation.SubmittedDate), len(invocation.Steps))
+	}
+	w.Flush()
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// terminateLine makes sure non empty output ends with a new line
+func terminateLine(output string) string {
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		return output + "\n"
+	}
+	return output
+}
+
+// valueOrDash shows a dash for values that are not known yet
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
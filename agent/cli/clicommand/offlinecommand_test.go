@@ -0,0 +1,201 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	completedCommandID = "01234567-890a-bcde-f012-34567890abcd"
+	pendingCommandID   = "11234567-890a-bcde-f012-34567890abcd"
+)
+
+// newTestStore creates a store with a completed command and a command the agent has not picked up yet
+func newTestStore(t *testing.T) (offlineCommandStore, string) {
+	root, err := ioutil.TempDir("", "offlinecommand")
+	assert.NoError(t, err)
+	store := offlineCommandStore{
+		submittedDir:  filepath.Join(root, "submitted"),
+		resultDir:     filepath.Join(root, "completed"),
+		dataStorePath: filepath.Join(root, "data"),
+		orchestration: "orchestration",
+	}
+	orchestrationDir := filepath.Join(store.dataStorePath, "i-123", appconfig.DefaultDocumentRootDirName, "orchestration", completedCommandID, "runShellScript")
+	for _, dir := range []string{store.submittedDir, store.resultDir, orchestrationDir} {
+		assert.NoError(t, os.MkdirAll(dir, 0700))
+	}
+
+	result := messageContracts.SendReplyPayload{
+		DocumentStatus: contracts.ResultStatusFailed,
+		RuntimeStatus: map[string]*contracts.PluginRuntimeStatus{
+			"second": {Status: contracts.ResultStatusFailed, Code: 1, StartDateTime: "2017-10-12T10:00:02.000Z", StandardError: "oops"},
+			"first":  {Status: contracts.ResultStatusSuccess, StartDateTime: "2017-10-12T10:00:01.000Z", StandardOutput: "hello"},
+		},
+	}
+	content, _ := jsonutil.Marshal(result)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(store.resultDir, completedCommandID), []byte(content), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(store.submittedDir, "doc1."+completedCommandID), []byte("{}"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(store.submittedDir, "doc2."+pendingCommandID), []byte("{}"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(orchestrationDir, "stdout"), []byte("hello"), 0600))
+
+	old := time.Now().Add(-48 * time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(store.submittedDir, "doc1."+completedCommandID), old, old))
+	return store, root
+}
+
+func TestLoadInvocation(t *testing.T) {
+	store, root := newTestStore(t)
+	defer os.RemoveAll(root)
+
+	invocation, err := store.loadInvocation(completedCommandID)
+	assert.NoError(t, err)
+	assert.Equal(t, offlineCommandStatusComplete, invocation.Status)
+	assert.Equal(t, "Failed", invocation.DocumentStatus)
+	assert.Equal(t, "doc1", invocation.DocumentName)
+	assert.Len(t, invocation.Steps, 2)
+	assert.Equal(t, "first", invocation.Steps[0].ID)
+	assert.Equal(t, "hello", invocation.Steps[0].StandardOutput)
+	assert.Equal(t, "second", invocation.Steps[1].ID)
+	assert.Equal(t, 1, invocation.Steps[1].ExitCode)
+	assert.Equal(t, "oops", invocation.Steps[1].StandardError)
+	assert.Contains(t, formatInvocationTable(invocation), "----- second standard error -----\noops")
+
+	_, err = store.loadInvocation(pendingCommandID)
+	assert.Error(t, err)
+}
+
+func TestListInvocations(t *testing.T) {
+	store, root := newTestStore(t)
+	defer os.RemoveAll(root)
+
+	invocations := store.listInvocations()
+	assert.Len(t, invocations, 2)
+	// most recently submitted first
+	assert.Equal(t, pendingCommandID, invocations[0].CommandID)
+	assert.Equal(t, offlineCommandStatusPending, invocations[0].Status)
+	assert.Equal(t, completedCommandID, invocations[1].CommandID)
+
+	now := time.Now()
+	match := func(filter listCommandsFilter) []string {
+		ids := []string{}
+		for _, invocation := range invocations {
+			if filter.matches(invocation, now) {
+				ids = append(ids, invocation.CommandID)
+			}
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	assert.Equal(t, []string{completedCommandID, pendingCommandID}, match(listCommandsFilter{}))
+	assert.Equal(t, []string{completedCommandID}, match(listCommandsFilter{status: "failed"}))
+	assert.Equal(t, []string{completedCommandID}, match(listCommandsFilter{status: "Complete"}))
+	assert.Equal(t, []string{pendingCommandID}, match(listCommandsFilter{since: 24 * time.Hour}))
+}
+
+func TestValidateListCommandsInput(t *testing.T) {
+	validation, filter, format := ListOfflineCommands{}.validateListCommandsInput(nil, map[string][]string{
+		listCommandsStatus:     {"In", "Progress"},
+		listCommandsSince:      {"2h"},
+		listCommandsMaxResults: {"5"},
+		outputFormatFlag:       {"JSON"},
+	})
+	assert.Empty(t, validation)
+	assert.Equal(t, listCommandsFilter{status: "In Progress", since: 2 * time.Hour, maxResults: 5}, filter)
+	assert.Equal(t, outputFormatJson, format)
+
+	validation, _, _ = ListOfflineCommands{}.validateListCommandsInput(nil, map[string][]string{
+		listCommandsSince:      {"yesterday"},
+		listCommandsMaxResults: {"0"},
+		outputFormatFlag:       {"xml"},
+		"bogus":                {},
+	})
+	assert.Len(t, validation, 4)
+}
+
+func TestExportCommand(t *testing.T) {
+	store, root := newTestStore(t)
+	defer os.RemoveAll(root)
+
+	// the output of the same plugin on another instance of the data store is kept apart
+	otherDir := filepath.Join(store.dataStorePath, "i-456", appconfig.DefaultDocumentRootDirName, "orchestration", completedCommandID, "runShellScript")
+	assert.NoError(t, os.MkdirAll(otherDir, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(otherDir, "stdout"), []byte("other"), 0600))
+
+	file := filepath.Join(root, "command.tar.gz")
+	assert.NoError(t, ExportOfflineCommand{}.exportCommand(store, completedCommandID, file))
+
+	archive, err := os.Open(file)
+	assert.NoError(t, err)
+	defer archive.Close()
+	gzipReader, err := gzip.NewReader(archive)
+	assert.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	names := []string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{
+		completedCommandID + "/document.json",
+		completedCommandID + "/invocation.json",
+		completedCommandID + "/orchestration/i-123/runShellScript/stdout",
+		completedCommandID + "/orchestration/i-456/runShellScript/stdout",
+		completedCommandID + "/result.json",
+	}, names)
+
+	assert.Error(t, ExportOfflineCommand{}.exportCommand(store, pendingCommandID, filepath.Join(root, "pending.tar.gz")))
+	assert.False(t, fileutil.Exists(filepath.Join(root, "pending.tar.gz")))
+}
+
+func TestSubmitCancelRequest(t *testing.T) {
+	store, root := newTestStore(t)
+	defer os.RemoveAll(root)
+	cancelDir := filepath.Join(root, "cancel")
+
+	// completed commands cannot be cancelled
+	assert.Error(t, CancelOfflineCommand{}.submitCancelRequest(store, cancelDir, completedCommandID))
+	assert.Error(t, CancelOfflineCommand{}.submitCancelRequest(store, cancelDir, "21234567-890a-bcde-f012-34567890abcd"))
+
+	assert.NoError(t, CancelOfflineCommand{}.submitCancelRequest(store, cancelDir, pendingCommandID))
+	assert.True(t, fileutil.Exists(filepath.Join(cancelDir, pendingCommandID)))
+
+	// the service replies with the cancelled status once the command stops
+	content, _ := jsonutil.Marshal(messageContracts.SendReplyPayload{DocumentStatus: contracts.ResultStatusCancelled})
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(store.resultDir, pendingCommandID), []byte(content), 0600))
+	status, found := store.status(pendingCommandID)
+	assert.True(t, found)
+	assert.Equal(t, offlineCommandStatusCancelled, status)
+	assert.Error(t, CancelOfflineCommand{}.submitCancelRequest(store, cancelDir, pendingCommandID))
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -27,6 +28,8 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docparser"
+	"github.com/aws/amazon-ssm-agent/agent/docsignature"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
@@ -35,8 +38,11 @@ import (
 )
 
 const (
-	sendCommand        = "send-offline-command"
-	sendCommandContent = "content"
+	sendCommand             = "send-offline-command"
+	sendCommandContent      = "content"
+	sendCommandDocumentName = "document-name"
+	sendCommandParameters   = "parameters"
+	sendCommandSignature    = "signature"
 )
 
 const sendCommandHelp = `NAME:
@@ -45,13 +51,27 @@ const sendCommandHelp = `NAME:
 DESCRIPTION
 SYNOPSIS
     {{.SendCommandName}}
-    {{.ContentFlag}}
+    {{.ContentFlag}} | {{.DocumentNameFlag}}
+    [{{.ParametersFlag}} <value>]
+    [{{.SignatureFlag}} <value>]
 
 PARAMETERS
     {{.ContentFlag}} (string) JSON or URL to command document.
-    A valid command document is a configuration document with all parameters filled in.
+    A valid command document is a configuration document, its parameters are filled in from {{.ParametersFlag}}
+    or their default values.
     For information about writing a configuration document, see Configuration Document in the SSM API Reference.
 
+    {{.DocumentNameFlag}} (string) Name of a command document stored in {{.DocumentRoot}}.
+    The document is read from <name> or <name>.json in that directory.
+
+    {{.ParametersFlag}} (string) Values of the document parameters, as key=value pairs separated by commas,
+    as JSON, or as a file:// URL to a JSON file. Repeat a key to pass several values to a StringList parameter.
+    Values are validated against the allowed values and pattern of the parameter definitions.
+
+    {{.SignatureFlag}} (string) Path of the detached signature of the document, required when the agent enforces
+    document signatures. The document is signed over the output of {{.GetCanonicalDocumentName}}, the parameters
+    of a signed document take their default values so {{.SignatureFlag}} cannot be used with {{.ParametersFlag}}.
+
 EXAMPLES
     This example runs a command in a document in S3.
 
@@ -63,14 +83,30 @@ EXAMPLES
 
       Successfully submitted with command id 01234567-890a-bcde-f012-34567890abcd
 
+    This example runs the document {{.DocumentRoot}}/RunShellScript.json with two commands.
+
+    Command:
+
+      {{.SsmCliName}} {{.SendCommandName}} {{.DocumentNameFlag}} RunShellScript {{.ParametersFlag}} commands=date,commands=uptime
+
+    Output:
+
+      Successfully submitted with command id 01234567-890a-bcde-f012-34567890abcd
+
 OUTPUT
-    Success message with command id or failure message - failure usually happens because you are not admin or provided invalid JSON
+    Success message with command id or failure message - failure usually happens because you are not admin,
+    provided invalid JSON or parameter values which do not match the document
 `
 
 type sendCommandHelpParams struct {
-	SsmCliName      string
-	SendCommandName string
-	ContentFlag     string
+	SsmCliName               string
+	SendCommandName          string
+	ContentFlag              string
+	DocumentNameFlag         string
+	ParametersFlag           string
+	SignatureFlag            string
+	GetCanonicalDocumentName string
+	DocumentRoot             string
 }
 
 func init() {
@@ -89,13 +125,35 @@ func (c *SendOfflineCommand) Execute(subcommands []string, parameters map[string
 		return errors.New(strings.Join(validation, "\n")), ""
 	}
 
-	if err, content := c.loadContent(parameters[sendCommandContent][0]); err != nil {
+	var signature []byte
+	if values, exists := parameters[sendCommandSignature]; exists {
+		var err error
+		if signature, err = ioutil.ReadFile(strings.TrimPrefix(values[0], "file://")); err != nil {
+			return err, ""
+		}
+	}
+
+	err, rawContent := c.loadRawDocument(appconfig.LocalDocumentRoot, parameters)
+	if err != nil {
+		return err, ""
+	}
+	var content contracts.DocumentContent
+	if err := json.Unmarshal(rawContent, &content); err != nil {
 		return err, ""
 	} else if err := c.validateContent(content); err != nil {
 		return err, ""
-	} else if contentString, err := jsonutil.Marshal(content); err != nil {
+	} else if err := c.applyParameters(&content, parameters[sendCommandParameters], signature != nil); err != nil {
 		return err, ""
-	} else if err, documentName := c.submitCommandDocument(contentString); err != nil {
+	}
+
+	// the signature is verified over the document as it was written, a signed document is submitted unchanged
+	contentString := string(rawContent)
+	if signature == nil {
+		if contentString, err = jsonutil.Marshal(content); err != nil {
+			return err, ""
+		}
+	}
+	if err, documentName := c.submitCommandDocument(contentString, signature); err != nil {
 		return err, ""
 	} else {
 		return nil, c.waitForSubmitStatus(documentName)
@@ -106,7 +164,16 @@ func (c *SendOfflineCommand) Execute(subcommands []string, parameters map[string
 func (c *SendOfflineCommand) Help() string {
 	if len(c.helpText) == 0 {
 		t, _ := template.New("SendOfflineCommandHelp").Parse(sendCommandHelp)
-		params := sendCommandHelpParams{cliutil.SsmCliName, sendCommand, cliutil.FormatFlag(sendCommandContent)}
+		params := sendCommandHelpParams{
+			SsmCliName:               cliutil.SsmCliName,
+			SendCommandName:          sendCommand,
+			ContentFlag:              cliutil.FormatFlag(sendCommandContent),
+			DocumentNameFlag:         cliutil.FormatFlag(sendCommandDocumentName),
+			ParametersFlag:           cliutil.FormatFlag(sendCommandParameters),
+			SignatureFlag:            cliutil.FormatFlag(sendCommandSignature),
+			GetCanonicalDocumentName: getCanonicalDocument,
+			DocumentRoot:             appconfig.LocalDocumentRoot,
+		}
 		buf := new(bytes.Buffer)
 		t.Execute(buf, params)
 		c.helpText = buf.String()
@@ -128,8 +195,18 @@ func (SendOfflineCommand) validateSendCommandInput(subcommands []string, paramet
 	}
 
 	// look for required parameters
-	if _, exists := parameters[sendCommandContent]; !exists {
-		validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(sendCommandContent)))
+	_, contentExists := parameters[sendCommandContent]
+	_, documentNameExists := parameters[sendCommandDocumentName]
+	if contentExists && documentNameExists {
+		validation = append(validation, fmt.Sprintf("%v and %v cannot be used together", cliutil.FormatFlag(sendCommandContent), cliutil.FormatFlag(sendCommandDocumentName)))
+	} else if documentNameExists {
+		if len(parameters[sendCommandDocumentName]) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(sendCommandDocumentName)))
+		} else if name := parameters[sendCommandDocumentName][0]; name == "" || name != filepath.Base(name) || name == ".." {
+			validation = append(validation, fmt.Sprintf("%v value must be the name of a document, not a path", cliutil.FormatFlag(sendCommandDocumentName)))
+		}
+	} else if !contentExists {
+		validation = append(validation, fmt.Sprintf("%v or %v is required", cliutil.FormatFlag(sendCommandContent), cliutil.FormatFlag(sendCommandDocumentName)))
 	} else if len(parameters[sendCommandContent]) != 1 {
 		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(sendCommandContent)))
 	} else {
@@ -140,22 +217,134 @@ func (SendOfflineCommand) validateSendCommandInput(subcommands []string, paramet
 		}
 	}
 
+	if values, exists := parameters[sendCommandParameters]; exists && len(values) == 0 {
+		validation = append(validation, fmt.Sprintf("expected at least 1 value for parameter %v", cliutil.FormatFlag(sendCommandParameters)))
+	}
+	if values, exists := parameters[sendCommandSignature]; exists {
+		if len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(sendCommandSignature)))
+		} else if _, parametersExist := parameters[sendCommandParameters]; parametersExist {
+			validation = append(validation, fmt.Sprintf("%v and %v cannot be used together", cliutil.FormatFlag(sendCommandSignature), cliutil.FormatFlag(sendCommandParameters)))
+		}
+	}
+
 	// look for unsupported parameters
 	for key := range parameters {
-		if key != sendCommandContent {
+		if key != sendCommandContent && key != sendCommandDocumentName && key != sendCommandParameters && key != sendCommandSignature {
 			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
 		}
 	}
 	return validation
 }
 
-// loadContent loads raw json or json obtained from a URL into DocumentContent
-func (SendOfflineCommand) loadContent(rawContent string) (error, contracts.DocumentContent) {
+// loadDocument loads the document given as content or by name from the local document library into DocumentContent
+func (c *SendOfflineCommand) loadDocument(documentRoot string, parameters map[string][]string) (error, contracts.DocumentContent) {
 	var content contracts.DocumentContent
-	if cliutil.ValidJson(rawContent) {
-		err := json.Unmarshal([]byte(rawContent), &content)
+	err, rawContent := c.loadRawDocument(documentRoot, parameters)
+	if err != nil {
 		return err, content
 	}
+	err = json.Unmarshal(rawContent, &content)
+	return err, content
+}
+
+// loadRawDocument loads the json of the document given as content or by name from the local document library
+func (c *SendOfflineCommand) loadRawDocument(documentRoot string, parameters map[string][]string) (error, []byte) {
+	if names, exists := parameters[sendCommandDocumentName]; exists {
+		return c.loadLibraryDocument(documentRoot, names[0])
+	}
+	return c.loadContent(parameters[sendCommandContent][0])
+}
+
+// loadLibraryDocument loads the json of a document stored in the local document library
+func (SendOfflineCommand) loadLibraryDocument(documentRoot string, name string) (error, []byte) {
+	for _, fileName := range []string{name, name + ".json"} {
+		documentPath := filepath.Join(documentRoot, fileName)
+		if fileutil.IsFile(documentPath) {
+			rawContent, err := ioutil.ReadFile(documentPath)
+			return err, rawContent
+		}
+	}
+	return fmt.Errorf("document %v not found in %v", name, documentRoot), nil
+}
+
+// applyParameters validates the parameter values against the document and substitutes them in the document,
+// a signed document is left as is so that it still matches its signature
+func (SendOfflineCommand) applyParameters(content *contracts.DocumentContent, values []string, signed bool) error {
+	if signed {
+		return nil
+	}
+	params, err := parseOfflineParameters(values)
+	if err != nil {
+		return err
+	}
+	return docparser.ApplyParameters(params, content)
+}
+
+// parseOfflineParameters reads key=value pairs, JSON or a file:// URL to JSON into parameter values
+func parseOfflineParameters(values []string) (map[string][]*string, error) {
+	params := make(map[string][]*string)
+	for _, value := range values {
+		if strings.HasPrefix(strings.ToLower(value), "file://") || cliutil.ValidJson(value) {
+			jsonParams, err := loadJsonParameters(value)
+			if err != nil {
+				return nil, err
+			}
+			for name, paramValues := range jsonParams {
+				params[name] = append(params[name], paramValues...)
+			}
+			continue
+		}
+		for _, pair := range strings.Split(value, ",") {
+			if pair == "" {
+				continue
+			}
+			keyValue := strings.SplitN(pair, "=", 2)
+			if len(keyValue) != 2 || keyValue[0] == "" {
+				return nil, fmt.Errorf("%v value %v is not a key=value pair", cliutil.FormatFlag(sendCommandParameters), pair)
+			}
+			params[keyValue[0]] = append(params[keyValue[0]], &keyValue[1])
+		}
+	}
+	return params, nil
+}
+
+// loadJsonParameters reads parameters in the SendCommand format, where each value is a string or a list of strings
+func loadJsonParameters(value string) (map[string][]*string, error) {
+	var raw map[string]interface{}
+	if strings.HasPrefix(strings.ToLower(value), "file://") {
+		if err := jsonutil.UnmarshalFile(value[7:], &raw); err != nil {
+			return nil, err
+		}
+	} else if err := jsonutil.Unmarshal(value, &raw); err != nil {
+		return nil, err
+	}
+
+	params := make(map[string][]*string)
+	for name, rawValue := range raw {
+		switch rawValue := rawValue.(type) {
+		case string:
+			params[name] = []*string{&rawValue}
+		case []interface{}:
+			for _, item := range rawValue {
+				itemString, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("value of parameter %v must be a string or a list of strings", name)
+				}
+				params[name] = append(params[name], &itemString)
+			}
+		default:
+			return nil, fmt.Errorf("value of parameter %v must be a string or a list of strings", name)
+		}
+	}
+	return params, nil
+}
+
+// loadContent loads raw json or json obtained from a URL
+func (SendOfflineCommand) loadContent(rawContent string) (error, []byte) {
+	if cliutil.ValidJson(rawContent) {
+		return nil, []byte(rawContent)
+	}
 	var url = rawContent
 	// TODO:MF: Write a URI loader utility - artifact really doesn't do that job
 	if strings.HasPrefix(strings.ToLower(url), "file://") {
@@ -164,9 +353,9 @@ func (SendOfflineCommand) loadContent(rawContent string) (error, contracts.Docum
 
 	input := &artifact.DownloadInput{SourceURL: url}
 	if output, err := artifact.Download(log.NewMockLog(), *input); err != nil {
-		return err, content
+		return err, nil
 	} else {
-		err = jsonutil.UnmarshalFile(output.LocalFilePath, &content)
+		content, err := ioutil.ReadFile(output.LocalFilePath)
 		// TODO:MF: ideally we'd delete the file if we downloaded it - but it might've been a local file and we don't have a good way to tell
 		return err, content
 	}
@@ -174,7 +363,6 @@ func (SendOfflineCommand) loadContent(rawContent string) (error, contracts.Docum
 
 //validateContent checks to see that content has at least one runtimeConfig for 1.2 or mainSteps for 2.0 and no unbound parameters
 func (SendOfflineCommand) validateContent(content contracts.DocumentContent) error {
-	// TODO:MF: also check for unbound parameters
 	if content.SchemaVersion == "1.2" {
 		if len(content.RuntimeConfig) == 0 {
 			return fmt.Errorf("runtimeConfig cannot be empty")
@@ -189,14 +377,21 @@ func (SendOfflineCommand) validateContent(content contracts.DocumentContent) err
 	return nil
 }
 
-// submitCommandDocument
-func (SendOfflineCommand) submitCommandDocument(content string) (error, string) {
+// submitCommandDocument writes the document to the local command folder, its signature is written first
+// so that the agent finds it along with the document
+func (SendOfflineCommand) submitCommandDocument(content string, signature []byte) (error, string) {
 	documentName := uuid.NewV4().String()
 	documentPath := filepath.Join(appconfig.LocalCommandRoot, documentName)
 
 	if err := fileutil.MakeDirs(appconfig.LocalCommandRoot); err != nil {
 		return errors.New("failed to submit command"), ""
-	} else if err := fileutil.WriteAllText(documentPath, content); err != nil {
+	}
+	if signature != nil {
+		if err := ioutil.WriteFile(documentPath+docsignature.SignatureExtension, signature, appconfig.ReadWriteAccess); err != nil {
+			return err, ""
+		}
+	}
+	if err := fileutil.WriteAllText(documentPath, content); err != nil {
 		return err, ""
 	}
 	return nil, documentName
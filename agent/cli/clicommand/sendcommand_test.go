@@ -0,0 +1,89 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const libraryDocument = `{"schemaVersion":"2.2","parameters":{` +
+	`"commands":{"type":"StringList"},` +
+	`"workingDirectory":{"type":"String","default":"/tmp"}},` +
+	`"mainSteps":[{"action":"aws:runShellScript","name":"run","inputs":{"runCommand":"{{ commands }}","workingDirectory":"{{ workingDirectory }}"}}]}`
+
+func TestParseOfflineParameters(t *testing.T) {
+	root, err := ioutil.TempDir("", "sendcommand")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	paramsFile := filepath.Join(root, "params.json")
+	assert.NoError(t, ioutil.WriteFile(paramsFile, []byte(`{"commands":["ls","pwd"],"workingDirectory":"/var"}`), 0600))
+
+	params, err := parseOfflineParameters([]string{"commands=date,commands=uptime", "workingDirectory=/a=b"})
+	assert.NoError(t, err)
+	assert.Len(t, params["commands"], 2)
+	assert.Equal(t, "uptime", *params["commands"][1])
+	assert.Equal(t, "/a=b", *params["workingDirectory"][0])
+
+	params, err = parseOfflineParameters([]string{"file://" + paramsFile})
+	assert.NoError(t, err)
+	assert.Len(t, params["commands"], 2)
+	assert.Equal(t, "/var", *params["workingDirectory"][0])
+
+	params, err = parseOfflineParameters([]string{`{"commands":"date"}`})
+	assert.NoError(t, err)
+	assert.Equal(t, "date", *params["commands"][0])
+
+	_, err = parseOfflineParameters([]string{"commands"})
+	assert.Error(t, err)
+	_, err = parseOfflineParameters([]string{`{"commands":1}`})
+	assert.Error(t, err)
+}
+
+func TestSendLibraryDocumentWithParameters(t *testing.T) {
+	root, err := ioutil.TempDir("", "sendcommand")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "RunShellScript.json"), []byte(libraryDocument), 0600))
+
+	command := &SendOfflineCommand{}
+	err, content := command.loadDocument(root, map[string][]string{sendCommandDocumentName: {"RunShellScript"}})
+	assert.NoError(t, err)
+	assert.NoError(t, command.applyParameters(&content, []string{"commands=date,commands=uptime"}, false))
+	inputs := content.MainSteps[0].Inputs.(map[string]interface{})
+	assert.Equal(t, []string{"date", "uptime"}, inputs["runCommand"])
+	assert.Equal(t, "/tmp", inputs["workingDirectory"])
+
+	err, _ = command.loadDocument(root, map[string][]string{sendCommandDocumentName: {"Missing"}})
+	assert.Error(t, err)
+
+	// parameters must be defined by the document
+	err, content = command.loadDocument(root, map[string][]string{sendCommandDocumentName: {"RunShellScript"}})
+	assert.NoError(t, err)
+	assert.Error(t, command.applyParameters(&content, []string{"commands=date,bogus=1"}, false))
+}
+
+func TestValidateSendCommandInput(t *testing.T) {
+	command := SendOfflineCommand{}
+	assert.Empty(t, command.validateSendCommandInput(nil, map[string][]string{sendCommandDocumentName: {"RunShellScript"}, sendCommandParameters: {"commands=date"}}))
+	assert.NotEmpty(t, command.validateSendCommandInput(nil, map[string][]string{}))
+	assert.NotEmpty(t, command.validateSendCommandInput(nil, map[string][]string{sendCommandDocumentName: {"../RunShellScript"}}))
+	assert.NotEmpty(t, command.validateSendCommandInput(nil, map[string][]string{sendCommandDocumentName: {"RunShellScript"}, sendCommandContent: {"{}"}}))
+	assert.Empty(t, command.validateSendCommandInput(nil, map[string][]string{sendCommandDocumentName: {"RunShellScript"}, sendCommandSignature: {"RunShellScript.sig"}}))
+	assert.NotEmpty(t, command.validateSendCommandInput(nil, map[string][]string{sendCommandDocumentName: {"RunShellScript"}, sendCommandSignature: {"RunShellScript.sig"}, sendCommandParameters: {"commands=date"}}))
+}
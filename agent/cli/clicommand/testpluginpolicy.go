@@ -0,0 +1,252 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docparser"
+	"github.com/aws/amazon-ssm-agent/agent/pluginpolicy"
+)
+
+const (
+	testPluginPolicy           = "test-plugin-policy"
+	testPluginPolicyPolicyFile = "policy-file"
+
+	pluginPolicyAllowed = "Allowed"
+	pluginPolicyDenied  = "Denied"
+)
+
+const testPluginPolicyHelp = `NAME:
+    {{.TestPluginPolicyName}}
+
+DESCRIPTION
+SYNOPSIS
+    {{.TestPluginPolicyName}}
+    {{.ContentFlag}} | {{.DocumentNameFlag}}
+    [{{.PolicyFileFlag}} <value>]
+    [{{.OutputFlag}} <value>]
+
+PARAMETERS
+    {{.ContentFlag}} (string) JSON or URL to command document.
+
+    {{.DocumentNameFlag}} (string) Name of a command document stored in {{.DocumentRoot}}.
+
+    {{.PolicyFileFlag}} (string) Path of the plugin policy to evaluate, {{.PolicyPath}} by default.
+
+    {{.OutputFlag}} (string) Format of the result - table (default) or json.
+
+EXAMPLES
+    This example tests the document {{.DocumentRoot}}/RunShellScript.json against the plugin policy of the agent.
+
+    Command:
+
+      {{.SsmCliName}} {{.TestPluginPolicyName}} {{.DocumentNameFlag}} RunShellScript
+
+    Output:
+
+      Document hash:  0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+      STEP            PLUGIN              DECISION  REASON
+      runShellScript  aws:runShellScript  Denied    plugin aws:runShellScript is not allowed for the document with hash 0123...
+
+OUTPUT
+    The decision of the policy for each step of the document. The documents sent with {{.SendCommandName}} are named
+    by their local caller, the policy does not match their name but their hash, list their hash in the policy to
+    allow their shell scripts.
+`
+
+type testPluginPolicyHelpParams struct {
+	SsmCliName           string
+	TestPluginPolicyName string
+	SendCommandName      string
+	ContentFlag          string
+	DocumentNameFlag     string
+	PolicyFileFlag       string
+	OutputFlag           string
+	DocumentRoot         string
+	PolicyPath           string
+}
+
+// pluginPolicyDecision is the decision of the plugin policy for a step of the document
+type pluginPolicyDecision struct {
+	StepName   string `json:"stepName"`
+	PluginName string `json:"pluginName"`
+	Decision   string `json:"decision"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// pluginPolicyResult is the decision of the plugin policy for each step of the document
+type pluginPolicyResult struct {
+	DocumentName string                 `json:"documentName,omitempty"`
+	DocumentHash string                 `json:"documentHash"`
+	Steps        []pluginPolicyDecision `json:"steps"`
+}
+
+func init() {
+	cliutil.Register(&TestPluginPolicyCommand{})
+}
+
+type TestPluginPolicyCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the test-plugin-policy cli command
+func (c *TestPluginPolicyCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation, policyFile, format := c.validateTestPluginPolicyInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	err, content := (&SendOfflineCommand{}).loadDocument(appconfig.LocalDocumentRoot, parameters)
+	if err != nil {
+		return err, ""
+	}
+	policy, err := pluginpolicy.Load(policyFile)
+	if err != nil {
+		return err, ""
+	}
+	var documentName string
+	if names, exists := parameters[sendCommandDocumentName]; exists {
+		documentName = names[0]
+	}
+
+	result := c.testPluginPolicy(policy, &content, documentName)
+	if format == outputFormatJson {
+		return formatJson(result)
+	}
+	return nil, formatPluginPolicyTable(result)
+}
+
+// Help prints help for the test-plugin-policy cli command
+func (c *TestPluginPolicyCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("TestPluginPolicyHelp").Parse(testPluginPolicyHelp)
+		params := testPluginPolicyHelpParams{
+			SsmCliName:           cliutil.SsmCliName,
+			TestPluginPolicyName: testPluginPolicy,
+			SendCommandName:      sendCommand,
+			ContentFlag:          cliutil.FormatFlag(sendCommandContent),
+			DocumentNameFlag:     cliutil.FormatFlag(sendCommandDocumentName),
+			PolicyFileFlag:       cliutil.FormatFlag(testPluginPolicyPolicyFile),
+			OutputFlag:           cliutil.FormatFlag(outputFormatFlag),
+			DocumentRoot:         appconfig.LocalDocumentRoot,
+			PolicyPath:           appconfig.PluginPolicyPath,
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (TestPluginPolicyCommand) Name() string {
+	return testPluginPolicy
+}
+
+// validateTestPluginPolicyInput checks the subcommands and parameters for required values and unsupported values,
+// the document is given like for send-offline-command
+func (TestPluginPolicyCommand) validateTestPluginPolicyInput(subcommands []string, parameters map[string][]string) (validation []string, policyFile string, format string) {
+	validation = make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", testPluginPolicy, subcommands), "")
+		return validation, "", "" // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	documentParameters := make(map[string][]string)
+	for _, key := range []string{sendCommandContent, sendCommandDocumentName} {
+		if values, exists := parameters[key]; exists {
+			documentParameters[key] = values
+		}
+	}
+	validation = append(validation, SendOfflineCommand{}.validateSendCommandInput(nil, documentParameters)...)
+
+	policyFile = appconfig.PluginPolicyPath
+	if values, exists := parameters[testPluginPolicyPolicyFile]; exists {
+		if len(values) != 1 || values[0] == "" {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(testPluginPolicyPolicyFile)))
+		} else {
+			policyFile = values[0]
+		}
+	}
+	formatValidation, format := validateOutputFormat(parameters)
+	validation = append(validation, formatValidation...)
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != sendCommandContent && key != sendCommandDocumentName && key != testPluginPolicyPolicyFile && key != outputFormatFlag {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation, policyFile, format
+}
+
+// testPluginPolicy evaluates the policy for each step of the document, like the agent does before running the step
+// of a document sent with send-offline-command, which is only matched by hash
+func (TestPluginPolicyCommand) testPluginPolicy(policy *pluginpolicy.Policy, content *contracts.DocumentContent, documentName string) pluginPolicyResult {
+	result := pluginPolicyResult{
+		DocumentName: documentName,
+		DocumentHash: docparser.DocumentHash(content),
+		Steps:        []pluginPolicyDecision{},
+	}
+	evaluate := func(stepName string, pluginName string) {
+		decision := pluginPolicyDecision{StepName: stepName, PluginName: pluginName, Decision: pluginPolicyAllowed}
+		step := pluginpolicy.Step{PluginName: pluginName, DocumentHash: result.DocumentHash}
+		if err := policy.Evaluate(step); err != nil {
+			decision.Decision = pluginPolicyDenied
+			decision.Reason = err.Error()
+		}
+		result.Steps = append(result.Steps, decision)
+	}
+
+	for _, step := range content.MainSteps {
+		evaluate(step.Name, step.Action)
+	}
+	// the steps of the 1.x documents are named after their plugin
+	pluginNames := make([]string, 0, len(content.RuntimeConfig))
+	for pluginName := range content.RuntimeConfig {
+		pluginNames = append(pluginNames, pluginName)
+	}
+	sort.Strings(pluginNames)
+	for _, pluginName := range pluginNames {
+		evaluate(pluginName, pluginName)
+	}
+	return result
+}
+
+// formatPluginPolicyTable renders the hash of the document and one line per step
+func formatPluginPolicyTable(result pluginPolicyResult) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Document hash:\t%v\n", result.DocumentHash)
+	w.Flush()
+	w = tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tPLUGIN\tDECISION\tREASON")
+	for _, step := range result.Steps {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", step.StepName, step.PluginName, step.Decision, valueOrDash(step.Reason))
+	}
+	w.Flush()
+	return strings.TrimRight(buf.String(), "\n")
+}
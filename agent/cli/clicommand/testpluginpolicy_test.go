@@ -0,0 +1,73 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/pluginpolicy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTestPluginPolicyInput(t *testing.T) {
+	validation, policyFile, format := TestPluginPolicyCommand{}.validateTestPluginPolicyInput(nil, map[string][]string{
+		sendCommandDocumentName: {"RunShellScript"},
+	})
+	assert.Empty(t, validation)
+	assert.Equal(t, appconfig.PluginPolicyPath, policyFile)
+	assert.Equal(t, outputFormatTable, format)
+
+	validation, policyFile, _ = TestPluginPolicyCommand{}.validateTestPluginPolicyInput(nil, map[string][]string{
+		sendCommandDocumentName:    {"RunShellScript"},
+		testPluginPolicyPolicyFile: {"/tmp/plugin-policy.json"},
+	})
+	assert.Empty(t, validation)
+	assert.Equal(t, "/tmp/plugin-policy.json", policyFile)
+
+	validation, _, _ = TestPluginPolicyCommand{}.validateTestPluginPolicyInput(nil, map[string][]string{
+		testPluginPolicyPolicyFile: {},
+		"bogus":                    {},
+	})
+	assert.Len(t, validation, 3)
+}
+
+func TestTestPluginPolicy(t *testing.T) {
+	content := contracts.DocumentContent{
+		SchemaVersion: "2.2",
+		MainSteps: []*contracts.InstancePluginConfig{
+			{Action: appconfig.PluginNameAwsRunShellScript, Name: "run"},
+			{Action: appconfig.PluginNameAwsConfigurePackage, Name: "install"},
+		},
+	}
+	policy := &pluginpolicy.Policy{
+		RunShellScript: pluginpolicy.DocumentRestriction{AllowedDocumentNames: []string{"RunShellScript"}},
+	}
+
+	// the name of a local document is not matched
+	result := TestPluginPolicyCommand{}.testPluginPolicy(policy, &content, "RunShellScript")
+	assert.Equal(t, "RunShellScript", result.DocumentName)
+	assert.Equal(t, 64, len(result.DocumentHash))
+	assert.Equal(t, 2, len(result.Steps))
+	assert.Equal(t, pluginPolicyDenied, result.Steps[0].Decision)
+	assert.NotEmpty(t, result.Steps[0].Reason)
+	assert.Equal(t, pluginPolicyAllowed, result.Steps[1].Decision)
+
+	// the document is allowed by its hash
+	policy.RunShellScript.AllowedDocumentHashes = []string{result.DocumentHash}
+	result = TestPluginPolicyCommand{}.testPluginPolicy(policy, &content, "")
+	assert.Equal(t, pluginPolicyAllowed, result.Steps[0].Decision)
+	assert.Contains(t, formatPluginPolicyTable(result), "run      aws:runShellScript")
+}
@@ -0,0 +1,121 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/history"
+)
+
+const (
+	verifyExecutionHistory = "verify-execution-history"
+)
+
+const verifyExecutionHistoryHelp = `NAME:
+    {{.VerifyExecutionHistoryName}}
+
+DESCRIPTION
+SYNOPSIS
+    {{.VerifyExecutionHistoryName}}
+
+EXAMPLES
+    This example checks that the execution history in {{.ExecutionHistoryPath}}
+    was not modified since the agent recorded it.
+
+    Command:
+
+      {{.SsmCliName}} {{.VerifyExecutionHistoryName}}
+
+    Output:
+
+      The execution history is intact, 42 entries verified
+
+OUTPUT
+    Number of entries verified, or the first entry that was modified, removed or inserted,
+    checked against the head file kept next to the history
+`
+
+type verifyExecutionHistoryHelpParams struct {
+	SsmCliName                 string
+	VerifyExecutionHistoryName string
+	ExecutionHistoryPath       string
+}
+
+func init() {
+	cliutil.Register(&VerifyExecutionHistoryCommand{})
+}
+
+type VerifyExecutionHistoryCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the verify-execution-history cli command
+func (c *VerifyExecutionHistoryCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateVerifyExecutionHistoryInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	return c.verifyExecutionHistory(history.NewHistory(appconfig.ExecutionHistoryPath))
+}
+
+// Help prints help for the verify-execution-history cli command
+func (c *VerifyExecutionHistoryCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("VerifyExecutionHistoryHelp").Parse(verifyExecutionHistoryHelp)
+		params := verifyExecutionHistoryHelpParams{cliutil.SsmCliName, verifyExecutionHistory, appconfig.ExecutionHistoryPath}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (VerifyExecutionHistoryCommand) Name() string {
+	return verifyExecutionHistory
+}
+
+// validateVerifyExecutionHistoryInput checks the subcommands and parameters for unsupported values
+func (VerifyExecutionHistoryCommand) validateVerifyExecutionHistoryInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", verifyExecutionHistory, subcommands), "")
+		return validation // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+	}
+	return validation
+}
+
+// verifyExecutionHistory checks the chain of the history
+func (VerifyExecutionHistoryCommand) verifyExecutionHistory(h *history.History) (error, string) {
+	count, err := h.Verify()
+	if err != nil {
+		return fmt.Errorf("the execution history is not intact after %v entries: %v", count, err), ""
+	}
+	return nil, fmt.Sprintf("The execution history is intact, %v entries verified", count)
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/history"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateVerifyExecutionHistoryInput(t *testing.T) {
+	assert.Empty(t, VerifyExecutionHistoryCommand{}.validateVerifyExecutionHistoryInput(nil, map[string][]string{}))
+	assert.Len(t, VerifyExecutionHistoryCommand{}.validateVerifyExecutionHistoryInput([]string{"sub"}, nil), 2)
+	assert.Len(t, VerifyExecutionHistoryCommand{}.validateVerifyExecutionHistoryInput(nil, map[string][]string{"bogus": {}}), 1)
+}
+
+func TestVerifyExecutionHistory(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tamper   func(lines []string) []string
+		expected string
+	}{
+		{
+			name: "modified entry",
+			tamper: func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], "Success", "Failed", 1)
+				return lines
+			},
+			expected: "the execution history is not intact after 1 entries: entry 2 at line 2 was modified, its hash does not match its content",
+		},
+		{
+			name: "removed entry",
+			tamper: func(lines []string) []string {
+				return append(lines[:1], lines[2:]...)
+			},
+			expected: "the execution history is not intact after 1 entries: entry 3 at line 2 follows entry 1, entries were removed or inserted",
+		},
+		{
+			name: "truncated tail",
+			tamper: func(lines []string) []string {
+				return lines[:2]
+			},
+			expected: "the execution history is not intact after 2 entries: the history ends with entry 2 but its head identifies entry 3, entries were removed",
+		},
+	}
+
+	for _, testCase := range testCases {
+		dir, err := ioutil.TempDir("", "history")
+		assert.Nil(t, err)
+		path := filepath.Join(dir, "executions.log")
+		h := history.NewHistory(path)
+		for _, documentID := range []string{"first", "second", "third"} {
+			assert.Nil(t, h.Append(history.Entry{DocumentID: documentID, DocumentName: "AWS-RunShellScript", Status: "Success"}))
+		}
+
+		err, result := VerifyExecutionHistoryCommand{}.verifyExecutionHistory(h)
+		assert.Nil(t, err, testCase.name)
+		assert.Equal(t, "The execution history is intact, 3 entries verified", result, testCase.name)
+
+		content, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		lines := testCase.tamper(strings.Split(strings.TrimSpace(string(content)), "\n"))
+		assert.Nil(t, ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+
+		err, result = VerifyExecutionHistoryCommand{}.verifyExecutionHistory(h)
+		assert.Empty(t, result, testCase.name)
+		assert.NotNil(t, err, testCase.name)
+		if err != nil {
+			assert.Equal(t, testCase.expected, err.Error(), testCase.name)
+		}
+		os.RemoveAll(dir)
+	}
+}
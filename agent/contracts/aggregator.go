@@ -28,7 +28,7 @@ func prepareRuntimeStatus(log log.T, pluginResult PluginResult) PluginRuntimeSta
 	var resultAsString string
 
 	if err := pluginResult.Error; err == nil {
-		resultAsString = fmt.Sprintf("%v", pluginResult.Output)
+		resultAsString = outputAsString(pluginResult.Output)
 	} else {
 		resultAsString = err.Error()
 	}
@@ -58,6 +58,15 @@ func prepareRuntimeStatus(log log.T, pluginResult PluginResult) PluginRuntimeSta
 	return runtimeStatus
 }
 
+// outputAsString returns the output of a plugin as reported, structured outputs are reported as JSON objects,
+// including the ones read back from the results of the worker processes
+func outputAsString(output interface{}) string {
+	if structured, ok := output.(map[string]interface{}); ok {
+		return StructuredOutput(structured).String()
+	}
+	return fmt.Sprintf("%v", output)
+}
+
 // DocumentResultAggregator aggregates the result from the plugins to construct the agent response
 func DocumentResultAggregator(log log.T,
 	pluginID string,
@@ -51,9 +51,15 @@ func TestPrepareRuntimeStatus(t *testing.T) {
 		assert.Equal(t, tst.Output, runtimeStatus)
 	}
 
+	// structured outputs are reported as JSON objects, including the ones read back from the worker processes
+	runtimeStatus := prepareRuntimeStatus(logger, PluginResult{Output: StructuredOutput{"version": "1.2", "count": 3}})
+	assert.Equal(t, `{"count":3,"version":"1.2"}`, runtimeStatus.Output)
+	runtimeStatus = prepareRuntimeStatus(logger, PluginResult{Output: map[string]interface{}{"installed": true}})
+	assert.Equal(t, `{"installed":true}`, runtimeStatus.Output)
+
 	// test that there is a runtime status on error
 	pluginResult := PluginResult{Error: fmt.Errorf("Plugin failed with error code 1")}
-	runtimeStatus := prepareRuntimeStatus(logger, pluginResult)
+	runtimeStatus = prepareRuntimeStatus(logger, pluginResult)
 	assert.NotNil(t, runtimeStatus.Output)
 	return
 }
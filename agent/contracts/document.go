@@ -68,6 +68,10 @@ type DocumentInfo struct {
 	DocumentStatus  ResultStatus
 	RunCount        int
 	ProcInfo        OSProcInfo
+	// DocumentHash is the SHA-256 of the document content before the parameters are applied
+	DocumentHash string
+	// ParametersHash is the SHA-256 of the parameters the document is executed with
+	ParametersHash string
 }
 
 // IOConfiguration represents information relevant to the output sources of a command
@@ -75,6 +79,40 @@ type IOConfiguration struct {
 	OrchestrationDirectory string
 	OutputS3BucketName     string
 	OutputS3KeyPrefix      string
+	OutputS3Encryption     S3EncryptionConfiguration
+	CloudWatchConfig       CloudWatchConfiguration
+	OutputLimits           OutputLimits
+	// OutputSinks names the destinations the output of the plugins is written to,
+	// the ones configured for the agent are used when empty
+	OutputSinks []string
+}
+
+// S3EncryptionConfiguration represents the server-side encryption of the output of a command uploaded to S3,
+// the encryption configured for the agent applies when ServerSideEncryption is empty
+type S3EncryptionConfiguration struct {
+	// ServerSideEncryption is either AES256 for SSE-S3 or aws:kms for SSE-KMS
+	ServerSideEncryption string
+	// KmsKeyId is the KMS key used with SSE-KMS, the default key of the account is used when empty
+	KmsKeyId string
+}
+
+// CloudWatchConfiguration represents the CloudWatch Logs destination of the output of a command,
+// the output is not sent to CloudWatch Logs when LogGroupName is empty
+type CloudWatchConfiguration struct {
+	LogGroupName    string
+	LogStreamPrefix string
+}
+
+// OutputLimits represents how much of the output of the plugins of a document is kept in the command result,
+// the agent configuration applies to the limits not set
+type OutputLimits struct {
+	MaxStdoutLength int `json:"maxStdoutLength" yaml:"maxStdoutLength"`
+	MaxStderrLength int `json:"maxStderrLength" yaml:"maxStderrLength"`
+	// Truncation is either Head, keeping the beginning of an output longer than its limit,
+	// or HeadAndTail, keeping its beginning and its end
+	Truncation string `json:"truncation" yaml:"truncation"`
+	// TailLength is the number of bytes of the limit kept from the end of the output in HeadAndTail mode
+	TailLength int `json:"tailLength" yaml:"tailLength"`
 }
 
 // DocumentState represents information relevant to a command that gets executed by agent
@@ -109,6 +109,12 @@ const (
 	AssociationStatusFailed = "Failed"
 	// AssociationStatusTimedOut represents TimedOut status
 	AssociationStatusTimedOut = "TimedOut"
+	// AssociationStatusSkipped represents Skipped status
+	AssociationStatusSkipped = "Skipped"
+	// AssociationStatusWaiting represents Waiting status
+	AssociationStatusWaiting = "Waiting"
+	// AssociationStatusDeferred represents Deferred status
+	AssociationStatusDeferred = "Deferred"
 )
 
 const (
@@ -128,6 +134,12 @@ const (
 	AssociationErrorCodeSubmitAssociationError = "SubmitAssocError"
 	// AssociationErrorCodeStuckAtInProgressError represents association stuck in InProgress Error
 	AssociationErrorCodeStuckAtInProgressError = "StuckAtInProgress"
+	// AssociationErrorCodeDependencyNotMet represents association skipped as its dependencies did not succeed
+	AssociationErrorCodeDependencyNotMet = "DependencyNotMet"
+	// AssociationErrorCodeOutsideMaintenanceWindow represents association skipped as it was due outside the maintenance windows
+	AssociationErrorCodeOutsideMaintenanceWindow = "OutsideMaintenanceWindow"
+	// AssociationErrorCodeNonCompliant represents association checked in check-only mode which found the system out of compliance
+	AssociationErrorCodeNonCompliant = "NonCompliant"
 	// AssociationErrorCodeNoError represents no error
 	AssociationErrorCodeNoError = ""
 )
@@ -190,6 +202,8 @@ type DocumentContent struct {
 	RuntimeConfig map[string]*PluginConfig `json:"runtimeConfig" yaml:"runtimeConfig"`
 	MainSteps     []*InstancePluginConfig  `json:"mainSteps" yaml:"mainSteps"`
 	Parameters    map[string]*Parameter    `json:"parameters" yaml:"parameters"`
+	// OutputLimits overrides the output limits of the agent configuration for the document
+	OutputLimits *OutputLimits `json:"outputLimits,omitempty" yaml:"outputLimits,omitempty"`
 }
 
 // AdditionalInfo section in agent response
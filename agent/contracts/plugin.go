@@ -15,6 +15,8 @@
 package contracts
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -42,6 +44,18 @@ type PluginResult struct {
 	StandardError      string       `json:"standardError"`
 }
 
+// StructuredOutput is the output of a plugin made of keys and values, it is reported as a JSON object
+type StructuredOutput map[string]interface{}
+
+// String returns the output as a JSON object
+func (output StructuredOutput) String() string {
+	content, err := json.Marshal(map[string]interface{}(output))
+	if err != nil {
+		return fmt.Sprintf("%v", map[string]interface{}(output))
+	}
+	return string(content)
+}
+
 // IPlugin is interface for authoring a functionality of work.
 // Every functionality of work is implemented as a plugin.
 type IPlugin interface {
@@ -78,6 +92,11 @@ type Configuration struct {
 	Preconditions           map[string][]string
 	IsPreconditionEnabled   bool
 	CurrentAssociations     []string
+	// CheckOnly tells the plugin to check whether the system complies with the configuration without changing it
+	CheckOnly bool
+	// DocumentName and DocumentHash identify the document of the step for the plugin policy
+	DocumentName string
+	DocumentHash string
 }
 
 // Plugin wraps the plugin configuration and plugin result.
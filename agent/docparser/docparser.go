@@ -18,14 +18,21 @@ package docparser
 import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docsignature"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/parameters"
 	"github.com/aws/amazon-ssm-agent/agent/parameterstore"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
 )
 
@@ -41,6 +48,19 @@ type DocumentParserInfo struct {
 	MessageId         string
 	DocumentId        string
 	DefaultWorkingDir string
+	S3Encryption      contracts.S3EncryptionConfiguration
+	CloudWatchConfig  contracts.CloudWatchConfiguration
+	OutputSinks       []string
+	// DocumentName is the name the plugin policy matches the steps with, it is only set for the documents delivered
+	// by the service as the local callers choose the names of their documents
+	DocumentName string
+	// SignatureRequired tells whether the document must carry a signature made with one of the keys of TrustedKeysDir
+	SignatureRequired bool
+	// Signature is the detached signature of the document content, if any
+	Signature []byte
+	// RawContent is the JSON of the document content as received, the signature is verified over it
+	RawContent     []byte
+	TrustedKeysDir string
 }
 
 // InitializeDocState is a method to obtain the state of the document.
@@ -52,13 +72,26 @@ func InitializeDocState(log log.T,
 	parserInfo DocumentParserInfo,
 	params map[string]interface{}) (docState contracts.DocumentState, err error) {
 
+	if parserInfo.SignatureRequired {
+		if err = verifySignature(docContent, parserInfo, params); err != nil {
+			return docState, fmt.Errorf("document %v was rejected, %v", docInfo.DocumentName, err)
+		}
+	}
 	docState.SchemaVersion = docContent.SchemaVersion
 	docState.DocumentType = documentType
 	docState.DocumentInformation = docInfo
+	docState.DocumentInformation.DocumentHash = DocumentHash(docContent)
+	docState.DocumentInformation.ParametersHash = parametersHash(params)
 	docState.IOConfig = contracts.IOConfiguration{
 		OrchestrationDirectory: parserInfo.OrchestrationDir,
 		OutputS3BucketName:     parserInfo.S3Bucket,
 		OutputS3KeyPrefix:      parserInfo.S3Prefix,
+		OutputS3Encryption:     parserInfo.S3Encryption,
+		CloudWatchConfig:       parserInfo.CloudWatchConfig,
+		OutputSinks:            parserInfo.OutputSinks,
+	}
+	if docContent.OutputLimits != nil {
+		docState.IOConfig.OutputLimits = *docContent.OutputLimits
 	}
 
 	pluginInfo, err := ParseDocument(log, docContent, parserInfo, params)
@@ -69,6 +102,47 @@ func InitializeDocState(log log.T,
 	return docState, nil
 }
 
+// verifySignature checks the document content was signed with one of the trusted keys and runs without parameters
+func verifySignature(docContent *contracts.DocumentContent, parserInfo DocumentParserInfo, params map[string]interface{}) error {
+	if len(params) > 0 {
+		return docsignature.ErrParametersNotSigned
+	}
+	keys, err := docsignature.LoadTrustedKeys(parserInfo.TrustedKeysDir)
+	if err != nil {
+		return fmt.Errorf("unable to load the trusted keys: %v", err)
+	}
+	if err = docsignature.Verify(parserInfo.RawContent, parserInfo.Signature, keys); err != nil {
+		return err
+	}
+	// the document which runs must be the one which was signed
+	var signedContent contracts.DocumentContent
+	if err = json.Unmarshal(parserInfo.RawContent, &signedContent); err != nil || !reflect.DeepEqual(signedContent, *docContent) {
+		return errors.New("document content does not match the signed content")
+	}
+	return nil
+}
+
+// DocumentHash returns the SHA-256 of the document content, it identifies the document that was executed
+func DocumentHash(docContent *contracts.DocumentContent) string {
+	content, err := json.Marshal(docContent)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// parametersHash returns the SHA-256 of the parameters, the parameter names are sorted so that the hash
+// only depends on the names and values of the parameters
+func parametersHash(params map[string]interface{}) string {
+	content, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
 // ParseDocument is a method used to parse documents that are not received by any service (MDS or State manager)
 func ParseDocument(log log.T,
 	docContent *contracts.DocumentContent,
@@ -78,11 +152,20 @@ func ParseDocument(log log.T,
 	if err = validateSchema(docContent.SchemaVersion); err != nil {
 		return
 	}
+	// the hash is computed before the parameters are replaced, like the hash of the document state
+	docHash := DocumentHash(docContent)
 	if err = getValidatedParameters(log, params, docContent); err != nil {
 		return
 	}
 
-	return parseDocumentContent(*docContent, parserInfo)
+	if pluginsInfo, err = parseDocumentContent(*docContent, parserInfo); err != nil {
+		return
+	}
+	for i := range pluginsInfo {
+		pluginsInfo[i].Configuration.DocumentName = parserInfo.DocumentName
+		pluginsInfo[i].Configuration.DocumentHash = docHash
+	}
+	return
 }
 
 // ParseParameters is a method to parse the ssm parameters into a string map interface
@@ -92,18 +175,9 @@ func ParseParameters(log log.T, params map[string][]*string, paramsDef map[strin
 	for name, param := range params {
 
 		if definition, ok := paramsDef[name]; ok {
-			switch definition.ParamType {
-			case contracts.ParamTypeString:
-				result[name] = *(param[0])
-			case contracts.ParamTypeStringList:
-				newParam := []string{}
-				for _, value := range param {
-					newParam = append(newParam, *value)
-				}
-				result[name] = newParam
-			case contracts.ParamTypeStringMap:
-				result[name] = *(param[0])
-			default:
+			if value, ok := parseParameter(definition, param); ok {
+				result[name] = value
+			} else {
 				log.Debug("unknown parameter type ", definition.ParamType)
 			}
 		}
@@ -112,6 +186,120 @@ func ParseParameters(log log.T, params map[string][]*string, paramsDef map[strin
 	return result
 }
 
+// parseParameter converts the values of a parameter to the type of its definition, it returns false when the type is unknown
+func parseParameter(definition *contracts.Parameter, param []*string) (interface{}, bool) {
+	switch definition.ParamType {
+	case contracts.ParamTypeString, contracts.ParamTypeStringMap:
+		return *(param[0]), true
+	case contracts.ParamTypeStringList:
+		newParam := []string{}
+		for _, value := range param {
+			newParam = append(newParam, *value)
+		}
+		return newParam, true
+	}
+	return nil, false
+}
+
+// ApplyParameters validates the parameter values against the document definitions and replaces them in the document content.
+// References to SSM parameters are kept and resolved when the document runs. The parameter definitions are removed
+// from the document once all of them have been substituted.
+func ApplyParameters(values map[string][]*string, docContent *contracts.DocumentContent) (err error) {
+	params := make(map[string]interface{})
+	for name, value := range values {
+		definition, ok := docContent.Parameters[name]
+		if !ok {
+			return fmt.Errorf("parameter %v is not defined in the document", name)
+		}
+		if !parameters.ValidName(name) {
+			return fmt.Errorf("invalid parameter name %v", name)
+		}
+		if len(value) == 0 {
+			return fmt.Errorf("parameter %v has no value", name)
+		}
+		if params[name], ok = parseParameter(definition, value); !ok {
+			return fmt.Errorf("parameter %v has the unknown type %v", name, definition.ParamType)
+		}
+	}
+
+	for name, definition := range docContent.Parameters {
+		value, ok := params[name]
+		if !ok {
+			if definition.DefaultVal == nil {
+				return fmt.Errorf("parameter %v is required", name)
+			}
+			params[name] = definition.DefaultVal
+			continue
+		}
+		if err := validateParameterValue(name, definition, value); err != nil {
+			return err
+		}
+	}
+
+	for _, pluginConfig := range docContent.RuntimeConfig {
+		if pluginConfig.Settings, err = parameters.Replace(pluginConfig.Settings, params); err != nil {
+			return err
+		}
+		if pluginConfig.Properties, err = parameters.Replace(pluginConfig.Properties, params); err != nil {
+			return err
+		}
+	}
+	for _, instancePluginConfig := range docContent.MainSteps {
+		if instancePluginConfig.Settings, err = parameters.Replace(instancePluginConfig.Settings, params); err != nil {
+			return err
+		}
+		if instancePluginConfig.Inputs, err = parameters.Replace(instancePluginConfig.Inputs, params); err != nil {
+			return err
+		}
+	}
+	docContent.Parameters = nil
+	return nil
+}
+
+// validateParameterValue checks a parameter value against the allowed values and pattern of its definition
+func validateParameterValue(name string, definition *contracts.Parameter, value interface{}) error {
+	var values []string
+	switch value := value.(type) {
+	case string:
+		values = []string{value}
+	case []string:
+		values = value
+	default:
+		return nil
+	}
+
+	var pattern *regexp.Regexp
+	if definition.AllowedPattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(definition.AllowedPattern); err != nil {
+			return fmt.Errorf("allowed pattern %v of parameter %v is invalid: %v", definition.AllowedPattern, name, err)
+		}
+	}
+	for _, v := range values {
+		if strings.Contains(v, "{{ssm:") {
+			// validated once the reference is resolved
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(v) {
+			return fmt.Errorf("Parameter value for %v does not match the allowed pattern %v", name, definition.AllowedPattern)
+		}
+		if len(definition.AllowedVal) > 0 && !stringInSlice(v, definition.AllowedVal) {
+			return fmt.Errorf("Parameter value for %v is not one of the allowed values %v", name, definition.AllowedVal)
+		}
+	}
+	return nil
+}
+
+// stringInSlice returns true if value is one of the items
+func stringInSlice(value string, items []string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
 // parseDocumentContent parses an SSM Document and returns the plugin information
 func parseDocumentContent(docContent contracts.DocumentContent, parserInfo DocumentParserInfo) (pluginsInfo []contracts.PluginState, err error) {
 
@@ -15,13 +15,19 @@
 package docparser
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docsignature"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/stretchr/testify/assert"
@@ -144,6 +150,66 @@ func TestInitializeDocState_Valid(t *testing.T) {
 	assert.Equal(t, testMessageID, pluginInfo[0].Configuration.MessageId)
 	assert.Equal(t, testDocumentID, pluginInfo[0].Configuration.BookKeepingFileName)
 	assert.Equal(t, testWorkingDir, pluginInfo[0].Configuration.DefaultWorkingDirectory)
+	assert.Equal(t, 64, len(docState.DocumentInformation.DocumentHash))
+	assert.Equal(t, DocumentHash(&testDocContent), docState.DocumentInformation.DocumentHash)
+	assert.Equal(t, parametersHash(nil), docState.DocumentInformation.ParametersHash)
+}
+
+func TestInitializeDocState_SignatureRequired(t *testing.T) {
+	mockLog := log.NewMockLog()
+	testParserInfo := DocumentParserInfo{
+		OrchestrationDir:  testOrchDir,
+		SignatureRequired: true,
+		TrustedKeysDir:    filepath.Join(testOrchDir, "missing"),
+	}
+
+	var testDocContent contracts.DocumentContent
+	err := json.Unmarshal(loadFile(t, "../runcommand/mds/testdata/validcommand12.json"), &testDocContent)
+	assert.Nil(t, err)
+
+	_, err = InitializeDocState(mockLog, contracts.SendCommandOffline, &testDocContent, contracts.DocumentInfo{DocumentName: "unsigned"}, testParserInfo, nil)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "document unsigned was rejected")
+
+	// parameters are rejected before the signature is checked, they are not covered by it
+	testParserInfo.Signature = []byte("signature")
+	_, err = InitializeDocState(mockLog, contracts.SendCommandOffline, &testDocContent, contracts.DocumentInfo{DocumentName: "signed"}, testParserInfo, map[string]interface{}{"commands": "id"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), docsignature.ErrParametersNotSigned.Error())
+
+	// the signature is verified over the raw content, which must be the document that runs
+	keysDir, err := ioutil.TempDir("", "trustedkeys")
+	assert.Nil(t, err)
+	defer os.RemoveAll(keysDir)
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(keysDir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	testParserInfo.TrustedKeysDir = keysDir
+	testParserInfo.RawContent = loadFile(t, "../runcommand/mds/testdata/validcommand12.json")
+	canonical, err := docsignature.Canonicalize(testParserInfo.RawContent)
+	assert.Nil(t, err)
+	testParserInfo.Signature = ed25519.Sign(privateKey, canonical)
+
+	_, err = InitializeDocState(mockLog, contracts.SendCommandOffline, &testDocContent, contracts.DocumentInfo{DocumentName: "signed"}, testParserInfo, nil)
+	assert.Nil(t, err)
+
+	testDocContent.Description = "changed after signing"
+	_, err = InitializeDocState(mockLog, contracts.SendCommandOffline, &testDocContent, contracts.DocumentInfo{DocumentName: "signed"}, testParserInfo, nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "does not match the signed content")
+}
+
+func TestParametersHash(t *testing.T) {
+	params := map[string]interface{}{"commands": []interface{}{"date"}, "workingDirectory": "/tmp"}
+	sameParams := map[string]interface{}{"workingDirectory": "/tmp", "commands": []interface{}{"date"}}
+	otherParams := map[string]interface{}{"commands": []interface{}{"uptime"}, "workingDirectory": "/tmp"}
+
+	assert.Equal(t, 64, len(parametersHash(params)))
+	assert.Equal(t, parametersHash(params), parametersHash(sameParams))
+	assert.NotEqual(t, parametersHash(params), parametersHash(otherParams))
 }
 
 func TestParseDocument_EmptyDocContent(t *testing.T) {
@@ -379,6 +445,47 @@ func TestParsingDocNameForVersion_InvalidVersion(t *testing.T) {
 	assert.Equal(t, docName, "AWS-RunShellScript")
 }
 
+func TestApplyParameters(t *testing.T) {
+	document := `{"schemaVersion":"2.2","parameters":{` +
+		`"commands":{"type":"StringList"},` +
+		`"level":{"type":"String","default":"info","allowedValues":["info","debug"]},` +
+		`"target":{"type":"String","allowedPattern":"^[a-z]+$"}},` +
+		`"mainSteps":[{"action":"aws:runShellScript","name":"run","inputs":{"runCommand":"{{ commands }}","workingDirectory":"/{{ target }}/{{ level }}"}}]}`
+	load := func() *contracts.DocumentContent {
+		var content contracts.DocumentContent
+		assert.NoError(t, json.Unmarshal([]byte(document), &content))
+		return &content
+	}
+	value := func(s string) *string { return &s }
+
+	content := load()
+	assert.NoError(t, ApplyParameters(map[string][]*string{
+		"commands": {value("date"), value("uptime")},
+		"target":   {value("tmp")},
+	}, content))
+	assert.Nil(t, content.Parameters)
+	inputs := content.MainSteps[0].Inputs.(map[string]interface{})
+	assert.Equal(t, []string{"date", "uptime"}, inputs["runCommand"])
+	assert.Equal(t, "/tmp/info", inputs["workingDirectory"])
+
+	// undefined, missing and invalid values are rejected
+	assert.Error(t, ApplyParameters(map[string][]*string{"commands": {value("date")}, "target": {value("tmp")}, "bogus": {value("x")}}, load()))
+	assert.Error(t, ApplyParameters(map[string][]*string{"commands": {value("date")}}, load()))
+	assert.Error(t, ApplyParameters(map[string][]*string{"commands": {value("date")}, "target": {value("TMP")}}, load()))
+	assert.Error(t, ApplyParameters(map[string][]*string{"commands": {value("date")}, "target": {value("tmp")}, "level": {value("trace")}}, load()))
+	assert.Error(t, ApplyParameters(map[string][]*string{"commands": {value("date")}, "target": {}}, load()))
+	// references to SSM parameters are resolved when the document runs
+	assert.NoError(t, ApplyParameters(map[string][]*string{"commands": {value("date")}, "target": {value("{{ssm:target}}")}}, load()))
+
+	// parameters with an invalid name or an unknown type are rejected
+	content = load()
+	content.Parameters["bad-name"] = &contracts.Parameter{ParamType: contracts.ParamTypeString, DefaultVal: "x"}
+	assert.Error(t, ApplyParameters(map[string][]*string{"commands": {value("date")}, "target": {value("tmp")}, "bad-name": {value("y")}}, content))
+	content = load()
+	content.Parameters["count"] = &contracts.Parameter{ParamType: "Integer", DefaultVal: "1"}
+	assert.Error(t, ApplyParameters(map[string][]*string{"commands": {value("date")}, "target": {value("tmp")}, "count": {value("2")}}, content))
+}
+
 func loadFile(t *testing.T, fileName string) (result []byte) {
 	result, err := ioutil.ReadFile(fileName)
 	if err != nil {
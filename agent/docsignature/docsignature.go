@@ -0,0 +1,182 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package docsignature verifies the detached signatures of the documents run by the agent.
+//
+// A document is signed over the canonical form of the JSON of its content as submitted: the compact encoding of the
+// same values, with the keys of the objects sorted at every depth, the numbers written as submitted and without
+// HTML escaping. The get-canonical-document command of ssm-cli prints it. As it does not depend on how the agent
+// decodes the content, new document fields do not change the canonical form of existing documents. The trusted keys are the PEM encoded PKIX public keys
+// of the .pem files of the trusted keys folder. RSA keys verify PKCS #1 v1.5 signatures, ECDSA keys verify ASN.1
+// signatures, both of the SHA-256 of the canonical form, and Ed25519 keys verify signatures of the canonical form
+// itself, so that the signatures made with openssl dgst -sha256 -sign or openssl pkeyutl -sign are accepted.
+package docsignature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+const (
+	// SignatureExtension is the extension of the detached signature files, appended to the name of the signed file
+	SignatureExtension = ".sig"
+
+	trustedKeyExtension = ".pem"
+)
+
+// ErrNotSigned is returned when a document which must be signed has no signature
+var ErrNotSigned = errors.New("document is not signed")
+
+// ErrParametersNotSigned is returned when parameters are passed to a signed document, the signature does not cover
+// them so that they could change what a signed document runs
+var ErrParametersNotSigned = errors.New("parameters cannot be passed to a signed document, its signature only covers the document content")
+
+// Required returns true if the agent requires the documents to be signed, local tells whether the document
+// was submitted on the instance, as an offline command or a local association
+func Required(config appconfig.AgentInfo, local bool) bool {
+	switch config.DocumentSignatureEnforcement {
+	case appconfig.DocumentSignatureEnforcementAll:
+		return true
+	case appconfig.DocumentSignatureEnforcementLocal:
+		return local
+	}
+	return false
+}
+
+// Canonicalize returns the canonical form of the JSON of a document content, the content signatures are verified over
+func Canonicalize(content []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid document content, %v", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid document content, unexpected data after the document")
+	}
+	if _, isObject := value.(map[string]interface{}); !isObject {
+		return nil, errors.New("invalid document content, the document must be a JSON object")
+	}
+
+	// the keys of the decoded objects are sorted by the encoder
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// LoadTrustedKeys reads the public keys of the .pem files of the folder
+func LoadTrustedKeys(dir string) (keys []crypto.PublicKey, err error) {
+	if !fileutil.Exists(dir) {
+		return nil, fmt.Errorf("trusted keys folder %v does not exist", dir)
+	}
+	fileNames, err := fileutil.GetFileNames(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, fileName := range fileNames {
+		if !strings.HasSuffix(strings.ToLower(fileName), trustedKeyExtension) {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+		fileKeys, err := parsePublicKeys(content)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", fileName, err)
+		}
+		keys = append(keys, fileKeys...)
+	}
+	return keys, nil
+}
+
+// parsePublicKeys returns the public keys of the PEM blocks of the content
+func parsePublicKeys(content []byte) (keys []crypto.PublicKey, err error) {
+	for {
+		var block *pem.Block
+		if block, content = pem.Decode(content); block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+			keys = append(keys, key)
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no public key found")
+	}
+	return keys, nil
+}
+
+// Verify returns nil if the signature of the JSON of the document content was made with one of the keys
+func Verify(content []byte, signature []byte, keys []crypto.PublicKey) error {
+	if len(signature) == 0 {
+		return ErrNotSigned
+	}
+	if len(keys) == 0 {
+		return errors.New("no trusted key is configured")
+	}
+	canonical, err := Canonicalize(content)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(canonical)
+	for _, key := range keys {
+		if verifySignature(key, canonical, digest[:], signature) {
+			return nil
+		}
+	}
+	return errors.New("document signature does not match any trusted key")
+}
+
+// verifySignature returns true if the signature of the message was made with the key
+func verifySignature(key crypto.PublicKey, message []byte, digest []byte, signature []byte) bool {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest, signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, message, signature)
+	}
+	return false
+}
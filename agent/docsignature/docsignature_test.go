@@ -0,0 +1,131 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docsignature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+var testDocContent = []byte(`{
+    "schemaVersion": "2.2",
+    "mainSteps": [
+        {"name": "run", "action": "aws:runShellScript", "inputs": {"runCommand": ["date"]}}
+    ]
+}`)
+
+// writePublicKey writes the PEM encoded public key to a file of the folder
+func writePublicKey(t *testing.T, dir string, name string, key crypto.PublicKey) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.Nil(t, err)
+	content := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), content, 0600))
+}
+
+func TestRequired(t *testing.T) {
+	assert.False(t, Required(appconfig.AgentInfo{}, true))
+	assert.True(t, Required(appconfig.AgentInfo{DocumentSignatureEnforcement: appconfig.DocumentSignatureEnforcementLocal}, true))
+	assert.False(t, Required(appconfig.AgentInfo{DocumentSignatureEnforcement: appconfig.DocumentSignatureEnforcementLocal}, false))
+	assert.True(t, Required(appconfig.AgentInfo{DocumentSignatureEnforcement: appconfig.DocumentSignatureEnforcementAll}, false))
+}
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trustedkeys")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	canonical, err := Canonicalize(testDocContent)
+	assert.Nil(t, err)
+	digest := sha256.Sum256(canonical)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	rsaSignature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	assert.Nil(t, err)
+	writePublicKey(t, dir, "rsa.pem", &rsaKey.PublicKey)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	ecdsaSignature, err := ecdsa.SignASN1(rand.Reader, ecdsaKey, digest[:])
+	assert.Nil(t, err)
+	writePublicKey(t, dir, "ecdsa.PEM", &ecdsaKey.PublicKey)
+
+	ed25519PublicKey, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	ed25519Signature := ed25519.Sign(ed25519Key, canonical)
+	writePublicKey(t, dir, "ed25519.pem", ed25519PublicKey)
+
+	// files which are not PEM files are ignored
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("trusted keys"), 0600))
+
+	keys, err := LoadTrustedKeys(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(keys))
+
+	for _, signature := range [][]byte{rsaSignature, ecdsaSignature, ed25519Signature} {
+		assert.Nil(t, Verify(testDocContent, signature, keys))
+	}
+
+	// the signature does not depend on the layout of the content
+	assert.Nil(t, Verify(canonical, rsaSignature, keys))
+	modified := []byte(strings.Replace(string(testDocContent), "2.2", "2.0", 1))
+	assert.NotNil(t, Verify(modified, rsaSignature, keys))
+	assert.Equal(t, ErrNotSigned, Verify(testDocContent, nil, keys))
+	assert.NotNil(t, Verify(testDocContent, rsaSignature, nil))
+}
+
+func TestCanonicalize(t *testing.T) {
+	canonical, err := Canonicalize([]byte(`{
+		"schemaVersion": "2.2",
+		"description": "<script> & more",
+		"mainSteps": [{"name": "run", "action": "aws:runShellScript", "inputs": {"timeoutSeconds": 3600.0, "runCommand": ["date"]}}],
+		"futureField": null
+	}`))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"description":"<script> & more","futureField":null,"mainSteps":[{"action":"aws:runShellScript",`+
+		`"inputs":{"runCommand":["date"],"timeoutSeconds":3600.0},"name":"run"}],"schemaVersion":"2.2"}`, string(canonical))
+
+	for _, invalid := range []string{``, `not json`, `["schemaVersion"]`, `{"schemaVersion": "2.2"} {}`} {
+		_, err = Canonicalize([]byte(invalid))
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestLoadTrustedKeysErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trustedkeys")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = LoadTrustedKeys(filepath.Join(dir, "missing"))
+	assert.NotNil(t, err)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "invalid.pem"), []byte("not a key"), 0600))
+	_, err = LoadTrustedKeys(dir)
+	assert.NotNil(t, err)
+}
@@ -85,6 +85,37 @@ func WriteAllText(filePath string, text string) (err error) {
 	return
 }
 
+// WriteFileAtomic writes the content to a hidden temporary file next to the given file and renames it over the file.
+// The temporary file is flushed to disk before the rename and the directory after it, so that a crash never leaves
+// a partial file behind and readers only see complete files
+func WriteFileAtomic(filePath string, content []byte, perm os.FileMode) error {
+	tempPath := filepath.Join(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp")
+	if err := writeFileSync(tempPath, content, perm); err != nil {
+		fs.Remove(tempPath)
+		return err
+	}
+	if err := fs.Rename(tempPath, filePath); err != nil {
+		fs.Remove(tempPath)
+		return err
+	}
+	return syncDir(filepath.Dir(filePath))
+}
+
+// writeFileSync writes the content to the file and flushes it to disk before closing it
+func writeFileSync(filePath string, content []byte, perm os.FileMode) (err error) {
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(content); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
 // Exists returns true if the given file exists, false otherwise, ignoring any underlying error
 func Exists(filePath string) bool {
 	exist, _ := LocalFileExist(filePath)
@@ -15,6 +15,7 @@ package fileutil
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -209,6 +210,31 @@ func TestMoveFile(t *testing.T) {
 	fs = osFS{}
 }
 
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutil")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	assert.NoError(t, WriteFileAtomic(path, []byte("first"), 0600))
+	assert.NoError(t, WriteFileAtomic(path, []byte("second"), 0600))
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", string(content))
+	// the temporary file is renamed over the file
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(files))
+
+	// the file is left as is when the rename fails
+	fs = osFSStub{err: fmt.Errorf("someerror")}
+	assert.Error(t, WriteFileAtomic(path, []byte("third"), 0600))
+	fs = osFS{}
+	content, err = ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", string(content))
+}
+
 func TestUnderDir(t *testing.T) {
 	// Remove one or more directory levels
 	assert.True(t, isUnderDir(`~/foo/bar/../`, `~/foo`))
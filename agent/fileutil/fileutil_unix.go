@@ -102,3 +102,13 @@ func GetDiskSpaceInfo() (diskSpaceInfo DiskSpaceInfo, err error) {
 func HardenDataFolder() error {
 	return nil // do nothing
 }
+
+// syncDir flushes the directory to disk so that a rename within it survives a crash
+func syncDir(dirPath string) error {
+	dir, err := os.Open(dirPath)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -61,3 +61,9 @@ func GetDiskSpaceInfo() (diskSpaceInfo DiskSpaceInfo, err error) {
 func HardenDataFolder() error {
 	return Harden(appconfig.SSMDataPath)
 }
+
+// syncDir does nothing on Windows, where directories cannot be opened to be flushed,
+// NTFS journals the rename itself
+func syncDir(dirPath string) error {
+	return nil
+}
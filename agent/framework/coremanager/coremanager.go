@@ -62,8 +62,13 @@ func NewCoreManager(instanceIdPtr *string, regionPtr *string, log logger.T) (cm
 
 	var region string
 	if region, err = platform.Region(); err != nil {
-		log.Errorf("error fetching the region, %v", err)
-		return
+		if !config.Agent.OfflineMode {
+			log.Errorf("error fetching the region, %v", err)
+			return
+		}
+		// offline agents do not need a region
+		log.Debugf("no region in offline mode, %v", err)
+		err = nil
 	}
 	log.Debug("Using region:", region)
 
@@ -93,8 +98,11 @@ func NewCoreManager(instanceIdPtr *string, regionPtr *string, log logger.T) (cm
 		return
 	}
 
-	// Initialize the client diagnostics
-	cloudwatchPublisher := initializeClientDiagnostics(log)
+	// Initialize the client diagnostics, offline agents have no cloudwatch to publish to
+	var cloudwatchPublisher *cloudwatchlogspublisher.CloudWatchPublisher
+	if !config.Agent.OfflineMode {
+		cloudwatchPublisher = initializeClientDiagnostics(log)
+	}
 
 	context := context.Default(log, config).With("[instanceID=" + instanceId + "]")
 	coreModules := coremodules.RegisteredCoreModules(context)
@@ -39,8 +39,14 @@ func RegisteredCoreModules(context context.T) *ModuleRegistry {
 
 // register core modules here
 func loadCoreModules(context context.T) {
-	registeredCoreModules = append(registeredCoreModules, health.NewHealthCheck(context))
-	registeredCoreModules = append(registeredCoreModules, runcommand.NewMDSService(context))
+	// offline agents only run the modules which do not depend on an AWS service
+	offlineMode := context.AppConfig().Agent.OfflineMode
+	if offlineMode {
+		context.Log().Info("Agent is running in offline mode, modules depending on AWS services are not loaded")
+	} else {
+		registeredCoreModules = append(registeredCoreModules, health.NewHealthCheck(context))
+		registeredCoreModules = append(registeredCoreModules, runcommand.NewMDSService(context))
+	}
 
 	if offlineProcessor, err := runcommand.NewOfflineService(context); err == nil {
 		registeredCoreModules = append(registeredCoreModules, offlineProcessor)
@@ -48,7 +54,9 @@ func loadCoreModules(context context.T) {
 		context.Log().Errorf("Failed to start offline command document processor")
 	}
 
-	registeredCoreModules = append(registeredCoreModules, startup.NewProcessor(context))
+	if !offlineMode {
+		registeredCoreModules = append(registeredCoreModules, startup.NewProcessor(context))
+	}
 
 	// registering the long running plugin manager as a core module
 	manager.EnsureInitialization(context)
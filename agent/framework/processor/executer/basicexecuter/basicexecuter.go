@@ -23,6 +23,7 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
@@ -51,6 +52,8 @@ func run(context context.T,
 		}
 	}()
 	docState := docStore.Load()
+	// the secrets resolved by the plugins are no longer needed once the document is done
+	defer redactor.Release(docState.DocumentInformation.DocumentID)
 	//document information summary
 	messageID := docState.DocumentInformation.MessageID
 	associationID := docState.DocumentInformation.AssociationID
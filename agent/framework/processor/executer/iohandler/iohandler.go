@@ -19,11 +19,13 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 )
 
 const (
@@ -42,17 +44,108 @@ type PluginConfig struct {
 	MaxStdoutLength       int
 	MaxStderrLength       int
 	OutputTruncatedSuffix string
+	// OutputTruncation is either Head, keeping the beginning of an output longer than its limit,
+	// or HeadAndTail, keeping its beginning and its last OutputTailLength bytes
+	OutputTruncation string
+	OutputTailLength int
+	// OutputSinks names the destinations the output is written to besides the command result
+	OutputSinks        []string
+	OutputHttpEndpoint string
 }
 
-// DefaultOutputConfig returns the default values for the plugin
+// agentConfig returns the agent configuration the output limits are read from
+var agentConfig = func() (appconfig.SsmagentConfig, error) {
+	return appconfig.Config(false)
+}
+
+// DefaultOutputConfig returns the default values for the plugin, the output limits are read from the agent configuration
 func DefaultOutputConfig() PluginConfig {
-	return PluginConfig{
+	config := PluginConfig{
 		StdoutFileName:        "stdout",
 		StderrFileName:        "stderr",
-		MaxStdoutLength:       24000,
-		MaxStderrLength:       8000,
+		MaxStdoutLength:       appconfig.MaxStdoutLength,
+		MaxStderrLength:       appconfig.MaxStderrLength,
 		OutputTruncatedSuffix: "--output truncated--",
+		OutputTruncation:      appconfig.DefaultPluginOutputTruncation,
+		OutputSinks:           appconfig.DefaultOutputSinks(),
+	}
+	if ssmConfig, err := agentConfig(); err == nil {
+		config.MaxStdoutLength = ssmConfig.Ssm.PluginStdoutMaxLength
+		config.MaxStderrLength = ssmConfig.Ssm.PluginStderrMaxLength
+		config.OutputTruncation = ssmConfig.Ssm.PluginOutputTruncation
+		config.OutputTailLength = ssmConfig.Ssm.PluginOutputTailLength
+		config.OutputSinks = ssmConfig.Ssm.OutputSinks
+		config.OutputHttpEndpoint = ssmConfig.Ssm.OutputHttpEndpoint
+	}
+	return config
+}
+
+// DocumentOutputConfig returns the values for the plugins of a document, the output limits and sinks set by the
+// document override the ones of the agent configuration
+func DocumentOutputConfig(ioConfig contracts.IOConfiguration) PluginConfig {
+	config := DefaultOutputConfig()
+	limits := ioConfig.OutputLimits
+	if isValidOutputLength(limits.MaxStdoutLength) {
+		config.MaxStdoutLength = limits.MaxStdoutLength
 	}
+	if isValidOutputLength(limits.MaxStderrLength) {
+		config.MaxStderrLength = limits.MaxStderrLength
+	}
+	switch limits.Truncation {
+	case appconfig.PluginOutputTruncationHead, appconfig.PluginOutputTruncationHeadAndTail:
+		config.OutputTruncation = limits.Truncation
+	}
+	if limits.TailLength > 0 {
+		config.OutputTailLength = limits.TailLength
+	}
+	if len(ioConfig.OutputSinks) > 0 {
+		config.OutputSinks = ioConfig.OutputSinks
+	}
+	return config
+}
+
+// isValidOutputLength returns true if the output limit is within the range allowed by the agent configuration
+func isValidOutputLength(length int) bool {
+	return length >= appconfig.PluginOutputMaxLengthMin && length <= appconfig.PluginOutputMaxLengthMax
+}
+
+// keepsTail returns true if the end of an output longer than its limit is kept
+func (config PluginConfig) keepsTail() bool {
+	return config.OutputTruncation == appconfig.PluginOutputTruncationHeadAndTail
+}
+
+// tailLength returns the number of bytes kept from the end of an output longer than the limit
+func (config PluginConfig) tailLength(limit int) int {
+	if !config.keepsTail() {
+		return 0
+	}
+	if config.OutputTailLength <= 0 || config.OutputTailLength > limit {
+		return limit / 2
+	}
+	return config.OutputTailLength
+}
+
+// truncatedMarker returns the marker replacing the bytes dropped from an output longer than its limit
+func (config PluginConfig) truncatedMarker() string {
+	if config.keepsTail() {
+		return "\n" + config.OutputTruncatedSuffix + "\n"
+	}
+	return config.OutputTruncatedSuffix
+}
+
+// TruncateStdout cuts the standard output down to MaxStdoutLength
+func (config PluginConfig) TruncateStdout(stdout string) string {
+	return iomodule.Truncate(stdout, config.MaxStdoutLength, config.tailLength(config.MaxStdoutLength), config.truncatedMarker())
+}
+
+// TruncateStderr cuts the standard error down to MaxStderrLength
+func (config PluginConfig) TruncateStderr(stderr string) string {
+	return iomodule.Truncate(stderr, config.MaxStderrLength, config.tailLength(config.MaxStderrLength), config.truncatedMarker())
+}
+
+// TruncateOutput truncates the output, the end of the standard output and error is kept in HeadAndTail mode
+func (config PluginConfig) TruncateOutput(stdout string, stderr string, capacity int) string {
+	return truncateOutput(stdout, stderr, capacity, config.keepsTail())
 }
 
 // IOHandler Interface defines interface for IOHandler type
@@ -99,6 +192,11 @@ type DefaultIOHandler struct {
 	ioConfig contracts.IOConfiguration
 	//refreshassociation and invoker write a different output rather than merging stdout and stderr
 	output interface{}
+	// redactor scrubs the secrets of the document from the output written by the plugin
+	redactor *redactor.Redactor
+
+	// droppedOutput records the output sinks which did not receive the whole output, it is reported on Close
+	droppedOutput *droppedOutput
 
 	// List of Writers attached to the IOHandler instance
 	StdoutWriter multiwriter.DocumentIOMultiWriter
@@ -117,7 +215,7 @@ func NewDefaultIOHandler(log log.T, ioConfig contracts.IOConfiguration) *Default
 // Init initializes the plugin output object by creating the necessary writers
 func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 
-	pluginConfig := DefaultOutputConfig()
+	pluginConfig := DocumentOutputConfig(out.ioConfig)
 	// Create path to output location for file and s3
 	fullPath := out.ioConfig.OrchestrationDirectory
 	s3KeyPrefix := out.ioConfig.OutputS3KeyPrefix
@@ -126,43 +224,45 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 		s3KeyPrefix = fileutil.BuildS3Path(s3KeyPrefix, element)
 	}
 
-	// Initialize file output module
-	stdoutFile := iomodule.File{
-		FileName:               pluginConfig.StdoutFileName,
+	sinkConfig := OutputSinkConfig{
 		OrchestrationDirectory: fullPath,
-		OutputS3BucketName:     out.ioConfig.OutputS3BucketName,
 		OutputS3KeyPrefix:      s3KeyPrefix,
+		FilePath:               filePath,
+		IOConfig:               out.ioConfig,
+		PluginConfig:           pluginConfig,
+		Redactor:               out.redactor,
 	}
+	out.droppedOutput = &droppedOutput{}
 
 	// Initialize console output module
 	stdoutConsole := iomodule.CommandOutput{
-		OutputLimit:  pluginConfig.MaxStdoutLength,
-		OutputString: &out.stdout,
+		OutputLimit:     pluginConfig.MaxStdoutLength,
+		TailLength:      pluginConfig.tailLength(pluginConfig.MaxStdoutLength),
+		TruncatedMarker: pluginConfig.truncatedMarker(),
+		OutputString:    &out.stdout,
+		Redactor:        out.redactor,
 	}
 
-	log.Debug("Initializing the Stdout Multi-writer with file and console listeners")
+	log.Debug("Initializing the Stdout Multi-writer with console and output sink listeners")
 	// Get a multi-writer for standard output
 	out.StdoutWriter = multiwriter.NewDocumentIOMultiWriter()
-	out.RegisterOutputSource(log, out.StdoutWriter, stdoutFile, stdoutConsole)
-
-	// Initialize file error module
-	stderrFile := iomodule.File{
-		FileName:               pluginConfig.StderrFileName,
-		OrchestrationDirectory: fullPath,
-		OutputS3BucketName:     out.ioConfig.OutputS3BucketName,
-		OutputS3KeyPrefix:      s3KeyPrefix,
-	}
+	sinkConfig.OutputName = pluginConfig.StdoutFileName
+	out.RegisterOutputSource(log, out.StdoutWriter, append([]iomodule.IOModule{stdoutConsole}, createOutputSinks(log, sinkConfig, out.droppedOutput)...)...)
 
 	// Initialize console error module
 	stderrConsole := iomodule.CommandOutput{
-		OutputLimit:  pluginConfig.MaxStderrLength,
-		OutputString: &out.stderr,
+		OutputLimit:     pluginConfig.MaxStderrLength,
+		TailLength:      pluginConfig.tailLength(pluginConfig.MaxStderrLength),
+		TruncatedMarker: pluginConfig.truncatedMarker(),
+		OutputString:    &out.stderr,
+		Redactor:        out.redactor,
 	}
 
-	log.Debug("Initializing the Stderr Multi-writer with file and console listeners")
+	log.Debug("Initializing the Stderr Multi-writer with console and output sink listeners")
 	// Get a multi-writer for standard error
 	out.StderrWriter = multiwriter.NewDocumentIOMultiWriter()
-	out.RegisterOutputSource(log, out.StderrWriter, stderrFile, stderrConsole)
+	sinkConfig.OutputName = pluginConfig.StderrFileName
+	out.RegisterOutputSource(log, out.StderrWriter, append([]iomodule.IOModule{stderrConsole}, createOutputSinks(log, sinkConfig, out.droppedOutput)...)...)
 }
 
 // RegisterOutputSource returns a new output source by creating a multiwriter for the output modules.
@@ -197,11 +297,21 @@ func (out *DefaultIOHandler) Close(log log.T) {
 	if out.StderrWriter != nil {
 		out.StderrWriter.Close()
 	}
+
+	// the writers are closed, the output sinks which dropped part of the output are reported in the standard error
+	// of the plugin result
+	for _, message := range out.droppedOutput.messages() {
+		if len(out.stderr) > 0 {
+			out.stderr = fmt.Sprintf("%v\n%v", out.stderr, message)
+		} else {
+			out.stderr = message
+		}
+	}
 }
 
 // String returns the output by concatenating stdout and stderr
 func (out DefaultIOHandler) String() (response string) {
-	return TruncateOutput(out.stdout, out.stderr, MaximumPluginOutputSize)
+	return DocumentOutputConfig(out.ioConfig).TruncateOutput(out.stdout, out.stderr, MaximumPluginOutputSize)
 }
 
 // GetOutput returns the output to be appended to the response
@@ -273,6 +383,11 @@ func (out *DefaultIOHandler) SetOutput(output interface{}) {
 	out.output = output
 }
 
+// SetRedactor sets the redactor used to scrub secrets from the output, it must be called before Init
+func (out *DefaultIOHandler) SetRedactor(r *redactor.Redactor) {
+	out.redactor = r
+}
+
 // Merge plugin output objects
 func (out *DefaultIOHandler) Merge(log log.T, mergeOutput *DefaultIOHandler) {
 
@@ -392,9 +507,23 @@ func (out *DefaultIOHandler) AppendErrorf(format string, params ...interface{})
 
 // TruncateOutput truncates the output
 func TruncateOutput(stdout string, stderr string, capacity int) (response string) {
+	return truncateOutput(stdout, stderr, capacity, false)
+}
+
+// truncateOutput truncates the output, keeping the end of the standard output and error along with their
+// beginning when keepTail is true
+func truncateOutput(stdout string, stderr string, capacity int, keepTail bool) (response string) {
 	outputSize := len(stdout)
 	errorSize := len(stderr)
 
+	// cut keeps size bytes of the text followed, or interrupted when keeping the tail, by the marker
+	cut := func(text string, size int, marker string) string {
+		if keepTail {
+			return iomodule.Truncate(text, size+len(marker), size/2, marker+"\n")
+		}
+		return fmt.Sprint(text[:size], marker)
+	}
+
 	// prepare error title
 	errorTitle := ""
 	lenErrorTitle := 0
@@ -414,16 +543,16 @@ func TruncateOutput(stdout string, stderr string, capacity int) (response string
 	// truncate out and error when both exceed the size
 	if outputSize > availableSpace/2 && errorSize > availableSpace/2 {
 		truncateSize := availableSpace - len(truncateError) - len(truncateOut)
-		return fmt.Sprint(stdout[:truncateSize/2], truncateOut, errorTitle, stderr[:truncateSize/2], truncateError)
+		return fmt.Sprint(cut(stdout, truncateSize/2, truncateOut), errorTitle, cut(stderr, truncateSize/2, truncateError))
 	}
 
 	// truncate error when output is short
 	if outputSize < availableSpace/2 {
 		truncateSize := availableSpace - len(truncateError)
-		return fmt.Sprint(stdout, errorTitle, stderr[:truncateSize-outputSize], truncateError)
+		return fmt.Sprint(stdout, errorTitle, cut(stderr, truncateSize-outputSize, truncateError))
 	}
 
 	// truncate output when error is short
 	truncateSize := availableSpace - len(truncateOut)
-	return fmt.Sprint(stdout[:truncateSize-errorSize], truncateOut, errorTitle, stderr)
+	return fmt.Sprint(cut(stdout, truncateSize-errorSize, truncateOut), errorTitle, stderr)
 }
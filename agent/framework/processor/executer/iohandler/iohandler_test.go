@@ -15,14 +15,19 @@ package iohandler
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"sync"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule/mock"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -67,6 +72,96 @@ func TestTruncateOutput(t *testing.T) {
 	}
 }
 
+func TestTruncateOutputHeadAndTail(t *testing.T) {
+	config := PluginConfig{OutputTruncation: appconfig.PluginOutputTruncationHeadAndTail}
+
+	// the output is kept as is when it fits
+	assert.Equal(t, "sample output\n----------ERROR-------\nsample error",
+		config.TruncateOutput("sample output", "sample error", sampleSize))
+
+	actual := config.TruncateOutput(longMessage, "", sampleSize)
+	assert.Equal(t, "This is a sample text. This is a sampl\n---Output truncated---\ns a sample text. This is a sample text", actual)
+	assert.True(t, len(actual) <= sampleSize)
+
+	actual = config.TruncateOutput(longMessage, longMessage, sampleSize)
+	assert.Equal(t, "This is\n---Output truncated---\nle text\n----------ERROR-------\nThis is\n---Error truncated----\nle text", actual)
+	assert.True(t, len(actual) <= sampleSize)
+}
+
+func TestDocumentOutputConfig(t *testing.T) {
+	defer func() { agentConfig = func() (appconfig.SsmagentConfig, error) { return appconfig.Config(false) } }()
+	agentConfig = func() (appconfig.SsmagentConfig, error) {
+		config := appconfig.DefaultConfig()
+		config.Ssm.PluginStdoutMaxLength = 1000
+		config.Ssm.PluginOutputTruncation = appconfig.PluginOutputTruncationHeadAndTail
+		config.Ssm.PluginOutputTailLength = 300
+		return config, nil
+	}
+
+	config := DefaultOutputConfig()
+	assert.Equal(t, 1000, config.MaxStdoutLength)
+	assert.Equal(t, appconfig.MaxStderrLength, config.MaxStderrLength)
+	assert.Equal(t, 300, config.tailLength(config.MaxStdoutLength))
+	assert.Equal(t, 300, config.tailLength(config.MaxStderrLength))
+
+	// the document overrides the agent configuration
+	config = DocumentOutputConfig(contracts.IOConfiguration{
+		OutputLimits: contracts.OutputLimits{MaxStdoutLength: 50000, MaxStderrLength: 200, Truncation: appconfig.PluginOutputTruncationHead},
+	})
+	assert.Equal(t, 50000, config.MaxStdoutLength)
+	assert.Equal(t, 200, config.MaxStderrLength)
+	assert.Equal(t, 0, config.tailLength(config.MaxStdoutLength))
+	assert.Equal(t, "--output truncated--", config.truncatedMarker())
+
+	// the limits out of range and the unknown truncation modes are ignored
+	config = DocumentOutputConfig(contracts.IOConfiguration{
+		OutputLimits: contracts.OutputLimits{MaxStdoutLength: 10, MaxStderrLength: 10 * 1024 * 1024, Truncation: "Tail"},
+	})
+	assert.Equal(t, 1000, config.MaxStdoutLength)
+	assert.Equal(t, appconfig.MaxStderrLength, config.MaxStderrLength)
+	assert.Equal(t, appconfig.PluginOutputTruncationHeadAndTail, config.OutputTruncation)
+	// the tail takes half of the limit when the configured tail does not fit
+	assert.Equal(t, 500, DocumentOutputConfig(contracts.IOConfiguration{
+		OutputLimits: contracts.OutputLimits{TailLength: 5000},
+	}).tailLength(1000))
+}
+
+func TestHeadAndTailOutput(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "iohandler")
+	assert.Nil(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	logger := log.NewMockLog()
+	output := NewDefaultIOHandler(logger, contracts.IOConfiguration{
+		OrchestrationDirectory: orchestrationDir,
+		OutputLimits: contracts.OutputLimits{
+			MaxStdoutLength: 200,
+			MaxStderrLength: 100,
+			Truncation:      appconfig.PluginOutputTruncationHeadAndTail,
+			TailLength:      60,
+		},
+	})
+	output.Init(logger, "plugin")
+	output.AppendInfo(longMessage)
+	output.AppendError("sample error")
+	output.Close(logger)
+
+	stdout := output.GetStdout()
+	assert.Equal(t, 200, len(stdout))
+	assert.Equal(t, longMessage[:118]+"\n--output truncated--\n"+longMessage[len(longMessage)-60:], stdout)
+	assert.Equal(t, "sample error", output.GetStderr())
+
+	// the result keeps the output as captured
+	config := DocumentOutputConfig(output.GetIOConfig())
+	assert.Equal(t, stdout, config.TruncateStdout(stdout))
+	assert.Equal(t, stdout, config.TruncateStdout(longMessage))
+
+	// the output file keeps the whole output
+	content, err := ioutil.ReadFile(filepath.Join(orchestrationDir, "plugin", "stdout"))
+	assert.Nil(t, err)
+	assert.Equal(t, longMessage, string(content))
+}
+
 var logger = log.NewMockLog()
 
 func TestRegisterOutputSource(t *testing.T) {
@@ -167,3 +262,27 @@ func TestAppendFormat(t *testing.T) {
 	assert.Contains(t, output.GetStdout(), testStringFormatted)
 	assert.Contains(t, output.GetStderr(), testStringFormatted)
 }
+
+func TestRedactedOutput(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "iohandler")
+	assert.Nil(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	docRedactor := redactor.New()
+	docRedactor.Register("s3cr3t")
+
+	logger := log.NewMockLog()
+	output := NewDefaultIOHandler(logger, contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir})
+	output.SetRedactor(docRedactor)
+	output.Init(logger, "plugin")
+	output.AppendInfo("password is s3cr3t")
+	output.AppendError("failed with s3cr3t")
+	output.Close(logger)
+
+	assert.Equal(t, "password is ****", output.GetStdout())
+	assert.Equal(t, "failed with ****", output.GetStderr())
+
+	content, err := ioutil.ReadFile(filepath.Join(orchestrationDir, "plugin", "stdout"))
+	assert.Nil(t, err)
+	assert.Equal(t, "password is ****", string(content))
+}
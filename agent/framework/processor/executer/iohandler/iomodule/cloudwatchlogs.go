@@ -0,0 +1,189 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+const (
+	// cloudWatchMaxBatchEvents and cloudWatchMaxBatchBytes are the limits of a PutLogEvents call,
+	// each event counts for its message length and cloudWatchEventOverheadBytes
+	cloudWatchMaxBatchEvents     = 10000
+	cloudWatchMaxBatchBytes      = 1048576
+	cloudWatchEventOverheadBytes = 26
+	// cloudWatchMaxEventBytes is the largest message of an event, longer lines are split
+	cloudWatchMaxEventBytes = 262144 - cloudWatchEventOverheadBytes
+	// cloudWatchLinesBufferSize is the number of lines read ahead while a batch is published
+	cloudWatchLinesBufferSize = 1000
+
+	defaultCloudWatchFlushInterval = 5 * time.Second
+)
+
+// CloudWatchLogs handles streaming the output lines to a CloudWatch Logs log stream
+type CloudWatchLogs struct {
+	LogGroupName  string
+	LogStreamName string
+	// FlushInterval is the longest time a line waits before it is published, 5 seconds if zero
+	FlushInterval time.Duration
+	// Redactor scrubs the secrets of the document from the output, if set
+	Redactor *redactor.Redactor
+	// Service publishes the log events, a new CloudWatchLogsService is used if nil
+	Service cloudwatchlogsinterface.ICloudWatchLogsService
+}
+
+// Read reads from the stream and publishes the lines to the log stream in batches
+func (c CloudWatchLogs) Read(log log.T, reader *io.PipeReader) {
+	defer func() { reader.Close() }()
+
+	service := c.Service
+	if service == nil {
+		service = cloudwatchlogspublisher.NewCloudWatchLogsService()
+	}
+	flushInterval := c.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultCloudWatchFlushInterval
+	}
+	batch := &cloudWatchLogsBatch{
+		service:       service,
+		logGroupName:  c.LogGroupName,
+		logStreamName: c.LogStreamName,
+	}
+
+	// lines are read ahead so that the plugin is not held up while a batch is published
+	lines := make(chan string, cloudWatchLinesBufferSize)
+	go func() {
+		defer close(lines)
+		readChunks(log, reader, c.Redactor, func(chunk []byte) bool {
+			for _, line := range strings.SplitAfter(string(chunk), "\n") {
+				if line != "" {
+					lines <- line
+				}
+			}
+			return true
+		})
+	}()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				batch.flush(log)
+				log.Debugf("Number of events published to log stream %v: %v", c.LogStreamName, batch.published)
+				return
+			}
+			batch.add(log, line, time.Now())
+		case <-ticker.C:
+			batch.flush(log)
+		}
+	}
+}
+
+// cloudWatchLogsBatch collects the events published together to a log stream.
+// CloudWatchLogsService only publishes the events it is given. The batching of cloudwatchlogsqueue is not reused
+// because it is the single queue of the agent logs, it publishes to one log group and stream and it only limits
+// the number of events, while the output of a plugin goes to its own stream and its lines must fit the size
+// limits of PutLogEvents.
+type cloudWatchLogsBatch struct {
+	service       cloudwatchlogsinterface.ICloudWatchLogsService
+	logGroupName  string
+	logStreamName string
+
+	events        []*cloudwatchlogs.InputLogEvent
+	size          int
+	sequenceToken *string
+	// created is true once the log group and stream exist, failed is true once publishing failed
+	created   bool
+	failed    bool
+	published int
+}
+
+// add appends the line to the batch, the batch is published first when the line does not fit
+func (b *cloudWatchLogsBatch) add(log log.T, line string, timestamp time.Time) {
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" || b.failed {
+		return
+	}
+	for len(line) > 0 {
+		message := line
+		if len(message) > cloudWatchMaxEventBytes {
+			// split between characters, the messages must be valid utf-8
+			end := cloudWatchMaxEventBytes
+			for end > 0 && !utf8.RuneStart(message[end]) {
+				end--
+			}
+			message = message[:end]
+		}
+		line = line[len(message):]
+
+		if len(b.events) == cloudWatchMaxBatchEvents || b.size+len(message)+cloudWatchEventOverheadBytes > cloudWatchMaxBatchBytes {
+			b.flush(log)
+		}
+		b.events = append(b.events, &cloudwatchlogs.InputLogEvent{
+			Message:   aws.String(message),
+			Timestamp: aws.Int64(timestamp.UnixNano() / int64(time.Millisecond)),
+		})
+		b.size += len(message) + cloudWatchEventOverheadBytes
+	}
+}
+
+// flush publishes the batch, the log group and stream are created on the first publication.
+// Once publishing failed the output is dropped so that the plugin is not held up by retries.
+func (b *cloudWatchLogsBatch) flush(log log.T) {
+	if len(b.events) == 0 || b.failed {
+		return
+	}
+	events := b.events
+	b.events = nil
+	b.size = 0
+
+	if !b.created {
+		if err := b.service.CreateLogGroup(log, b.logGroupName); err != nil {
+			b.fail(log, err)
+			return
+		}
+		if err := b.service.CreateLogStream(log, b.logGroupName, b.logStreamName); err != nil {
+			b.fail(log, err)
+			return
+		}
+		b.sequenceToken = b.service.GetSequenceTokenForStream(log, b.logGroupName, b.logStreamName)
+		b.created = true
+	}
+
+	sequenceToken, err := b.service.PutLogEvents(log, events, b.logGroupName, b.logStreamName, b.sequenceToken)
+	if err != nil {
+		b.fail(log, err)
+		return
+	}
+	b.sequenceToken = sequenceToken
+	b.published += len(events)
+}
+
+func (b *cloudWatchLogsBatch) fail(log log.T, err error) {
+	log.Errorf("Failed to publish the output to log stream %v of log group %v, the rest of the output is not published: %v",
+		b.logStreamName, b.logGroupName, err)
+	b.failed = true
+}
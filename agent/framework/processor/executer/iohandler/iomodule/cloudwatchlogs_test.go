@@ -0,0 +1,218 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCloudWatchLogsClient keeps the log groups, log streams and events in memory
+type fakeCloudWatchLogsClient struct {
+	mutex   sync.Mutex
+	groups  map[string]bool
+	streams map[string][]*cloudwatchlogs.InputLogEvent
+	tokens  map[string]int
+	calls   int
+	putErr  error
+}
+
+func newFakeCloudWatchLogsClient() *fakeCloudWatchLogsClient {
+	return &fakeCloudWatchLogsClient{
+		groups:  make(map[string]bool),
+		streams: make(map[string][]*cloudwatchlogs.InputLogEvent),
+		tokens:  make(map[string]int),
+	}
+}
+
+func (c *fakeCloudWatchLogsClient) CreateLogGroup(input *cloudwatchlogs.CreateLogGroupInput) (*cloudwatchlogs.CreateLogGroupOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.groups[*input.LogGroupName] {
+		return nil, awserr.New("ResourceAlreadyExistsException", "exists", nil)
+	}
+	c.groups[*input.LogGroupName] = true
+	return &cloudwatchlogs.CreateLogGroupOutput{}, nil
+}
+
+func (c *fakeCloudWatchLogsClient) CreateLogStream(input *cloudwatchlogs.CreateLogStreamInput) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.groups[*input.LogGroupName] {
+		return nil, awserr.New("ResourceNotFoundException", "no group", nil)
+	}
+	c.streams[*input.LogGroupName+"|"+*input.LogStreamName] = []*cloudwatchlogs.InputLogEvent{}
+	return &cloudwatchlogs.CreateLogStreamOutput{}, nil
+}
+
+func (c *fakeCloudWatchLogsClient) DescribeLogGroups(input *cloudwatchlogs.DescribeLogGroupsInput) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	return &cloudwatchlogs.DescribeLogGroupsOutput{}, nil
+}
+
+func (c *fakeCloudWatchLogsClient) DescribeLogStreams(input *cloudwatchlogs.DescribeLogStreamsInput) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	output := &cloudwatchlogs.DescribeLogStreamsOutput{}
+	key := *input.LogGroupName + "|" + *input.LogStreamNamePrefix
+	if _, ok := c.streams[key]; ok {
+		stream := &cloudwatchlogs.LogStream{LogStreamName: input.LogStreamNamePrefix}
+		if c.tokens[key] > 0 {
+			stream.UploadSequenceToken = aws.String(fmt.Sprint(c.tokens[key]))
+		}
+		output.LogStreams = append(output.LogStreams, stream)
+	}
+	return output, nil
+}
+
+func (c *fakeCloudWatchLogsClient) PutLogEvents(input *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.calls++
+	if c.putErr != nil {
+		return nil, c.putErr
+	}
+	key := *input.LogGroupName + "|" + *input.LogStreamName
+	expected := ""
+	if c.tokens[key] > 0 {
+		expected = fmt.Sprint(c.tokens[key])
+	}
+	if aws.StringValue(input.SequenceToken) != expected {
+		return nil, awserr.New("InvalidParameterException", "unexpected sequence token", nil)
+	}
+	c.streams[key] = append(c.streams[key], input.LogEvents...)
+	c.tokens[key]++
+	return &cloudwatchlogs.PutLogEventsOutput{NextSequenceToken: aws.String(fmt.Sprint(c.tokens[key]))}, nil
+}
+
+func (c *fakeCloudWatchLogsClient) messages(logGroup, logStream string) []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var messages []string
+	for _, event := range c.streams[logGroup+"|"+logStream] {
+		messages = append(messages, *event.Message)
+	}
+	return messages
+}
+
+func testCloudWatchLogs(module CloudWatchLogs, write func(w io.Writer)) {
+	r, w := io.Pipe()
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		module.Read(logger, r)
+	}()
+	write(w)
+	w.Close()
+	wg.Wait()
+}
+
+func TestCloudWatchLogsPublishesLines(t *testing.T) {
+	client := newFakeCloudWatchLogsClient()
+	docRedactor := redactor.New()
+	docRedactor.Register("s3cr3t")
+	module := CloudWatchLogs{
+		LogGroupName:  "/aws/ssm/AWS-RunShellScript",
+		LogStreamName: "cmd-1/i-1/awsrunShellScript/stdout",
+		Redactor:      docRedactor,
+		Service:       cloudwatchlogspublisher.NewCloudWatchLogsServiceWithClient(client),
+	}
+
+	testCloudWatchLogs(module, func(w io.Writer) {
+		w.Write([]byte("user admin\npassword s3cr3t\n\ndone"))
+	})
+
+	assert.Equal(t, []string{"user admin", "password ****", "done"}, client.messages(module.LogGroupName, module.LogStreamName))
+}
+
+func TestCloudWatchLogsFlushesOnInterval(t *testing.T) {
+	client := newFakeCloudWatchLogsClient()
+	module := CloudWatchLogs{
+		LogGroupName:  "group",
+		LogStreamName: "stream",
+		FlushInterval: 10 * time.Millisecond,
+		Service:       cloudwatchlogspublisher.NewCloudWatchLogsServiceWithClient(client),
+	}
+
+	testCloudWatchLogs(module, func(w io.Writer) {
+		w.Write([]byte("first\n"))
+		for i := 0; i < 100 && len(client.messages("group", "stream")) == 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		w.Write([]byte("second\n"))
+	})
+
+	// each batch is published with the sequence token returned for the previous one
+	assert.Equal(t, []string{"first", "second"}, client.messages("group", "stream"))
+	assert.Equal(t, 2, client.calls)
+}
+
+func TestCloudWatchLogsBatchLimits(t *testing.T) {
+	client := newFakeCloudWatchLogsClient()
+	batch := &cloudWatchLogsBatch{
+		service:       cloudwatchlogspublisher.NewCloudWatchLogsServiceWithClient(client),
+		logGroupName:  "group",
+		logStreamName: "stream",
+	}
+	now := time.Now()
+
+	// long lines are split into several events, without splitting characters
+	longLine := strings.Repeat("a", cloudWatchMaxEventBytes-1) + "é" + "end"
+	batch.add(logger, longLine, now)
+	assert.Equal(t, 2, len(batch.events))
+	assert.Equal(t, cloudWatchMaxEventBytes-1, len(*batch.events[0].Message))
+	assert.Equal(t, "éend", *batch.events[1].Message)
+
+	// the batch is published before it grows over the size limit of a call
+	for i := 0; i < 3; i++ {
+		batch.add(logger, strings.Repeat("b", cloudWatchMaxEventBytes), now)
+	}
+	assert.Equal(t, 1, client.calls)
+	batch.flush(logger)
+	assert.Equal(t, 2, client.calls)
+	assert.Equal(t, 5, batch.published)
+	assert.Equal(t, 5, len(client.messages("group", "stream")))
+}
+
+func TestCloudWatchLogsDropsOutputOnceFailed(t *testing.T) {
+	client := newFakeCloudWatchLogsClient()
+	client.putErr = errors.New("unreachable")
+	module := CloudWatchLogs{
+		LogGroupName:  "group",
+		LogStreamName: "stream",
+		FlushInterval: time.Millisecond,
+		Service:       cloudwatchlogspublisher.NewCloudWatchLogsServiceWithClient(client),
+	}
+
+	testCloudWatchLogs(module, func(w io.Writer) {
+		for i := 0; i < 10; i++ {
+			w.Write([]byte("line\n"))
+			time.Sleep(2 * time.Millisecond)
+		}
+	})
+
+	assert.Equal(t, 1, client.calls)
+}
@@ -14,40 +14,59 @@
 package iomodule
 
 import (
-	"bufio"
-	"fmt"
 	"io"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 )
 
 // CommandOutput handles writing output to a string.
 type CommandOutput struct {
 	// limit to the number of bytes to be written to the output string
-	OutputLimit  int
-	OutputString *string
+	OutputLimit int
+	// TailLength is the number of bytes of the limit kept from the end of an output longer than the limit,
+	// only its beginning is kept when zero
+	TailLength int
+	// TruncatedMarker replaces the bytes dropped from an output longer than the limit
+	TruncatedMarker string
+	OutputString    *string
+	// Redactor scrubs the secrets of the document from the output, if set
+	Redactor *redactor.Redactor
 }
 
 // Read reads from the stream and writes to the output string
 func (c CommandOutput) Read(log log.T, reader *io.PipeReader) {
 	defer func() { reader.Close() }()
 
-	// Read byte by byte
-	scanner := bufio.NewScanner(reader)
-	scanner.Split(bufio.ScanBytes)
+	// Read in chunks so that secrets can be scrubbed before they reach the output string, the beginning of the output
+	// and its last TailLength bytes are kept in memory, reading stops past the limit when no tail is kept
+	outputLength, written := 0, 0
+	if c.OutputLimit > 0 {
+		var head, tail []byte
+		readChunks(log, reader, c.Redactor, func(chunk []byte) bool {
+			outputLength += len(chunk)
+			if len(head) < c.OutputLimit {
+				headChunk := chunk
+				if len(head)+len(headChunk) > c.OutputLimit {
+					headChunk = headChunk[:c.OutputLimit-len(head)]
+				}
+				head = append(head, headChunk...)
+			}
+			if c.TailLength > 0 {
+				tail = append(tail, chunk...)
+				if len(tail) > c.TailLength {
+					tail = append(tail[:0], tail[len(tail)-c.TailLength:]...)
+				}
+			}
+			return outputLength <= c.OutputLimit || c.TailLength > 0
+		})
 
-	outputLimit := 0
-	for scanner.Scan() {
-		// Check if size of output is greater than the output limit
-		outputLimit++
-		if outputLimit > c.OutputLimit {
-			break
+		output := string(head)
+		if outputLength > c.OutputLimit {
+			output = joinTruncated(output, string(tail), c.OutputLimit, c.TailLength, c.TruncatedMarker)
 		}
-		*c.OutputString = fmt.Sprintf("%v%v", *c.OutputString, scanner.Text())
-	}
-	log.Debugf("Number of bytes written to console output: %v", outputLimit)
-
-	if err := scanner.Err(); err != nil {
-		log.Error("Error with the scanner while reading the stream")
+		written = len(output)
+		*c.OutputString += output
 	}
+	log.Debugf("Number of bytes written to console output: %v", written)
 }
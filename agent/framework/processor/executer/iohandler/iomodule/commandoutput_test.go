@@ -5,9 +5,11 @@ import (
 
 	"io"
 
+	"strings"
 	"sync"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,6 +32,10 @@ var TestInputCases = [...]string{
 var logger = log.NewMockLog()
 
 func testCommandOuput(testCase string, limit int) string {
+	return testRedactedCommandOuput(testCase, limit, nil)
+}
+
+func testRedactedCommandOuput(testCase string, limit int, docRedactor *redactor.Redactor) string {
 	r, w := io.Pipe()
 	wg := new(sync.WaitGroup)
 	var stdout string
@@ -38,6 +44,7 @@ func testCommandOuput(testCase string, limit int) string {
 	stdoutConsole := CommandOutput{
 		OutputLimit:  limit,
 		OutputString: &stdout,
+		Redactor:     docRedactor,
 	}
 	wg.Add(1)
 
@@ -63,3 +70,81 @@ func TestCommandOuput(t *testing.T) {
 		}
 	}
 }
+
+func TestCommandOuputRedactsSecrets(t *testing.T) {
+	docRedactor := redactor.New()
+	docRedactor.Register("s3cr3t")
+
+	stdout := testRedactedCommandOuput("user admin\npassword s3cr3t\ndone", 100, docRedactor)
+	assert.Equal(t, "user admin\npassword ****\ndone", stdout)
+
+	stdout = testRedactedCommandOuput("password s3cr3t\ndone", 12, docRedactor)
+	assert.Equal(t, "password ***", stdout)
+}
+
+func testTruncatedCommandOuput(testCase string, limit int, tailLength int) string {
+	r, w := io.Pipe()
+	var stdout string
+	stdoutConsole := CommandOutput{
+		OutputLimit:     limit,
+		TailLength:      tailLength,
+		TruncatedMarker: "\n--truncated--\n",
+		OutputString:    &stdout,
+	}
+	done := make(chan bool)
+	go func() {
+		stdoutConsole.Read(logger, r)
+		close(done)
+	}()
+
+	// write in small pieces so that the head and the tail are collected across many chunks
+	for len(testCase) > 0 {
+		n := 1000
+		if n > len(testCase) {
+			n = len(testCase)
+		}
+		w.Write([]byte(testCase[:n]))
+		testCase = testCase[n:]
+	}
+	w.Close()
+	<-done
+	return stdout
+}
+
+func TestCommandOuputTruncation(t *testing.T) {
+	longOutput := strings.Repeat("start\n", 1000) + strings.Repeat("x", 3*chunkSize) + "\nerror: failed at the end\n"
+
+	// an output within the limit is kept as is
+	assert.Equal(t, "short output\n", testTruncatedCommandOuput("short output\n", 100, 50))
+	assert.Equal(t, TestInputCases[3], testTruncatedCommandOuput(TestInputCases[3], len(TestInputCases[3]), 50))
+
+	// head only, the marker ends the output
+	stdout := testTruncatedCommandOuput(longOutput, 100, 0)
+	assert.Equal(t, 100, len(stdout))
+	assert.Equal(t, longOutput[:85]+"\n--truncated--\n", stdout)
+
+	// head and tail, the marker replaces the middle of the output
+	stdout = testTruncatedCommandOuput(longOutput, 100, 40)
+	assert.Equal(t, 100, len(stdout))
+	assert.Equal(t, longOutput[:45]+"\n--truncated--\n"+longOutput[len(longOutput)-40:], stdout)
+	assert.True(t, strings.HasSuffix(stdout, "error: failed at the end\n"))
+
+	// the tail is taken from the end of the output even when the output is only just over the limit
+	stdout = testTruncatedCommandOuput(TestInputCases[3], len(TestInputCases[3])-1, 20)
+	assert.Equal(t, Truncate(TestInputCases[3], len(TestInputCases[3])-1, 20, "\n--truncated--\n"), stdout)
+}
+
+func TestTruncate(t *testing.T) {
+	// the text is kept when it fits
+	assert.Equal(t, "abcdef", Truncate("abcdef", 6, 2, "..."))
+	// head only
+	assert.Equal(t, "abc...", Truncate("abcdefghij", 6, 0, "..."))
+	// head and tail
+	assert.Equal(t, "ab...ij", Truncate("abcdefghij", 7, 2, "..."))
+	// the tail is limited to the space left by the marker
+	assert.Equal(t, "...hij", Truncate("abcdefghij", 6, 5, "..."))
+	// the marker is cut when the limit is shorter
+	assert.Equal(t, "..", Truncate("abcdefghij", 2, 1, "..."))
+	// the tail does not start in the middle of a character
+	assert.Equal(t, "a...", Truncate("abcd℃", 6, 2, "..."))
+}
@@ -14,7 +14,6 @@
 package iomodule
 
 import (
-	"bufio"
 	"io"
 	"os"
 	"path/filepath"
@@ -22,18 +21,18 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
-	"github.com/aws/amazon-ssm-agent/agent/s3util"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 )
 
-// File handles writing to an output file and upload to s3
+// File handles writing to an output file
 type File struct {
 	FileName               string
 	OrchestrationDirectory string
-	OutputS3BucketName     string
-	OutputS3KeyPrefix      string
+	// Redactor scrubs the secrets of the document from the file, if set
+	Redactor *redactor.Redactor
 }
 
-// Read reads from the stream and writes to the output file and s3.
+// Read reads from the stream and writes to the output file.
 func (file File) Read(log log.T, reader *io.PipeReader) {
 	defer func() { reader.Close() }()
 
@@ -55,31 +54,11 @@ func (file File) Read(log log.T, reader *io.PipeReader) {
 
 	defer fileWriter.Close()
 
-	// Read byte by byte and write to file
-	scanner := bufio.NewScanner(reader)
-	scanner.Split(bufio.ScanBytes)
-	for scanner.Scan() {
-		if _, err = fileWriter.Write([]byte(scanner.Text())); err != nil {
+	// Read in chunks so that secrets can be scrubbed before they are written to file
+	readChunks(log, reader, file.Redactor, func(chunk []byte) bool {
+		if _, err = fileWriter.Write(chunk); err != nil {
 			log.Errorf("Failed to write the message to stdout: %v", err)
 		}
-	}
-
-	// Check if scanner exited because of an error
-	if err := scanner.Err(); err != nil {
-		log.Error("Error with the scanner while reading the stream")
-	}
-
-	fi, err := fileWriter.Stat()
-	if err != nil {
-		log.Errorf("Failed to get file size: %v", err)
-		return
-	}
-
-	// Upload output file to S3
-	if file.OutputS3BucketName != "" && fi.Size() > 0 {
-		s3Key := fileutil.BuildS3Path(file.OutputS3KeyPrefix, file.FileName)
-		if err := s3util.NewAmazonS3Util(log, file.OutputS3BucketName).S3Upload(log, file.OutputS3BucketName, s3Key, filePath); err != nil {
-			log.Errorf("Failed to upload the output to s3: %v", err)
-		}
-	}
+		return true
+	})
 }
@@ -0,0 +1,82 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
+)
+
+const (
+	// HttpOutputPathHeader is the header naming the output file the posted output belongs to
+	HttpOutputPathHeader = "X-Amz-Ssm-Output-Path"
+
+	defaultHttpTimeout = 10 * time.Second
+)
+
+// HttpEndpoint handles posting the output to a local http endpoint, each request carries a chunk of complete lines
+type HttpEndpoint struct {
+	Url string
+	// OutputPath is the path of the output file, sent with each request
+	OutputPath string
+	// Redactor scrubs the secrets of the document from the output, if set
+	Redactor *redactor.Redactor
+	// Client posts the output, a client with a 10 seconds timeout is used if nil
+	Client *http.Client
+}
+
+// Read reads from the stream and posts it to the endpoint, the output is no longer posted once a request failed
+func (h HttpEndpoint) Read(log log.T, reader *io.PipeReader) {
+	defer func() { reader.Close() }()
+
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultHttpTimeout}
+	}
+	readChunks(log, reader, h.Redactor, func(chunk []byte) bool {
+		if err := h.post(client, chunk); err != nil {
+			log.Errorf("Failed to post the output to %v: %v", h.Url, err)
+			return false
+		}
+		return true
+	})
+}
+
+// post sends a chunk of the output to the endpoint
+func (h HttpEndpoint) post(client *http.Client, chunk []byte) error {
+	request, err := http.NewRequest("POST", h.Url, bytes.NewReader(chunk))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "text/plain")
+	request.Header.Set(HttpOutputPathHeader, h.OutputPath)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("the endpoint returned %v", response.Status)
+	}
+	return nil
+}
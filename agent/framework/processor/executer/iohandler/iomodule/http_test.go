@@ -0,0 +1,69 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpEndpointPostsOutput(t *testing.T) {
+	var lock sync.Mutex
+	var posted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		posted = append(posted, r.Header.Get(HttpOutputPathHeader)+"|"+string(body))
+	}))
+	defer server.Close()
+
+	docRedactor := redactor.New()
+	docRedactor.Register("s3cr3t")
+	readModule(HttpEndpoint{Url: server.URL, OutputPath: "/orchestration/cmd-1/plugin/stdout", Redactor: docRedactor}, func(w io.Writer) {
+		w.Write([]byte("user admin\npassword s3cr3t\n"))
+		w.Write([]byte("done"))
+	})
+
+	assert.Equal(t, "user admin\npassword ****\ndone", strings.Replace(strings.Join(posted, ""), "/orchestration/cmd-1/plugin/stdout|", "", -1))
+}
+
+func TestHttpEndpointStopsOnceFailed(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	readModule(HttpEndpoint{Url: server.URL}, func(w io.Writer) {
+		w.Write([]byte("first line\n"))
+		// the module no longer reads the output once the endpoint failed
+		for i := 0; i < 10; i++ {
+			if _, err := w.Write([]byte("next line\n")); err != nil {
+				return
+			}
+		}
+		t.Error("the output is still read after the endpoint failed")
+	})
+	assert.Equal(t, 1, requests)
+}
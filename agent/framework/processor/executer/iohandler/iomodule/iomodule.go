@@ -15,12 +15,55 @@
 package iomodule
 
 import (
+	"bytes"
 	"io"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 )
 
+// chunkSize is the size of the buffer the output modules read the stream with
+const chunkSize = 64 * 1024
+
 // IOModule is an interface for output modules
 type IOModule interface {
 	Read(log.T, *io.PipeReader)
 }
+
+// readChunks reads the stream in chunks of at most chunkSize bytes and passes them to process, with the secrets
+// scrubbed, until process returns false or the stream ends. Chunks end with a full line unless a line does not
+// fit in a chunk, so the memory used does not depend on the size of the output nor on the length of its lines.
+// The chunk is only valid until process returns.
+func readChunks(log log.T, reader io.Reader, r *redactor.Redactor, process func(chunk []byte) bool) {
+	buffer := make([]byte, chunkSize)
+	pending := 0
+	for {
+		n, err := reader.Read(buffer[pending:])
+		pending += n
+
+		// pass the complete lines, a full buffer without line end is passed up to where a secret may be cut
+		cut := pending
+		if err == nil {
+			if i := bytes.LastIndexByte(buffer[:pending], '\n'); i >= 0 {
+				cut = i + 1
+			} else if pending < len(buffer) {
+				cut = 0
+			} else if cut = r.SafePrefixLength(string(buffer)); cut == 0 {
+				cut = pending
+			}
+		}
+		if cut > 0 {
+			if !process(r.RedactBytes(buffer[:cut])) {
+				return
+			}
+			pending = copy(buffer, buffer[cut:pending])
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				log.Error("Error with the reader while reading the stream")
+			}
+			return
+		}
+	}
+}
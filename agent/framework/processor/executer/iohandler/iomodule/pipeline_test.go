@@ -0,0 +1,199 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
+	"github.com/stretchr/testify/assert"
+)
+
+// lineFile and lineCommandOutput are the output modules as they were before the output was read in chunks,
+// reading and writing one line at a time, they are kept to compare the results and the throughput
+type lineFile struct {
+	filePath string
+	redactor *redactor.Redactor
+}
+
+func (file lineFile) Read(log log.T, reader *io.PipeReader) {
+	defer reader.Close()
+	fileWriter, err := os.OpenFile(file.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer fileWriter.Close()
+	bufReader := bufio.NewReader(reader)
+	for {
+		line, readErr := bufReader.ReadString('\n')
+		if len(line) > 0 {
+			fileWriter.Write([]byte(file.redactor.Redact(line)))
+		}
+		if readErr != nil {
+			break
+		}
+	}
+}
+
+type lineCommandOutput struct {
+	outputLimit  int
+	outputString *string
+	redactor     *redactor.Redactor
+}
+
+func (c lineCommandOutput) Read(log log.T, reader *io.PipeReader) {
+	defer reader.Close()
+	bufReader := bufio.NewReader(reader)
+	outputLength := 0
+	for outputLength < c.outputLimit {
+		line, err := bufReader.ReadString('\n')
+		line = c.redactor.Redact(line)
+		if outputLength+len(line) > c.outputLimit {
+			line = line[:c.outputLimit-outputLength]
+		}
+		outputLength += len(line)
+		*c.outputString += line
+		if err != nil {
+			break
+		}
+	}
+}
+
+// runPipeline writes the output to the modules through a multi-writer, in writes of writeSize bytes
+func runPipeline(output []byte, writeSize int, modules ...IOModule) {
+	writer := multiwriter.NewDocumentIOMultiWriter()
+	wg := writer.GetWaitGroup()
+	for _, module := range modules {
+		r, w := io.Pipe()
+		writer.AddWriter(w)
+		go func(module IOModule, r *io.PipeReader) {
+			defer wg.Done()
+			module.Read(logger, r)
+		}(module, r)
+	}
+	for len(output) > 0 {
+		n := writeSize
+		if n > len(output) {
+			n = len(output)
+		}
+		writer.Write(output[:n])
+		output = output[n:]
+	}
+	writer.Close()
+}
+
+// testOutput returns lines of the given length up to the given size, every tenth line shows the secret
+func testOutput(size int, lineLength int) []byte {
+	var buffer bytes.Buffer
+	line := strings.Repeat("x", lineLength-1) + "\n"
+	secretLine := "secret s3cr3t " + line[14:]
+	for i := 0; buffer.Len() < size; i++ {
+		if i%10 == 0 {
+			buffer.WriteString(secretLine)
+		} else {
+			buffer.WriteString(line)
+		}
+	}
+	return buffer.Bytes()
+}
+
+func TestChunkedOutputMatchesLineOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iomodule")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	docRedactor := redactor.New()
+	docRedactor.Register("s3cr3t")
+
+	// a secret across the chunk boundary, in a line longer than a chunk
+	longLine := strings.Repeat("y", chunkSize-3) + "s3cr3t" + strings.Repeat("z", chunkSize) + "\nend"
+	outputs := []string{
+		"",
+		"no line end",
+		"user admin\npassword s3cr3t\ndone",
+		string(testOutput(3*chunkSize, 100)),
+		longLine,
+	}
+	outputs = append(outputs, TestInputCases[:]...)
+
+	for i, output := range outputs {
+		for _, writeSize := range []int{1, 7, 32 * 1024} {
+			var chunkedOutput, lineOutput string
+			chunkedFile := filepath.Join(dir, "chunked")
+			lineFilePath := filepath.Join(dir, "line")
+			os.Remove(chunkedFile)
+			os.Remove(lineFilePath)
+			runPipeline([]byte(output), writeSize,
+				File{FileName: "chunked", OrchestrationDirectory: dir, Redactor: docRedactor},
+				CommandOutput{OutputLimit: 24000, OutputString: &chunkedOutput, Redactor: docRedactor})
+			runPipeline([]byte(output), writeSize,
+				lineFile{filePath: lineFilePath, redactor: docRedactor},
+				lineCommandOutput{outputLimit: 24000, outputString: &lineOutput, redactor: docRedactor})
+
+			chunkedContent, _ := ioutil.ReadFile(chunkedFile)
+			lineContent, _ := ioutil.ReadFile(lineFilePath)
+			assert.Equal(t, string(lineContent), string(chunkedContent), "file of output %v written by %v", i, writeSize)
+			assert.Equal(t, lineOutput, chunkedOutput, "console of output %v written by %v", i, writeSize)
+			assert.NotContains(t, string(chunkedContent), "s3cr3t")
+		}
+	}
+}
+
+func benchmarkPipeline(b *testing.B, lineLength int, newModules func(dir string, console *string) []IOModule) {
+	dir, err := ioutil.TempDir("", "iomodule")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := testOutput(8*1024*1024, lineLength)
+
+	b.SetBytes(int64(len(output)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var console string
+		os.RemoveAll(filepath.Join(dir, "stdout"))
+		runPipeline(output, 32*1024, newModules(dir, &console)...)
+	}
+}
+
+func newLineModules(dir string, console *string) []IOModule {
+	docRedactor := redactor.New()
+	docRedactor.Register("s3cr3t")
+	return []IOModule{
+		lineFile{filePath: filepath.Join(dir, "stdout"), redactor: docRedactor},
+		lineCommandOutput{outputLimit: 24000, outputString: console, redactor: docRedactor},
+	}
+}
+
+func newChunkedModules(dir string, console *string) []IOModule {
+	docRedactor := redactor.New()
+	docRedactor.Register("s3cr3t")
+	return []IOModule{
+		File{FileName: "stdout", OrchestrationDirectory: dir, Redactor: docRedactor},
+		CommandOutput{OutputLimit: 24000, OutputString: console, Redactor: docRedactor},
+	}
+}
+
+func BenchmarkLineOutputShortLines(b *testing.B)    { benchmarkPipeline(b, 80, newLineModules) }
+func BenchmarkChunkedOutputShortLines(b *testing.B) { benchmarkPipeline(b, 80, newChunkedModules) }
+func BenchmarkLineOutputLongLines(b *testing.B)     { benchmarkPipeline(b, 4096, newLineModules) }
+func BenchmarkChunkedOutputLongLines(b *testing.B)  { benchmarkPipeline(b, 4096, newChunkedModules) }
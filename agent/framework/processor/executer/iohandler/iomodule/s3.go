@@ -0,0 +1,86 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
+	"github.com/aws/amazon-ssm-agent/agent/s3util"
+)
+
+// s3Uploader uploads a file to S3
+type s3Uploader interface {
+	S3UploadWithEncryption(log log.T, bucketName string, objectKey string, filePath string, encryption contracts.S3EncryptionConfiguration) error
+}
+
+// newS3Uploader returns the uploader of the output to the bucket
+var newS3Uploader = func(log log.T, bucketName string) s3Uploader {
+	return s3util.NewAmazonS3Util(log, bucketName)
+}
+
+// S3 handles uploading the output to s3, the output is spooled to a temporary file in the
+// orchestration directory until the stream ends so that it is uploaded independently of the output file
+type S3 struct {
+	FileName               string
+	OrchestrationDirectory string
+	OutputS3BucketName     string
+	OutputS3KeyPrefix      string
+	// OutputS3Encryption is the server-side encryption of the uploaded output
+	OutputS3Encryption contracts.S3EncryptionConfiguration
+	// Redactor scrubs the secrets of the document from the uploaded output, if set
+	Redactor *redactor.Redactor
+}
+
+// Read reads from the stream and uploads the output to s3 once the stream ends.
+func (s S3) Read(log log.T, reader *io.PipeReader) {
+	defer func() { reader.Close() }()
+
+	if err := fileutil.MakeDirs(s.OrchestrationDirectory); err != nil {
+		log.Errorf("failed to create orchestrationDir directory at %v: %v", s.OrchestrationDirectory, err)
+		return
+	}
+	spool, err := ioutil.TempFile(s.OrchestrationDirectory, "."+s.FileName+".s3-")
+	if err != nil {
+		log.Errorf("Failed to create the file the output is uploaded from: %v", err)
+		return
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	var size int64
+	readChunks(log, reader, s.Redactor, func(chunk []byte) bool {
+		var n int
+		n, err = spool.Write(chunk)
+		size += int64(n)
+		return err == nil
+	})
+	if err != nil {
+		log.Errorf("Failed to write the output uploaded to s3: %v", err)
+		return
+	}
+	if size == 0 {
+		return
+	}
+
+	s3Key := fileutil.BuildS3Path(s.OutputS3KeyPrefix, s.FileName)
+	if err := newS3Uploader(log, s.OutputS3BucketName).S3UploadWithEncryption(log, s.OutputS3BucketName, s3Key, spool.Name(), s.OutputS3Encryption); err != nil {
+		log.Errorf("Failed to upload the output to s3: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
+	"github.com/stretchr/testify/assert"
+)
+
+// readModule runs the module on the output written by write
+func readModule(module IOModule, write func(w io.Writer)) {
+	r, w := io.Pipe()
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		module.Read(logger, r)
+	}()
+	write(w)
+	w.Close()
+	wg.Wait()
+}
+
+// fakeS3Uploader keeps the content of the uploaded files
+type fakeS3Uploader struct {
+	uploads    map[string]string
+	encryption contracts.S3EncryptionConfiguration
+	err        error
+}
+
+func (u *fakeS3Uploader) S3UploadWithEncryption(log log.T, bucketName string, objectKey string, filePath string, encryption contracts.S3EncryptionConfiguration) error {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	u.uploads[bucketName+"/"+objectKey] = string(content)
+	u.encryption = encryption
+	return u.err
+}
+
+func testS3Uploads(t *testing.T, uploader *fakeS3Uploader, write func(w io.Writer)) {
+	dir, err := ioutil.TempDir("", "iomodule")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(previous func(log.T, string) s3Uploader) { newS3Uploader = previous }(newS3Uploader)
+	newS3Uploader = func(log log.T, bucketName string) s3Uploader { return uploader }
+
+	docRedactor := redactor.New()
+	docRedactor.Register("s3cr3t")
+	readModule(S3{
+		FileName:               "stdout",
+		OrchestrationDirectory: dir,
+		OutputS3BucketName:     "bucket",
+		OutputS3KeyPrefix:      "cmd-1/i-1/plugin",
+		OutputS3Encryption:     contracts.S3EncryptionConfiguration{ServerSideEncryption: appconfig.S3ServerSideEncryptionAES256},
+		Redactor:               docRedactor,
+	}, write)
+
+	// the spooled output is removed once uploaded
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestS3UploadsOutput(t *testing.T) {
+	uploader := &fakeS3Uploader{uploads: make(map[string]string)}
+	testS3Uploads(t, uploader, func(w io.Writer) {
+		w.Write([]byte("user admin\npassword s3cr3t\n"))
+		w.Write([]byte("done"))
+	})
+
+	assert.Equal(t, map[string]string{"bucket/cmd-1/i-1/plugin/stdout": "user admin\npassword ****\ndone"}, uploader.uploads)
+	assert.Equal(t, appconfig.S3ServerSideEncryptionAES256, uploader.encryption.ServerSideEncryption)
+}
+
+func TestS3SkipsEmptyOutput(t *testing.T) {
+	uploader := &fakeS3Uploader{uploads: make(map[string]string)}
+	testS3Uploads(t, uploader, func(w io.Writer) {})
+	assert.Empty(t, uploader.uploads)
+
+	// a failed upload is only logged
+	uploader.err = errors.New("unreachable")
+	testS3Uploads(t, uploader, func(w io.Writer) { w.Write([]byte("output")) })
+	assert.Equal(t, 1, len(uploader.uploads))
+}
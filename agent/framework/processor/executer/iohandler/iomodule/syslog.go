@@ -0,0 +1,71 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"io"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
+)
+
+// syslogWriter writes messages to the local syslog daemon
+type syslogWriter interface {
+	Info(message string) error
+	Err(message string) error
+	Close() error
+}
+
+// dialSyslog connects to the local syslog daemon, the messages are tagged with tag
+var dialSyslog = newSyslogWriter
+
+// Syslog handles forwarding the output lines to the local syslog daemon
+type Syslog struct {
+	// Tag identifies the command and plugin the lines come from
+	Tag string
+	// Error logs the lines with the error severity rather than the informational one
+	Error bool
+	// Redactor scrubs the secrets of the document from the output, if set
+	Redactor *redactor.Redactor
+}
+
+// Read reads from the stream and logs each line, the output is no longer forwarded once logging failed
+func (s Syslog) Read(log log.T, reader *io.PipeReader) {
+	defer func() { reader.Close() }()
+
+	writer, err := dialSyslog(s.Tag)
+	if err != nil {
+		log.Errorf("Failed to connect to syslog: %v", err)
+		return
+	}
+	defer writer.Close()
+
+	write := writer.Info
+	if s.Error {
+		write = writer.Err
+	}
+	readChunks(log, reader, s.Redactor, func(chunk []byte) bool {
+		for _, line := range strings.Split(string(chunk), "\n") {
+			if line == "" {
+				continue
+			}
+			if err := write(line); err != nil {
+				log.Errorf("Failed to forward the output to syslog: %v", err)
+				return false
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSyslogWriter keeps the logged messages with their severity
+type fakeSyslogWriter struct {
+	tag      string
+	messages []string
+	closed   bool
+}
+
+func (w *fakeSyslogWriter) Info(message string) error {
+	w.messages = append(w.messages, "info|"+message)
+	return nil
+}
+
+func (w *fakeSyslogWriter) Err(message string) error {
+	w.messages = append(w.messages, "err|"+message)
+	return nil
+}
+
+func (w *fakeSyslogWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestSyslogForwardsLines(t *testing.T) {
+	writer := &fakeSyslogWriter{}
+	defer func(previous func(string) (syslogWriter, error)) { dialSyslog = previous }(dialSyslog)
+	dialSyslog = func(tag string) (syslogWriter, error) {
+		writer.tag = tag
+		return writer, nil
+	}
+
+	docRedactor := redactor.New()
+	docRedactor.Register("s3cr3t")
+	readModule(Syslog{Tag: "amazon-ssm-agent/cmd-1/plugin/stderr", Error: true, Redactor: docRedactor}, func(w io.Writer) {
+		w.Write([]byte("user admin\npassword s3cr3t\n\ndone"))
+	})
+
+	assert.Equal(t, "amazon-ssm-agent/cmd-1/plugin/stderr", writer.tag)
+	assert.Equal(t, []string{"err|user admin", "err|password ****", "err|done"}, writer.messages)
+	assert.True(t, writer.closed)
+}
+
+func TestSyslogUnavailable(t *testing.T) {
+	defer func(previous func(string) (syslogWriter, error)) { dialSyslog = previous }(dialSyslog)
+	dialSyslog = func(tag string) (syslogWriter, error) {
+		return nil, errors.New("no syslog daemon")
+	}
+
+	// the output is no longer read, the writer is told so
+	readModule(Syslog{Tag: "amazon-ssm-agent"}, func(w io.Writer) {
+		_, err := w.Write([]byte("output"))
+		assert.Error(t, err)
+	})
+}
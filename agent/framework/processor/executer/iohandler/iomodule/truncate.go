@@ -0,0 +1,47 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import "unicode/utf8"
+
+// Truncate cuts a text longer than limit bytes down to limit bytes, the marker included. The beginning of the text is
+// kept, followed by the marker and the last tailLength bytes of the text. The text is returned as is when it fits
+func Truncate(text string, limit int, tailLength int, marker string) string {
+	if len(text) <= limit {
+		return text
+	}
+	return joinTruncated(text, text, limit, tailLength, marker)
+}
+
+// joinTruncated joins the beginning of head, the marker and the last tailLength bytes of tail in limit bytes,
+// head must be at least limit bytes long. The tail does not start in the middle of a character
+func joinTruncated(head string, tail string, limit int, tailLength int, marker string) string {
+	if limit <= len(marker) {
+		return marker[:limit]
+	}
+	available := limit - len(marker)
+	if tailLength > available {
+		tailLength = available
+	}
+	if tailLength > len(tail) {
+		tailLength = len(tail)
+	}
+
+	headLength := available - tailLength
+	tailStart := len(tail) - tailLength
+	for tailStart < len(tail) && !utf8.RuneStart(tail[tailStart]) {
+		tailStart++
+	}
+	return head[:headLength] + marker + tail[tailStart:]
+}
@@ -28,8 +28,18 @@ type MockDocumentIOMultiWriter struct {
 }
 
 // AddWriter is a mocked method that just returns what mock tells it to.
+// Like the multi-writer, it adds the writer to the wait group returned by GetWaitGroup, whose reader calls Done.
 func (m *MockDocumentIOMultiWriter) AddWriter(writer *io.PipeWriter) {
 	m.Called(writer)
+	for _, call := range m.ExpectedCalls {
+		if call.Method != "GetWaitGroup" || len(call.ReturnArguments) == 0 {
+			continue
+		}
+		if wg, ok := call.ReturnArguments.Get(0).(*sync.WaitGroup); ok {
+			wg.Add(1)
+		}
+		return
+	}
 }
 
 // GetStreamClosedChannel is a mocked method that just returns what mock tells it to.
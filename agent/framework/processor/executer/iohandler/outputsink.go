@@ -0,0 +1,374 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iohandler
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
+)
+
+// OutputSinkConfig describes the output of a plugin an output sink is created for
+type OutputSinkConfig struct {
+	// OutputName is the name of the output, the standard output or standard error file name
+	OutputName string
+	// OrchestrationDirectory and OutputS3KeyPrefix are where the output of the plugin is written
+	OrchestrationDirectory string
+	OutputS3KeyPrefix      string
+	// FilePath is the path of the plugin output below the output of the command
+	FilePath     []string
+	IOConfig     contracts.IOConfiguration
+	PluginConfig PluginConfig
+	// Redactor scrubs the secrets of the document from the output, if set
+	Redactor *redactor.Redactor
+}
+
+// isStderr returns true if the sink is created for the standard error of the plugin
+func (config OutputSinkConfig) isStderr() bool {
+	return config.OutputName == config.PluginConfig.StderrFileName
+}
+
+// OutputSinkFactory creates the output module writing an output of a plugin to a destination,
+// it returns no module when the destination does not apply to the command
+type OutputSinkFactory interface {
+	Create(log log.T, config OutputSinkConfig) (iomodule.IOModule, error)
+}
+
+// OutputSinkRegistry stores the output sink factories by name
+type OutputSinkRegistry map[string]OutputSinkFactory
+
+var outputSinksLock sync.RWMutex
+
+// registeredOutputSinks stores the registered output sinks.
+var registeredOutputSinks = OutputSinkRegistry{
+	appconfig.OutputSinkFile:           FileSinkFactory{},
+	appconfig.OutputSinkS3:             S3SinkFactory{},
+	appconfig.OutputSinkCloudWatchLogs: CloudWatchLogsSinkFactory{},
+	appconfig.OutputSinkSyslog:         SyslogSinkFactory{},
+	appconfig.OutputSinkHttp:           HttpSinkFactory{},
+}
+
+// RegisterOutputSink registers an output sink, replacing the one registered with the same name
+func RegisterOutputSink(name string, factory OutputSinkFactory) {
+	outputSinksLock.Lock()
+	defer outputSinksLock.Unlock()
+	registeredOutputSinks[name] = factory
+}
+
+// RegisteredOutputSinks returns the registered output sinks
+func RegisteredOutputSinks() OutputSinkRegistry {
+	outputSinksLock.RLock()
+	defer outputSinksLock.RUnlock()
+	sinks := OutputSinkRegistry{}
+	for name, factory := range registeredOutputSinks {
+		sinks[name] = factory
+	}
+	return sinks
+}
+
+// createOutputSinks returns the modules of the output sinks enabled for the output, the sinks which are not
+// registered or fail to be created are skipped so that they do not affect the other sinks nor the command.
+// The sinks which do not receive the whole output are recorded in dropped.
+func createOutputSinks(log log.T, config OutputSinkConfig, dropped *droppedOutput) []iomodule.IOModule {
+	registry := RegisteredOutputSinks()
+	created := make(map[string]bool)
+	var modules []iomodule.IOModule
+	for _, name := range config.PluginConfig.OutputSinks {
+		if created[name] {
+			continue
+		}
+		created[name] = true
+
+		factory, ok := registry[name]
+		if !ok {
+			log.Warnf("Output sink %v is not registered, the output is not written to it", name)
+			continue
+		}
+		module, err := factory.Create(log, config)
+		if err != nil {
+			log.Errorf("Failed to create output sink %v, the output is not written to it: %v", name, err)
+			continue
+		}
+		if module != nil {
+			modules = append(modules, isolatedOutputSink{
+				name:   name,
+				module: module,
+				// the output files are the complete output the other results are built from, the plugin waits for them
+				backpressure: name == appconfig.OutputSinkFile,
+				outputName:   config.OutputName,
+				dropped:      dropped,
+			})
+		}
+	}
+	return modules
+}
+
+// outputSinkQueueLength and outputSinkChunkSize bound the output queued for a sink which does not keep up,
+// outputSinkCloseTimeout bounds the time the sink is given to finish once the output is complete
+var (
+	outputSinkQueueLength  = 1024
+	outputSinkChunkSize    = 8 * 1024
+	outputSinkCloseTimeout = time.Minute
+)
+
+// isolatedOutputSink keeps a failing or slow output sink from holding up the plugin and the other sinks
+type isolatedOutputSink struct {
+	name   string
+	module iomodule.IOModule
+	// backpressure makes the output wait for the sink instead of dropping the output the sink does not keep up with
+	backpressure bool
+	// outputName is the output the sink writes, dropped records the sink when it does not receive the whole output
+	outputName string
+	dropped    *droppedOutput
+}
+
+// Read queues the output for the sink, which runs in its own routine. Unless the sink applies backpressure,
+// the stream is always read to the end so that writing the output never waits for the sink: the sink is disabled
+// when its queue overflows and is no longer waited for when it does not finish in time
+func (sink isolatedOutputSink) Read(log log.T, reader *io.PipeReader) {
+	defer reader.Close()
+
+	sinkReader, sinkWriter := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Errorf("Output sink %v failed: %v", sink.name, r)
+			}
+			sinkReader.Close()
+			close(done)
+		}()
+		sink.module.Read(log, sinkReader)
+	}()
+
+	queue := make(chan []byte, outputSinkQueueLength)
+	go func() {
+		defer sinkWriter.Close()
+		for chunk := range queue {
+			// the writes fail once the sink stopped reading, the queue is still emptied
+			sinkWriter.Write(chunk)
+		}
+	}()
+
+	disabled := false
+	buffer := make([]byte, outputSinkChunkSize)
+	for {
+		n, err := reader.Read(buffer)
+		if n > 0 && !disabled {
+			chunk := make([]byte, n)
+			copy(chunk, buffer[:n])
+			if sink.backpressure {
+				queue <- chunk
+			} else {
+				select {
+				case queue <- chunk:
+				default:
+					log.Warnf("Output sink %v does not keep up with the output, the rest of the output is not written to it", sink.name)
+					sink.dropped.add(sink.name, sink.outputName)
+					disabled = true
+				}
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	close(queue)
+
+	if sink.backpressure {
+		<-done
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(outputSinkCloseTimeout):
+		log.Warnf("Output sink %v did not finish within %v, it is no longer waited for", sink.name, outputSinkCloseTimeout)
+		sink.dropped.add(sink.name, sink.outputName)
+		sinkReader.Close()
+	}
+}
+
+// droppedOutput records the output sinks which did not receive the whole output of a plugin
+type droppedOutput struct {
+	lock sync.Mutex
+	// sinks are the names of the sinks by output name, in the order they were recorded
+	sinks map[string][]string
+	order []string
+}
+
+// add records that the sink did not receive the whole output, a nil record is ignored
+func (dropped *droppedOutput) add(sink string, outputName string) {
+	if dropped == nil {
+		return
+	}
+	dropped.lock.Lock()
+	defer dropped.lock.Unlock()
+	if dropped.sinks == nil {
+		dropped.sinks = make(map[string][]string)
+	}
+	for _, name := range dropped.sinks[outputName] {
+		if name == sink {
+			return
+		}
+	}
+	if len(dropped.sinks[outputName]) == 0 {
+		dropped.order = append(dropped.order, outputName)
+	}
+	dropped.sinks[outputName] = append(dropped.sinks[outputName], sink)
+}
+
+// messages returns one message per output part of which was dropped and clears the records
+func (dropped *droppedOutput) messages() (messages []string) {
+	if dropped == nil {
+		return nil
+	}
+	dropped.lock.Lock()
+	defer dropped.lock.Unlock()
+	for _, outputName := range dropped.order {
+		messages = append(messages, fmt.Sprintf("Part of the %v output was not written to output sink %v, it did not keep up with the output",
+			outputName, strings.Join(dropped.sinks[outputName], ", ")))
+	}
+	dropped.sinks = nil
+	dropped.order = nil
+	return messages
+}
+
+// FileSinkFactory writes the output to a file in the orchestration directory
+type FileSinkFactory struct {
+}
+
+func (f FileSinkFactory) Create(log log.T, config OutputSinkConfig) (iomodule.IOModule, error) {
+	return iomodule.File{
+		FileName:               config.OutputName,
+		OrchestrationDirectory: config.OrchestrationDirectory,
+		Redactor:               config.Redactor,
+	}, nil
+}
+
+// S3SinkFactory uploads the output to the S3 bucket of the command, if any
+type S3SinkFactory struct {
+}
+
+func (f S3SinkFactory) Create(log log.T, config OutputSinkConfig) (iomodule.IOModule, error) {
+	if config.IOConfig.OutputS3BucketName == "" {
+		return nil, nil
+	}
+	return iomodule.S3{
+		FileName:               config.OutputName,
+		OrchestrationDirectory: config.OrchestrationDirectory,
+		OutputS3BucketName:     config.IOConfig.OutputS3BucketName,
+		OutputS3KeyPrefix:      config.OutputS3KeyPrefix,
+		OutputS3Encryption:     config.IOConfig.OutputS3Encryption,
+		Redactor:               config.Redactor,
+	}, nil
+}
+
+// CloudWatchLogsSinkFactory streams the output to the CloudWatch Logs log group of the command, if any,
+// the log stream is named from the command, the instance and the plugin output
+type CloudWatchLogsSinkFactory struct {
+}
+
+func (f CloudWatchLogsSinkFactory) Create(log log.T, config OutputSinkConfig) (iomodule.IOModule, error) {
+	cloudWatchConfig := config.IOConfig.CloudWatchConfig
+	if cloudWatchConfig.LogGroupName == "" {
+		return nil, nil
+	}
+
+	elements := []string{cloudWatchConfig.LogStreamPrefix}
+	elements = append(elements, config.FilePath...)
+	elements = append(elements, config.OutputName)
+	return iomodule.CloudWatchLogs{
+		LogGroupName:  cloudWatchConfig.LogGroupName,
+		LogStreamName: cloudWatchLogStreamName(elements...),
+		Redactor:      config.Redactor,
+	}, nil
+}
+
+// cloudWatchLogStreamName joins the non empty elements, the characters log stream names must not contain are replaced
+func cloudWatchLogStreamName(elements ...string) string {
+	var names []string
+	for _, element := range elements {
+		if element = strings.Trim(element, "/"); element != "" {
+			names = append(names, strings.NewReplacer(":", "-", "*", "-").Replace(element))
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// SyslogSinkFactory forwards the output to the local syslog daemon, the standard error with the error severity
+type SyslogSinkFactory struct {
+}
+
+func (f SyslogSinkFactory) Create(log log.T, config OutputSinkConfig) (iomodule.IOModule, error) {
+	return iomodule.Syslog{
+		Tag:      syslogTag(config),
+		Error:    config.isStderr(),
+		Redactor: config.Redactor,
+	}, nil
+}
+
+// syslogTag returns the tag of the output lines, it names the agent and the orchestration directory of the output
+func syslogTag(config OutputSinkConfig) string {
+	elements := []string{appconfig.DefaultAgentName, filepath.Base(config.IOConfig.OrchestrationDirectory)}
+	elements = append(elements, config.FilePath...)
+	elements = append(elements, config.OutputName)
+	return cloudWatchLogStreamName(elements...)
+}
+
+// HttpSinkFactory posts the output to the local endpoint configured for the agent
+type HttpSinkFactory struct {
+}
+
+func (f HttpSinkFactory) Create(log log.T, config OutputSinkConfig) (iomodule.IOModule, error) {
+	endpoint := config.PluginConfig.OutputHttpEndpoint
+	if endpoint == "" {
+		return nil, fmt.Errorf("no endpoint is configured")
+	}
+	if err := validateLocalEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return iomodule.HttpEndpoint{
+		Url:        endpoint,
+		OutputPath: filepath.Join(config.OrchestrationDirectory, config.OutputName),
+		Redactor:   config.Redactor,
+	}, nil
+}
+
+// validateLocalEndpoint returns an error if the endpoint is not an http url of the instance itself,
+// the output of the commands must not leave the instance without the command asking for it
+func validateLocalEndpoint(endpoint string) error {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %v: %v", endpoint, err)
+	}
+	if endpointUrl.Scheme != "http" && endpointUrl.Scheme != "https" {
+		return fmt.Errorf("endpoint %v is not an http url", endpoint)
+	}
+	host := endpointUrl.Hostname()
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("endpoint %v is not a local endpoint", endpoint)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iohandler
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testSinkFactory creates sinks collecting the output, or sinks which panic or stop reading
+type testSinkFactory struct {
+	outputs map[string]*string
+	panics  bool
+	stops   bool
+	err     error
+}
+
+func (f testSinkFactory) Create(log log.T, config OutputSinkConfig) (iomodule.IOModule, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	output := new(string)
+	f.outputs[config.OutputName] = output
+	return testSink{output: output, panics: f.panics, stops: f.stops}, nil
+}
+
+type testSink struct {
+	output *string
+	panics bool
+	stops  bool
+}
+
+func (s testSink) Read(log log.T, reader *io.PipeReader) {
+	if s.stops {
+		return
+	}
+	if s.panics {
+		panic("sink failure")
+	}
+	content, _ := ioutil.ReadAll(reader)
+	*s.output = string(content)
+}
+
+func TestOutputSinks(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "iohandler")
+	assert.Nil(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	collected := testSinkFactory{outputs: make(map[string]*string)}
+	RegisterOutputSink("test", collected)
+	RegisterOutputSink("panics", testSinkFactory{outputs: make(map[string]*string), panics: true})
+	RegisterOutputSink("stops", testSinkFactory{outputs: make(map[string]*string), stops: true})
+	RegisterOutputSink("fails", testSinkFactory{err: assert.AnError})
+	defer func() {
+		outputSinksLock.Lock()
+		defer outputSinksLock.Unlock()
+		for _, name := range []string{"test", "panics", "stops", "fails"} {
+			delete(registeredOutputSinks, name)
+		}
+	}()
+
+	// the failing, stopping and unknown sinks affect neither the other sinks nor the output of the plugin
+	logger := log.NewMockLog()
+	logger.On("Warnf", mock.Anything, mock.Anything).Return(nil)
+	output := NewDefaultIOHandler(logger, contracts.IOConfiguration{
+		OrchestrationDirectory: orchestrationDir,
+		OutputSinks:            []string{"panics", "stops", "fails", "unknown", "test", appconfig.OutputSinkFile, "test"},
+	})
+	output.Init(logger, "plugin")
+	for i := 0; i < 1000; i++ {
+		output.AppendInfo(longMessage)
+	}
+	output.AppendError("sample error")
+	output.Close(logger)
+
+	assert.Equal(t, 2, len(collected.outputs))
+	assert.Equal(t, "sample error", *collected.outputs["stderr"])
+	assert.Equal(t, "sample error", output.GetStderr())
+	assert.NotEmpty(t, output.GetStdout())
+	content, err := ioutil.ReadFile(filepath.Join(orchestrationDir, "plugin", "stdout"))
+	assert.Nil(t, err)
+	assert.True(t, len(content) >= len(longMessage)*1000)
+	assert.Equal(t, string(content), *collected.outputs["stdout"])
+}
+
+// blockingSink does not read the output until it is released
+type blockingSink struct {
+	release chan struct{}
+}
+
+func (s blockingSink) Read(log log.T, reader *io.PipeReader) {
+	<-s.release
+	ioutil.ReadAll(reader)
+}
+
+func TestBlockingOutputSink(t *testing.T) {
+	defer func(queueLength int, closeTimeout time.Duration) {
+		outputSinkQueueLength = queueLength
+		outputSinkCloseTimeout = closeTimeout
+	}(outputSinkQueueLength, outputSinkCloseTimeout)
+	outputSinkQueueLength = 10
+	outputSinkCloseTimeout = 100 * time.Millisecond
+
+	release := make(chan struct{})
+	defer close(release)
+	logger := log.NewMockLog()
+	logger.On("Warnf", mock.Anything, mock.Anything).Return(nil)
+	var collected string
+	output := NewDefaultIOHandler(logger, contracts.IOConfiguration{})
+	output.droppedOutput = &droppedOutput{}
+	output.StdoutWriter = multiwriter.NewDocumentIOMultiWriter()
+	output.RegisterOutputSource(logger, output.StdoutWriter,
+		isolatedOutputSink{name: "blocking", module: blockingSink{release: release}, outputName: "stdout", dropped: output.droppedOutput},
+		isolatedOutputSink{name: "test", module: testSink{output: &collected}, outputName: "stdout", dropped: output.droppedOutput})
+
+	// the blocked sink holds up neither the output nor the other sink, it is disabled and no longer waited for
+	written := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			output.StdoutWriter.WriteString(longMessage)
+		}
+		output.Close(logger)
+		close(written)
+	}()
+	select {
+	case <-written:
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "writing the output was held up by the blocked sink")
+		return
+	}
+	assert.Equal(t, strings.Repeat(longMessage, 1000), collected)
+	logger.AssertCalled(t, "Warnf", "Output sink %v does not keep up with the output, the rest of the output is not written to it", []interface{}{"blocking"})
+	logger.AssertCalled(t, "Warnf", "Output sink %v did not finish within %v, it is no longer waited for", []interface{}{"blocking", outputSinkCloseTimeout})
+	// the plugin result tells which sink dropped part of the output
+	assert.Equal(t, "Part of the stdout output was not written to output sink blocking, it did not keep up with the output", output.GetStderr())
+}
+
+// slowSink reads the output after a delay
+type slowSink struct {
+	delay  time.Duration
+	output *string
+}
+
+func (s slowSink) Read(log log.T, reader *io.PipeReader) {
+	time.Sleep(s.delay)
+	content, _ := ioutil.ReadAll(reader)
+	*s.output = string(content)
+}
+
+func TestBackpressureOutputSink(t *testing.T) {
+	defer func(queueLength int, closeTimeout time.Duration) {
+		outputSinkQueueLength = queueLength
+		outputSinkCloseTimeout = closeTimeout
+	}(outputSinkQueueLength, outputSinkCloseTimeout)
+	outputSinkQueueLength = 10
+	outputSinkCloseTimeout = 100 * time.Millisecond
+
+	logger := log.NewMockLog()
+	var collected string
+	output := NewDefaultIOHandler(logger, contracts.IOConfiguration{})
+	output.droppedOutput = &droppedOutput{}
+	output.StdoutWriter = multiwriter.NewDocumentIOMultiWriter()
+	output.RegisterOutputSource(logger, output.StdoutWriter,
+		isolatedOutputSink{name: "slow", module: slowSink{delay: 300 * time.Millisecond, output: &collected},
+			backpressure: true, outputName: "stdout", dropped: output.droppedOutput})
+
+	// the output waits for the slow sink, which receives the whole output even after the close timeout
+	for i := 0; i < 1000; i++ {
+		output.StdoutWriter.WriteString(longMessage)
+	}
+	output.Close(logger)
+	assert.Equal(t, strings.Repeat(longMessage, 1000), collected)
+	assert.Empty(t, output.GetStderr())
+}
+
+func TestFileSinkAppliesBackpressure(t *testing.T) {
+	modules := createOutputSinks(log.NewMockLog(), OutputSinkConfig{
+		OutputName:   "stdout",
+		PluginConfig: PluginConfig{OutputSinks: []string{appconfig.OutputSinkFile, appconfig.OutputSinkSyslog}},
+	}, nil)
+	assert.Equal(t, 2, len(modules))
+	assert.True(t, modules[0].(isolatedOutputSink).backpressure)
+	assert.False(t, modules[1].(isolatedOutputSink).backpressure)
+}
+
+func TestOutputSinksOfDocument(t *testing.T) {
+	defer func() { agentConfig = func() (appconfig.SsmagentConfig, error) { return appconfig.Config(false) } }()
+	agentConfig = func() (appconfig.SsmagentConfig, error) {
+		config := appconfig.DefaultConfig()
+		config.Ssm.OutputSinks = []string{appconfig.OutputSinkFile, appconfig.OutputSinkSyslog}
+		return config, nil
+	}
+
+	assert.Equal(t, []string{appconfig.OutputSinkFile, appconfig.OutputSinkSyslog}, DocumentOutputConfig(contracts.IOConfiguration{}).OutputSinks)
+	assert.Equal(t, []string{appconfig.OutputSinkHttp}, DocumentOutputConfig(contracts.IOConfiguration{
+		OutputSinks: []string{appconfig.OutputSinkHttp},
+	}).OutputSinks)
+}
+
+func TestS3Sink(t *testing.T) {
+	config := OutputSinkConfig{OutputName: "stdout", OrchestrationDirectory: "dir", OutputS3KeyPrefix: "prefix/plugin"}
+	module, err := S3SinkFactory{}.Create(logger, config)
+	assert.Nil(t, err)
+	assert.Nil(t, module)
+
+	config.IOConfig.OutputS3BucketName = "bucket"
+	config.IOConfig.OutputS3Encryption.ServerSideEncryption = appconfig.S3ServerSideEncryptionAES256
+	module, err = S3SinkFactory{}.Create(logger, config)
+	assert.Nil(t, err)
+	assert.Equal(t, iomodule.S3{
+		FileName:               "stdout",
+		OrchestrationDirectory: "dir",
+		OutputS3BucketName:     "bucket",
+		OutputS3KeyPrefix:      "prefix/plugin",
+		OutputS3Encryption:     contracts.S3EncryptionConfiguration{ServerSideEncryption: appconfig.S3ServerSideEncryptionAES256},
+	}, module)
+}
+
+func TestCloudWatchLogsSink(t *testing.T) {
+	module, err := CloudWatchLogsSinkFactory{}.Create(logger, OutputSinkConfig{OutputName: "stdout", FilePath: []string{"awsrunShellScript"}})
+	assert.Nil(t, err)
+	assert.Nil(t, module)
+
+	module, err = CloudWatchLogsSinkFactory{}.Create(logger, OutputSinkConfig{
+		OutputName: "stderr",
+		FilePath:   []string{"aws:runShellScript", ""},
+		IOConfig: contracts.IOConfiguration{
+			CloudWatchConfig: contracts.CloudWatchConfiguration{
+				LogGroupName:    "/aws/ssm/AWS-RunShellScript",
+				LogStreamPrefix: "cmd-1/i-1",
+			},
+		},
+	})
+	assert.Nil(t, err)
+	cloudWatchLogs := module.(iomodule.CloudWatchLogs)
+	assert.Equal(t, "/aws/ssm/AWS-RunShellScript", cloudWatchLogs.LogGroupName)
+	assert.Equal(t, "cmd-1/i-1/aws-runShellScript/stderr", cloudWatchLogs.LogStreamName)
+}
+
+func TestSyslogSink(t *testing.T) {
+	config := OutputSinkConfig{
+		OutputName:   "stderr",
+		FilePath:     []string{"aws:runShellScript"},
+		IOConfig:     contracts.IOConfiguration{OrchestrationDirectory: filepath.Join("orchestration", "cmd-1")},
+		PluginConfig: PluginConfig{StdoutFileName: "stdout", StderrFileName: "stderr"},
+	}
+	module, err := SyslogSinkFactory{}.Create(logger, config)
+	assert.Nil(t, err)
+	assert.Equal(t, iomodule.Syslog{Tag: "amazon-ssm-agent/cmd-1/aws-runShellScript/stderr", Error: true}, module)
+}
+
+func TestHttpSink(t *testing.T) {
+	config := OutputSinkConfig{OutputName: "stdout", OrchestrationDirectory: "dir"}
+	_, err := HttpSinkFactory{}.Create(logger, config)
+	assert.Error(t, err)
+
+	for _, endpoint := range []string{"http://example.com/output", "ftp://localhost/output", "http://10.0.0.1:8080/", "::"} {
+		config.PluginConfig.OutputHttpEndpoint = endpoint
+		_, err = HttpSinkFactory{}.Create(logger, config)
+		assert.Error(t, err, endpoint)
+	}
+	for _, endpoint := range []string{"http://localhost:8080/output", "https://127.0.0.1/output", "http://[::1]:9000"} {
+		config.PluginConfig.OutputHttpEndpoint = endpoint
+		module, err := HttpSinkFactory{}.Create(logger, config)
+		assert.Nil(t, err, endpoint)
+		assert.Equal(t, iomodule.HttpEndpoint{Url: endpoint, OutputPath: filepath.Join("dir", "stdout")}, module)
+	}
+}
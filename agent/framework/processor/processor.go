@@ -24,12 +24,14 @@ import (
 	"path/filepath"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc"
+	"github.com/aws/amazon-ssm-agent/agent/history"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/rebooter"
@@ -39,6 +41,12 @@ import (
 
 type ExecuterCreator func(ctx context.T) executer.Executer
 
+// newAuditor returns the auditor of the documents executed by the processor
+var newAuditor = audit.NewAuditor
+
+// appendHistory records the outcome of the documents executed by the processor in the execution history
+var appendHistory = history.Append
+
 const (
 
 	// hardstopTimeout is the time before the processor will be shutdown during a hardstop
@@ -309,6 +317,9 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 	messageID := docState.DocumentInformation.MessageID
 	e := executerCreator(context)
 	docStore := executer.NewDocumentFileStore(context, instanceID, documentID, appconfig.DefaultLocationOfCurrent, docState, docMgr)
+	auditor := newAuditor(context.AppConfig())
+	startTime := time.Now()
+	auditor.Audit(log, audit.DocumentStarted(docState))
 	statusChan := e.Run(
 		cancelFlag,
 		&docStore,
@@ -332,11 +343,16 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 	if final == nil || final.LastPlugin != "" {
 		log.Infof("document %v still in progress, shutting down...", messageID)
 		return
-	} else if final.Status == contracts.ResultStatusSuccessAndReboot {
+	}
+	auditor.Audit(log, audit.DocumentFinished(docState, *final, startTime))
+	if final.Status == contracts.ResultStatusSuccessAndReboot {
 		log.Infof("document %v requested reboot, need to resume", messageID)
 		rebooter.RequestPendingReboot(context.Log())
 		return
 	}
+	if err := appendHistory(history.NewEntry(docState, *final)); err != nil {
+		log.Errorf("Failed to record document %v in the execution history: %v", documentID, err)
+	}
 
 	//persist : commands execution in completed folder (terminal state folder)
 	log.Infof("execution of %v is over. Removing interimState from current folder", messageID)
@@ -20,10 +20,12 @@ import (
 	"fmt"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/mock"
+	"github.com/aws/amazon-ssm-agent/agent/history"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
@@ -94,6 +96,103 @@ func TestEngineProcessor_Stop(t *testing.T) {
 	cancelCommandPoolMock.AssertExpectations(t)
 }
 
+func init() {
+	// keep the tests from writing to the execution history of the agent
+	appendHistory = func(entry history.Entry) error { return nil }
+}
+
+// recordingAuditor keeps the audit records
+type recordingAuditor struct {
+	records []audit.Record
+}
+
+func (a *recordingAuditor) Audit(log log.T, record audit.Record) {
+	a.records = append(a.records, record)
+}
+
+func TestProcessCommandAudit(t *testing.T) {
+	auditor := &recordingAuditor{}
+	defer func() { newAuditor = audit.NewAuditor }()
+	newAuditor = func(config appconfig.SsmagentConfig) audit.Auditor { return auditor }
+
+	ctx := context.NewMockDefault()
+	docState := contracts.DocumentState{}
+	docState.DocumentInformation.MessageID = "messageID"
+	docState.DocumentInformation.InstanceID = "instanceID"
+	docState.DocumentInformation.DocumentID = "documentID"
+	docState.DocumentInformation.CommandID = "commandID"
+	docState.InstancePluginsInformation = []contracts.PluginState{{Id: "plugin0", Name: "aws:runShellScript"}}
+	executerMock := executermocks.NewMockExecuter()
+	resChan := make(chan contracts.DocumentResult)
+	statusChan := make(chan contracts.DocumentResult)
+	cancelFlag := task.NewChanneledCancelFlag()
+	executerMock.On("Run", cancelFlag, mock.AnythingOfType("*executer.DocumentFileStore")).Return(statusChan)
+	creator := func(ctx context.T) executer.Executer {
+		return executerMock
+	}
+	go func() {
+		statusChan <- contracts.DocumentResult{
+			Status:        contracts.ResultStatusSuccess,
+			PluginResults: map[string]*contracts.PluginResult{"plugin0": {Status: contracts.ResultStatusSuccess}},
+		}
+		<-resChan
+		close(statusChan)
+	}()
+	docMock := new(DocumentMgrMock)
+	docMock.On("MoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
+	docMock.On("RemoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfCurrent)
+	processCommand(ctx, creator, cancelFlag, resChan, &docState, docMock)
+
+	assert.Equal(t, 2, len(auditor.records))
+	assert.Equal(t, audit.EventDocumentStarted, auditor.records[0].Event)
+	assert.Equal(t, "commandID", auditor.records[0].CommandID)
+	assert.Equal(t, audit.EventDocumentFinished, auditor.records[1].Event)
+	assert.Equal(t, "Success", auditor.records[1].Status)
+	assert.Equal(t, 0, *auditor.records[1].Steps[0].ExitCode)
+}
+
+func TestProcessCommandHistory(t *testing.T) {
+	var entries []history.Entry
+	defer func(original func(entry history.Entry) error) { appendHistory = original }(appendHistory)
+	appendHistory = func(entry history.Entry) error {
+		entries = append(entries, entry)
+		return nil
+	}
+
+	ctx := context.NewMockDefault()
+	docState := contracts.DocumentState{}
+	docState.DocumentInformation.MessageID = "messageID"
+	docState.DocumentInformation.InstanceID = "instanceID"
+	docState.DocumentInformation.DocumentID = "documentID"
+	docState.DocumentInformation.DocumentName = "AWS-RunShellScript"
+	docState.DocumentInformation.DocumentHash = "documentHash"
+	docState.DocumentInformation.ParametersHash = "parametersHash"
+	executerMock := executermocks.NewMockExecuter()
+	resChan := make(chan contracts.DocumentResult)
+	statusChan := make(chan contracts.DocumentResult)
+	cancelFlag := task.NewChanneledCancelFlag()
+	executerMock.On("Run", cancelFlag, mock.AnythingOfType("*executer.DocumentFileStore")).Return(statusChan)
+	creator := func(ctx context.T) executer.Executer {
+		return executerMock
+	}
+	go func() {
+		statusChan <- contracts.DocumentResult{Status: contracts.ResultStatusFailed}
+		<-resChan
+		close(statusChan)
+	}()
+	docMock := new(DocumentMgrMock)
+	docMock.On("MoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
+	docMock.On("RemoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfCurrent)
+	processCommand(ctx, creator, cancelFlag, resChan, &docState, docMock)
+
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "documentID", entries[0].DocumentID)
+	assert.Equal(t, "AWS-RunShellScript", entries[0].DocumentName)
+	assert.Equal(t, "documentHash", entries[0].DocumentHash)
+	assert.Equal(t, "parametersHash", entries[0].ParametersHash)
+	assert.Equal(t, "Failed", entries[0].Status)
+}
+
 //TODO add shutdown and reboot test once we encapsulate docmanager
 func TestProcessCommand(t *testing.T) {
 	ctx := context.NewMockDefault()
@@ -15,11 +15,13 @@
 package runpluginutil
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
@@ -27,7 +29,9 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/pluginpolicy"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
@@ -41,6 +45,14 @@ type T interface {
 	Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler)
 }
 
+// Checker is implemented by the plugins which can check whether the system complies with their configuration
+// without changing it. The check succeeds when the system is compliant and fails otherwise.
+// It is optional rather than part of T since most plugins cannot tell whether their configuration is applied
+// without applying it, the check-only steps of the plugins which do not implement it are skipped.
+type Checker interface {
+	Check(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler)
+}
+
 type Factory interface {
 	Create(context context.T) (T, error)
 }
@@ -50,6 +62,12 @@ type PluginRegistry map[string]Factory
 
 var SSMPluginRegistry PluginRegistry
 
+// newAuditor returns the auditor of the plugins run by RunPlugins
+var newAuditor = audit.NewAuditor
+
+// currentPluginPolicy returns the plugin policy the steps run by RunPlugins are evaluated against
+var currentPluginPolicy = pluginpolicy.Current
+
 // allPlugins is the list of all known plugins.
 // This allows us to differentiate between the case where a document asks for a plugin that exists but isn't supported on this platform
 // and the case where a plugin name isn't known at all to this version of the agent (and the user should probably upgrade their agent)
@@ -89,6 +107,9 @@ func RunPlugins(
 ) (pluginOutputs map[string]*contracts.PluginResult) {
 
 	pluginOutputs = make(map[string]*contracts.PluginResult)
+	auditor := newAuditor(context.AppConfig())
+	// the policy is read for each document so that its changes apply without restarting the agent
+	policy := currentPluginPolicy(context.Log())
 
 	for _, pluginState := range plugins {
 		pluginID := pluginState.Id     // the identifier of the plugin
@@ -123,6 +144,7 @@ func RunPlugins(
 		}
 
 		context.Log().Debugf("Executing plugin - %v", pluginName)
+		startTime := time.Now()
 
 		// populate plugin start time and status
 		configuration := pluginState.Configuration
@@ -149,11 +171,15 @@ func RunPlugins(
 			isSupported,
 			pluginHandlerFound,
 			configuration.IsPreconditionEnabled,
-			configuration.Preconditions)
+			configuration.Preconditions,
+			policy,
+			configuration.DocumentName,
+			configuration.DocumentHash)
 
 		switch operation {
 		case executeStep:
 			context.Log().Infof("Running plugin %s", pluginName)
+			auditor.Audit(context.Log(), audit.PluginStarted(configuration))
 			r = runPlugin(context, p, pluginName, configuration, cancelFlag, ioConfig)
 			pluginOutputs[pluginID].Code = r.Code
 			pluginOutputs[pluginID].Status = r.Status
@@ -168,7 +194,7 @@ func RunPlugins(
 			pluginOutputs[pluginID].Code = 0
 			pluginOutputs[pluginID].Output = logMessage
 		case failStep:
-			err := fmt.Errorf(logMessage)
+			err := errors.New(logMessage)
 			pluginOutputs[pluginID].Status = contracts.ResultStatusFailed
 			pluginOutputs[pluginID].Error = err
 			context.Log().Error(err)
@@ -181,6 +207,7 @@ func RunPlugins(
 
 		// set end time.
 		pluginOutputs[pluginID].EndDateTime = time.Now()
+		auditor.Audit(context.Log(), audit.PluginFinished(configuration, *pluginOutputs[pluginID], startTime))
 		context.Log().Infof("Sending plugin %v completion message", pluginID)
 		// send to buffer channel, guaranteed to not block since buffer size is plugin number
 		resChan <- *pluginOutputs[pluginID]
@@ -206,6 +233,9 @@ func runPlugin(
 	context = context.With("[pluginName=" + pluginName + "]")
 
 	log := context.Log()
+	docRedactor := redactor.ForDocument(config.BookKeepingFileName)
+	// scrub the secrets of the document from the result, it is persisted and sent back to the service
+	defer redactPluginResult(docRedactor, &res)
 	defer func() {
 		// recover in case the plugin panics
 		// this should handle some kind of seg fault errors.
@@ -231,6 +261,7 @@ func runPlugin(
 	defer func() { res.EndDateTime = time.Now() }()
 
 	output := iohandler.NewDefaultIOHandler(log, ioConfig)
+	output.SetRedactor(docRedactor)
 	//check if properties is a list. If true, then unroll
 	switch config.Properties.(type) {
 	case []interface{}:
@@ -242,6 +273,7 @@ func runPlugin(
 		for _, prop := range properties {
 			config.Properties = prop
 			propOutput := iohandler.NewDefaultIOHandler(log, ioConfig)
+			propOutput.SetRedactor(docRedactor)
 			executePlugin(context, p, pluginName, config, cancelFlag, propOutput)
 			output.Merge(log, propOutput)
 		}
@@ -249,16 +281,55 @@ func runPlugin(
 	default:
 		executePlugin(context, p, pluginName, config, cancelFlag, output)
 	}
-	pluginConfig := iohandler.DefaultOutputConfig()
+	pluginConfig := iohandler.DocumentOutputConfig(output.GetIOConfig())
 
 	res.Code = output.GetExitCode()
 	res.Status = output.GetStatus()
 	res.Output = output.GetOutput()
-	res.StandardOutput = pluginutil.StringPrefix(output.GetStdout(), pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
-	res.StandardError = pluginutil.StringPrefix(output.GetStderr(), pluginConfig.MaxStderrLength, pluginConfig.OutputTruncatedSuffix)
+	res.StandardOutput = pluginConfig.TruncateStdout(output.GetStdout())
+	res.StandardError = pluginConfig.TruncateStderr(output.GetStderr())
 	return
 }
 
+// redactPluginResult scrubs the secrets of the document from the plugin result.
+func redactPluginResult(docRedactor *redactor.Redactor, res *contracts.PluginResult) {
+	if docRedactor.IsEmpty() {
+		return
+	}
+	res.StandardOutput = docRedactor.Redact(res.StandardOutput)
+	res.StandardError = docRedactor.Redact(res.StandardError)
+	switch output := res.Output.(type) {
+	case string:
+		res.Output = docRedactor.Redact(output)
+	case contracts.StructuredOutput:
+		res.Output = redactValue(docRedactor, map[string]interface{}(output))
+	}
+	if res.Error != nil {
+		res.Error = errors.New(docRedactor.Redact(res.Error.Error()))
+	}
+}
+
+// redactValue scrubs the secrets of the document from the strings of a structured output.
+func redactValue(docRedactor *redactor.Redactor, value interface{}) interface{} {
+	switch typed := value.(type) {
+	case string:
+		return docRedactor.Redact(typed)
+	case map[string]interface{}:
+		redacted := make(contracts.StructuredOutput, len(typed))
+		for key, item := range typed {
+			redacted[key] = redactValue(docRedactor, item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(typed))
+		for i, item := range typed {
+			redacted[i] = redactValue(docRedactor, item)
+		}
+		return redacted
+	}
+	return value
+}
+
 func executePlugin(context context.T,
 	p T,
 	pluginName string,
@@ -286,7 +357,16 @@ func executePlugin(context context.T,
 		// Create the output object and execute the plugin
 		defer output.Close(log)
 		output.Init(log, pluginName, propID)
-		p.Execute(context, config, cancelFlag, output)
+		if !config.CheckOnly {
+			p.Execute(context, config, cancelFlag, output)
+		} else if checker, ok := p.(Checker); ok {
+			checker.Check(context, config, cancelFlag, output)
+		} else {
+			// the step is left out of the compliance of the document
+			log.Infof("Plugin %v does not support check-only mode, skipping step", pluginName)
+			output.AppendInfof("Plugin %v does not support check-only mode, step was not checked", pluginName)
+			output.SetStatus(contracts.ResultStatusSkipped)
+		}
 	}
 }
 
@@ -297,7 +377,7 @@ func GetPropertyName(rawPluginInput interface{}) (propertyName string, err error
 	return
 }
 
-// Checks plugin compatibility and step precondition and returns if it should be executed, skipped or failed
+// Checks plugin compatibility, step precondition and plugin policy and returns if it should be executed, skipped or failed
 func getStepExecutionOperation(
 	log log.T,
 	pluginName string,
@@ -307,6 +387,9 @@ func getStepExecutionOperation(
 	isPluginHandlerFound bool,
 	isPreconditionEnabled bool,
 	preconditions map[string][]string,
+	policy *pluginpolicy.Policy,
+	documentName string,
+	documentHash string,
 ) (string, string) {
 	log.Debugf("isSupported flag = %t", isSupported)
 	log.Debugf("isPluginHandlerFound flag = %t", isPluginHandlerFound)
@@ -335,7 +418,7 @@ func getStepExecutionOperation(
 				pluginName,
 				pluginId)
 		} else {
-			return executeStep, ""
+			return evaluatePluginPolicy(policy, pluginName, pluginId, documentName, documentHash)
 		}
 	} else {
 		// 2.2 or higher (cross-platform) document
@@ -349,7 +432,7 @@ func getStepExecutionOperation(
 					pluginName,
 					pluginId)
 			} else if isSupported && isPluginHandlerFound {
-				return executeStep, ""
+				return evaluatePluginPolicy(policy, pluginName, pluginId, documentName, documentHash)
 			} else {
 				return skipStep, fmt.Sprintf(
 					"Step execution skipped due to incompatible platform. Step name: %s",
@@ -375,12 +458,30 @@ func getStepExecutionOperation(
 					strings.Join(unrecognizedPreconditionList, ", "),
 					pluginId)
 			} else {
-				return executeStep, ""
+				return evaluatePluginPolicy(policy, pluginName, pluginId, documentName, documentHash)
 			}
 		}
 	}
 }
 
+// Evaluate the plugin policy for a step which can be executed, the steps it denies fail
+func evaluatePluginPolicy(
+	policy *pluginpolicy.Policy,
+	pluginName string,
+	pluginId string,
+	documentName string,
+	documentHash string,
+) (string, string) {
+	step := pluginpolicy.Step{PluginName: pluginName, DocumentName: documentName, DocumentHash: documentHash}
+	if err := policy.Evaluate(step); err != nil {
+		return failStep, fmt.Sprintf(
+			"Step execution denied by the plugin policy, %v. Step name: %s",
+			err,
+			pluginId)
+	}
+	return executeStep, ""
+}
+
 // Evaluate precondition and return precondition result and unrecognized preconditions (if any)
 func evaluatePreconditions(
 	log log.T,
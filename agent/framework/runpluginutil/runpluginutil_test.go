@@ -16,12 +16,19 @@ package runpluginutil
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/pluginpolicy"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -36,6 +43,11 @@ const (
 
 var origIsSupported func(log log.T, pluginName string) (isKnown bool, isSupported bool, message string)
 
+func init() {
+	// the tests do not depend on the plugin policy of the host
+	currentPluginPolicy = func(log.T) *pluginpolicy.Policy { return &pluginpolicy.Policy{} }
+}
+
 func setIsSupportedMock() {
 	origIsSupported = isSupportedPlugin
 	isSupportedPlugin = func(log log.T, pluginName string) (isKnown bool, isSupported bool, message string) {
@@ -54,6 +66,13 @@ func restoreIsSupported() {
 	isSupportedPlugin = origIsSupported
 }
 
+// newTestIOConfig writes the output of the plugins to a temporary directory, removed by the caller
+func newTestIOConfig(t *testing.T) contracts.IOConfiguration {
+	orchestrationDir, err := ioutil.TempDir("", "runpluginutil")
+	assert.Nil(t, err)
+	return contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+}
+
 // TestRunPlugins tests that RunPluginsWithRegistry calls all the expected plugins.
 func TestRunPluginsWithNewDocument(t *testing.T) {
 	setIsSupportedMock()
@@ -68,7 +87,8 @@ func TestRunPluginsWithNewDocument(t *testing.T) {
 	ctx := context.NewMockDefault()
 	defaultTime := time.Now()
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	for index, name := range pluginNames {
 
@@ -158,7 +178,8 @@ func TestRunPluginsWithMissingPluginHandler(t *testing.T) {
 	defaultTime := time.Now()
 	defaultOutput := ""
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	for index, name := range pluginNames {
 
@@ -244,7 +265,8 @@ func TestRunPluginsWithCancelFlagShutdown(t *testing.T) {
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 	ctx := context.NewMockDefault()
 	defaultTime := time.Now()
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	for index, name := range pluginNames {
 		plugins[name] = new(PluginMock)
@@ -308,7 +330,8 @@ func TestRunPluginsWithInProgressDocuments(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	plugins := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 	ctx := context.NewMockDefault()
@@ -406,7 +429,8 @@ func TestRunPluginsWithDuplicatePluginType(t *testing.T) {
 	// create an instance of our test object
 	plugin := new(PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	var cancelFlag task.CancelFlag
 	ctx := context.NewMockDefault()
@@ -491,7 +515,8 @@ func TestRunPluginsWithCompatiblePrecondition(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -581,7 +606,8 @@ func TestRunPluginsWithCompatiblePreconditionWithValueFirst(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -669,7 +695,8 @@ func TestRunPluginsWithIncompatiblePrecondition(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -758,7 +785,8 @@ func TestRunPluginsWithCompatiblePreconditionButMissingPluginHandler(t *testing.
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -845,7 +873,8 @@ func TestRunPluginsWithMoreThanOnePrecondition(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -943,7 +972,8 @@ func TestRunPluginsWithUnrecognizedPreconditionOperator(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -1039,7 +1069,8 @@ func TestRunPluginsWithUnrecognizedPreconditionOperand(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -1136,7 +1167,8 @@ func TestRunPluginsWithUnrecognizedPreconditionDuplicateVariable(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -1233,7 +1265,8 @@ func TestRunPluginsWithMoreThanTwoPreconditionOperands(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -1330,7 +1363,8 @@ func TestRunPluginsWithUnknownPlugin(t *testing.T) {
 	pluginResults := make(map[string]*contracts.PluginResult)
 	pluginInstances := make(map[string]*PluginMock)
 	pluginRegistry := PluginRegistry{}
-	ioConfig := contracts.IOConfiguration{}
+	ioConfig := newTestIOConfig(t)
+	defer os.RemoveAll(ioConfig.OrchestrationDirectory)
 
 	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
 
@@ -1427,3 +1461,251 @@ func TestRunPluginsWithUnknownPlugin(t *testing.T) {
 
 	assert.Equal(t, pluginResults, outputs)
 }
+
+// TestRunPluginsWithSecureParameters tests that the references to secure parameters reach the plugin as is and that the
+// secrets resolved while the plugin runs are scrubbed from the results.
+func TestRunPluginsWithSecureParameters(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	defer redactor.Release("documentID")
+
+	orchestrationDir, err := ioutil.TempDir("", "runpluginutil")
+	assert.Nil(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	ctx := context.NewMockDefault()
+	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+	pluginState := contracts.PluginState{
+		Name: testPlugin1,
+		Id:   "step1",
+		Configuration: contracts.Configuration{
+			PluginID:            "step1",
+			PluginName:          testPlugin1,
+			BookKeepingFileName: "documentID",
+			Properties:          map[string]interface{}{"runCommand": "echo {{ssm-secure:password}}"},
+		},
+	}
+
+	plugin := new(PluginMock)
+	plugin.On("Execute", mock.Anything, pluginState.Configuration, cancelFlag, mock.Anything).Run(func(args mock.Arguments) {
+		// the plugin resolves the secret with the redactor of the document
+		redactor.ForDocument("documentID").Register("s3cr3t")
+		output := args.Get(3).(iohandler.IOHandler)
+		output.AppendInfo("password is s3cr3t")
+		output.MarkAsFailed(fmt.Errorf("failed with s3cr3t"))
+	}).Return()
+	pluginFactory := new(PluginFactoryMock)
+	pluginFactory.On("Create", mock.Anything).Return(plugin, nil)
+	pluginRegistry := PluginRegistry{testPlugin1: pluginFactory}
+
+	ch := make(chan contracts.PluginResult, 1)
+	outputs := RunPlugins(ctx, []contracts.PluginState{pluginState}, ioConfig, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	plugin.AssertExpectations(t)
+	assert.Equal(t, "password is ****", outputs["step1"].StandardOutput)
+	assert.Equal(t, "failed with ****", outputs["step1"].StandardError)
+	assert.NotContains(t, outputs["step1"].Output, "s3cr3t")
+	assert.Equal(t, "echo {{ssm-secure:password}}", pluginState.Configuration.Properties.(map[string]interface{})["runCommand"])
+}
+
+func TestRunPluginsInCheckOnlyMode(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+
+	orchestrationDir, err := ioutil.TempDir("", "runpluginutil")
+	assert.Nil(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	ctx := context.NewMockDefault()
+	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+	pluginStates := []contracts.PluginState{}
+	for _, name := range []string{testPlugin1, testPlugin2} {
+		pluginStates = append(pluginStates, contracts.PluginState{
+			Name: name,
+			Id:   name,
+			Configuration: contracts.Configuration{
+				PluginID:   name,
+				PluginName: name,
+				Properties: map[string]interface{}{"id": name},
+				CheckOnly:  true,
+			},
+		})
+	}
+
+	// the first plugin supports the check-only mode and finds the system out of compliance
+	checker := new(CheckerPluginMock)
+	checker.On("Check", mock.Anything, pluginStates[0].Configuration, cancelFlag, mock.Anything).Run(func(args mock.Arguments) {
+		output := args.Get(3).(iohandler.IOHandler)
+		output.SetExitCode(1)
+		output.SetStatus(contracts.ResultStatusFailed)
+	}).Return()
+	checkerFactory := new(PluginFactoryMock)
+	checkerFactory.On("Create", mock.Anything).Return(checker, nil)
+
+	// the second plugin only executes
+	plugin := new(PluginMock)
+	pluginFactory := new(PluginFactoryMock)
+	pluginFactory.On("Create", mock.Anything).Return(plugin, nil)
+	pluginRegistry := PluginRegistry{testPlugin1: checkerFactory, testPlugin2: pluginFactory}
+
+	ch := make(chan contracts.PluginResult, 2)
+	outputs := RunPlugins(ctx, pluginStates, ioConfig, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	checker.AssertExpectations(t)
+	checker.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	plugin.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, contracts.ResultStatusFailed, outputs[testPlugin1].Status)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs[testPlugin2].Status)
+	assert.Contains(t, outputs[testPlugin2].StandardOutput, "does not support check-only mode")
+}
+
+// recordingAuditor keeps the audit records
+type recordingAuditor struct {
+	records []audit.Record
+}
+
+func (a *recordingAuditor) Audit(log log.T, record audit.Record) {
+	a.records = append(a.records, record)
+}
+
+func TestRunPluginsAudit(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	auditor := &recordingAuditor{}
+	defer func() { newAuditor = audit.NewAuditor }()
+	newAuditor = func(config appconfig.SsmagentConfig) audit.Auditor { return auditor }
+
+	orchestrationDir, err := ioutil.TempDir("", "runpluginutil")
+	assert.Nil(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	ctx := context.NewMockDefault()
+	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+	pluginStates := []contracts.PluginState{}
+	for _, name := range []string{testPlugin1, testUnknownPlugin} {
+		pluginStates = append(pluginStates, contracts.PluginState{
+			Name:          name,
+			Id:            name,
+			Configuration: contracts.Configuration{PluginID: name, PluginName: name, BookKeepingFileName: "documentID"},
+		})
+	}
+	plugin := new(PluginMock)
+	plugin.On("Execute", mock.Anything, pluginStates[0].Configuration, cancelFlag, mock.Anything).Run(func(args mock.Arguments) {
+		output := args.Get(3).(iohandler.IOHandler)
+		output.SetExitCode(3)
+		output.SetStatus(contracts.ResultStatusFailed)
+	}).Return()
+	pluginFactory := new(PluginFactoryMock)
+	pluginFactory.On("Create", mock.Anything).Return(plugin, nil)
+	pluginRegistry := PluginRegistry{testPlugin1: pluginFactory}
+
+	ch := make(chan contracts.PluginResult, 2)
+	RunPlugins(ctx, pluginStates, ioConfig, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	// the plugin which is not run only has its finished record
+	var events []string
+	for _, record := range auditor.records {
+		events = append(events, record.Event+" "+record.PluginID+" "+record.Status)
+		assert.Equal(t, "documentID", record.DocumentID)
+	}
+	assert.Equal(t, []string{
+		"PluginStarted plugin1 ",
+		"PluginFinished plugin1 Failed",
+		"PluginFinished plugin3 Failed",
+	}, events)
+	assert.Equal(t, 3, *auditor.records[1].ExitCode)
+}
+
+func TestRunPluginsDeniedByPolicy(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	defer func() { currentPluginPolicy = func(log.T) *pluginpolicy.Policy { return &pluginpolicy.Policy{} } }()
+	currentPluginPolicy = func(log.T) *pluginpolicy.Policy {
+		return &pluginpolicy.Policy{DeniedPlugins: []string{testPlugin2}}
+	}
+
+	orchestrationDir, err := ioutil.TempDir("", "runpluginutil")
+	assert.Nil(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	ctx := context.NewMockDefault()
+	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+	pluginStates := []contracts.PluginState{}
+	pluginRegistry := PluginRegistry{}
+	plugins := make(map[string]*PluginMock)
+	for _, name := range []string{testPlugin1, testPlugin2} {
+		pluginStates = append(pluginStates, contracts.PluginState{
+			Name:          name,
+			Id:            name,
+			Configuration: contracts.Configuration{PluginID: name, PluginName: name, Properties: map[string]interface{}{"id": name}},
+		})
+		plugins[name] = new(PluginMock)
+		pluginFactory := new(PluginFactoryMock)
+		pluginFactory.On("Create", mock.Anything).Return(plugins[name], nil)
+		pluginRegistry[name] = pluginFactory
+	}
+	plugins[testPlugin1].On("Execute", mock.Anything, pluginStates[0].Configuration, cancelFlag, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(3).(iohandler.IOHandler).SetStatus(contracts.ResultStatusSuccess)
+	}).Return()
+
+	ch := make(chan contracts.PluginResult, 2)
+	outputs := RunPlugins(ctx, pluginStates, ioConfig, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	plugins[testPlugin1].AssertExpectations(t)
+	plugins[testPlugin2].AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testPlugin1].Status)
+	assert.Equal(t, contracts.ResultStatusFailed, outputs[testPlugin2].Status)
+	assert.Contains(t, outputs[testPlugin2].Error.Error(), "denied by the plugin policy")
+}
+
+func TestGetStepExecutionOperationWithPolicy(t *testing.T) {
+	policy := &pluginpolicy.Policy{
+		RunShellScript: pluginpolicy.DocumentRestriction{AllowedDocumentNames: []string{"AWS-RunShellScript"}},
+	}
+	shellScript := appconfig.PluginNameAwsRunShellScript
+	linux := map[string][]string{"StringEquals": {"platformType", "Linux"}}
+
+	operation, _ := getStepExecutionOperation(log.NewMockLog(), shellScript, "run", true, true, true, false, nil, policy, "AWS-RunShellScript", "")
+	assert.Equal(t, executeStep, operation)
+
+	operation, message := getStepExecutionOperation(log.NewMockLog(), shellScript, "run", true, true, true, false, nil, policy, "Custom", "hash")
+	assert.Equal(t, failStep, operation)
+	assert.Contains(t, message, "Step name: run")
+
+	operation, _ = getStepExecutionOperation(log.NewMockLog(), shellScript, "run", true, true, true, true, nil, policy, "Custom", "hash")
+	assert.Equal(t, failStep, operation)
+
+	// the steps skipped on this platform are not evaluated
+	operation, _ = getStepExecutionOperation(log.NewMockLog(), shellScript, "run", true, false, true, true, nil, policy, "Custom", "hash")
+	assert.Equal(t, skipStep, operation)
+	operation, _ = getStepExecutionOperation(log.NewMockLog(), shellScript, "run", true, true, true, true, linux, policy, "Custom", "hash")
+	assert.NotEqual(t, executeStep, operation)
+}
+
+func TestRedactStructuredOutput(t *testing.T) {
+	docRedactor := redactor.New()
+	docRedactor.Register("s3cr3t")
+
+	res := contracts.PluginResult{
+		Output: contracts.StructuredOutput{
+			"password": "s3cr3t",
+			"users":    []interface{}{"admin", map[string]interface{}{"token": "token-s3cr3t"}},
+			"count":    float64(2),
+		},
+	}
+	redactPluginResult(docRedactor, &res)
+
+	assert.Equal(t, contracts.StructuredOutput{
+		"password": "****",
+		"users":    []interface{}{"admin", contracts.StructuredOutput{"token": "token-****"}},
+		"count":    float64(2),
+	}, res.Output)
+}
@@ -0,0 +1,320 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package history keeps the local execution history of the documents executed by the agent.
+//
+// The history is an append-only file with one JSON entry per line. Each entry carries the SHA-256 of the previous
+// entry and its own SHA-256, computed over the entry encoded without its hash, so that modifying, removing or
+// reordering entries breaks the chain. The sequence and the hash of the last entry are also kept in a separate head
+// file, which anchors the chain so that removing the last entries or the whole history is detected too. Unlike the document states and the orchestration directories, the history is
+// never removed by the retention of the agent.
+package history
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+// maxEntrySize is the size of the longest entry read from the history
+const maxEntrySize = 1024 * 1024
+
+// headSuffix is appended to the path of the history to name its head file
+const headSuffix = ".head"
+
+// Entry is the record of the execution of a document in the history
+type Entry struct {
+	Sequence        int       `json:"sequence"`
+	Time            time.Time `json:"time"`
+	DocumentID      string    `json:"documentId"`
+	CommandID       string    `json:"commandId,omitempty"`
+	AssociationID   string    `json:"associationId,omitempty"`
+	DocumentName    string    `json:"documentName,omitempty"`
+	DocumentVersion string    `json:"documentVersion,omitempty"`
+	DocumentHash    string    `json:"documentHash,omitempty"`
+	ParametersHash  string    `json:"parametersHash,omitempty"`
+	Status          string    `json:"status"`
+	PreviousHash    string    `json:"previousHash"`
+	Hash            string    `json:"hash,omitempty"`
+}
+
+// NewEntry returns the entry recording the outcome of the execution of a document
+func NewEntry(docState *contracts.DocumentState, result contracts.DocumentResult) Entry {
+	info := docState.DocumentInformation
+	return Entry{
+		Time:            time.Now().UTC(),
+		DocumentID:      info.DocumentID,
+		CommandID:       info.CommandID,
+		AssociationID:   info.AssociationID,
+		DocumentName:    info.DocumentName,
+		DocumentVersion: info.DocumentVersion,
+		DocumentHash:    info.DocumentHash,
+		ParametersHash:  info.ParametersHash,
+		Status:          string(result.Status),
+	}
+}
+
+// computeHash returns the SHA-256 of the entry encoded without its hash
+func (entry Entry) computeHash() (string, error) {
+	entry.Hash = ""
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// head is the content of the head file, it identifies the last entry of the history
+type head struct {
+	Sequence int    `json:"sequence"`
+	Hash     string `json:"hash"`
+}
+
+// readHead returns the head of the history, or nil if the history has no head yet
+func readHead(path string) (*head, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var h head
+	if err = json.Unmarshal(content, &h); err != nil {
+		return nil, fmt.Errorf("%v is not a history head: %v", path, err)
+	}
+	return &h, nil
+}
+
+// writeHead replaces the head of the history with the given entry
+func writeHead(path string, entry Entry) error {
+	content, err := json.Marshal(head{Sequence: entry.Sequence, Hash: entry.Hash})
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(path, content, appconfig.ReadWriteAccess)
+}
+
+// Filter selects the entries returned by Query, the zero value selects all the entries
+type Filter struct {
+	// StartTime and EndTime bound the time of the entries, when set
+	StartTime time.Time
+	EndTime   time.Time
+	// DocumentName selects the executions of a document, when set
+	DocumentName string
+}
+
+// matches returns true if the entry passes the filter
+func (filter Filter) matches(entry Entry) bool {
+	if !filter.StartTime.IsZero() && entry.Time.Before(filter.StartTime) {
+		return false
+	}
+	if !filter.EndTime.IsZero() && entry.Time.After(filter.EndTime) {
+		return false
+	}
+	if filter.DocumentName != "" && filter.DocumentName != entry.DocumentName {
+		return false
+	}
+	return true
+}
+
+// History is the execution history stored in a file
+type History struct {
+	path string
+	lock sync.Mutex
+	// last is the last entry of the file, once read
+	last *Entry
+}
+
+// NewHistory returns the history stored in the given file
+func NewHistory(path string) *History {
+	return &History{path: path}
+}
+
+var defaultHistory = NewHistory(appconfig.ExecutionHistoryPath)
+
+// Append records the execution of a document in the default history of the agent
+func Append(entry Entry) error {
+	return defaultHistory.Append(entry)
+}
+
+// Append chains the entry to the last entry of the history and writes it to the file
+func (h *History) Append(entry Entry) (err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.last == nil {
+		if h.last, err = readLastEntry(h.path); err != nil {
+			return err
+		}
+	}
+	entry.Sequence = 1
+	entry.PreviousHash = ""
+	if h.last != nil {
+		entry.Sequence = h.last.Sequence + 1
+		entry.PreviousHash = h.last.Hash
+	}
+	if entry.Hash, err = entry.computeHash(); err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err = fileutil.MakeDirsWithExecuteAccess(filepath.Dir(h.path)); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(h.path, appconfig.FileFlagsCreateOrAppend, appconfig.ReadWriteAccess)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		// the file may end with a partial entry, read it again before the next entry
+		h.last = nil
+		return err
+	}
+	h.last = &entry
+	return writeHead(h.path+headSuffix, entry)
+}
+
+// Query returns the entries of the history passing the filter, oldest first
+func (h *History) Query(filter Filter) ([]Entry, error) {
+	entries := []Entry{}
+	err := readEntries(h.path, func(lineNumber int, line []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("line %v of %v is not a history entry: %v", lineNumber, h.path, err)
+		}
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Verify checks the chain of the history against its head and returns the number of entries, the error describes
+// the first entry which was modified, removed or inserted. The history may end with one entry past its head, which
+// is left behind when the agent stops between writing an entry and writing the head
+func (h *History) Verify() (count int, err error) {
+	last, err := readHead(h.path + headSuffix)
+	if err != nil {
+		return count, err
+	}
+	headSequence := 0
+	if last != nil {
+		headSequence = last.Sequence
+	}
+
+	// previous is the last entry read, atHead is the entry identified by the head
+	var previous, atHead *Entry
+	err = readEntries(h.path, func(lineNumber int, line []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("line %v of %v is not a history entry: %v", lineNumber, h.path, err)
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return err
+		}
+		if hash != entry.Hash {
+			return fmt.Errorf("entry %v at line %v was modified, its hash does not match its content", entry.Sequence, lineNumber)
+		}
+		expectedSequence, expectedPreviousHash := 1, ""
+		if previous != nil {
+			expectedSequence, expectedPreviousHash = previous.Sequence+1, previous.Hash
+		}
+		if entry.Sequence != expectedSequence {
+			return fmt.Errorf("entry %v at line %v follows entry %v, entries were removed or inserted", entry.Sequence, lineNumber, expectedSequence-1)
+		}
+		if entry.PreviousHash != expectedPreviousHash {
+			return fmt.Errorf("entry %v at line %v is not chained to the previous entry", entry.Sequence, lineNumber)
+		}
+		previous = &entry
+		if entry.Sequence == headSequence {
+			atHead = &entry
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	switch {
+	case last == nil && previous == nil:
+		return count, nil
+	case previous == nil:
+		return count, fmt.Errorf("the history is empty but its head identifies entry %v, entries were removed", last.Sequence)
+	case previous.Sequence < headSequence:
+		return count, fmt.Errorf("the history ends with entry %v but its head identifies entry %v, entries were removed", previous.Sequence, last.Sequence)
+	case last == nil && previous.Sequence > 1:
+		return count, fmt.Errorf("the head of the history is missing, it should identify entry %v", previous.Sequence)
+	case previous.Sequence > headSequence+1:
+		return count, fmt.Errorf("the history ends with entry %v but its head identifies entry %v, entries were inserted", previous.Sequence, last.Sequence)
+	case last != nil && atHead.Hash != last.Hash:
+		return count, fmt.Errorf("entry %v does not match the head of the history", atHead.Sequence)
+	}
+	return count, nil
+}
+
+// readEntries calls process for each line of the history, a missing history has no entries
+func readEntries(path string, process func(lineNumber int, line []byte) error) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntrySize)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err = process(lineNumber, scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// readLastEntry returns the last entry of the history, or nil if the history is empty
+func readLastEntry(path string) (last *Entry, err error) {
+	err = readEntries(path, func(lineNumber int, line []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("line %v of %v is not a history entry: %v", lineNumber, path, err)
+		}
+		last = &entry
+		return nil
+	})
+	return last, err
+}
@@ -0,0 +1,287 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package history
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHistory(t *testing.T) (*History, func()) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+	return NewHistory(filepath.Join(dir, "history", "executions.log")), func() { os.RemoveAll(dir) }
+}
+
+func appendTestEntries(t *testing.T, h *History) {
+	start := time.Date(2017, 10, 12, 10, 0, 0, 0, time.UTC)
+	for i, name := range []string{"AWS-RunShellScript", "AWS-ConfigureAWSPackage", "AWS-RunShellScript"} {
+		entry := Entry{
+			Time:         start.Add(time.Duration(i) * time.Hour),
+			DocumentID:   fmt.Sprintf("%v-%v", name, i),
+			DocumentName: name,
+			Status:       string(contracts.ResultStatusSuccess),
+		}
+		assert.Nil(t, h.Append(entry))
+	}
+}
+
+func TestNewEntry(t *testing.T) {
+	docState := &contracts.DocumentState{
+		DocumentInformation: contracts.DocumentInfo{
+			DocumentID:     "associationID.runID",
+			AssociationID:  "associationID",
+			DocumentName:   "AWS-RunShellScript",
+			DocumentHash:   "documentHash",
+			ParametersHash: "parametersHash",
+		},
+	}
+	entry := NewEntry(docState, contracts.DocumentResult{Status: contracts.ResultStatusFailed})
+
+	assert.Equal(t, "associationID.runID", entry.DocumentID)
+	assert.Equal(t, "associationID", entry.AssociationID)
+	assert.Equal(t, "documentHash", entry.DocumentHash)
+	assert.Equal(t, "parametersHash", entry.ParametersHash)
+	assert.Equal(t, "Failed", entry.Status)
+	assert.False(t, entry.Time.IsZero())
+}
+
+func TestAppendChainsEntries(t *testing.T) {
+	h, cleanup := newTestHistory(t)
+	defer cleanup()
+	appendTestEntries(t, h)
+
+	// a new history over the same file continues the chain
+	assert.Nil(t, NewHistory(h.path).Append(Entry{DocumentID: "last", Status: "Success"}))
+
+	entries, err := h.Query(Filter{})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(entries))
+	assert.Equal(t, "", entries[0].PreviousHash)
+	for i, entry := range entries {
+		assert.Equal(t, i+1, entry.Sequence)
+		assert.Equal(t, 64, len(entry.Hash))
+		if i > 0 {
+			assert.Equal(t, entries[i-1].Hash, entry.PreviousHash)
+		}
+	}
+
+	count, err := h.Verify()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, count)
+}
+
+func TestQuery(t *testing.T) {
+	h, cleanup := newTestHistory(t)
+	defer cleanup()
+	appendTestEntries(t, h)
+
+	entries, err := h.Query(Filter{DocumentName: "AWS-RunShellScript"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, 1, entries[0].Sequence)
+	assert.Equal(t, 3, entries[1].Sequence)
+
+	entries, err = h.Query(Filter{
+		StartTime: time.Date(2017, 10, 12, 10, 30, 0, 0, time.UTC),
+		EndTime:   time.Date(2017, 10, 12, 11, 0, 0, 0, time.UTC),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "AWS-ConfigureAWSPackage", entries[0].DocumentName)
+}
+
+func TestQueryMissingHistory(t *testing.T) {
+	h, cleanup := newTestHistory(t)
+	defer cleanup()
+
+	entries, err := h.Query(Filter{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(entries))
+
+	count, err := h.Verify()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tamper   func(lines []string) []string
+		expected string
+	}{
+		{
+			name: "modified",
+			tamper: func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], `"status":"Success"`, `"status":"Failed"`, 1)
+				return lines
+			},
+			expected: "entry 2 at line 2 was modified",
+		},
+		{
+			name: "removed",
+			tamper: func(lines []string) []string {
+				return append(lines[:1], lines[2:]...)
+			},
+			expected: "entry 3 at line 2 follows entry 1",
+		},
+		{
+			name: "reordered",
+			tamper: func(lines []string) []string {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			expected: "entry 3 at line 2 follows entry 1",
+		},
+		{
+			name: "truncated",
+			tamper: func(lines []string) []string {
+				return lines[:2]
+			},
+			expected: "the history ends with entry 2 but its head identifies entry 3",
+		},
+		{
+			name: "emptied",
+			tamper: func(lines []string) []string {
+				return []string{}
+			},
+			expected: "the history is empty but its head identifies entry 3",
+		},
+	}
+
+	for _, testCase := range testCases {
+		h, cleanup := newTestHistory(t)
+		appendTestEntries(t, h)
+		content, err := ioutil.ReadFile(h.path)
+		assert.Nil(t, err)
+		lines := testCase.tamper(strings.Split(strings.TrimSpace(string(content)), "\n"))
+		assert.Nil(t, ioutil.WriteFile(h.path, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+
+		_, err = h.Verify()
+		assert.NotNil(t, err, testCase.name)
+		if err != nil {
+			assert.Contains(t, err.Error(), testCase.expected, testCase.name)
+		}
+		cleanup()
+	}
+}
+
+func TestVerifyDetectsRewrittenTail(t *testing.T) {
+	h, cleanup := newTestHistory(t)
+	defer cleanup()
+	appendTestEntries(t, h)
+
+	// the last entry is removed and a consistent entry is chained in its place, only the head reveals it
+	content, err := ioutil.ReadFile(h.path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	head, err := ioutil.ReadFile(h.path + headSuffix)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(h.path, []byte(strings.Join(lines[:2], "\n")+"\n"), 0600))
+	assert.Nil(t, NewHistory(h.path).Append(Entry{DocumentID: "forged", Status: "Success"}))
+	assert.Nil(t, ioutil.WriteFile(h.path+headSuffix, head, 0600))
+
+	count, err := h.Verify()
+	assert.Equal(t, 3, count)
+	assert.NotNil(t, err)
+	if err != nil {
+		assert.Contains(t, err.Error(), "entry 3 does not match the head of the history")
+	}
+}
+
+func TestVerifyAcceptsInterruptedAppend(t *testing.T) {
+	h, cleanup := newTestHistory(t)
+	defer cleanup()
+	appendTestEntries(t, h)
+
+	// the agent stopped after writing the last entry but before writing the head
+	head, err := ioutil.ReadFile(h.path + headSuffix)
+	assert.Nil(t, err)
+	assert.Nil(t, h.Append(Entry{DocumentID: "interrupted", Status: "Success"}))
+	assert.Nil(t, ioutil.WriteFile(h.path+headSuffix, head, 0600))
+
+	count, err := h.Verify()
+	assert.Equal(t, 4, count)
+	assert.Nil(t, err)
+
+	// the next entry moves the head past the interrupted entry
+	assert.Nil(t, NewHistory(h.path).Append(Entry{DocumentID: "next", Status: "Success"}))
+	count, err = h.Verify()
+	assert.Equal(t, 5, count)
+	assert.Nil(t, err)
+}
+
+func TestVerifyAcceptsInterruptedFirstAppend(t *testing.T) {
+	h, cleanup := newTestHistory(t)
+	defer cleanup()
+	assert.Nil(t, h.Append(Entry{DocumentID: "interrupted", Status: "Success"}))
+	assert.Nil(t, os.Remove(h.path+headSuffix))
+
+	count, err := h.Verify()
+	assert.Equal(t, 1, count)
+	assert.Nil(t, err)
+}
+
+func TestVerifyDetectsInsertedTail(t *testing.T) {
+	h, cleanup := newTestHistory(t)
+	defer cleanup()
+	appendTestEntries(t, h)
+
+	// two entries past the head cannot come from an interrupted append
+	head, err := ioutil.ReadFile(h.path + headSuffix)
+	assert.Nil(t, err)
+	assert.Nil(t, h.Append(Entry{DocumentID: "forged-1", Status: "Success"}))
+	assert.Nil(t, h.Append(Entry{DocumentID: "forged-2", Status: "Success"}))
+	assert.Nil(t, ioutil.WriteFile(h.path+headSuffix, head, 0600))
+
+	_, err = h.Verify()
+	assert.NotNil(t, err)
+	if err != nil {
+		assert.Contains(t, err.Error(), "the history ends with entry 5 but its head identifies entry 3")
+	}
+}
+
+func TestVerifyDetectsMissingHead(t *testing.T) {
+	h, cleanup := newTestHistory(t)
+	defer cleanup()
+	appendTestEntries(t, h)
+	assert.Nil(t, os.Remove(h.path+headSuffix))
+
+	_, err := h.Verify()
+	assert.NotNil(t, err)
+	if err != nil {
+		assert.Contains(t, err.Error(), "the head of the history is missing")
+	}
+}
+
+func TestVerifyDetectsRemovedHistory(t *testing.T) {
+	h, cleanup := newTestHistory(t)
+	defer cleanup()
+	appendTestEntries(t, h)
+	assert.Nil(t, os.Remove(h.path))
+
+	_, err := h.Verify()
+	assert.NotNil(t, err)
+	if err != nil {
+		assert.Contains(t, err.Error(), "the history is empty but its head identifies entry 3")
+	}
+}
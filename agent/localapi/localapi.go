@@ -0,0 +1,409 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package localapi implements the control api the agent exposes to local callers on a unix domain socket.
+package localapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/association/schedulemanager"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docsignature"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+const (
+	commandsPath     = "/commands"
+	associationsPath = "/associations"
+	healthPath       = "/health"
+	cancelSuffix     = "/cancel"
+
+	commandStatusPending    = "Pending"
+	commandStatusInProgress = "InProgress"
+
+	// socketAccess only lets the owner of the socket, root, connect
+	socketAccess = 0600
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// CommandHandler runs and cancels the commands submitted through the local api
+type CommandHandler interface {
+	SubmitCommand(documentName string, content []byte, parameters map[string]interface{}, signature []byte) (commandID string, err error)
+	CancelCommand(commandID string) error
+}
+
+// SubmitCommandRequest is the body of a request to run a document
+type SubmitCommandRequest struct {
+	DocumentName string `json:"documentName"`
+	// Content is the document, kept as sent so that its signature can be verified over it
+	Content    json.RawMessage        `json:"content"`
+	Parameters map[string]interface{} `json:"parameters"`
+	// Signature is the base64 encoded detached signature of the content, required when the agent enforces signatures.
+	// It only covers the content, so a signed document is run without parameters
+	Signature []byte `json:"signature"`
+}
+
+// SubmitCommandResponse is the body of the response to a request to run a document
+type SubmitCommandResponse struct {
+	CommandID string `json:"commandId"`
+}
+
+// CommandResult is the status of a command and the output of its steps
+type CommandResult struct {
+	CommandID     string                                    `json:"commandId"`
+	Status        string                                    `json:"status"`
+	RuntimeStatus map[string]*contracts.PluginRuntimeStatus `json:"runtimeStatus,omitempty"`
+}
+
+// Association is an association scheduled on the instance
+type Association struct {
+	AssociationID      string `json:"associationId"`
+	Name               string `json:"name"`
+	ScheduleExpression string `json:"scheduleExpression,omitempty"`
+	NextScheduledDate  string `json:"nextScheduledDate,omitempty"`
+	DetailedStatus     string `json:"detailedStatus,omitempty"`
+	CheckOnly          bool   `json:"checkOnly,omitempty"`
+}
+
+// Health describes the running agent
+type Health struct {
+	Status        string `json:"status"`
+	AgentVersion  string `json:"agentVersion"`
+	InstanceID    string `json:"instanceId"`
+	StartTime     string `json:"startTime"`
+	UptimeSeconds int64  `json:"uptimeSeconds"`
+}
+
+// errorResponse is the body of the responses to failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// schedules returns the associations scheduled on the instance
+var schedules = schedulemanager.Schedules
+
+// Server serves the local control api
+type Server struct {
+	log        log.T
+	socketPath string
+	instanceID string
+	handler    CommandHandler
+	resultDir  string
+	stateDir   string
+	startTime  time.Time
+	listener   net.Listener
+	httpServer *http.Server
+}
+
+// NewServer creates a server listening on socketPath, commands are handed to handler and their results
+// read from the replies recorded by the offline service
+func NewServer(log log.T, socketPath string, instanceID string, stateDir string, handler CommandHandler) *Server {
+	return &Server{
+		log:        log,
+		socketPath: socketPath,
+		instanceID: instanceID,
+		handler:    handler,
+		resultDir:  appconfig.LocalCommandRootCompleted,
+		stateDir:   stateDir,
+		startTime:  time.Now(),
+	}
+}
+
+// Start listens on the socket and serves requests in the background
+func (s *Server) Start() (err error) {
+	if err = checkPlatform(); err != nil {
+		return err
+	}
+	if err = fileutil.MakeDirs(filepath.Dir(s.socketPath)); err != nil {
+		return err
+	}
+	// a socket left behind by an agent which did not stop cleanly prevents listening
+	if fileutil.Exists(s.socketPath) {
+		if err = os.Remove(s.socketPath); err != nil {
+			return fmt.Errorf("failed to remove stale socket %v: %v", s.socketPath, err)
+		}
+	}
+	listener, err := listen(s.socketPath)
+	if err != nil {
+		return err
+	}
+	s.listener = &authorizedListener{Listener: listener, log: s.log}
+	s.httpServer = &http.Server{Handler: s.Handler()}
+
+	s.log.Infof("Local api listening on %v", s.socketPath)
+	go func() {
+		if err := s.httpServer.Serve(s.listener); err != nil && err != http.ErrServerClosed {
+			s.log.Errorf("Local api stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// listen creates the socket in a private directory, which only root can open, and moves it to socketPath once its
+// access rights are restricted, so that other users cannot connect before the socket is restricted
+func listen(socketPath string) (net.Listener, error) {
+	privateDir, err := ioutil.TempDir(filepath.Dir(socketPath), ".localapi")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(privateDir)
+	privatePath := filepath.Join(privateDir, filepath.Base(socketPath))
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: privatePath, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// the socket no longer is at privatePath when the listener closes, Stop removes it
+	listener.SetUnlinkOnClose(false)
+	if err = os.Chmod(privatePath, socketAccess); err == nil {
+		err = os.Rename(privatePath, socketPath)
+	}
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// Stop closes the socket and waits for the requests in progress
+func (s *Server) Stop() {
+	if s.httpServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		s.log.Warnf("Local api did not stop within %v: %v", shutdownTimeout, err)
+		s.httpServer.Close()
+	}
+	os.Remove(s.socketPath)
+}
+
+// Handler returns the http handler of the api
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(commandsPath, s.handleCommands)
+	mux.HandleFunc(commandsPath+"/", s.handleCommand)
+	mux.HandleFunc(associationsPath, s.handleAssociations)
+	mux.HandleFunc(healthPath, s.handleHealth)
+	return mux
+}
+
+// handleCommands runs a document - POST /commands
+func (s *Server) handleCommands(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+		return
+	}
+	var request SubmitCommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+	if err := validateSubmitCommandRequest(request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	commandID, err := s.handler.SubmitCommand(request.DocumentName, request.Content, request.Parameters, request.Signature)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.log.Infof("Local api submitted command %v", commandID)
+	writeJson(w, http.StatusAccepted, SubmitCommandResponse{CommandID: commandID})
+}
+
+// handleCommand returns the result of a command - GET /commands/{id} - or cancels it - POST /commands/{id}/cancel
+func (s *Server) handleCommand(w http.ResponseWriter, r *http.Request) {
+	commandID := strings.TrimPrefix(r.URL.Path, commandsPath+"/")
+	cancel := strings.HasSuffix(commandID, cancelSuffix)
+	commandID = strings.TrimSuffix(commandID, cancelSuffix)
+	if commandID == "" || strings.Contains(commandID, "/") {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown path %v", r.URL.Path))
+		return
+	}
+
+	result, found := s.commandResult(commandID)
+	if cancel {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+			return
+		}
+		if !found {
+			writeError(w, http.StatusNotFound, fmt.Errorf("command %v not found", commandID))
+			return
+		}
+		if result.Status != commandStatusPending && result.Status != commandStatusInProgress {
+			writeError(w, http.StatusConflict, fmt.Errorf("command %v cannot be cancelled, its status is %v", commandID, result.Status))
+			return
+		}
+		if err := s.handler.CancelCommand(commandID); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		s.log.Infof("Local api cancelled command %v", commandID)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("command %v not found", commandID))
+		return
+	}
+	writeJson(w, http.StatusOK, result)
+}
+
+// handleAssociations lists the associations and their next run - GET /associations
+func (s *Server) handleAssociations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+		return
+	}
+	associations := make([]Association, 0)
+	for _, assoc := range schedules() {
+		associations = append(associations, newAssociation(assoc))
+	}
+	writeJson(w, http.StatusOK, associations)
+}
+
+// handleHealth describes the agent - GET /health
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+		return
+	}
+	writeJson(w, http.StatusOK, Health{
+		Status:        "Healthy",
+		AgentVersion:  version.Version,
+		InstanceID:    s.instanceID,
+		StartTime:     times.ToIso8601UTC(s.startTime),
+		UptimeSeconds: int64(time.Since(s.startTime).Seconds()),
+	})
+}
+
+// commandResult reads the reply recorded for the command, or finds it among the documents waiting to run
+func (s *Server) commandResult(commandID string) (result CommandResult, found bool) {
+	result.CommandID = commandID
+	var reply messageContracts.SendReplyPayload
+	if err := jsonutil.UnmarshalFile(filepath.Join(s.resultDir, commandID), &reply); err == nil {
+		result.Status = string(reply.DocumentStatus)
+		result.RuntimeStatus = reply.RuntimeStatus
+		return result, true
+	}
+	if fileutil.Exists(filepath.Join(s.stateDir, appconfig.DefaultLocationOfPending, commandID)) {
+		result.Status = commandStatusPending
+		return result, true
+	}
+	if fileutil.Exists(filepath.Join(s.stateDir, appconfig.DefaultLocationOfCurrent, commandID)) {
+		result.Status = commandStatusInProgress
+		return result, true
+	}
+	return result, false
+}
+
+// validateSubmitCommandRequest checks the request holds a document with steps to run
+func validateSubmitCommandRequest(request SubmitCommandRequest) error {
+	if len(request.Content) == 0 || string(request.Content) == "null" {
+		return errors.New("content is required")
+	}
+	var content contracts.DocumentContent
+	if err := json.Unmarshal(request.Content, &content); err != nil {
+		return fmt.Errorf("invalid content: %v", err)
+	}
+	if len(content.RuntimeConfig) == 0 && len(content.MainSteps) == 0 {
+		return errors.New("content must have runtimeConfig or mainSteps")
+	}
+	if request.DocumentName != "" && request.DocumentName != filepath.Base(request.DocumentName) {
+		return errors.New("documentName must not be a path")
+	}
+	if len(request.Signature) > 0 && len(request.Parameters) > 0 {
+		return docsignature.ErrParametersNotSigned
+	}
+	return nil
+}
+
+// newAssociation converts a scheduled association to its api representation
+func newAssociation(assoc *model.InstanceAssociation) Association {
+	result := Association{CheckOnly: assoc.CheckOnly}
+	if assoc.Association != nil {
+		result.AssociationID = stringValue(assoc.Association.AssociationId)
+		result.Name = stringValue(assoc.Association.Name)
+		result.ScheduleExpression = stringValue(assoc.Association.ScheduleExpression)
+		result.DetailedStatus = stringValue(assoc.Association.DetailedStatus)
+	}
+	if assoc.NextScheduledDate != nil {
+		result.NextScheduledDate = times.ToIso8601UTC(*assoc.NextScheduledDate)
+	}
+	return result
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, errorResponse{Error: err.Error()})
+}
+
+// authorizedListener drops the connections of the callers who are not allowed to use the api
+type authorizedListener struct {
+	net.Listener
+	log log.T
+}
+
+// Accept returns the next connection from an authorized caller
+func (l *authorizedListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizePeer(conn); err != nil {
+			l.log.Warnf("Local api refused connection: %v", err)
+			conn.Close()
+			continue
+		}
+		return conn, nil
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	completedCommandID = "01234567-890a-bcde-f012-34567890abcd"
+	pendingCommandID   = "11234567-890a-bcde-f012-34567890abcd"
+)
+
+type commandHandlerMock struct {
+	mock.Mock
+}
+
+func (m *commandHandlerMock) SubmitCommand(documentName string, content []byte, parameters map[string]interface{}, signature []byte) (string, error) {
+	args := m.Called(documentName, content, parameters, signature)
+	return args.String(0), args.Error(1)
+}
+
+func (m *commandHandlerMock) CancelCommand(commandID string) error {
+	return m.Called(commandID).Error(0)
+}
+
+// newTestServer creates a server with a completed and a pending command
+func newTestServer(t *testing.T, handler CommandHandler) (*Server, string) {
+	root, err := ioutil.TempDir("", "localapi")
+	assert.NoError(t, err)
+	server := NewServer(log.NewMockLog(), filepath.Join(root, "api.sock"), "i-123", filepath.Join(root, "state"), handler)
+	server.resultDir = filepath.Join(root, "completed")
+	assert.NoError(t, os.MkdirAll(server.resultDir, 0700))
+	assert.NoError(t, os.MkdirAll(filepath.Join(server.stateDir, appconfig.DefaultLocationOfPending), 0700))
+
+	reply, _ := jsonutil.Marshal(messageContracts.SendReplyPayload{
+		DocumentStatus: contracts.ResultStatusSuccess,
+		RuntimeStatus: map[string]*contracts.PluginRuntimeStatus{
+			"run": {Status: contracts.ResultStatusSuccess, StandardOutput: "hello"},
+		},
+	})
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(server.resultDir, completedCommandID), []byte(reply), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(server.stateDir, appconfig.DefaultLocationOfPending, pendingCommandID), []byte("{}"), 0600))
+	return server, root
+}
+
+func request(server *Server, method string, path string, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+	return recorder
+}
+
+func TestSubmitCommand(t *testing.T) {
+	handler := &commandHandlerMock{}
+	server, root := newTestServer(t, handler)
+	defer os.RemoveAll(root)
+	handler.On("SubmitCommand", "Hello", mock.Anything, map[string]interface{}{"commands": []interface{}{"date"}}, []byte(nil)).Return(completedCommandID, nil)
+	signedContent := `{"schemaVersion": "2.2", "mainSteps": [{"action": "aws:runShellScript", "name": "run"}]}`
+	handler.On("SubmitCommand", "Signed", []byte(signedContent), map[string]interface{}(nil), []byte("signature")).Return(completedCommandID, nil)
+
+	response := request(server, http.MethodPost, "/commands",
+		`{"documentName":"Signed","content":`+signedContent+`,"signature":"c2lnbmF0dXJl"}`)
+	assert.Equal(t, http.StatusAccepted, response.Code)
+
+	response = request(server, http.MethodPost, "/commands",
+		`{"documentName":"Hello","content":{"schemaVersion":"2.2","mainSteps":[{"action":"aws:runShellScript","name":"run"}]},"parameters":{"commands":["date"]}}`)
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	var submitted SubmitCommandResponse
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &submitted))
+	assert.Equal(t, completedCommandID, submitted.CommandID)
+	handler.AssertExpectations(t)
+
+	assert.Equal(t, http.StatusBadRequest, request(server, http.MethodPost, "/commands", `{"documentName":"Hello"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(server, http.MethodPost, "/commands", `{"documentName":"Hello","content":["date"]}`).Code)
+	// the signature does not cover the parameters
+	assert.Equal(t, http.StatusBadRequest, request(server, http.MethodPost, "/commands",
+		`{"content":{"schemaVersion":"2.2","mainSteps":[{"action":"aws:runShellScript","name":"run"}]},"parameters":{"commands":["id"]},"signature":"c2lnbmF0dXJl"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(server, http.MethodPost, "/commands", `not json`).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, request(server, http.MethodGet, "/commands", "").Code)
+}
+
+func TestGetCommand(t *testing.T) {
+	server, root := newTestServer(t, &commandHandlerMock{})
+	defer os.RemoveAll(root)
+
+	response := request(server, http.MethodGet, "/commands/"+completedCommandID, "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	var result CommandResult
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+	assert.Equal(t, "Success", result.Status)
+	assert.Equal(t, "hello", result.RuntimeStatus["run"].StandardOutput)
+
+	response = request(server, http.MethodGet, "/commands/"+pendingCommandID, "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+	assert.Equal(t, commandStatusPending, result.Status)
+
+	assert.Equal(t, http.StatusNotFound, request(server, http.MethodGet, "/commands/21234567-890a-bcde-f012-34567890abcd", "").Code)
+}
+
+func TestCancelCommand(t *testing.T) {
+	handler := &commandHandlerMock{}
+	server, root := newTestServer(t, handler)
+	defer os.RemoveAll(root)
+	handler.On("CancelCommand", pendingCommandID).Return(nil)
+
+	assert.Equal(t, http.StatusAccepted, request(server, http.MethodPost, "/commands/"+pendingCommandID+"/cancel", "").Code)
+	assert.Equal(t, http.StatusConflict, request(server, http.MethodPost, "/commands/"+completedCommandID+"/cancel", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, request(server, http.MethodGet, "/commands/"+pendingCommandID+"/cancel", "").Code)
+	handler.AssertNumberOfCalls(t, "CancelCommand", 1)
+}
+
+func TestAssociationsAndHealth(t *testing.T) {
+	server, root := newTestServer(t, &commandHandlerMock{})
+	defer os.RemoveAll(root)
+	next := time.Date(2017, 10, 12, 10, 0, 0, 0, time.UTC)
+	schedules = func() []*model.InstanceAssociation {
+		return []*model.InstanceAssociation{{
+			NextScheduledDate: &next,
+			Association: &ssm.InstanceAssociationSummary{
+				AssociationId:      aws.String("assoc-1"),
+				Name:               aws.String("AWS-RunShellScript"),
+				ScheduleExpression: aws.String("rate(30 minutes)"),
+			},
+		}}
+	}
+
+	response := request(server, http.MethodGet, "/associations", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	var associations []Association
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &associations))
+	assert.Equal(t, []Association{{
+		AssociationID:      "assoc-1",
+		Name:               "AWS-RunShellScript",
+		ScheduleExpression: "rate(30 minutes)",
+		NextScheduledDate:  "2017-10-12T10:00:00.000Z",
+	}}, associations)
+
+	response = request(server, http.MethodGet, "/health", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	var health Health
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &health))
+	assert.Equal(t, "Healthy", health.Status)
+	assert.Equal(t, "i-123", health.InstanceID)
+}
+
+func TestServeOnSocket(t *testing.T) {
+	server, root := newTestServer(t, &commandHandlerMock{})
+	defer os.RemoveAll(root)
+	// a socket left behind is replaced
+	assert.NoError(t, ioutil.WriteFile(server.socketPath, []byte{}, 0600))
+	if runtime.GOOS == "windows" {
+		// the callers cannot be authenticated, the api does not start
+		assert.Error(t, server.Start())
+		return
+	}
+
+	assert.NoError(t, server.Start())
+	info, err := os.Stat(server.socketPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(socketAccess), info.Mode().Perm())
+	// the private directory the socket was created in is removed
+	privateDirs, err := filepath.Glob(filepath.Join(root, ".localapi*"))
+	assert.NoError(t, err)
+	assert.Empty(t, privateDirs)
+
+	client := http.Client{Transport: &http.Transport{
+		Dial: func(network, addr string) (net.Conn, error) {
+			return net.Dial("unix", server.socketPath)
+		},
+	}}
+	response, err := client.Get("http://localapi/commands/" + completedCommandID)
+	if os.Getuid() == 0 {
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		response.Body.Close()
+	} else {
+		// only root may use the api
+		assert.Error(t, err)
+	}
+
+	server.Stop()
+	_, err = os.Stat(server.socketPath)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build linux
+
+// Package localapi implements the control api the agent exposes to local callers on a unix domain socket.
+package localapi
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// checkPlatform allows the api, the callers are authorized from the credentials of the socket
+func checkPlatform() error {
+	return nil
+}
+
+// authorizePeer only lets root connect, the credentials of the caller are read from the socket
+func authorizePeer(conn net.Conn) error {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return fmt.Errorf("unexpected connection type %T", conn)
+	}
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var credentials *syscall.Ucred
+	var credentialsErr error
+	if err = rawConn.Control(func(fd uintptr) {
+		credentials, credentialsErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return err
+	}
+	if credentialsErr != nil {
+		return credentialsErr
+	}
+	if credentials.Uid != 0 {
+		return fmt.Errorf("caller uid %v pid %v is not root", credentials.Uid, credentials.Pid)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build windows
+
+// Package localapi implements the control api the agent exposes to local callers on a unix domain socket.
+package localapi
+
+import (
+	"errors"
+	"net"
+)
+
+// errPlatformNotSupported is returned on windows, where the access rights of the socket file do not restrict who
+// connects and the identity of the caller cannot be read from the connection
+var errPlatformNotSupported = errors.New("the local api is not supported on windows, its callers cannot be authenticated")
+
+// checkPlatform refuses to start the api
+func checkPlatform() error {
+	return errPlatformNotSupported
+}
+
+// authorizePeer refuses every caller
+func authorizePeer(conn net.Conn) error {
+	return errPlatformNotSupported
+}
@@ -14,7 +14,10 @@
 package log
 
 import (
+	"fmt"
 	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 )
 
 // DelegateLogger holds the base logger for logging
@@ -43,6 +46,7 @@ type FormatFilter interface {
 // and writes to log with level = Trace.
 func (w *Wrapper) Tracef(format string, params ...interface{}) {
 	format, params = w.Format.Filterf(format, params...)
+	format, params = redactf(format, params...)
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -53,6 +57,7 @@ func (w *Wrapper) Tracef(format string, params ...interface{}) {
 // and writes to log with level = Debug.
 func (w *Wrapper) Debugf(format string, params ...interface{}) {
 	format, params = w.Format.Filterf(format, params...)
+	format, params = redactf(format, params...)
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -63,6 +68,7 @@ func (w *Wrapper) Debugf(format string, params ...interface{}) {
 // and writes to log with level = Info.
 func (w *Wrapper) Infof(format string, params ...interface{}) {
 	format, params = w.Format.Filterf(format, params...)
+	format, params = redactf(format, params...)
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -73,6 +79,7 @@ func (w *Wrapper) Infof(format string, params ...interface{}) {
 // and writes to log with level = Warn.
 func (w *Wrapper) Warnf(format string, params ...interface{}) error {
 	format, params = w.Format.Filterf(format, params...)
+	format, params = redactf(format, params...)
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -83,6 +90,7 @@ func (w *Wrapper) Warnf(format string, params ...interface{}) error {
 // and writes to log with level = Error.
 func (w *Wrapper) Errorf(format string, params ...interface{}) error {
 	format, params = w.Format.Filterf(format, params...)
+	format, params = redactf(format, params...)
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -93,6 +101,7 @@ func (w *Wrapper) Errorf(format string, params ...interface{}) error {
 // and writes to log with level = Critical.
 func (w *Wrapper) Criticalf(format string, params ...interface{}) error {
 	format, params = w.Format.Filterf(format, params...)
+	format, params = redactf(format, params...)
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -103,6 +112,7 @@ func (w *Wrapper) Criticalf(format string, params ...interface{}) error {
 // and writes to log with level = Trace
 func (w *Wrapper) Trace(v ...interface{}) {
 	v = w.Format.Filter(v...)
+	v = redact(v...)
 	w.M.Lock()
 	defer w.M.Unlock()
 	w.Delegate.BaseLoggerInstance.Trace(v...)
@@ -112,6 +122,7 @@ func (w *Wrapper) Trace(v ...interface{}) {
 // and writes to log with level = Debug
 func (w *Wrapper) Debug(v ...interface{}) {
 	v = w.Format.Filter(v...)
+	v = redact(v...)
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -122,6 +133,7 @@ func (w *Wrapper) Debug(v ...interface{}) {
 // and writes to log with level = Info
 func (w *Wrapper) Info(v ...interface{}) {
 	v = w.Format.Filter(v...)
+	v = redact(v...)
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -132,6 +144,7 @@ func (w *Wrapper) Info(v ...interface{}) {
 // and writes to log with level = Warn
 func (w *Wrapper) Warn(v ...interface{}) error {
 	v = w.Format.Filter(v...)
+	v = redact(v...)
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -142,6 +155,7 @@ func (w *Wrapper) Warn(v ...interface{}) error {
 // and writes to log with level = Error
 func (w *Wrapper) Error(v ...interface{}) error {
 	v = w.Format.Filter(v...)
+	v = redact(v...)
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -152,6 +166,7 @@ func (w *Wrapper) Error(v ...interface{}) error {
 // and writes to log with level = Critical
 func (w *Wrapper) Critical(v ...interface{}) error {
 	v = w.Format.Filter(v...)
+	v = redact(v...)
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -180,3 +195,19 @@ func (w *Wrapper) ReplaceDelegate(newLogger T) {
 	w.Delegate.BaseLoggerInstance = newLogger
 	w.Delegate.BaseLoggerInstance.Info("Logger Replaced. New Logger Used to log the message")
 }
+
+// redactf scrubs the secrets registered by the running documents from a formatted log message.
+func redactf(format string, params ...interface{}) (string, []interface{}) {
+	if !redactor.HasSecrets() {
+		return format, params
+	}
+	return "%s", []interface{}{redactor.RedactAll(fmt.Sprintf(format, params...))}
+}
+
+// redact scrubs the secrets registered by the running documents from a log message.
+func redact(v ...interface{}) []interface{} {
+	if !redactor.HasSecrets() {
+		return v
+	}
+	return []interface{}{redactor.RedactAll(fmt.Sprint(v...))}
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapperRedactsRegisteredSecrets(t *testing.T) {
+	delegate := NewMockLog()
+	logger := &Wrapper{Format: &ContextFormatFilter{Context: []string{"[ctx]"}}, M: new(sync.Mutex), Delegate: &DelegateLogger{BaseLoggerInstance: delegate}}
+
+	redactor.ForDocument("documentID").Register("s3cr3t")
+	defer redactor.Release("documentID")
+
+	logger.Infof("password is %v", "s3cr3t")
+	logger.Info("password is ", "s3cr3t")
+
+	delegate.AssertCalled(t, "Infof", "%s", []interface{}{"[ctx] password is ****"})
+	delegate.AssertCalled(t, "Info", []interface{}{"[ctx] password is ****"})
+}
+
+func TestWrapperWithoutSecrets(t *testing.T) {
+	delegate := NewMockLog()
+	logger := &Wrapper{Format: &ContextFormatFilter{}, M: new(sync.Mutex), Delegate: &DelegateLogger{BaseLoggerInstance: delegate}}
+
+	logger.Infof("value is %v", "plain")
+
+	delegate.AssertCalled(t, "Infof", "value is %v", []interface{}{"plain"})
+	assert.False(t, redactor.HasSecrets())
+}
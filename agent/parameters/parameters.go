@@ -39,6 +39,27 @@ const paramNameRegex = "^[a-zA-Z0-9]+$"
 //
 // Returns a new object with replaced parameters.
 func ReplaceParameters(input interface{}, parameters map[string]interface{}, logger log.T) interface{} {
+	return replaceParameters(input, parameters, func(err error) {
+		logger.Error(err)
+	}, func(input interface{}) {
+		logger.Debugf("Type is - %v which was not found. Returning parameter without replacement", reflect.TypeOf(input))
+	})
+}
+
+// Replace replaces the parameters in the input like ReplaceParameters, for the callers without a logger.
+// The error reports the first parameter value which could not be converted to a string.
+func Replace(input interface{}, parameters map[string]interface{}) (output interface{}, err error) {
+	output = replaceParameters(input, parameters, func(conversionErr error) {
+		if err == nil {
+			err = conversionErr
+		}
+	}, nil)
+	return output, err
+}
+
+// replaceParameters implements ReplaceParameters, onError is called for the parameter values which cannot be
+// converted to a string and onUnknownType, when set, for the inputs of a type without parameters
+func replaceParameters(input interface{}, parameters map[string]interface{}, onError func(error), onUnknownType func(interface{})) interface{} {
 	switch input := input.(type) {
 	case string:
 		// handle single parameter case first
@@ -53,7 +74,7 @@ func ReplaceParameters(input interface{}, parameters map[string]interface{}, log
 			var parameterValueString string
 			var err error
 			if parameterValueString, err = convertToString(parameterValue); err != nil {
-				logger.Error(err)
+				onError(err)
 			}
 			input = ReplaceParameter(input, parameterName, parameterValueString)
 		}
@@ -63,7 +84,7 @@ func ReplaceParameters(input interface{}, parameters map[string]interface{}, log
 		// for slices, recursively replace parameters on each element of the slice
 		out := make([]interface{}, len(input))
 		for i, v := range input {
-			out[i] = ReplaceParameters(v, parameters, logger)
+			out[i] = replaceParameters(v, parameters, onError, onUnknownType)
 		}
 		return out
 
@@ -71,7 +92,7 @@ func ReplaceParameters(input interface{}, parameters map[string]interface{}, log
 		// this case is not caught by the one above because map cannot be converted to interface{}
 		out := make([]map[string]interface{}, len(input))
 		for i, v := range input {
-			out[i] = ReplaceParameters(v, parameters, logger).(map[string]interface{})
+			out[i] = replaceParameters(v, parameters, onError, onUnknownType).(map[string]interface{})
 		}
 		return out
 
@@ -79,7 +100,7 @@ func ReplaceParameters(input interface{}, parameters map[string]interface{}, log
 		// for maps, recursively replace parameters on each value in the map
 		out := make(map[string]interface{})
 		for k, v := range input {
-			out[k] = ReplaceParameters(v, parameters, logger)
+			out[k] = replaceParameters(v, parameters, onError, onUnknownType)
 		}
 		return out
 
@@ -88,13 +109,15 @@ func ReplaceParameters(input interface{}, parameters map[string]interface{}, log
 		for k, v := range input {
 			switch k := k.(type) {
 			case string:
-				out[k] = ReplaceParameters(v, parameters, logger)
+				out[k] = replaceParameters(v, parameters, onError, onUnknownType)
 			}
 		}
 		return out
 	default:
 		// any other type, return as is
-		logger.Debugf("Type is - %v which was not found. Returning parameter without replacement", reflect.TypeOf(input))
+		if onUnknownType != nil {
+			onUnknownType(input)
+		}
 		return input
 	}
 }
@@ -123,7 +146,7 @@ func ReplaceParameter(input string, paramName string, paramValue string) string
 func ValidParameters(log log.T, params map[string]interface{}) map[string]interface{} {
 	validParams := make(map[string]interface{})
 	for paramName, paramValue := range params {
-		if validName(paramName) {
+		if ValidName(paramName) {
 			validParams[paramName] = paramValue
 		} else {
 			log.Errorf("invalid parameter name %v", paramName)
@@ -132,8 +155,8 @@ func ValidParameters(log log.T, params map[string]interface{}) map[string]interf
 	return validParams
 }
 
-// validName checks whether the given parameter name is valid.
-func validName(paramName string) bool {
+// ValidName checks whether the given parameter name is valid.
+func ValidName(paramName string) bool {
 	paramNameValidator := regexp.MustCompile(paramNameRegex)
 	return paramNameValidator.MatchString(paramName)
 }
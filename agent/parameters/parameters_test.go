@@ -66,7 +66,7 @@ func TestValidateName(t *testing.T) {
 	}
 
 	for _, test := range validateNameTests {
-		r := validName(test.ParamName)
+		r := ValidName(test.ParamName)
 		assert.Equal(t, test.Result, r)
 	}
 }
@@ -84,6 +84,20 @@ func TestReplaceParameters(t *testing.T) {
 	}
 }
 
+func TestReplace(t *testing.T) {
+	testCases := generateReplaceParamTestCases()
+	for _, testCase := range testCases {
+		output, err := Replace(testCase.Input, testCase.Params)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.Output, output)
+	}
+
+	// a value which cannot be converted to a string is replaced by an empty string and reported
+	output, err := Replace([]interface{}{"put {{ param }} here"}, map[string]interface{}{"param": make(chan int)})
+	assert.Error(t, err)
+	assert.Equal(t, []interface{}{"put  here"}, output)
+}
+
 func generateReplaceParamTestCases() []ReplaceParamTestCase {
 	params := map[string]interface{}{
 		"param1": "a parameter",
@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 )
 
 var cachedRegion, cachedAvailabilityZone, cachedInstanceType string
@@ -125,12 +127,18 @@ func IsManagedInstance() (bool, error) {
 }
 
 // fetchInstanceID fetches the instance id with the following preference order.
+// 0. local instance ID in offline mode
 // 1. managed instance registration
 // 2. EC2 Instance Metadata
 func fetchInstanceID() (string, error) {
 	var err error
 	var instanceID string
 
+	// offline agents assign themselves an instance ID
+	if agentConfig().OfflineMode {
+		return localInstanceID(appconfig.LocalInstanceIDPath)
+	}
+
 	// trying to get instance id from managed instance registration
 	if instanceID = managedInstance.InstanceID(); instanceID != "" {
 		return instanceID, nil
@@ -152,6 +160,10 @@ func fetchInstanceType() (string, error) {
 	var err error
 	var instanceType string
 
+	if agentConfig().OfflineMode {
+		return "", fmt.Errorf(offlineErrorMessage, "instance type")
+	}
+
 	// trying to get instance id from ec2 metadata
 	if instanceType, err = metadata.GetMetadata("instance-type"); instanceType != "" && err == nil {
 		return instanceType, nil
@@ -162,6 +174,7 @@ func fetchInstanceType() (string, error) {
 }
 
 // fetchRegion fetches the region with the following preference order.
+// 0. region of the agent configuration in offline mode
 // 1. managed instance registration
 // 2. EC2 Instance Metadata
 // 3. EC2 Instance Dynamic Data
@@ -169,6 +182,13 @@ func fetchRegion() (string, error) {
 	var err error
 	var region string
 
+	if config := agentConfig(); config.OfflineMode {
+		if config.Region == "" {
+			return "", fmt.Errorf(offlineErrorMessage, "region")
+		}
+		return config.Region, nil
+	}
+
 	// trying to get region from managed instance registration
 	if region = managedInstance.Region(); region != "" {
 		return region, nil
@@ -196,6 +216,10 @@ func fetchAvailabilityZone() (string, error) {
 	var err error
 	var availabilityZone string
 
+	if agentConfig().OfflineMode {
+		return "", fmt.Errorf(offlineErrorMessage, "availability zone")
+	}
+
 	// trying to get instance id from ec2 metadata
 	if availabilityZone, err = metadata.GetMetadata("placement/availability-zone"); availabilityZone != "" && err == nil {
 		return availabilityZone, nil
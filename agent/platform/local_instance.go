@@ -0,0 +1,85 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package platform provides instance information
+package platform
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+// localInstanceIDPrefix is the prefix of the instance IDs the agent assigns itself in offline mode,
+// like i- for EC2 instances and mi- for managed instances
+const localInstanceIDPrefix = "li-"
+
+const offlineErrorMessage = "%s is not available in offline mode"
+
+var cachedLocalInstanceID string
+var localInstanceLock sync.Mutex
+
+// dependency for the agent configuration
+var agentConfig = func() appconfig.AgentInfo {
+	config, _ := appconfig.Config(false)
+	return config.Agent
+}
+
+// localInstanceID returns the instance ID kept in the given file, a new one is generated and saved on first use
+// so that the instance ID stays the same across restarts
+func localInstanceID(path string) (string, error) {
+	localInstanceLock.Lock()
+	defer localInstanceLock.Unlock()
+	if cachedLocalInstanceID != "" {
+		return cachedLocalInstanceID, nil
+	}
+
+	if fileutil.Exists(path) {
+		content, err := fileutil.ReadAllText(path)
+		if err != nil {
+			return "", err
+		}
+		if instanceID := strings.TrimSpace(content); instanceID != "" {
+			cachedLocalInstanceID = instanceID
+			return instanceID, nil
+		}
+	}
+
+	instanceID, err := newLocalInstanceID()
+	if err != nil {
+		return "", err
+	}
+	if err = fileutil.MakeDirs(filepath.Dir(path)); err != nil {
+		return "", err
+	}
+	if err = fileutil.WriteAllText(path, instanceID); err != nil {
+		return "", fmt.Errorf("failed to save local instance ID to %v, %v", path, err)
+	}
+	cachedLocalInstanceID = instanceID
+	return instanceID, nil
+}
+
+// newLocalInstanceID generates a random instance ID shaped like the ID of EC2 instances
+func newLocalInstanceID() (string, error) {
+	random := make([]byte, 9)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return localInstanceIDPrefix + hex.EncodeToString(random)[:17], nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package platform provides instance information
+package platform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalInstanceID(t *testing.T) {
+	root, err := ioutil.TempDir("", "platform")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	defer func() { cachedLocalInstanceID = "" }()
+	path := filepath.Join(root, "ssm", "localinstanceid")
+
+	cachedLocalInstanceID = ""
+	instanceID, err := localInstanceID(path)
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile("^li-[0-9a-f]{17}$"), instanceID)
+	content, _ := fileutil.ReadAllText(path)
+	assert.Equal(t, instanceID, content)
+
+	// the instance ID survives restarts
+	cachedLocalInstanceID = ""
+	reloaded, err := localInstanceID(path)
+	assert.NoError(t, err)
+	assert.Equal(t, instanceID, reloaded)
+}
+
+func TestOfflineMode(t *testing.T) {
+	defer func(original func() appconfig.AgentInfo) {
+		agentConfig = original
+		cachedLocalInstanceID = ""
+	}(agentConfig)
+	metadata = &metadataStub{instanceID: sampleInstanceID, region: sampleInstanceRegion}
+	managedInstance = registrationStub{instanceID: sampleManagedInstID, region: sampleManagedInstRegion}
+	cachedLocalInstanceID = "li-0123456789abcdef0"
+
+	agentConfig = func() appconfig.AgentInfo { return appconfig.AgentInfo{OfflineMode: true} }
+	instanceID, err := fetchInstanceID()
+	assert.NoError(t, err)
+	assert.Equal(t, "li-0123456789abcdef0", instanceID)
+	_, err = fetchRegion()
+	assert.Error(t, err)
+	_, err = fetchInstanceType()
+	assert.Error(t, err)
+	_, err = fetchAvailabilityZone()
+	assert.Error(t, err)
+
+	agentConfig = func() appconfig.AgentInfo { return appconfig.AgentInfo{OfflineMode: true, Region: "eu-west-1"} }
+	region, err := fetchRegion()
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-west-1", region)
+}
@@ -0,0 +1,149 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package pluginpolicy restricts the plugins the documents run by the agent may execute.
+//
+// The policy is a JSON file local to the host, for example
+//
+//	{
+//	    "AllowedPlugins": ["aws:runShellScript", "aws:configurePackage"],
+//	    "DeniedPlugins": ["aws:runDocument"],
+//	    "RunShellScript": {
+//	        "AllowedDocumentNames": ["AWS-RunShellScript"],
+//	        "AllowedDocumentHashes": ["<SHA-256 of the document, as printed by ssm-cli test-plugin-policy>"]
+//	    }
+//	}
+//
+// A denied plugin is never executed. When AllowedPlugins is not empty, only its plugins are executed.
+// When RunShellScript lists documents, the aws:runShellScript steps are only executed for the documents
+// with one of the names or whose content has one of the hashes. The names only match the documents delivered by
+// the service, the documents of the offline commands, the local api and the local associations are named by their
+// local caller so they are only matched by hash. Without a policy file every plugin is allowed, and a policy file
+// which cannot be read denies every plugin.
+package pluginpolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// Policy restricts the plugins the documents may execute
+type Policy struct {
+	AllowedPlugins []string
+	DeniedPlugins  []string
+	RunShellScript DocumentRestriction
+
+	// loadErr is the reason every step is denied when the policy file could not be read
+	loadErr error
+}
+
+// DocumentRestriction lists the documents allowed to run a plugin, by name or by SHA-256 of their content
+type DocumentRestriction struct {
+	AllowedDocumentNames  []string
+	AllowedDocumentHashes []string
+}
+
+// Step is a step of a document the policy is evaluated for
+type Step struct {
+	PluginName string
+	// DocumentName is empty for the documents whose name was chosen by a local caller
+	DocumentName string
+	DocumentHash string
+}
+
+// Load reads the policy file, a missing file allows every plugin
+func Load(path string) (*Policy, error) {
+	if !fileutil.Exists(path) {
+		return &Policy{}, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy Policy
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// a misspelled field would silently allow more than intended
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("invalid plugin policy %v: %v", path, err)
+	}
+	return &policy, nil
+}
+
+// Invalid returns a policy which denies every step because the policy file could not be read
+func Invalid(err error) *Policy {
+	return &Policy{loadErr: err}
+}
+
+// Current returns the policy of the host, read from appconfig.PluginPolicyPath
+func Current(log log.T) *Policy {
+	policy, err := Load(appconfig.PluginPolicyPath)
+	if err != nil {
+		log.Errorf("Failed to load the plugin policy, every plugin is denied: %v", err)
+		return Invalid(err)
+	}
+	return policy
+}
+
+// Evaluate returns nil if the policy allows the step, or the reason the step is denied
+func (p *Policy) Evaluate(step Step) error {
+	if p.loadErr != nil {
+		return fmt.Errorf("plugin policy could not be loaded, %v", p.loadErr)
+	}
+	if contains(p.DeniedPlugins, step.PluginName) {
+		return fmt.Errorf("plugin %v is denied", step.PluginName)
+	}
+	if len(p.AllowedPlugins) > 0 && !contains(p.AllowedPlugins, step.PluginName) {
+		return fmt.Errorf("plugin %v is not allowed", step.PluginName)
+	}
+	if step.PluginName == appconfig.PluginNameAwsRunShellScript && !p.RunShellScript.allows(step) {
+		if step.DocumentName == "" {
+			return fmt.Errorf("plugin %v is not allowed for the document with hash %v", step.PluginName, step.DocumentHash)
+		}
+		return fmt.Errorf("plugin %v is not allowed for document %v with hash %v", step.PluginName, step.DocumentName, step.DocumentHash)
+	}
+	return nil
+}
+
+// allows returns true if the restriction lists no document or lists the document of the step
+func (r DocumentRestriction) allows(step Step) bool {
+	if len(r.AllowedDocumentNames) == 0 && len(r.AllowedDocumentHashes) == 0 {
+		return true
+	}
+	if step.DocumentName != "" && contains(r.AllowedDocumentNames, step.DocumentName) {
+		return true
+	}
+	for _, hash := range r.AllowedDocumentHashes {
+		if step.DocumentHash != "" && strings.EqualFold(hash, step.DocumentHash) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns true if value is one of the items
+func contains(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
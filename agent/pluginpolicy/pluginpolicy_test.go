@@ -0,0 +1,76 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pluginpolicy
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	policy := Policy{
+		AllowedPlugins: []string{"aws:runShellScript", "aws:configurePackage", "aws:runDocument"},
+		DeniedPlugins:  []string{"aws:runDocument"},
+		RunShellScript: DocumentRestriction{
+			AllowedDocumentNames:  []string{"AWS-RunShellScript"},
+			AllowedDocumentHashes: []string{"ABCDEF"},
+		},
+	}
+	testCases := []struct {
+		step    Step
+		allowed bool
+	}{
+		{Step{PluginName: "aws:configurePackage"}, true},
+		{Step{PluginName: "aws:runDocument"}, false},
+		{Step{PluginName: "aws:runPowerShellScript"}, false},
+		{Step{PluginName: "aws:runShellScript", DocumentName: "AWS-RunShellScript"}, true},
+		{Step{PluginName: "aws:runShellScript", DocumentName: "Custom", DocumentHash: "abcdef"}, true},
+		{Step{PluginName: "aws:runShellScript", DocumentName: "Custom", DocumentHash: "012345"}, false},
+		{Step{PluginName: "aws:runShellScript"}, false},
+	}
+	for _, testCase := range testCases {
+		err := policy.Evaluate(testCase.step)
+		assert.Equal(t, testCase.allowed, err == nil, "%v", testCase.step)
+	}
+
+	// an empty policy allows every plugin
+	assert.Nil(t, (&Policy{}).Evaluate(Step{PluginName: "aws:runShellScript"}))
+	assert.NotNil(t, Invalid(errors.New("invalid")).Evaluate(Step{PluginName: "aws:configurePackage"}))
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pluginpolicy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plugin-policy.json")
+
+	policy, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, &Policy{}, policy)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"DeniedPlugins": ["aws:runDocument"], "RunShellScript": {"AllowedDocumentNames": ["AWS-RunShellScript"]}}`), 0600))
+	policy, err = Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"aws:runDocument"}, policy.DeniedPlugins)
+	assert.Equal(t, []string{"AWS-RunShellScript"}, policy.RunShellScript.AllowedDocumentNames)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"DenyPlugins": ["aws:runDocument"]}`), 0600))
+	_, err = Load(path)
+	assert.NotNil(t, err)
+}
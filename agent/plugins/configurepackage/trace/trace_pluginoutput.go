@@ -15,7 +15,6 @@ package trace
 
 import (
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
-	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 )
 
@@ -76,7 +75,7 @@ func (out *PluginOutputTrace) MarkAsShutdown() {
 
 func (out *PluginOutputTrace) String() string {
 	p := out.Tracer.ToPluginOutput()
-	return iohandler.TruncateOutput(p.GetStdout(), p.GetStderr(), iohandler.MaximumPluginOutputSize)
+	return p.String()
 }
 
 // Forward to tracer
@@ -27,6 +27,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/s3resource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/ssmdocresource"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 
 	"errors"
@@ -60,7 +61,7 @@ func NewPlugin() (*Plugin, error) {
 
 // Plugin is the type for the aws:downloadContent plugin.
 type Plugin struct {
-	remoteResourceCreator func(log log.T, sourceType string, SourceInfo string) (remoteresource.RemoteResource, error)
+	remoteResourceCreator func(log log.T, sourceType string, SourceInfo string, docRedactor *redactor.Redactor) (remoteresource.RemoteResource, error)
 	filesys               filemanager.FileSystem
 }
 
@@ -74,13 +75,14 @@ type DownloadContentPlugin struct {
 	// TODO: https://amazon.awsapps.com/workdocs/index.html#/document/7d56a42ea5b040a7c33548d77dc98040f0fb380bbbfb2fd580c861225e2ee1c7
 }
 
-// newRemoteResource switches between the source type and returns a struct of the source type that implements remoteresource
-func newRemoteResource(log log.T, SourceType string, SourceInfo string) (resource remoteresource.RemoteResource, err error) {
+// newRemoteResource switches between the source type and returns a struct of the source type that implements remoteresource,
+// the secrets resolved to access the resource are registered with the document redactor
+func newRemoteResource(log log.T, SourceType string, SourceInfo string, docRedactor *redactor.Redactor) (resource remoteresource.RemoteResource, err error) {
 	switch SourceType {
 	case GitHub:
 		// TODO: meloniam@ 08/24/2017 Replace string type to map[string]inteface{} type once Runcommand supports string maps
 		// TODO: https://amazon.awsapps.com/workdocs/index.html#/document/7d56a42ea5b040a7c33548d77dc98040f0fb380bbbfb2fd580c861225e2ee1c7
-		token := privategithub.NewTokenInfoImpl()
+		token := privategithub.NewTokenInfoImpl(docRedactor)
 		return gitresource.NewGitResource(log, SourceInfo, token)
 	case S3:
 		return s3resource.NewS3Resource(log, SourceInfo)
@@ -121,7 +123,7 @@ func (p *Plugin) runCopyContent(log log.T, input *DownloadContentPlugin, config
 
 	// remoteResourceCreator makes a call to a function that creates a new remote resource based on the source type
 	log.Debug("Creating resource of type - ", input.SourceType)
-	remoteResource, err := p.remoteResourceCreator(log, input.SourceType, input.SourceInfo)
+	remoteResource, err := p.remoteResourceCreator(log, input.SourceType, input.SourceInfo, redactor.ForDocument(config.BookKeepingFileName))
 	if err != nil {
 		output.MarkAsFailed(err)
 		return
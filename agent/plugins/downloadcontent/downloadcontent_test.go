@@ -29,6 +29,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
 	resourcemock "github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource/mock"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -42,7 +43,7 @@ var contextMock = context.NewMockDefault()
 func TestNewRemoteResource_InvalidLocationType(t *testing.T) {
 
 	var mockLocationInfo string
-	remoteresource, err := newRemoteResource(logger, "invalid", mockLocationInfo)
+	remoteresource, err := newRemoteResource(logger, "invalid", mockLocationInfo, nil)
 
 	assert.Nil(t, remoteresource)
 	assert.Error(t, err)
@@ -56,7 +57,7 @@ func TestNewRemoteResource_Github(t *testing.T) {
 		"owner" : "test-owner",
 		"repository" :	 "test-repo"
 		}`
-	remoteresource, err := newRemoteResource(logger, "GitHub", locationInfo)
+	remoteresource, err := newRemoteResource(logger, "GitHub", locationInfo, nil)
 
 	assert.NotNil(t, remoteresource)
 	assert.NoError(t, err)
@@ -68,7 +69,7 @@ func TestNewRemoteResource_S3(t *testing.T) {
 	locationInfo := `{
 		"path" : "https://s3.amazonaws.com/test-bucket/fake-key/"
 		}`
-	remoteresource, err := newRemoteResource(logger, "S3", locationInfo)
+	remoteresource, err := newRemoteResource(logger, "S3", locationInfo, nil)
 
 	assert.NotNil(t, remoteresource)
 	assert.NoError(t, err)
@@ -81,7 +82,7 @@ func TestNewRemoteResource_SSMDocument(t *testing.T) {
 		"name" : "doc-name",
 		"version" : "1"
 		}`
-	remoteresource, err := newRemoteResource(logger, "SSMDocument", locationInfo)
+	remoteresource, err := newRemoteResource(logger, "SSMDocument", locationInfo, nil)
 
 	assert.NotNil(t, remoteresource)
 	assert.NoError(t, err)
@@ -217,7 +218,7 @@ func TestPlugin_ExecuteGitHubFile(t *testing.T) {
 	mockIOHandler.On("AppendInfof", mock.Anything, mock.Anything).Return()
 	mockIOHandler.On("MarkAsSucceeded").Return()
 
-	githubRemoteresourceMock := func(log log.T, locationtype, locationInfo string) (remoteresource.RemoteResource, error) {
+	githubRemoteresourceMock := func(log log.T, locationtype, locationInfo string, docRedactor *redactor.Redactor) (remoteresource.RemoteResource, error) {
 
 		githubcopyContentResourceMock.On("ValidateLocationInfo").Return(true, nil).Once()
 		githubcopyContentResourceMock.On("Download", contextMock.Log(), githubCopyContentFileMock, "orch/downloads/destination").Return(nil).Once()
@@ -257,7 +258,7 @@ func TestPlugin_ExecuteS3File(t *testing.T) {
 	mockIOHandler.On("AppendInfof", mock.Anything, mock.Anything).Return()
 	mockIOHandler.On("MarkAsSucceeded").Return()
 
-	s3MockRemoteResource := func(log log.T, locationtype, locationInfo string) (remoteresource.RemoteResource, error) {
+	s3MockRemoteResource := func(log log.T, locationtype, locationInfo string, docRedactor *redactor.Redactor) (remoteresource.RemoteResource, error) {
 
 		s3copyContentResourceMock.On("ValidateLocationInfo").Return(true, nil).Once()
 		s3copyContentResourceMock.On("Download", contextMock.Log(), s3CopyContentFileMock, "/var/tmp/destination").Return(nil).Once()
@@ -296,7 +297,7 @@ func TestPlugin_ExecuteSSMDoc(t *testing.T) {
 	mockIOHandler.On("AppendInfof", mock.Anything, mock.Anything).Return()
 	mockIOHandler.On("MarkAsSucceeded").Return()
 
-	ssmDocMockRemoteResource := func(log log.T, locationtype, locationInfo string) (remoteresource.RemoteResource, error) {
+	ssmDocMockRemoteResource := func(log log.T, locationtype, locationInfo string, docRedactor *redactor.Redactor) (remoteresource.RemoteResource, error) {
 		ssmDocCopyContentResourceMock.On("ValidateLocationInfo").Return(true, nil).Once()
 		ssmDocCopyContentResourceMock.On("Download", contextMock.Log(), ssmDocCopyContentFileMock, "/var/tmp/destination/").Return(nil).Once()
 		return ssmDocCopyContentResourceMock, nil
@@ -333,7 +334,7 @@ func TestPlugin_ExecuteSSMDocError(t *testing.T) {
 	var ssmDocCopyContentFileMock = filemock.FileSystemMock{}
 	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
 
-	ssmDocMockRemoteResource := func(log log.T, locationtype, locationInfo string) (remoteresource.RemoteResource, error) {
+	ssmDocMockRemoteResource := func(log log.T, locationtype, locationInfo string, docRedactor *redactor.Redactor) (remoteresource.RemoteResource, error) {
 		ssmDoccopyContentResourceMock.On("Download", contextMock.Log(), ssmDocCopyContentFileMock, "/var/tmp/destination/").Return(errors.New("Document name must be specified")).Once()
 		ssmDoccopyContentResourceMock.On("ValidateLocationInfo").Return(true, nil).Once()
 		return ssmDoccopyContentResourceMock, nil
@@ -405,21 +406,21 @@ func TestParseAndValidateInput_NoInput(t *testing.T) {
 }
 
 // Mock and stub functions
-func fakeRemoteResource(log log.T, locationType string, locationInfo string) (remoteresource.RemoteResource, error) {
+func fakeRemoteResource(log log.T, locationType string, locationInfo string, docRedactor *redactor.Redactor) (remoteresource.RemoteResource, error) {
 
 	copyContentResourceMock.On("ValidateLocationInfo").Return(true, nil).Once()
 	copyContentResourceMock.On("Download", logger, copyContentFileMock, mock.Anything).Return(nil).Once()
 	return copyContentResourceMock, nil
 }
 
-func absoluteDestinationDirRemoteResource(log log.T, locationType string, locationInfo string) (remoteresource.RemoteResource, error) {
+func absoluteDestinationDirRemoteResource(log log.T, locationType string, locationInfo string, docRedactor *redactor.Redactor) (remoteresource.RemoteResource, error) {
 
 	copyContentResourceMock.On("ValidateLocationInfo").Return(true, nil).Once()
 	copyContentResourceMock.On("Download", logger, copyContentFileMock, "/var/temp/fake-dir").Return(nil).Once()
 	return copyContentResourceMock, nil
 }
 
-func relativeDestinationDirRemoteResource(log log.T, locationType string, locationInfo string) (remoteresource.RemoteResource, error) {
+func relativeDestinationDirRemoteResource(log log.T, locationType string, locationInfo string, docRedactor *redactor.Redactor) (remoteresource.RemoteResource, error) {
 	copyContentResourceMock.On("ValidateLocationInfo").Return(true, nil).Once()
 	copyContentResourceMock.On("Download", logger, copyContentFileMock, "orch/downloads/temp/fake-dir/").Return(nil).Once()
 	return copyContentResourceMock, nil
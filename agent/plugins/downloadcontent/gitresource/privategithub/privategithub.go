@@ -19,6 +19,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/githubclient"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/parameterstore"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
 
 	"errors"
@@ -40,6 +41,8 @@ type TokenInfoImpl struct {
 		resolverOptions ssmparameterresolver.ResolveOptions) (info map[string]ssmparameterresolver.SsmParameterInfo, err error)
 	paramAccess    ssmparameterresolver.SsmParameterService
 	gitoauthclient githubclient.IOAuthClient
+	// redactor registers the resolved token so that it is scrubbed from the output of the document
+	redactor *redactor.Redactor
 }
 
 // GetOAuthClient is the only method from privategithub package that is accessible to gitresource
@@ -68,6 +71,7 @@ func (t TokenInfoImpl) GetOAuthClient(log log.T, tokenInfo string) (client *http
 
 	resolverOptions := ssmparameterresolver.ResolveOptions{
 		IgnoreSecureParameters: false,
+		Redactor:               t.redactor,
 	}
 
 	// Get the parameter value from parameter store.
@@ -112,12 +116,13 @@ func validateTokenParameter(tokenInfo string) (valid bool, err error) {
 		"Please specify parameter as '{{ ssm-secure:parameter-name }}'")
 }
 
-// NewTokenInfoImpl returns an object of type TokenInfoImpl
-func NewTokenInfoImpl() TokenInfoImpl {
+// NewTokenInfoImpl returns an object of type TokenInfoImpl, the token is registered with the given document redactor
+func NewTokenInfoImpl(docRedactor *redactor.Redactor) TokenInfoImpl {
 	parameterService := ssmparameterresolver.NewService()
 	return TokenInfoImpl{
 		SsmParameter:   getSSMParameter,
 		paramAccess:    parameterService,
 		gitoauthclient: githubclient.OAuthClient{},
+		redactor:       docRedactor,
 	}
 }
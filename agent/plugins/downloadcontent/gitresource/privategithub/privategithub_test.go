@@ -20,6 +20,7 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/parameterstore"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
 	"github.com/stretchr/testify/assert"
 
@@ -52,6 +53,30 @@ func TestTokenInfoImpl_GetOAuthClient_Success(t *testing.T) {
 	oauthclientmock.AssertExpectations(t)
 }
 
+func TestTokenInfoImpl_GetOAuthClient_RegistersTokenWithRedactor(t *testing.T) {
+
+	oauthclientmock := gitmock.OAuthClientMock{}
+	var clientVal *http.Client
+	oauthclientmock.On("GetGithubOauthClient", "lskjksjgshfg1234jdskjhgvs").Return(clientVal)
+
+	docRedactor := redactor.New()
+	var usedRedactor *redactor.Redactor
+	tokenInfo := TokenInfoImpl{
+		SsmParameter: func(log log.T, paramService ssmparameterresolver.ISsmParameterService, parameterReferences []string,
+			resolverOptions ssmparameterresolver.ResolveOptions) (map[string]ssmparameterresolver.SsmParameterInfo, error) {
+			usedRedactor = resolverOptions.Redactor
+			return getMockedSecureParam(log, paramService, parameterReferences, resolverOptions)
+		},
+		gitoauthclient: oauthclientmock,
+		redactor:       docRedactor,
+	}
+
+	_, err := tokenInfo.GetOAuthClient(logMock, `{{ ssm-secure:dummysecureparam }}`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, docRedactor, usedRedactor)
+}
+
 func TestTokenInfoImpl_ValidateTokenParameter_Failure(t *testing.T) {
 
 	// tokenInfoInput has a format that is unsupported for token information.
@@ -16,6 +16,7 @@ package runscript
 
 import (
 	"fmt"
+	"io"
 	"path/filepath"
 
 	"strings"
@@ -55,6 +56,16 @@ type RunScriptPluginInput struct {
 	ID               string
 	WorkingDirectory string
 	TimeoutSeconds   interface{}
+	// CheckCommand is run instead of RunCommand in check-only mode, it exits with 0 when the system is compliant
+	CheckCommand []string
+	// OutputFile is the path of the file, relative to the working directory, the commands write a JSON object to,
+	// the object becomes the output of the step
+	OutputFile string
+	// OutputFromStdout takes the output of the step from the JSON object the commands print between the lines
+	// ##ssm-output-begin## and ##ssm-output-end##, OutputFile takes precedence
+	OutputFromStdout bool
+	// OutputSchema is the JSON schema the structured output of the step must match
+	OutputSchema map[string]interface{}
 }
 
 // Execute runs multiple sets of commands and returns their outputs.
@@ -73,6 +84,34 @@ func (p *Plugin) Execute(context context.T, config contracts.Configuration, canc
 	}
 }
 
+// Check runs the check commands of one set of commands instead of the commands themselves.
+// The check succeeds when the check commands exit with 0, i.e. the system is compliant.
+func (p *Plugin) Check(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v checking configuration %v", p.Name, config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	var pluginInput RunScriptPluginInput
+	if err := jsonutil.Remarshal(config.Properties, &pluginInput); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+	if len(pluginInput.CheckCommand) == 0 {
+		output.AppendInfo("No checkCommand provided, step was not checked")
+		output.SetStatus(contracts.ResultStatusSkipped)
+		return
+	}
+	pluginInput.RunCommand = pluginInput.CheckCommand
+	p.runCommands(log, config.PluginID, pluginInput, config.OrchestrationDirectory, config.DefaultWorkingDirectory, cancelFlag, output)
+}
+
 // runCommandsRawInput executes one set of commands and returns their output.
 // The input is in the default json unmarshal format (e.g. map[string]interface{}).
 func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput interface{}, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
@@ -129,8 +168,16 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 	commandName := p.ShellCommand
 	commandArguments := append(p.ShellArguments, scriptPath, appconfig.ExitCodeTrap)
 
+	// Scan the standard output for the structured output when the commands print it
+	var stdoutWriter io.Writer = output.GetStdoutWriter()
+	var outputBlock *outputBlockWriter
+	if pluginInput.OutputFile == "" && pluginInput.OutputFromStdout {
+		outputBlock = new(outputBlockWriter)
+		stdoutWriter = io.MultiWriter(stdoutWriter, outputBlock)
+	}
+
 	// Execute Command
-	exitCode, err := p.CommandExecuter.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments)
+	exitCode, err := p.CommandExecuter.NewExecute(log, workingDir, stdoutWriter, output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments)
 
 	// Set output status
 	output.SetExitCode(exitCode)
@@ -144,4 +191,36 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 			output.MarkAsFailed(fmt.Errorf("failed to run commands: %v", err))
 		}
 	}
+
+	if pluginInput.OutputFile != "" || pluginInput.OutputFromStdout {
+		setStructuredOutput(log, pluginInput, workingDir, outputBlock, output)
+	}
+}
+
+// setStructuredOutput sets the JSON object written by the successful commands as the output of the step,
+// the step fails when the object is missing or does not match the output schema
+func setStructuredOutput(log log.T, pluginInput RunScriptPluginInput, workingDir string, outputBlock *outputBlockWriter, output iohandler.IOHandler) {
+	if output.GetStatus() != contracts.ResultStatusSuccess {
+		return
+	}
+
+	var content []byte
+	var err error
+	if pluginInput.OutputFile != "" {
+		content, err = readOutputFile(outputFilePath(pluginInput.OutputFile, workingDir))
+	} else {
+		content, err = outputBlock.output()
+	}
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	structuredOutput, err := parseStructuredOutput(content, pluginInput.OutputSchema)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	log.Debugf("Structured output of the commands has %v keys", len(structuredOutput))
+	output.SetOutput(structuredOutput)
 }
@@ -15,11 +15,15 @@ package runscript
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
@@ -185,6 +189,58 @@ func TestExecute(t *testing.T) {
 	}
 }
 
+// TestCheck tests the run command plugin's Check method, which runs the check commands instead of the commands.
+func TestCheck(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "runscript")
+	assert.Nil(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	for _, testCase := range TestCases {
+		testCase.Input.CheckCommand = []string{"test -f /etc/compliant"}
+		checkTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+			setCancelFlagExpectations(mockCancelFlag, 1)
+			setExecuterExpectations(mockExecuter, testCase, mockCancelFlag, p)
+			setIOHandlerExpectations(mockIOHandler, testCase)
+
+			p.Check(
+				context.NewMockDefault(),
+				contracts.Configuration{
+					Properties:             singleValuePropertyBuilder(t, testCase),
+					OrchestrationDirectory: orchestrationDir,
+					PluginID:               pluginID,
+					CheckOnly:              true,
+				}, mockCancelFlag, mockIOHandler)
+
+			script, err := ioutil.ReadFile(filepath.Join(fileutil.BuildPath(orchestrationDir, testCase.Input.ID), p.ScriptName))
+			assert.Nil(t, err)
+			assert.Contains(t, string(script), "test -f /etc/compliant")
+			assert.NotContains(t, string(script), testCase.Input.RunCommand[0])
+		}
+
+		testExecution(t, checkTester)
+	}
+}
+
+// TestCheckWithoutCheckCommand tests that steps without check commands are not checked.
+func TestCheckWithoutCheckCommand(t *testing.T) {
+	checkTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		mockIOHandler.On("AppendInfo", "No checkCommand provided, step was not checked").Return()
+		mockIOHandler.On("SetStatus", contracts.ResultStatusSkipped).Return()
+
+		p.Check(
+			context.NewMockDefault(),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, TestCases[0]),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+				CheckOnly:              true,
+			}, mockCancelFlag, mockIOHandler)
+	}
+
+	testExecution(t, checkTester)
+}
+
 func arrayPropertyBuilder(t *testing.T, testCases []TestCase) interface{} {
 	var pluginProperties []interface{}
 
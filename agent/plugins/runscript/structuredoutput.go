@@ -0,0 +1,246 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runscript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+const (
+	// outputBeginMarker and outputEndMarker surround, each on its own line, the JSON object the commands print
+	// to standard output as the structured output of the step
+	outputBeginMarker = "##ssm-output-begin##"
+	outputEndMarker   = "##ssm-output-end##"
+
+	// maxStructuredOutputSize is the maximum size of the JSON object of a structured output
+	maxStructuredOutputSize = 256 * 1024
+)
+
+// outputBlockWriter captures the last block of lines the commands print between the output markers,
+// it keeps at most maxStructuredOutputSize bytes
+type outputBlockWriter struct {
+	line         []byte
+	lineTooLong  bool
+	block        bytes.Buffer
+	inBlock      bool
+	blockTooLong bool
+	found        bool
+	result       []byte
+	resultErr    error
+}
+
+// Write scans the output of the commands for the output markers
+func (w *outputBlockWriter) Write(p []byte) (int, error) {
+	data := p
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			w.appendLine(data)
+			break
+		}
+		w.appendLine(data[:end+1])
+		w.endLine()
+		data = data[end+1:]
+	}
+	return len(p), nil
+}
+
+// appendLine adds a part of the current line, the lines longer than a structured output are dropped
+func (w *outputBlockWriter) appendLine(part []byte) {
+	if len(w.line)+len(part) > maxStructuredOutputSize {
+		w.lineTooLong = true
+		return
+	}
+	w.line = append(w.line, part...)
+}
+
+// endLine handles a complete line, either a marker or a line of the block
+func (w *outputBlockWriter) endLine() {
+	text := strings.TrimSpace(string(w.line))
+	switch {
+	case !w.lineTooLong && text == outputBeginMarker:
+		w.inBlock = true
+		w.blockTooLong = false
+		w.block.Reset()
+	case !w.lineTooLong && text == outputEndMarker && w.inBlock:
+		w.inBlock = false
+		w.found = true
+		w.result = append([]byte(nil), w.block.Bytes()...)
+		w.resultErr = nil
+		if w.blockTooLong {
+			w.result = nil
+			w.resultErr = fmt.Errorf("the output between %v and %v is larger than %v bytes", outputBeginMarker, outputEndMarker, maxStructuredOutputSize)
+		}
+	case w.inBlock:
+		if w.lineTooLong || w.block.Len()+len(w.line) > maxStructuredOutputSize {
+			w.blockTooLong = true
+		} else {
+			w.block.Write(w.line)
+		}
+	}
+	w.line = w.line[:0]
+	w.lineTooLong = false
+}
+
+// output returns the last complete block printed between the output markers
+func (w *outputBlockWriter) output() ([]byte, error) {
+	if len(w.line) > 0 || w.lineTooLong {
+		w.endLine()
+	}
+	if !w.found {
+		return nil, fmt.Errorf("no output was printed between %v and %v", outputBeginMarker, outputEndMarker)
+	}
+	return w.result, w.resultErr
+}
+
+// readOutputFile reads the JSON object the commands wrote to the output file
+func readOutputFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the output file %v: %v", path, err)
+	}
+	if info.Size() > maxStructuredOutputSize {
+		return nil, fmt.Errorf("the output file %v is larger than %v bytes", path, maxStructuredOutputSize)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the output file %v: %v", path, err)
+	}
+	return content, nil
+}
+
+// outputFilePath returns the path of the output file, relative paths are relative to the working directory
+func outputFilePath(outputFile string, workingDir string) string {
+	if filepath.IsAbs(outputFile) {
+		return outputFile
+	}
+	return filepath.Join(workingDir, outputFile)
+}
+
+// parseStructuredOutput parses the JSON object of a structured output and validates it against the schema, if any
+func parseStructuredOutput(content []byte, schema map[string]interface{}) (contracts.StructuredOutput, error) {
+	var value interface{}
+	if err := json.Unmarshal(content, &value); err != nil {
+		return nil, fmt.Errorf("the output is not valid JSON: %v", err)
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the output is not a JSON object")
+	}
+	if len(schema) > 0 {
+		if err := validateOutput(schema, object, "output"); err != nil {
+			return nil, fmt.Errorf("the output does not match the output schema: %v", err)
+		}
+	}
+	return contracts.StructuredOutput(object), nil
+}
+
+// validateOutput validates a value against a schema, the schema supports the type, enum, properties, required,
+// additionalProperties and items keywords of JSON schema
+func validateOutput(schema map[string]interface{}, value interface{}, path string) error {
+	if schemaType, ok := schema["type"].(string); ok && !hasType(value, schemaType) {
+		return fmt.Errorf("%v is not of type %v", path, schemaType)
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%v is not one of %v", path, enum)
+		}
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if key, ok := name.(string); ok {
+					if _, exists := typed[key]; !exists {
+						return fmt.Errorf("%v.%v is required", path, key)
+					}
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			propertySchema, defined := properties[key].(map[string]interface{})
+			if !defined {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%v.%v is not allowed", path, key)
+				}
+				continue
+			}
+			if err := validateOutput(propertySchema, typed[key], path+"."+key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range typed {
+				if err := validateOutput(itemSchema, item, fmt.Sprintf("%v[%v]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// hasType returns true if the JSON value is of the JSON schema type
+func hasType(value interface{}, schemaType string) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
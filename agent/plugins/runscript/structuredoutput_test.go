@@ -0,0 +1,162 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runscript
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOutputBlockWriter(t *testing.T) {
+	w := new(outputBlockWriter)
+	_, err := w.output()
+	assert.Error(t, err)
+
+	// the markers may be split across writes, the last complete block wins
+	for _, part := range []string{
+		"installing\n##ssm-output-begin##\n{\"version\": \"1\"}\n##ssm-output-end##\n",
+		"done\n  ##ssm-output-",
+		"begin##\r\n{\"version\":\n \"2\"}\r\n##ssm-output-end##",
+	} {
+		w.Write([]byte(part))
+	}
+	content, err := w.output()
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"version\":\n \"2\"}\r\n", string(content))
+
+	// an unterminated block is ignored
+	w = new(outputBlockWriter)
+	w.Write([]byte("##ssm-output-begin##\n{}\n##ssm-output-end##\n##ssm-output-begin##\n{\"partial\": "))
+	content, err = w.output()
+	assert.NoError(t, err)
+	assert.Equal(t, "{}\n", string(content))
+
+	// a block larger than the maximum size fails
+	w = new(outputBlockWriter)
+	w.Write([]byte("##ssm-output-begin##\n"))
+	w.Write([]byte(strings.Repeat("x", maxStructuredOutputSize+1)))
+	w.Write([]byte("\n##ssm-output-end##\n"))
+	_, err = w.output()
+	assert.Error(t, err)
+}
+
+func TestParseStructuredOutput(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"version", "packages"},
+		"properties": map[string]interface{}{
+			"version":  map[string]interface{}{"type": "string"},
+			"status":   map[string]interface{}{"enum": []interface{}{"installed", "upgraded"}},
+			"packages": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+		},
+		"additionalProperties": false,
+	}
+
+	output, err := parseStructuredOutput([]byte(`{"version": "1.2", "status": "installed", "packages": [1, 2]}`), schema)
+	assert.NoError(t, err)
+	assert.Equal(t, contracts.StructuredOutput{"version": "1.2", "status": "installed", "packages": []interface{}{float64(1), float64(2)}}, output)
+
+	// any object is accepted without schema
+	output, err = parseStructuredOutput([]byte(`{"free": {"form": true}}`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, contracts.StructuredOutput{"free": map[string]interface{}{"form": true}}, output)
+
+	for content, message := range map[string]string{
+		`not json`:                                              "not valid JSON",
+		`["version"]`:                                           "not a JSON object",
+		`{"version": "1.2"}`:                                    "output.packages is required",
+		`{"version": 1, "packages": []}`:                        "output.version is not of type string",
+		`{"version": "1", "packages": [1.5]}`:                   "output.packages[0] is not of type integer",
+		`{"version": "1", "packages": [], "extra": 1}`:          "output.extra is not allowed",
+		`{"version": "1", "packages": [], "status": "removed"}`: "output.status is not one of",
+	} {
+		_, err = parseStructuredOutput([]byte(content), schema)
+		if assert.Error(t, err, content) {
+			assert.Contains(t, err.Error(), message, content)
+		}
+	}
+}
+
+// runStructuredOutputCommands runs the commands with an executer printing stdout, the output handler writes
+// the output to the orchestration directory
+func runStructuredOutputCommands(t *testing.T, input RunScriptPluginInput, exitCode int, stdout string) *iohandler.DefaultIOHandler {
+	orchestrationDir, err := ioutil.TempDir("", "runscript")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	mockExecuter := new(executers.MockCommandExecuter)
+	mockExecuter.On("NewExecute", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		io.WriteString(args.Get(2).(io.Writer), stdout)
+	}).Return(exitCode, nil)
+	p := &Plugin{CommandExecuter: mockExecuter, Name: "aws:runShellScript", ScriptName: "_script.sh", ShellCommand: "sh"}
+
+	output := iohandler.NewDefaultIOHandler(logger, contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir})
+	output.Init(logger, pluginID)
+	p.runCommands(logger, pluginID, input, orchestrationDir, orchestrationDir, task.NewChanneledCancelFlag(), output)
+	output.Close(logger)
+	return output
+}
+
+func TestRunCommandsWithStructuredOutput(t *testing.T) {
+	input := RunScriptPluginInput{
+		RunCommand:       []string{"./install.sh"},
+		OutputFromStdout: true,
+		OutputSchema:     map[string]interface{}{"required": []interface{}{"version"}},
+	}
+
+	stdout := "installing\n##ssm-output-begin##\n{\"version\": \"1.2\"}\n##ssm-output-end##\n"
+	output := runStructuredOutputCommands(t, input, 0, stdout)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Equal(t, contracts.StructuredOutput{"version": "1.2"}, output.GetOutput())
+	assert.Equal(t, stdout, output.GetStdout())
+
+	// the step fails when the output does not match the schema
+	output = runStructuredOutputCommands(t, input, 0, "##ssm-output-begin##\n{}\n##ssm-output-end##\n")
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), "output.version is required")
+
+	// the output of failed commands is not parsed
+	output = runStructuredOutputCommands(t, input, 1, "failed\n")
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Equal(t, "failed\n", output.GetOutput())
+}
+
+func TestRunCommandsWithOutputFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runscript")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	outputFile := filepath.Join(dir, "output.json")
+	assert.NoError(t, ioutil.WriteFile(outputFile, []byte(`{"rebooted": false}`), 0600))
+
+	input := RunScriptPluginInput{RunCommand: []string{"./install.sh"}, OutputFile: outputFile, OutputFromStdout: true}
+	output := runStructuredOutputCommands(t, input, 0, "##ssm-output-begin##\n{}\n##ssm-output-end##\n")
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Equal(t, contracts.StructuredOutput{"rebooted": false}, output.GetOutput())
+
+	input.OutputFile = filepath.Join(dir, "missing.json")
+	output = runStructuredOutputCommands(t, input, 0, "")
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), "failed to read the output file")
+}
@@ -0,0 +1,187 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package redactor keeps track of secret values used by documents and scrubs them from any text
+// before it leaves the agent, e.g. plugin output, orchestration files, uploaded outputs and log lines.
+package redactor
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// Mask is the string that replaces every occurrence of a secret value
+	Mask = "****"
+)
+
+// Redactor holds the secret values registered for a single document.
+type Redactor struct {
+	mutex   sync.RWMutex
+	secrets []string
+}
+
+// registry holds the redactors of the documents currently executing, indexed by document ID
+var registry = struct {
+	sync.RWMutex
+	redactors map[string]*Redactor
+}{redactors: make(map[string]*Redactor)}
+
+// New creates an empty Redactor.
+func New() *Redactor {
+	return &Redactor{}
+}
+
+// ForDocument returns the redactor of the given document, creating it if needed.
+func ForDocument(documentID string) *Redactor {
+	registry.Lock()
+	defer registry.Unlock()
+	r, ok := registry.redactors[documentID]
+	if !ok {
+		r = New()
+		registry.redactors[documentID] = r
+	}
+	return r
+}
+
+// Release removes the redactor of the given document once the document is done executing.
+func Release(documentID string) {
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.redactors, documentID)
+}
+
+// RedactAll scrubs the secrets of every registered document from the given text.
+// It is used for text that is not bound to a single document, such as agent log lines.
+func RedactAll(text string) string {
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, r := range registry.redactors {
+		text = r.Redact(text)
+	}
+	return text
+}
+
+// HasSecrets returns true if any registered document has at least one secret.
+func HasSecrets() bool {
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, r := range registry.redactors {
+		if !r.IsEmpty() {
+			return true
+		}
+	}
+	return false
+}
+
+// Register adds a secret value to the redactor. Empty values are ignored.
+func (r *Redactor) Register(secret string) {
+	if r == nil || secret == "" {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, s := range r.secrets {
+		if s == secret {
+			return
+		}
+	}
+	r.secrets = append(r.secrets, secret)
+	// replace longer secrets first so that a secret containing another one is fully masked
+	sort.Sort(byLengthDesc(r.secrets))
+}
+
+// IsEmpty returns true if no secret has been registered.
+func (r *Redactor) IsEmpty() bool {
+	if r == nil {
+		return true
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.secrets) == 0
+}
+
+// Redact replaces every registered secret in the given text with Mask.
+func (r *Redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, s := range r.secrets {
+		text = strings.Replace(text, s, Mask, -1)
+	}
+	return text
+}
+
+// RedactBytes replaces every registered secret in the given text with Mask,
+// the text is returned as is when there is no secret.
+func (r *Redactor) RedactBytes(text []byte) []byte {
+	if r.IsEmpty() {
+		return text
+	}
+	return []byte(r.Redact(string(text)))
+}
+
+// SafePrefixLength returns the length of the beginning of a partial text which can be redacted on its own.
+// The rest of the text may be the beginning of a secret completed by the text that follows, or the end of
+// a secret which starts before the returned length, it must be redacted along with the text that follows.
+func (r *Redactor) SafePrefixLength(text string) int {
+	if r == nil {
+		return len(text)
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	safe := len(text)
+	for _, s := range r.secrets {
+		// longest end of the text which is the beginning of the secret
+		for k := len(s) - 1; k > 0; k-- {
+			if k <= len(text) && strings.HasSuffix(text, s[:k]) {
+				if len(text)-k < safe {
+					safe = len(text) - k
+				}
+				break
+			}
+		}
+	}
+
+	// move the boundary before the secrets it would cut
+	for moved := true; moved; {
+		moved = false
+		for _, s := range r.secrets {
+			start, end := safe-len(s)+1, safe+len(s)-1
+			if start < 0 {
+				start = 0
+			}
+			if end > len(text) {
+				end = len(text)
+			}
+			if start < end {
+				if i := strings.Index(text[start:end], s); i >= 0 {
+					safe = start + i
+					moved = true
+				}
+			}
+		}
+	}
+	return safe
+}
+
+// byLengthDesc sorts secrets from the longest to the shortest
+type byLengthDesc []string
+
+func (s byLengthDesc) Len() int           { return len(s) }
+func (s byLengthDesc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byLengthDesc) Less(i, j int) bool { return len(s[i]) > len(s[j]) }
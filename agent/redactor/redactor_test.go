@@ -0,0 +1,87 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package redactor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	r := New()
+	assert.True(t, r.IsEmpty())
+	assert.Equal(t, "password is secret", r.Redact("password is secret"))
+
+	r.Register("secret")
+	r.Register("")
+	assert.False(t, r.IsEmpty())
+	assert.Equal(t, "password is ****, again ****", r.Redact("password is secret, again secret"))
+}
+
+func TestRedactLongestSecretFirst(t *testing.T) {
+	r := New()
+	r.Register("abc")
+	r.Register("abcdef")
+	assert.Equal(t, "**** and ****", r.Redact("abcdef and abc"))
+}
+
+func TestRedactNilRedactor(t *testing.T) {
+	var r *Redactor
+	r.Register("secret")
+	assert.True(t, r.IsEmpty())
+	assert.Equal(t, "secret", r.Redact("secret"))
+}
+
+func TestRedactBytes(t *testing.T) {
+	r := New()
+	text := []byte("password is secret")
+	assert.Equal(t, &text[0], &r.RedactBytes(text)[0])
+
+	r.Register("secret")
+	assert.Equal(t, "password is ****", string(r.RedactBytes(text)))
+}
+
+func TestSafePrefixLength(t *testing.T) {
+	var empty *Redactor
+	assert.Equal(t, 10, empty.SafePrefixLength("0123456789"))
+
+	r := New()
+	r.Register("secret")
+	r.Register("tokens")
+	assert.Equal(t, 12, r.SafePrefixLength("password is "))
+	// the end may be the beginning of a secret
+	assert.Equal(t, 12, r.SafePrefixLength("password is secr"))
+	assert.Equal(t, 19, r.SafePrefixLength("password is secret!"))
+	// the boundary does not cut a secret completed in the text
+	assert.Equal(t, 4, r.SafePrefixLength("the tokensecre"))
+	assert.Equal(t, 12, r.SafePrefixLength("password is secret"))
+}
+
+func TestDocumentRegistry(t *testing.T) {
+	assert.False(t, HasSecrets())
+
+	ForDocument("doc1").Register("first")
+	ForDocument("doc2").Register("second")
+	assert.True(t, HasSecrets())
+	assert.Equal(t, ForDocument("doc1"), ForDocument("doc1"))
+	assert.Equal(t, "**** ****", RedactAll("first second"))
+	assert.Equal(t, "**** second", ForDocument("doc1").Redact("first second"))
+
+	Release("doc1")
+	assert.Equal(t, "first ****", RedactAll("first second"))
+
+	Release("doc2")
+	assert.False(t, HasSecrets())
+}
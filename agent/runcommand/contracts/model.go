@@ -35,6 +35,17 @@ type SendCommandPayload struct {
 	DocumentName       string                    `json:"DocumentName"`
 	OutputS3KeyPrefix  string                    `json:"OutputS3KeyPrefix"`
 	OutputS3BucketName string                    `json:"OutputS3BucketName"`
+	// OutputS3ServerSideEncryption and OutputS3KmsKeyId override the encryption of the output uploaded to S3
+	OutputS3ServerSideEncryption string `json:"OutputS3ServerSideEncryption"`
+	OutputS3KmsKeyId             string `json:"OutputS3KmsKeyId"`
+	// OutputSinks overrides the destinations the output of the plugins is written to
+	OutputSinks []string `json:"OutputSinks"`
+	// CloudWatchOutputEnabled streams the output of the plugins to CloudWatchLogGroupName,
+	// or to a log group named from the document when it is empty
+	CloudWatchOutputEnabled bool   `json:"CloudWatchOutputEnabled"`
+	CloudWatchLogGroupName  string `json:"CloudWatchLogGroupName"`
+	// DocumentSignature is the detached signature of the document content, if any
+	DocumentSignature []byte `json:"DocumentSignature"`
 }
 
 // SendReplyPayload represents the json structure of a reply sent to MDS.
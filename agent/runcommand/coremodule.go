@@ -39,6 +39,9 @@ const (
 	cloudwatchPlugin = "aws:cloudWatch"
 	properties       = "properties"
 	parameters       = "Parameters"
+
+	// defaultCloudWatchLogGroupPrefix prefixes the document name to name the log group of the command output
+	defaultCloudWatchLogGroupPrefix = "/aws/ssm/"
 )
 
 var singletonMapOfUnsupportedSSMDocs map[string]bool
@@ -63,6 +66,11 @@ func (s *RunCommandService) ModuleExecute(context context.T) (err error) {
 		return
 	}
 	go s.listenReply(resultChan)
+	if s.localApi != nil {
+		if err := s.localApi.Start(); err != nil {
+			log.Errorf("unable to start local api: %v", err)
+		}
+	}
 	log.Info("Starting message polling")
 	if s.messagePollJob, err = scheduler.Every(pollMessageFrequencyMinutes).Minutes().Run(s.loop); err != nil {
 		context.Log().Errorf("unable to schedule message poll job. %v", err)
@@ -75,8 +83,11 @@ func (s *RunCommandService) ModuleExecute(context context.T) (err error) {
 }
 
 func (s *RunCommandService) ModuleRequestStop(stopType contracts.StopType) (err error) {
-	//first stop the message poller
+	//first stop the message poller and the local api
 	s.stop()
+	if s.localApi != nil {
+		s.localApi.Stop()
+	}
 	//second stop the message processor
 	s.processor.Stop(stopType)
 
@@ -0,0 +1,56 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runcommand implements runcommand core processing module
+package runcommand
+
+import (
+	"fmt"
+
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	mdsService "github.com/aws/amazon-ssm-agent/agent/runcommand/mds"
+)
+
+// localApiDocumentName is the name of the documents submitted through the local api without a name
+const localApiDocumentName = "LocalApiDocument"
+
+// SubmitCommand runs a document submitted through the local api the same way as a document received from the service
+func (s *RunCommandService) SubmitCommand(documentName string, content []byte, parameters map[string]interface{}, signature []byte) (string, error) {
+	localService, ok := s.service.(mdsService.LocalCommandService)
+	if !ok {
+		return "", fmt.Errorf("%v does not accept local commands", s.name)
+	}
+	if documentName == "" {
+		documentName = localApiDocumentName
+	}
+	msg, err := localService.NewSendCommandMessage(s.context.Log(), s.config.InstanceID, documentName, content, parameters, signature)
+	if err != nil {
+		return "", err
+	}
+	s.processMessage(msg)
+	return messageContracts.GetCommandID(*msg.MessageId)
+}
+
+// CancelCommand cancels a command submitted through the local api or the local command folder
+func (s *RunCommandService) CancelCommand(commandID string) error {
+	localService, ok := s.service.(mdsService.LocalCommandService)
+	if !ok {
+		return fmt.Errorf("%v does not accept local commands", s.name)
+	}
+	msg, err := localService.NewCancelCommandMessage(s.context.Log(), s.config.InstanceID, commandID)
+	if err != nil {
+		return err
+	}
+	s.processMessage(msg)
+	return nil
+}
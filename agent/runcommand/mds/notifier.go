@@ -0,0 +1,174 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package service is a wrapper for the SSM Message Delivery Service and Offline Command Service
+package service
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+)
+
+// OutboxResult is the normalized result of an offline command written to the outbox
+type OutboxResult struct {
+	CommandID    string             `json:"commandId"`
+	DocumentName string             `json:"documentName"`
+	Status       string             `json:"status"`
+	CompletedAt  string             `json:"completedAt"`
+	Steps        []OutboxResultStep `json:"steps"`
+}
+
+// OutboxResultStep is the result of a step of an offline command
+type OutboxResultStep struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	ExitCode       int    `json:"exitCode"`
+	StartedAt      string `json:"startedAt"`
+	CompletedAt    string `json:"completedAt"`
+	Output         string `json:"output"`
+	StandardOutput string `json:"standardOutput"`
+	StandardError  string `json:"standardError"`
+}
+
+// resultNotifier tells local tools about the offline commands which completed
+type resultNotifier struct {
+	outboxDir string
+	command   []string
+	timeout   time.Duration
+}
+
+// newResultNotifier returns the notifier configured for the agent, nil when neither an outbox nor a command is configured
+func newResultNotifier(config appconfig.AgentInfo) *resultNotifier {
+	if config.LocalCommandOutbox == "" && len(config.LocalCommandNotifier) == 0 {
+		return nil
+	}
+	return &resultNotifier{
+		outboxDir: config.LocalCommandOutbox,
+		command:   config.LocalCommandNotifier,
+		timeout:   time.Duration(config.LocalCommandNotifierTimeoutSeconds) * time.Second,
+	}
+}
+
+// isCommandComplete returns true if the reply is the final one of its command
+func isCommandComplete(reply messageContracts.SendReplyPayload) bool {
+	switch reply.DocumentStatus {
+	case "", contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress,
+		contracts.ResultStatusSuccessAndReboot, contracts.ResultStatusPassedAndReboot:
+		return false
+	}
+	return true
+}
+
+// notify writes the result of a completed command to the outbox and runs the notifier command with its path,
+// resultPath is passed to the command when there is no outbox
+func (n *resultNotifier) notify(log log.T, commandID string, documentName string, payload string, resultPath string) {
+	var reply messageContracts.SendReplyPayload
+	if err := jsonutil.Unmarshal(payload, &reply); err != nil {
+		log.Errorf("failed to parse command %v result: %v", commandID, err)
+		return
+	}
+	if !isCommandComplete(reply) {
+		return
+	}
+
+	if n.outboxDir != "" {
+		var err error
+		if resultPath, err = n.writeOutboxResult(commandID, newOutboxResult(commandID, documentName, reply)); err != nil {
+			log.Errorf("failed to write command %v result to outbox %v: %v", commandID, n.outboxDir, err)
+			return
+		}
+		log.Debugf("wrote command %v result to %v", commandID, resultPath)
+	}
+	if len(n.command) > 0 {
+		// the notifier command must not hold up the processing of the command replies
+		go n.runCommand(log, commandID, resultPath)
+	}
+}
+
+// newOutboxResult normalizes the reply of a command, its steps are sorted by the time they started
+func newOutboxResult(commandID string, documentName string, reply messageContracts.SendReplyPayload) OutboxResult {
+	result := OutboxResult{
+		CommandID:    commandID,
+		DocumentName: documentName,
+		Status:       string(reply.DocumentStatus),
+		CompletedAt:  reply.AdditionalInfo.DateTime,
+		Steps:        make([]OutboxResultStep, 0, len(reply.RuntimeStatus)),
+	}
+	for name, status := range reply.RuntimeStatus {
+		if status == nil {
+			continue
+		}
+		result.Steps = append(result.Steps, OutboxResultStep{
+			Name:           name,
+			Status:         string(status.Status),
+			ExitCode:       status.Code,
+			StartedAt:      status.StartDateTime,
+			CompletedAt:    status.EndDateTime,
+			Output:         status.Output,
+			StandardOutput: status.StandardOutput,
+			StandardError:  status.StandardError,
+		})
+	}
+	sort.Slice(result.Steps, func(i, j int) bool {
+		if result.Steps[i].StartedAt != result.Steps[j].StartedAt {
+			return result.Steps[i].StartedAt < result.Steps[j].StartedAt
+		}
+		return result.Steps[i].Name < result.Steps[j].Name
+	})
+	return result
+}
+
+// writeOutboxResult writes the result under a hidden temporary name and renames it, so that the tools watching
+// the outbox never read a partial result
+func (n *resultNotifier) writeOutboxResult(commandID string, result OutboxResult) (string, error) {
+	content, err := jsonutil.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	if err = fileutil.MakeDirs(n.outboxDir); err != nil {
+		return "", err
+	}
+	resultPath := filepath.Join(n.outboxDir, commandID+".json")
+	if err = fileutil.WriteFileAtomic(resultPath, []byte(content), appconfig.ReadWriteAccess); err != nil {
+		return "", err
+	}
+	return resultPath, nil
+}
+
+// runCommand runs the notifier command with the path of the result, the command is killed when it runs too long
+func (n *resultNotifier) runCommand(log log.T, commandID string, resultPath string) {
+	ctx, cancel := context.WithTimeout(context.Background(), n.timeout)
+	defer cancel()
+	args := append(append([]string{}, n.command[1:]...), resultPath)
+	output, err := exec.CommandContext(ctx, n.command[0], args...).CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %v", n.timeout)
+	}
+	if err != nil {
+		log.Errorf("notifier %v failed for command %v: %v\n%v", n.command[0], commandID, err, strings.TrimSpace(string(output)))
+		return
+	}
+	log.Debugf("notifier %v ran for command %v", n.command[0], commandID)
+}
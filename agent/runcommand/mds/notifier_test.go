@@ -0,0 +1,133 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package service is a wrapper for the SSM Message Delivery Service and Offline Command Service
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestReply(status contracts.ResultStatus) string {
+	reply, _ := jsonutil.Marshal(messageContracts.SendReplyPayload{
+		AdditionalInfo: contracts.AdditionalInfo{DateTime: "2017-10-12T10:00:02.000Z"},
+		DocumentStatus: status,
+		RuntimeStatus: map[string]*contracts.PluginRuntimeStatus{
+			"second": {Status: status, Code: 1, StartDateTime: "2017-10-12T10:00:01.000Z", StandardError: "oops"},
+			"first":  {Status: contracts.ResultStatusSuccess, StartDateTime: "2017-10-12T10:00:00.000Z", StandardOutput: "hello"},
+		},
+	})
+	return reply
+}
+
+func TestNewResultNotifier(t *testing.T) {
+	assert.Nil(t, newResultNotifier(appconfig.AgentInfo{}))
+	notifier := newResultNotifier(appconfig.AgentInfo{LocalCommandOutbox: "outbox", LocalCommandNotifierTimeoutSeconds: 5})
+	assert.Equal(t, "outbox", notifier.outboxDir)
+	assert.Equal(t, 5*time.Second, notifier.timeout)
+}
+
+func TestIsCommandComplete(t *testing.T) {
+	for status, complete := range map[contracts.ResultStatus]bool{
+		contracts.ResultStatusInProgress:       false,
+		contracts.ResultStatusSuccessAndReboot: false,
+		contracts.ResultStatusSuccess:          true,
+		contracts.ResultStatusFailed:           true,
+		contracts.ResultStatusCancelled:        true,
+		contracts.ResultStatusTimedOut:         true,
+	} {
+		assert.Equal(t, complete, isCommandComplete(messageContracts.SendReplyPayload{DocumentStatus: status}), string(status))
+	}
+}
+
+func TestNotifyWritesOutbox(t *testing.T) {
+	outbox, err := ioutil.TempDir("", "outbox")
+	assert.Nil(t, err)
+	defer os.RemoveAll(outbox)
+	notifier := &resultNotifier{outboxDir: outbox}
+
+	notifier.notify(logger, "cmd-1", "Hello", newTestReply(contracts.ResultStatusInProgress), "")
+	assert.Equal(t, 0, FileCount(outbox))
+
+	notifier.notify(logger, "cmd-1", "Hello", newTestReply(contracts.ResultStatusFailed), "")
+	files, _ := fileutil.GetFileNames(outbox)
+	assert.Equal(t, []string{"cmd-1.json"}, files)
+	var result OutboxResult
+	assert.Nil(t, jsonutil.UnmarshalFile(filepath.Join(outbox, "cmd-1.json"), &result))
+	assert.Equal(t, OutboxResult{
+		CommandID:    "cmd-1",
+		DocumentName: "Hello",
+		Status:       "Failed",
+		CompletedAt:  "2017-10-12T10:00:02.000Z",
+		Steps: []OutboxResultStep{
+			{Name: "first", Status: "Success", StartedAt: "2017-10-12T10:00:00.000Z", StandardOutput: "hello"},
+			{Name: "second", Status: "Failed", ExitCode: 1, StartedAt: "2017-10-12T10:00:01.000Z", StandardError: "oops"},
+		},
+	}, result)
+}
+
+func TestNotifyRunsCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the notifier command is a shell script")
+	}
+	outbox, err := ioutil.TempDir("", "outbox")
+	assert.Nil(t, err)
+	defer os.RemoveAll(outbox)
+	notified := filepath.Join(outbox, "notified")
+	notifier := &resultNotifier{
+		command: []string{"sh", "-c", `cp "$0" ` + notified},
+		timeout: 10 * time.Second,
+	}
+	resultPath := filepath.Join(outbox, "result")
+	reply := newTestReply(contracts.ResultStatusSuccess)
+	assert.Nil(t, fileutil.WriteAllText(resultPath, reply))
+
+	notifier.notify(logger, "cmd-1", "Hello", reply, resultPath)
+
+	for i := 0; i < 100 && !fileutil.Exists(notified); i++ {
+		time.Sleep(50 * time.Millisecond)
+	}
+	content, err := fileutil.ReadAllText(notified)
+	assert.Nil(t, err)
+	assert.Equal(t, reply, content)
+}
+
+func TestSendReplyNotifies(t *testing.T) {
+	outbox, err := ioutil.TempDir("", "outbox")
+	assert.Nil(t, err)
+	defer os.RemoveAll(outbox)
+	service := GetTestService().(*offlineService)
+	defer CleanTestDirs()
+	service.notifier = &resultNotifier{outboxDir: outbox}
+	commandID := "01234567-890a-bcde-f012-34567890abcd"
+	assert.Nil(t, fileutil.WriteAllText(filepath.Join(submittedCommands, "My.Document."+commandID), "{}"))
+
+	service.SendReply(logger, "aws.ssm."+commandID+".i-bar", newTestReply(contracts.ResultStatusSuccess))
+
+	var result OutboxResult
+	assert.Nil(t, jsonutil.UnmarshalFile(filepath.Join(outbox, commandID+".json"), &result))
+	assert.Equal(t, "My.Document", result.DocumentName)
+	assert.Equal(t, "Success", result.Status)
+}
@@ -15,15 +15,19 @@
 package service
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"errors"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docsignature"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -35,14 +39,27 @@ import (
 
 type offlineService struct {
 	TopicPrefix         string
+	CancelTopicPrefix   string
 	newCommandDir       string
 	submittedCommandDir string
 	commandResultDir    string
 	invalidCommandDir   string
+	cancelCommandDir    string
+
+	// cancelCommandIDs holds the ids given to cancel requests, their replies are not command results
+	cancelCommandIDs      map[string]bool
+	cancelCommandIDsMutex sync.Mutex
+
+	// notifier tells local tools about the completed commands, nil when none is configured
+	notifier *resultNotifier
+
+	// signatureRequired rejects the documents of the local command folder which are not signed with a key of trustedKeysDir
+	signatureRequired bool
+	trustedKeysDir    string
 }
 
 // NewOfflineService initializes a service that looks for work in a local command folder
-func NewOfflineService(log log.T, topicPrefix string) (Service, error) {
+func NewOfflineService(log log.T, topicPrefix string, cancelTopicPrefix string, agentConfig appconfig.AgentInfo) (Service, error) {
 	uuid.SwitchFormat(uuid.CleanHyphen)
 	// Create and harden local document folder if needed
 	err := fileutil.MakeDirs(appconfig.LocalCommandRoot)
@@ -53,10 +70,16 @@ func NewOfflineService(log log.T, topicPrefix string) (Service, error) {
 	err = fileutil.MakeDirs(appconfig.LocalCommandRootCompleted)
 	return &offlineService{
 		TopicPrefix:         topicPrefix,
+		CancelTopicPrefix:   cancelTopicPrefix,
 		newCommandDir:       appconfig.LocalCommandRoot,
 		submittedCommandDir: appconfig.LocalCommandRootSubmitted,
 		invalidCommandDir:   appconfig.LocalCommandRootInvalid,
 		commandResultDir:    appconfig.LocalCommandRootCompleted,
+		cancelCommandDir:    appconfig.LocalCommandRootCancel,
+		cancelCommandIDs:    make(map[string]bool),
+		notifier:            newResultNotifier(agentConfig),
+		signatureRequired:   docsignature.Required(agentConfig, true),
+		trustedKeysDir:      agentConfig.TrustedKeysDir,
 	}, err
 }
 
@@ -73,9 +96,14 @@ func (ols *offlineService) GetMessages(log log.T, instanceID string) (messages *
 	}
 	messages.Messages = make([]*ssmmds.Message, 0, len(filenames))
 	for _, filename := range filenames {
+		if strings.HasSuffix(filename, docsignature.SignatureExtension) {
+			// signatures are read along with the document they sign
+			continue
+		}
 		docName = filename
 		docPath = filepath.Join(ols.newCommandDir, docName)
 		log.Debugf("Found local command document %v | %v", docName, docPath)
+		signature := readSignature(log, docPath)
 
 		requestUuid := uuid.NewV4().String()
 		messages.MessagesRequestId = &requestUuid // TODO:MF: Can this be the same as the commandID?
@@ -85,20 +113,31 @@ func (ols *offlineService) GetMessages(log log.T, instanceID string) (messages *
 
 		// Parse file
 		var content contracts.DocumentContent
-		if errContent := jsonutil.UnmarshalFile(docPath, &content); errContent != nil {
+		rawContent, errContent := ioutil.ReadFile(docPath)
+		if errContent == nil {
+			errContent = json.Unmarshal(rawContent, &content)
+		}
+		if errContent != nil {
 			log.Errorf("Error parsing command document %v:\n%v", docName, errContent)
 			if errMove := moveCommandDocument(ols.newCommandDir, ols.invalidCommandDir, docName, commandID); errMove != nil {
 				log.Errorf("Command %v was invalid but failed to move to invalid folder: %v", commandID, errMove.Error())
 			}
 			continue
 		}
+		if errSignature := ols.verifySignature(rawContent, signature); errSignature != nil {
+			log.Errorf("Rejecting command document %v, %v", docName, errSignature)
+			if errMove := moveCommandDocument(ols.newCommandDir, ols.invalidCommandDir, docName, commandID); errMove != nil {
+				log.Errorf("Command %v was invalid but failed to move to invalid folder: %v", commandID, errMove.Error())
+			}
+			continue
+		}
 		debugContent, _ := jsonutil.Marshal(content)
 		log.Debugf("Local command content:\n%v", debugContent)
 
 		// Turn it into a message
-		payload := &messageContracts.SendCommandPayload{DocumentContent: content, CommandID: commandID, DocumentName: docName}
+		payload := &messageContracts.SendCommandPayload{DocumentContent: content, CommandID: commandID, DocumentName: docName, DocumentSignature: signature}
 		var payloadstr string
-		if payloadstr, err = jsonutil.Marshal(payload); err != nil {
+		if payloadstr, err = marshalSendCommandPayload(payload, rawContent); err != nil {
 			log.Errorf("Error marshalling message for command document %v with message ID %v:\n%v", docName, messageID, err)
 			if errMove := moveCommandDocument(ols.newCommandDir, ols.invalidCommandDir, docName, commandID); errMove != nil {
 				log.Errorf("Command %v was invalid but failed to move to invalid folder: %v", commandID, errMove.Error())
@@ -123,11 +162,163 @@ func (ols *offlineService) GetMessages(log log.T, instanceID string) (messages *
 		messages.Messages = append(messages.Messages, message)
 	}
 
+	// Look for requests to cancel commands submitted earlier
+	messages.Messages = append(messages.Messages, ols.getCancelMessages(log, instanceID)...)
+
 	debugMessages, _ := jsonutil.Marshal(messages)
 	log.Debugf("Local messages:\n%v", debugMessages)
 	return messages, nil
 }
 
+// readSignature returns the detached signature of a document of the local command folder, if any, the signature file
+// is removed once read as the signature is recorded with the command
+func readSignature(log log.T, docPath string) []byte {
+	signaturePath := docPath + docsignature.SignatureExtension
+	if !fileutil.Exists(signaturePath) {
+		return nil
+	}
+	signature, err := ioutil.ReadFile(signaturePath)
+	if err != nil {
+		log.Errorf("Failed to read the signature of command document %v: %v", docPath, err)
+	}
+	if err = fileutil.DeleteFile(signaturePath); err != nil {
+		log.Errorf("Failed to remove the signature of command document %v: %v", docPath, err)
+	}
+	return signature
+}
+
+// verifySignature checks the document is signed with a trusted key when signatures are required
+func (ols *offlineService) verifySignature(content []byte, signature []byte) error {
+	if !ols.signatureRequired {
+		return nil
+	}
+	keys, err := docsignature.LoadTrustedKeys(ols.trustedKeysDir)
+	if err != nil {
+		return fmt.Errorf("unable to load the trusted keys: %v", err)
+	}
+	return docsignature.Verify(content, signature, keys)
+}
+
+// marshalSendCommandPayload marshals the payload with the document content as it was submitted, rather than as it was
+// decoded, so that the signature of the document can be verified over it
+func marshalSendCommandPayload(payload *messageContracts.SendCommandPayload, rawContent []byte) (string, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(payloadJSON, &fields); err != nil {
+		return "", err
+	}
+	fields["DocumentContent"] = json.RawMessage(rawContent)
+	return jsonutil.Marshal(fields)
+}
+
+// getCancelMessages turns the cancel requests written by the cli into cancel command messages
+func (ols *offlineService) getCancelMessages(log log.T, instanceID string) []*ssmmds.Message {
+	messages := make([]*ssmmds.Message, 0)
+	if ols.cancelCommandDir == "" || !fileutil.Exists(ols.cancelCommandDir) {
+		return messages
+	}
+	filenames, err := fileutil.GetFileNames(ols.cancelCommandDir)
+	if err != nil {
+		log.Debugf("offlineservice: error: %v", err.Error())
+		return messages
+	}
+	for _, filename := range filenames {
+		// the cancel request is named after the command to cancel, it is consumed whether it is valid or not
+		targetCommandID := filename
+		if errDelete := fileutil.DeleteFile(filepath.Join(ols.cancelCommandDir, filename)); errDelete != nil {
+			log.Errorf("Failed to remove cancel request for command %v: %v", targetCommandID, errDelete)
+			continue
+		}
+		if len(targetCommandID) != 36 {
+			log.Errorf("Ignoring cancel request %v, name is not a command ID", filename)
+			continue
+		}
+		log.Debugf("Found cancel request for local command %v", targetCommandID)
+
+		message, err := ols.NewCancelCommandMessage(log, instanceID, targetCommandID)
+		if err != nil {
+			log.Errorf("Error creating cancel message for command %v:\n%v", targetCommandID, err)
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// NewSendCommandMessage creates the message running a document submitted by a local caller, the document
+// is recorded in the submitted folder like the documents dropped in the local command folder,
+// its signature is verified along with the document when the message is processed
+func (ols *offlineService) NewSendCommandMessage(log log.T, instanceID string, documentName string, rawContent []byte, parameters map[string]interface{}, signature []byte) (*ssmmds.Message, error) {
+	if len(signature) > 0 && len(parameters) > 0 {
+		return nil, docsignature.ErrParametersNotSigned
+	}
+	var content contracts.DocumentContent
+	if err := json.Unmarshal(rawContent, &content); err != nil {
+		return nil, fmt.Errorf("invalid document content, %v", err)
+	}
+	commandID := uuid.NewV4().String()
+	payload := &messageContracts.SendCommandPayload{
+		DocumentContent:   content,
+		Parameters:        parameters,
+		CommandID:         commandID,
+		DocumentName:      documentName,
+		DocumentSignature: signature,
+	}
+	payloadstr, err := marshalSendCommandPayload(payload, rawContent)
+	if err != nil {
+		return nil, err
+	}
+	if err = fileutil.MakeDirs(ols.submittedCommandDir); err != nil {
+		return nil, err
+	}
+	if err = fileutil.WriteAllText(filepath.Join(ols.submittedCommandDir, strings.Join([]string{documentName, commandID}, ".")), string(rawContent)); err != nil {
+		return nil, err
+	}
+	return newOfflineMessage(fmt.Sprintf("%v.%v", ols.TopicPrefix, documentName), commandID, instanceID, payloadstr), nil
+}
+
+// NewCancelCommandMessage creates the message cancelling a command submitted earlier, its own reply is not recorded
+func (ols *offlineService) NewCancelCommandMessage(log log.T, instanceID string, targetCommandID string) (*ssmmds.Message, error) {
+	commandID := uuid.NewV4().String()
+	payload := &messageContracts.CancelPayload{CancelMessageID: fmt.Sprintf("aws.ssm.%v.%v", targetCommandID, instanceID)}
+	payloadstr, err := jsonutil.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	ols.cancelCommandIDsMutex.Lock()
+	ols.cancelCommandIDs[commandID] = true
+	ols.cancelCommandIDsMutex.Unlock()
+	return newOfflineMessage(fmt.Sprintf("%v.%v", ols.CancelTopicPrefix, targetCommandID), commandID, instanceID, payloadstr), nil
+}
+
+// newOfflineMessage wraps a payload into a message as it would be received from MDS
+func newOfflineMessage(topic string, commandID string, instanceID string, payload string) *ssmmds.Message {
+	created := times.ToIso8601UTC(time.Now())
+	messageID := fmt.Sprintf("aws.ssm.%v.%v", commandID, instanceID)
+	return &ssmmds.Message{
+		CreatedDate: &created,
+		Destination: &instanceID,
+		MessageId:   &messageID,
+		Payload:     &payload,
+		Topic:       &topic,
+	}
+}
+
+// isCancelCommand returns true if the command was created for a cancel request, and forgets about it
+func (ols *offlineService) isCancelCommand(commandID string) bool {
+	ols.cancelCommandIDsMutex.Lock()
+	defer ols.cancelCommandIDsMutex.Unlock()
+	if ols.cancelCommandIDs[commandID] {
+		delete(ols.cancelCommandIDs, commandID)
+		return true
+	}
+	return false
+}
+
 // TODO:MF: clean up old documents in dstDir?  Or maybe do that in SendReply?  Maybe both
 // moveCommandDocument moves a command into its final destination and attaches the command ID file extension
 func moveCommandDocument(srcDir string, dstDir string, docName string, commandID string) error {
@@ -161,12 +352,31 @@ func (ols *offlineService) SendReply(log log.T, messageID string, payload string
 		log.Errorf("failed to parse messageID: %v", err)
 		return nil
 	}
-	if err := fileutil.WriteAllText(filepath.Join(ols.commandResultDir, commandID), payload); err != nil {
+	if ols.isCancelCommand(commandID) {
+		// the outcome of the cancel request shows in the result of the cancelled command
+		log.Debugf("not recording reply for cancel command %v", commandID)
+		return nil
+	}
+	resultPath := filepath.Join(ols.commandResultDir, commandID)
+	if err := fileutil.WriteAllText(resultPath, payload); err != nil {
 		log.Errorf("failed to write command %v result: %v", commandID, err)
+		return nil
+	}
+	if ols.notifier != nil {
+		ols.notifier.notify(log, commandID, ols.submittedDocumentName(commandID), payload, resultPath)
 	}
 	return nil
 }
 
+// submittedDocumentName returns the name of the document the command was submitted with
+func (ols *offlineService) submittedDocumentName(commandID string) string {
+	matches, _ := filepath.Glob(filepath.Join(ols.submittedCommandDir, "*."+commandID))
+	if len(matches) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(filepath.Base(matches[0]), "."+commandID)
+}
+
 func (ols *offlineService) FailMessage(log log.T, messageID string, failureType FailureType) error {
 	return nil
 }
@@ -15,11 +15,22 @@
 package service
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/docsignature"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,6 +41,7 @@ const (
 	submittedCommands = "testdata/new/submitted"
 	invalidCommands   = "testdata/new/invalid"
 	completeDir       = "testdata/new/completed"
+	cancelCommands    = "testdata/new/cancel"
 )
 
 func TestValid(t *testing.T) {
@@ -80,6 +92,50 @@ func TestBothVersions(t *testing.T) {
 	assert.Equal(t, 2, FileCount(submittedCommands))
 }
 
+func TestSignatureRequired(t *testing.T) {
+	keysDir, err := ioutil.TempDir("", "trustedkeys")
+	assert.Nil(t, err)
+	defer os.RemoveAll(keysDir)
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(keysDir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+
+	service := GetTestService().(*offlineService)
+	defer CleanTestDirs()
+	service.signatureRequired = true
+	service.trustedKeysDir = keysDir
+
+	// the signed document is submitted along with its signature, the unsigned document is rejected
+	content, err := ioutil.ReadFile(filepath.Join("testdata", "validcommand20.json"))
+	assert.Nil(t, err)
+	canonical, err := docsignature.Canonicalize(content)
+	assert.Nil(t, err)
+	signature := ed25519.Sign(privateKey, canonical)
+	assert.Nil(t, SubmitTestDoc("validcommand20.json"))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(newCommands, "validcommand20.json"+docsignature.SignatureExtension), signature, 0600))
+	assert.Nil(t, SubmitTestDoc("validcommand12.json"))
+
+	messages, err := service.GetMessages(logger, "i-bar")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages.Messages))
+	assert.Equal(t, 0, FileCount(newCommands))
+	assert.Equal(t, 1, FileCount(submittedCommands))
+	assert.Equal(t, 1, FileCount(invalidCommands))
+	var payload messageContracts.SendCommandPayload
+	assert.Nil(t, jsonutil.Unmarshal(*messages.Messages[0].Payload, &payload))
+	assert.Equal(t, "validcommand20.json", payload.DocumentName)
+	assert.Equal(t, signature, payload.DocumentSignature)
+	// the payload holds the document as it was signed, with its keys in the same order
+	var fields map[string]json.RawMessage
+	assert.Nil(t, json.Unmarshal([]byte(*messages.Messages[0].Payload), &fields))
+	var compacted bytes.Buffer
+	assert.Nil(t, json.Compact(&compacted, content))
+	assert.Equal(t, compacted.String(), string(fields["DocumentContent"]))
+}
+
 func TestOfflineService_SendReply(t *testing.T) {
 	service := GetTestService()
 	defer CleanTestDirs()
@@ -87,14 +143,68 @@ func TestOfflineService_SendReply(t *testing.T) {
 	assert.Equal(t, 1, FileCount(completeDir))
 }
 
+func TestCancel(t *testing.T) {
+	service := GetTestService()
+	defer CleanTestDirs()
+	targetCommandID := "01234567-890a-bcde-f012-34567890abcd"
+	assert.Nil(t, fileutil.WriteAllText(filepath.Join(cancelCommands, targetCommandID), ""))
+	assert.Nil(t, fileutil.WriteAllText(filepath.Join(cancelCommands, "notacommand"), ""))
+
+	messages, err := service.GetMessages(logger, "i-bar")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages.Messages))
+	assert.Equal(t, 0, FileCount(cancelCommands))
+	message := messages.Messages[0]
+	assert.Equal(t, "bar."+targetCommandID, *message.Topic)
+	var payload messageContracts.CancelPayload
+	assert.Nil(t, jsonutil.Unmarshal(*message.Payload, &payload))
+	assert.Equal(t, "aws.ssm."+targetCommandID+".i-bar", payload.CancelMessageID)
+
+	// replies to the cancel command are not recorded as command results
+	service.SendReply(logger, *message.MessageId, "payload")
+	assert.Equal(t, 0, FileCount(completeDir))
+}
+
+func TestNewSendCommandMessage(t *testing.T) {
+	service := GetTestService().(LocalCommandService)
+	defer CleanTestDirs()
+	content := []byte(`{"schemaVersion": "2.2", "futureField": 1}`)
+	parameters := map[string]interface{}{"commands": []string{"date"}}
+
+	message, err := service.NewSendCommandMessage(logger, "i-bar", "Local", content, parameters, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "foo.Local", *message.Topic)
+	assert.Equal(t, "i-bar", *message.Destination)
+	var payload messageContracts.SendCommandPayload
+	assert.Nil(t, jsonutil.Unmarshal(*message.Payload, &payload))
+	assert.Equal(t, "Local", payload.DocumentName)
+	assert.Equal(t, "2.2", payload.DocumentContent.SchemaVersion)
+	assert.Equal(t, "aws.ssm."+payload.CommandID+".i-bar", *message.MessageId)
+	submitted, err := ioutil.ReadFile(filepath.Join(submittedCommands, "Local."+payload.CommandID))
+	assert.Nil(t, err)
+	assert.Equal(t, string(content), string(submitted))
+
+	_, err = service.NewSendCommandMessage(logger, "i-bar", "Local", []byte("not json"), nil, nil)
+	assert.NotNil(t, err)
+
+	// the signature does not cover the parameters
+	_, err = service.NewSendCommandMessage(logger, "i-bar", "Local", content, parameters, []byte("signature"))
+	assert.Equal(t, docsignature.ErrParametersNotSigned, err)
+}
+
 func GetTestService() Service {
 	CleanTestDirs()
 	return &offlineService{
 		TopicPrefix:         "foo",
+		CancelTopicPrefix:   "bar",
 		newCommandDir:       newCommands,
 		submittedCommandDir: submittedCommands,
 		invalidCommandDir:   invalidCommands,
 		commandResultDir:    completeDir,
+		cancelCommandDir:    cancelCommands,
+		cancelCommandIDs:    make(map[string]bool),
 	}
 }
 
@@ -106,28 +216,36 @@ func SubmitTestDoc(name string) error {
 	}
 }
 
+// testPlaceholder keeps the test folders in git, CleanTestDirs leaves it in the folders
+const testPlaceholder = "dummy"
+
+// CleanTestDirs removes the files left by the tests and restores the placeholders they consumed
 func CleanTestDirs() {
 	var files []string
-	files, _ = fileutil.GetFileNames(submittedCommands)
-	for _, file := range files {
-		fileutil.DeleteFile(filepath.Join(submittedCommands, file))
-	}
-	files, _ = fileutil.GetFileNames(invalidCommands)
-	for _, file := range files {
-		fileutil.DeleteFile(filepath.Join(invalidCommands, file))
+	for _, dir := range []string{submittedCommands, invalidCommands, completeDir, cancelCommands} {
+		files, _ = fileutil.GetFileNames(dir)
+		for _, file := range files {
+			if file != testPlaceholder {
+				fileutil.DeleteFile(filepath.Join(dir, file))
+			}
+		}
+		fileutil.WriteAllText(filepath.Join(dir, testPlaceholder), "placeholder to ensure directory is created in git")
 	}
 	files, _ = fileutil.GetFileNames(newCommands)
 	for _, file := range files {
 		fileutil.DeleteFile(filepath.Join(newCommands, file))
 	}
-	files, _ = fileutil.GetFileNames(completeDir)
-	for _, file := range files {
-		fileutil.DeleteFile(filepath.Join(completeDir, file))
-	}
 }
 
+// FileCount returns the number of files in the folder, its placeholder excluded
 func FileCount(path string) int {
 	var files []string
 	files, _ = fileutil.GetFileNames(path)
-	return len(files)
+	count := 0
+	for _, file := range files {
+		if file != testPlaceholder {
+			count++
+		}
+	}
+	return count
 }
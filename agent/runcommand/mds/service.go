@@ -58,6 +58,12 @@ type Service interface {
 	Stop()
 }
 
+// LocalCommandService is implemented by the services which accept commands from local callers.
+type LocalCommandService interface {
+	NewSendCommandMessage(log log.T, instanceID string, documentName string, content []byte, parameters map[string]interface{}, signature []byte) (*ssmmds.Message, error)
+	NewCancelCommandMessage(log log.T, instanceID string, commandID string) (*ssmmds.Message, error)
+}
+
 // sdkService is an service wrapper that delegates to the ssm sdk.
 type sdkService struct {
 	sdk         *ssmmds.SSMMDS
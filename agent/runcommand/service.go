@@ -23,9 +23,11 @@ import (
 	associationProcessor "github.com/aws/amazon-ssm-agent/agent/association/processor"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/localapi"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
@@ -90,6 +92,7 @@ type RunCommandService struct {
 	processorStopPolicy *sdkutil.StopPolicy
 	pollAssociations    bool
 	processor           processor.Processor
+	localApi            *localapi.Server
 }
 
 // NewOfflineProcessor initialize a new offline command document processor
@@ -98,12 +101,19 @@ func NewOfflineService(context context.T) (*RunCommandService, error) {
 	log := messageContext.Log()
 
 	log.Debug("Creating offline command document service")
-	offlineService, err := newOfflineService(log)
+	offlineService, err := newOfflineService(log, context.AppConfig())
 	if err != nil {
 		return nil, err
 	}
 
-	return NewService(messageContext, offlineName, offlineService, 1, 1, false, []contracts.DocumentType{contracts.SendCommandOffline, contracts.CancelCommandOffline}), nil
+	// without the mds service, the offline service polls the local associations
+	pollAssoc := context.AppConfig().Agent.OfflineMode
+	service := NewService(messageContext, offlineName, offlineService, 1, 1, pollAssoc, []contracts.DocumentType{contracts.SendCommandOffline, contracts.CancelCommandOffline})
+	if service != nil && context.AppConfig().Agent.LocalApiEnabled {
+		instanceID := service.config.InstanceID
+		service.localApi = localapi.NewServer(log, appconfig.LocalApiSocketPath, instanceID, docmanager.DocumentStateDir(instanceID, ""), service)
+	}
+	return service, nil
 }
 
 // NewMdsProcessor initializes a new mds processor with the given parameters.
@@ -206,8 +216,8 @@ func processSendReply(log log.T, messageID string, mdsService mdsService.Service
 	}
 }
 
-var newOfflineService = func(log log.T) (mdsService.Service, error) {
-	return mdsService.NewOfflineService(log, string(SendCommandTopicPrefixOffline))
+var newOfflineService = func(log log.T, config appconfig.SsmagentConfig) (mdsService.Service, error) {
+	return mdsService.NewOfflineService(log, string(SendCommandTopicPrefixOffline), string(CancelCommandTopicPrefixOffline), config.Agent)
 }
 
 var newMdsService = func(config appconfig.SsmagentConfig) mdsService.Service {
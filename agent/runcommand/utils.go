@@ -26,6 +26,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/docparser"
+	"github.com/aws/amazon-ssm-agent/agent/docsignature"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
@@ -60,6 +61,15 @@ func validate(msg *ssmmds.Message) error {
 	return nil
 }
 
+// trustedDocumentName returns the name of the documents delivered by the service, the name of the offline commands is
+// chosen by their local caller and does not identify their content
+func trustedDocumentName(documentType contracts.DocumentType, documentName string) string {
+	if documentType != contracts.SendCommand {
+		return ""
+	}
+	return documentName
+}
+
 // newDocumentInfo initializes new DocumentInfo object
 func newDocumentInfo(msg ssmmds.Message, parsedMsg messageContracts.SendCommandPayload) contracts.DocumentInfo {
 
@@ -134,6 +144,13 @@ func parseSendCommandMessage(context context.T, msg *ssmmds.Message, messagesOrc
 		log.Errorf(errorMsg)
 		return nil, fmt.Errorf("%v", errorMsg)
 	}
+	// the signature of the document is verified over its content as received
+	var rawMessage struct {
+		DocumentContent json.RawMessage
+	}
+	if err = json.Unmarshal([]byte(*msg.Payload), &rawMessage); err != nil {
+		return nil, err
+	}
 
 	// adapt plugin configuration format from MDS to plugin expected format
 	s3KeyPrefix := path.Join(parsedMessage.OutputS3KeyPrefix, parsedMessage.CommandID, *msg.Destination)
@@ -149,10 +166,21 @@ func parseSendCommandMessage(context context.T, msg *ssmmds.Message, messagesOrc
 	documentInfo := newDocumentInfo(*msg, parsedMessage)
 	parserInfo := docparser.DocumentParserInfo{
 		OrchestrationDir: messageOrchestrationDirectory,
+		DocumentName:     trustedDocumentName(documentType, documentInfo.DocumentName),
 		S3Bucket:         parsedMessage.OutputS3BucketName,
 		S3Prefix:         s3KeyPrefix,
 		MessageId:        documentInfo.MessageID,
 		DocumentId:       documentInfo.DocumentID,
+		S3Encryption: contracts.S3EncryptionConfiguration{
+			ServerSideEncryption: parsedMessage.OutputS3ServerSideEncryption,
+			KmsKeyId:             parsedMessage.OutputS3KmsKeyId,
+		},
+		CloudWatchConfig:  newCloudWatchConfig(parsedMessage, *msg.Destination),
+		OutputSinks:       parsedMessage.OutputSinks,
+		SignatureRequired: docsignature.Required(context.AppConfig().Agent, documentType == contracts.SendCommandOffline),
+		Signature:         parsedMessage.DocumentSignature,
+		RawContent:        rawMessage.DocumentContent,
+		TrustedKeysDir:    context.AppConfig().Agent.TrustedKeysDir,
 	}
 
 	//Data format persisted in Current Folder is defined by the struct - CommandState
@@ -215,3 +243,19 @@ func isUpdatePlugin(plugins map[string]*contracts.PluginResult) bool {
 	}
 	return false
 }
+
+// newCloudWatchConfig returns where the output of the command is streamed in CloudWatch Logs, one log stream
+// per command, instance and plugin output
+func newCloudWatchConfig(parsedMessage messageContracts.SendCommandPayload, instanceID string) contracts.CloudWatchConfiguration {
+	if !parsedMessage.CloudWatchOutputEnabled {
+		return contracts.CloudWatchConfiguration{}
+	}
+	logGroupName := parsedMessage.CloudWatchLogGroupName
+	if logGroupName == "" {
+		logGroupName = defaultCloudWatchLogGroupPrefix + parsedMessage.DocumentName
+	}
+	return contracts.CloudWatchConfiguration{
+		LogGroupName:    logGroupName,
+		LogStreamPrefix: path.Join(parsedMessage.CommandID, instanceID),
+	}
+}
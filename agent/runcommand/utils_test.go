@@ -0,0 +1,43 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runcommand implements runcommand core processing module
+package runcommand
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCloudWatchConfig(t *testing.T) {
+	payload := messageContracts.SendCommandPayload{CommandID: "cmd-1", DocumentName: "AWS-RunShellScript"}
+	assert.Equal(t, contracts.CloudWatchConfiguration{}, newCloudWatchConfig(payload, "i-1"))
+
+	payload.CloudWatchOutputEnabled = true
+	assert.Equal(t, contracts.CloudWatchConfiguration{
+		LogGroupName:    "/aws/ssm/AWS-RunShellScript",
+		LogStreamPrefix: "cmd-1/i-1",
+	}, newCloudWatchConfig(payload, "i-1"))
+
+	payload.CloudWatchLogGroupName = "commands"
+	assert.Equal(t, "commands", newCloudWatchConfig(payload, "i-1").LogGroupName)
+}
+
+func TestTrustedDocumentName(t *testing.T) {
+	assert.Equal(t, "AWS-RunShellScript", trustedDocumentName(contracts.SendCommand, "AWS-RunShellScript"))
+	// the offline commands are named by their local caller
+	assert.Equal(t, "", trustedDocumentName(contracts.SendCommandOffline, "AWS-RunShellScript"))
+}
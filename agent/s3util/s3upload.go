@@ -0,0 +1,191 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package s3util
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// uploadPartSize is the size of the parts of the multipart uploads, smaller files are uploaded in a single request
+	uploadPartSize = 8 * 1024 * 1024
+	// uploadRetries is the number of times a part is uploaded again after a failure
+	uploadRetries = 3
+	// uploadRetryDelay is the delay before the first retry of a part, it doubles with each retry
+	uploadRetryDelay = time.Second
+
+	uploadContentType = "text/plain"
+)
+
+// s3Upload uploads one file to S3, sending the MD5 of each request body for S3 to verify it
+// and checking the ETag S3 returns when it is the MD5 of the content
+type s3Upload struct {
+	*AmazonS3Util
+	log        log.T
+	bucketName string
+	objectKey  string
+	encryption contracts.S3EncryptionConfiguration
+}
+
+// putObject uploads a file small enough to be uploaded in a single request
+func (upload s3Upload) putObject(file *os.File, size int64) error {
+	content := make([]byte, size)
+	if _, err := io.ReadFull(file, content); err != nil {
+		return fmt.Errorf("failed to read %v: %v", file.Name(), err)
+	}
+	sum := md5.Sum(content)
+
+	return upload.withRetries("the file", func() error {
+		input := &s3.PutObjectInput{
+			Bucket:        aws.String(upload.bucketName),
+			Key:           aws.String(upload.objectKey),
+			Body:          bytes.NewReader(content),
+			ContentLength: aws.Int64(size),
+			ContentMD5:    aws.String(base64.StdEncoding.EncodeToString(sum[:])),
+			ContentType:   aws.String(uploadContentType),
+		}
+		if upload.encryption.ServerSideEncryption != "" {
+			input.ServerSideEncryption = aws.String(upload.encryption.ServerSideEncryption)
+		}
+		if upload.encryption.KmsKeyId != "" {
+			input.SSEKMSKeyId = aws.String(upload.encryption.KmsKeyId)
+		}
+		output, err := upload.client.PutObject(input)
+		if err != nil {
+			return err
+		}
+		return upload.verifyETag(output.ETag, sum[:])
+	})
+}
+
+// multipartUpload uploads a large file in parts, the upload is aborted when a part fails after its retries
+func (upload s3Upload) multipartUpload(file *os.File, size int64) (err error) {
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(upload.bucketName),
+		Key:         aws.String(upload.objectKey),
+		ContentType: aws.String(uploadContentType),
+	}
+	if upload.encryption.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(upload.encryption.ServerSideEncryption)
+	}
+	if upload.encryption.KmsKeyId != "" {
+		input.SSEKMSKeyId = aws.String(upload.encryption.KmsKeyId)
+	}
+	created, err := upload.client.CreateMultipartUpload(input)
+	if err != nil {
+		return err
+	}
+	uploadID := created.UploadId
+	defer func() {
+		if err != nil {
+			upload.abort(uploadID)
+		}
+	}()
+
+	var parts []*s3.CompletedPart
+	buffer := make([]byte, upload.partSize)
+	for partNumber, offset := int64(1), int64(0); offset < size; partNumber, offset = partNumber+1, offset+upload.partSize {
+		partSize := upload.partSize
+		if size-offset < partSize {
+			partSize = size - offset
+		}
+		content := buffer[:partSize]
+		if _, err = file.ReadAt(content, offset); err != nil {
+			return fmt.Errorf("failed to read %v: %v", file.Name(), err)
+		}
+		sum := md5.Sum(content)
+
+		var etag *string
+		err = upload.withRetries(fmt.Sprintf("part %v", partNumber), func() error {
+			output, err := upload.client.UploadPart(&s3.UploadPartInput{
+				Bucket:        aws.String(upload.bucketName),
+				Key:           aws.String(upload.objectKey),
+				UploadId:      uploadID,
+				PartNumber:    aws.Int64(partNumber),
+				Body:          bytes.NewReader(content),
+				ContentLength: aws.Int64(partSize),
+				ContentMD5:    aws.String(base64.StdEncoding.EncodeToString(sum[:])),
+			})
+			if err != nil {
+				return err
+			}
+			etag = output.ETag
+			return upload.verifyETag(output.ETag, sum[:])
+		})
+		if err != nil {
+			return err
+		}
+		parts = append(parts, &s3.CompletedPart{ETag: etag, PartNumber: aws.Int64(partNumber)})
+	}
+
+	return upload.withRetries("the completion of the upload", func() error {
+		_, err := upload.client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(upload.bucketName),
+			Key:             aws.String(upload.objectKey),
+			UploadId:        uploadID,
+			MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+		})
+		return err
+	})
+}
+
+// abort aborts a multipart upload so that S3 drops its parts
+func (upload s3Upload) abort(uploadID *string) {
+	if _, err := upload.client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(upload.bucketName),
+		Key:      aws.String(upload.objectKey),
+		UploadId: uploadID,
+	}); err != nil {
+		upload.log.Warnf("Failed to abort the upload to s3://%v/%v: %v", upload.bucketName, upload.objectKey, err)
+	}
+}
+
+// verifyETag checks that the ETag returned by S3 is the MD5 of the uploaded content,
+// the ETag of the content encrypted with SSE-KMS is not its MD5 and is not checked
+func (upload s3Upload) verifyETag(etag *string, sum []byte) error {
+	if upload.encryption.ServerSideEncryption == appconfig.S3ServerSideEncryptionKMS || etag == nil {
+		return nil
+	}
+	if expected := hex.EncodeToString(sum); strings.Trim(*etag, "\"") != expected {
+		return fmt.Errorf("the uploaded content does not match, expected MD5 %v but S3 returned ETag %v", expected, *etag)
+	}
+	return nil
+}
+
+// withRetries calls upload until it succeeds, or fails after the retries, waiting longer before each retry
+func (upload s3Upload) withRetries(description string, uploadFunc func() error) (err error) {
+	delay := upload.retryDelay
+	for retry := 0; ; retry++ {
+		if err = uploadFunc(); err == nil || retry >= upload.retries {
+			return err
+		}
+		upload.log.Warnf("Failed to upload %v to s3://%v/%v, retrying in %v: %v", description, upload.bucketName, upload.objectKey, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package s3util
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeS3 is a local stand-in for the S3 api used by the uploads, it verifies the Content-MD5 of the bodies
+// like S3 does. Failures can be injected for the requests named "put", "create", "part<N>" and "complete"
+type fakeS3 struct {
+	lock      sync.Mutex
+	objects   map[string][]byte
+	headers   map[string]http.Header
+	parts     map[int][]byte
+	created   http.Header
+	aborted   bool
+	aclSet    bool
+	failures  map[string]int
+	wrongETag map[string]int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		objects:   make(map[string][]byte),
+		headers:   make(map[string]http.Header),
+		parts:     make(map[int][]byte),
+		failures:  make(map[string]int),
+		wrongETag: make(map[string]int),
+	}
+}
+
+type completedUpload struct {
+	Parts []struct {
+		ETag       string
+		PartNumber int
+	} `xml:"Part"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	query := r.URL.Query()
+	body, _ := ioutil.ReadAll(r.Body)
+
+	var name string
+	switch {
+	case r.Method == "PUT" && query.Get("acl") == "" && query.Get("partNumber") == "" && !strings.Contains(r.URL.RawQuery, "acl"):
+		name = "put"
+	case r.Method == "POST" && strings.Contains(r.URL.RawQuery, "uploads"):
+		name = "create"
+	case r.Method == "PUT" && query.Get("partNumber") != "":
+		name = "part" + query.Get("partNumber")
+	case r.Method == "POST" && query.Get("uploadId") != "":
+		name = "complete"
+	}
+	if f.failures[name] > 0 {
+		f.failures[name]--
+		writeS3Error(w, http.StatusInternalServerError, "InternalError")
+		return
+	}
+	if contentMD5 := r.Header.Get("Content-MD5"); name == "put" || strings.HasPrefix(name, "part") {
+		sum := md5.Sum(body)
+		if contentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+			writeS3Error(w, http.StatusBadRequest, "BadDigest")
+			return
+		}
+		etag := hex.EncodeToString(sum[:])
+		if f.wrongETag[name] > 0 {
+			f.wrongETag[name]--
+			etag = "0123456789abcdef"
+		}
+		w.Header().Set("ETag", fmt.Sprintf("%q", etag))
+	}
+
+	switch {
+	case strings.Contains(r.URL.RawQuery, "acl"):
+		f.aclSet = true
+	case name == "put":
+		f.objects[key] = body
+		f.headers[key] = r.Header
+	case name == "create":
+		f.created = r.Header
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><Key>%v</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>", key)
+	case strings.HasPrefix(name, "part"):
+		var partNumber int
+		fmt.Sscan(query.Get("partNumber"), &partNumber)
+		f.parts[partNumber] = body
+	case name == "complete":
+		var completed completedUpload
+		if err := xml.Unmarshal(body, &completed); err != nil {
+			writeS3Error(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		sort.Slice(completed.Parts, func(i, j int) bool { return completed.Parts[i].PartNumber < completed.Parts[j].PartNumber })
+		var content []byte
+		for _, part := range completed.Parts {
+			sum := md5.Sum(f.parts[part.PartNumber])
+			if strings.Trim(part.ETag, "\"") != hex.EncodeToString(sum[:]) {
+				writeS3Error(w, http.StatusBadRequest, "InvalidPart")
+				return
+			}
+			content = append(content, f.parts[part.PartNumber]...)
+		}
+		f.objects[key] = content
+		f.headers[key] = f.created
+		fmt.Fprintf(w, "<CompleteMultipartUploadResult><Key>%v</Key><ETag>\"multipart\"</ETag></CompleteMultipartUploadResult>", key)
+	case r.Method == "DELETE":
+		f.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%v</Code><Message>%v</Message></Error>", code, code)
+}
+
+// newTestS3Util returns an s3 util sending its requests to the server, the sdk does not retry by itself
+func newTestS3Util(server *httptest.Server) *AmazonS3Util {
+	config := &aws.Config{
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		MaxRetries:       aws.Int(0),
+	}
+	return &AmazonS3Util{
+		client:   s3.New(session.New(config)),
+		partSize: 1024,
+		retries:  2,
+	}
+}
+
+// newTestLog returns a mock log which also accepts the warnings logged for retried requests
+func newTestLog() *log.Mock {
+	testLog := log.NewMockLog()
+	testLog.On("Warnf", mock.Anything, mock.Anything).Return(nil)
+	return testLog
+}
+
+func writeTestFile(t *testing.T, size int) (string, []byte) {
+	dir, err := ioutil.TempDir("", "s3util")
+	assert.NoError(t, err)
+	content := make([]byte, size)
+	rand.Read(content)
+	path := filepath.Join(dir, "stdout")
+	assert.NoError(t, ioutil.WriteFile(path, content, 0600))
+	return path, content
+}
+
+func TestS3UploadSingleRequest(t *testing.T) {
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	path, content := writeTestFile(t, 1000)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	// the etag returned once does not match the content, the upload is retried
+	fake.wrongETag["put"] = 1
+	u := newTestS3Util(server)
+	err := u.S3UploadWithEncryption(newTestLog(), "bucket", "prefix/stdout", path, contracts.S3EncryptionConfiguration{
+		ServerSideEncryption: appconfig.S3ServerSideEncryptionAES256,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, content, fake.objects["bucket/prefix/stdout"])
+	assert.Equal(t, "AES256", fake.headers["bucket/prefix/stdout"].Get("x-amz-server-side-encryption"))
+	assert.Equal(t, "text/plain", fake.headers["bucket/prefix/stdout"].Get("Content-Type"))
+	assert.True(t, fake.aclSet)
+}
+
+func TestS3UploadDefaultEncryption(t *testing.T) {
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	path, content := writeTestFile(t, 3000)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	// kms encrypted objects do not have the md5 of their content as etag, the parts are not verified against it
+	u := newTestS3Util(server)
+	u.encryption = contracts.S3EncryptionConfiguration{ServerSideEncryption: appconfig.S3ServerSideEncryptionKMS, KmsKeyId: "alias/ssm"}
+	assert.NoError(t, u.S3Upload(newTestLog(), "bucket", "stdout", path))
+	assert.Equal(t, content, fake.objects["bucket/stdout"])
+	assert.Equal(t, "aws:kms", fake.headers["bucket/stdout"].Get("x-amz-server-side-encryption"))
+	assert.Equal(t, "alias/ssm", fake.headers["bucket/stdout"].Get("x-amz-server-side-encryption-aws-kms-key-id"))
+}
+
+func TestS3UploadMultipart(t *testing.T) {
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	path, content := writeTestFile(t, 3000)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	// the second part fails twice before it succeeds, the last part is once returned with a wrong etag
+	fake.failures["part2"] = 2
+	fake.wrongETag["part3"] = 1
+	u := newTestS3Util(server)
+	err := u.S3UploadWithEncryption(newTestLog(), "bucket", "stdout", path, contracts.S3EncryptionConfiguration{
+		ServerSideEncryption: appconfig.S3ServerSideEncryptionAES256,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(fake.parts))
+	assert.True(t, bytes.Equal(content, fake.objects["bucket/stdout"]))
+	assert.Equal(t, "AES256", fake.headers["bucket/stdout"].Get("x-amz-server-side-encryption"))
+	assert.False(t, fake.aborted)
+}
+
+func TestS3UploadMultipartAbortsFailedUpload(t *testing.T) {
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	path, _ := writeTestFile(t, 3000)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	fake.failures["part2"] = 3
+	u := newTestS3Util(server)
+	err := u.S3Upload(newTestLog(), "bucket", "stdout", path)
+
+	assert.Error(t, err)
+	assert.True(t, fake.aborted)
+	assert.Empty(t, fake.objects)
+	assert.False(t, fake.aclSet)
+}
@@ -22,13 +22,14 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 )
 
 const (
@@ -44,7 +45,14 @@ type IAmazonS3Util interface {
 }
 
 type AmazonS3Util struct {
-	myUploader *s3manager.Uploader
+	client s3iface.S3API
+	// encryption is the server-side encryption of the uploads which do not set their own
+	encryption contracts.S3EncryptionConfiguration
+	// partSize is the size of the parts of the multipart uploads, smaller files are uploaded in a single request
+	partSize int64
+	// retries is the number of times a part, or a file uploaded in a single request, is uploaded again after a failure
+	retries    int
+	retryDelay time.Duration
 }
 
 func NewAmazonS3Util(log log.T, bucketName string) *AmazonS3Util {
@@ -73,12 +81,25 @@ func NewAmazonS3Util(log log.T, bucketName string) *AmazonS3Util {
 	config.Region = &bucketRegion
 
 	return &AmazonS3Util{
-		myUploader: s3manager.NewUploader(session.New(config)),
+		client: s3.New(session.New(config)),
+		encryption: contracts.S3EncryptionConfiguration{
+			ServerSideEncryption: appConfig.S3.ServerSideEncryption,
+			KmsKeyId:             appConfig.S3.KmsKeyId,
+		},
+		partSize:   uploadPartSize,
+		retries:    uploadRetries,
+		retryDelay: uploadRetryDelay,
 	}
 }
 
-// S3Upload uploads a file to s3.
+// S3Upload uploads a file to s3 with the server-side encryption configured for the agent.
 func (u *AmazonS3Util) S3Upload(log log.T, bucketName string, objectKey string, filePath string) (err error) {
+	return u.S3UploadWithEncryption(log, bucketName, objectKey, filePath, contracts.S3EncryptionConfiguration{})
+}
+
+// S3UploadWithEncryption uploads a file to s3 with the given server-side encryption, or the one configured
+// for the agent when the encryption is not set. Large files are uploaded in parts, each part is retried on failure.
+func (u *AmazonS3Util) S3UploadWithEncryption(log log.T, bucketName string, objectKey string, filePath string, encryption contracts.S3EncryptionConfiguration) (err error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		log.Errorf("Failed to open file %v", err)
@@ -86,16 +107,32 @@ func (u *AmazonS3Util) S3Upload(log log.T, bucketName string, objectKey string,
 	}
 	defer file.Close()
 
+	fileInfo, err := file.Stat()
+	if err != nil {
+		log.Errorf("Failed to get file size %v", err)
+		return err
+	}
+	if encryption.ServerSideEncryption == "" {
+		encryption = u.encryption
+	}
+
 	log.Infof("Uploading %v to s3://%v/%v", filePath, bucketName, objectKey)
-	params := &s3manager.UploadInput{
-		Bucket:      aws.String(bucketName),
-		Key:         aws.String(objectKey),
-		Body:        file,
-		ContentType: aws.String("text/plain"),
+	upload := s3Upload{
+		AmazonS3Util: u,
+		log:          log,
+		bucketName:   bucketName,
+		objectKey:    objectKey,
+		encryption:   encryption,
 	}
-	if result, err := u.myUploader.Upload(params); err == nil {
-		log.Infof("Successfully uploaded file to ", result.Location)
-		if _, aclErr := u.myUploader.S3.PutObjectAcl(&s3.PutObjectAclInput{
+	if fileInfo.Size() <= u.partSize {
+		err = upload.putObject(file, fileInfo.Size())
+	} else {
+		err = upload.multipartUpload(file, fileInfo.Size())
+	}
+
+	if err == nil {
+		log.Infof("Successfully uploaded file to s3://%v/%v", bucketName, objectKey)
+		if _, aclErr := u.client.PutObjectAcl(&s3.PutObjectAclInput{
 			Bucket: aws.String(bucketName),
 			Key:    aws.String(objectKey),
 			ACL:    aws.String("bucket-owner-full-control"),
@@ -14,7 +14,11 @@
 // Package ssmparameterresolver contains types and methods for resolving SSM Parameter references.
 package ssmparameterresolver
 
-import "regexp"
+import (
+	"regexp"
+
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
+)
 
 const (
 	ssmNonSecurePrefix = "ssm:"
@@ -38,8 +42,10 @@ type SsmParameterInfo struct {
 }
 
 // ResolveOptions structure represents a set of options for the parameter resolution.
-// At this time it has only one flag IgnoreSecureParameters
 // if IgnoreSecureParameters == true the parameters prefixed with ssm-secure: will not be resolved.
+// if Redactor is set, the values of the resolved secure parameters are registered with it so that
+// they can be scrubbed from the document output and logs.
 type ResolveOptions struct {
 	IgnoreSecureParameters bool
+	Redactor               *redactor.Redactor
 }
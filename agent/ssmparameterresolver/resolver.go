@@ -15,10 +15,12 @@
 package ssmparameterresolver
 
 import (
+	"encoding/json"
 	"errors"
 	"regexp"
 	"strings"
 
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 )
 
@@ -45,6 +47,7 @@ func ExtractParametersFromText(
 		return nil, prefixValidationError
 	}
 
+	registerSecureParameters(parametersWithValues, options)
 	return parametersWithValues, nil
 }
 
@@ -78,6 +81,7 @@ func ResolveParameterReferenceList(
 		return nil, prefixValidationError
 	}
 
+	registerSecureParameters(parametersWithValues, options)
 	return parametersWithValues, nil
 }
 
@@ -102,6 +106,53 @@ func ResolveParametersInText(
 	return input, nil
 }
 
+// ResolveParametersInObject takes a json compatible object, resolves all parameters found in its string values according
+// to ResolveOptions and returns the resolved object.
+func ResolveParametersInObject(
+	service ISsmParameterService,
+	log log.T,
+	input interface{},
+	options ResolveOptions) (interface{}, error) {
+
+	text, err := jsonutil.Marshal(input)
+	if err != nil {
+		return input, err
+	}
+
+	resolvedParametersMap, err := ExtractParametersFromText(service, log, text, options)
+	if err != nil || resolvedParametersMap == nil || len(resolvedParametersMap) == 0 {
+		return input, err
+	}
+
+	for ref, param := range resolvedParametersMap {
+		// the value is substituted inside a json string, escape it the same way
+		escapedValue, err := json.Marshal(param.Value)
+		if err != nil {
+			return input, err
+		}
+		var placeholder = regexp.MustCompile("{{\\s*" + regexp.QuoteMeta(ref) + "\\s*}}")
+		text = placeholder.ReplaceAllLiteralString(text, string(escapedValue[1:len(escapedValue)-1]))
+	}
+
+	var output interface{}
+	if err = jsonutil.Unmarshal(text, &output); err != nil {
+		return input, err
+	}
+	return output, nil
+}
+
+// registerSecureParameters registers the values of the resolved secure parameters with the redactor of the options.
+func registerSecureParameters(resolvedParametersMap map[string]SsmParameterInfo, options ResolveOptions) {
+	if options.Redactor == nil {
+		return
+	}
+	for _, param := range resolvedParametersMap {
+		if param.Type == secureStringType {
+			options.Redactor.Register(param.Value)
+		}
+	}
+}
+
 func validateParameterReferencePrefix(resolvedParametersMap *map[string]SsmParameterInfo) error {
 	for key, value := range *resolvedParametersMap {
 		if strings.HasPrefix(key, ssmSecurePrefix) && value.Type != secureStringType {
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redactor"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -203,3 +204,48 @@ func TestResolveParametersInTextIgnoreSecureParams(t *testing.T) {
 	assert.NotNil(t, output)
 	assert.True(t, expectedOutput == output)
 }
+
+func TestResolveParametersInTextRegistersSecureParams(t *testing.T) {
+	serviceObject := newServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/a/b/c/param1": {Name: "/a/b/c/param1", Type: stringType, Value: "value_/a/b/c/param1"},
+		"ssm-secure:param2": {Name: "param2", Type: secureStringType, Value: "value_param2"},
+	})
+
+	log := log.Logger()
+	docRedactor := redactor.New()
+	text := "Some text {{ ssm:/a/b/c/param1}}, some more text {{ssm-secure:param2}}."
+	output, err := ResolveParametersInText(&serviceObject, log, text, ResolveOptions{
+		IgnoreSecureParameters: false,
+		Redactor:               docRedactor,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Some text value_/a/b/c/param1, some more text value_param2.", output)
+	assert.Equal(t, "Some text value_/a/b/c/param1, some more text ****.", docRedactor.Redact(output))
+}
+
+func TestResolveParametersInObject(t *testing.T) {
+	serviceObject := newServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm-secure:param2": {Name: "param2", Type: secureStringType, Value: `va"lue_$1`},
+	})
+
+	log := log.Logger()
+	docRedactor := redactor.New()
+	input := map[string]interface{}{
+		"id":         "0.aws:runShellScript",
+		"runCommand": []interface{}{"echo {{ssm-secure:param2}}", "date"},
+	}
+	output, err := ResolveParametersInObject(&serviceObject, log, input, ResolveOptions{
+		IgnoreSecureParameters: false,
+		Redactor:               docRedactor,
+	})
+
+	expectedOutput := map[string]interface{}{
+		"id":         "0.aws:runShellScript",
+		"runCommand": []interface{}{`echo va"lue_$1`, "date"},
+	}
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedOutput, output)
+	assert.Equal(t, "echo ****", docRedactor.Redact(`echo va"lue_$1`))
+}
@@ -135,7 +135,7 @@ func prepareRuntimeStatus(update *UpdateDetail) contracts.PluginRuntimeStatus {
 		code = 1
 	}
 
-	output := iohandler.TruncateOutput(update.StandardOut,
+	output := iohandler.DefaultOutputConfig().TruncateOutput(update.StandardOut,
 		update.StandardError,
 		iohandler.MaximumPluginOutputSize)
 